@@ -0,0 +1,44 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var changeset_StackName *string
+var changeset_ChangesetName *string
+
+// changesetCmd represents the changeset command
+var changesetCmd = &cobra.Command{
+	Use:   "changeset",
+	Short: "Work with the change sets of your stacks",
+	Long: `Changeset lets you inspect and manage the change sets of your CloudFormation stacks.
+
+For details see the subcommands.`,
+}
+
+func init() {
+	rootCmd.AddCommand(changesetCmd)
+	changeset_StackName = changesetCmd.PersistentFlags().StringP("stackname", "n", "", "The name for the stack")
+	changeset_ChangesetName = changesetCmd.PersistentFlags().StringP("changeset", "c", "", "The name of the change set")
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/cobra"
+)
+
+var changesetdelete_All *bool
+
+// changesetDeleteCmd represents the changeset delete command
+var changesetDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete change sets of a stack",
+	Long: `Deletes a change set of a stack.
+
+When a change set is provided with --changeset, that change set is deleted.
+Otherwise the most recently created change set of the stack is deleted. With
+--all, every change set that was created or has failed is deleted instead.
+
+Examples:
+
+$ fog changeset delete --stackname my-awesome-stack --changeset fog-2024-01-01T12-00-00
+$ fog changeset delete --stackname my-awesome-stack
+$ fog changeset delete --stackname my-awesome-stack --all
+`,
+	Run: deleteChangesets,
+}
+
+func init() {
+	changesetCmd.AddCommand(changesetDeleteCmd)
+	changesetdelete_All = changesetDeleteCmd.Flags().Bool("all", false, "Delete all change sets of the stack that were created or have failed")
+}
+
+func deleteChangesets(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *changeset_StackName == "" {
+		failWithError(fmt.Errorf("the stackname flag is required"))
+	}
+	if *changesetdelete_All && *changeset_ChangesetName != "" {
+		failWithError(fmt.Errorf("the all and changeset flags can't be used together"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	changesets, err := lib.ListChangesets(*changeset_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	selected, err := lib.SelectChangesetsForDeletion(changesets, *changeset_StackName, *changeset_ChangesetName, *changesetdelete_All)
+	if err != nil {
+		failWithError(err)
+	}
+	if len(selected) == 0 {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Stack %v doesn't have any change sets to delete", *changeset_StackName)))
+		return
+	}
+	deleted, failed := lib.DeleteChangesets(selected, svc)
+	for _, name := range deleted {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Deleted change set %v", name)))
+	}
+	if len(failed) != 0 {
+		names := make([]string, 0, len(failed))
+		for name := range failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Unable to delete change set %v: %v", name, failed[name])))
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var changesetexecute_Wait *bool
+var changesetexecute_Timeout *time.Duration
+
+// changesetExecuteCmd represents the changeset execute command
+var changesetExecuteCmd = &cobra.Command{
+	Use:   "execute",
+	Short: "Execute an existing change set",
+	Long: `Executes an existing change set of a stack.
+
+This allows you to create a change set in one step of your pipeline, for
+example with fog deploy --create-changeset, and execute it after it has been
+approved. Only change sets that are available for execution can be executed;
+change sets become obsolete when the stack was updated after they were created.
+
+With --wait, fog shows the events of the deployment until it's finished and
+exits with an error if the deployment failed.
+
+Examples:
+
+$ fog changeset execute --stackname my-awesome-stack --changeset fog-2024-01-01T12-00-00
+$ fog changeset execute --stackname my-awesome-stack --changeset fog-2024-01-01T12-00-00 --wait
+`,
+	Run: executeChangeset,
+}
+
+func init() {
+	changesetCmd.AddCommand(changesetExecuteCmd)
+	changesetexecute_Wait = changesetExecuteCmd.Flags().Bool("wait", false, "Wait until the deployment is finished")
+	changesetexecute_Timeout = changesetExecuteCmd.Flags().Duration("timeout", 0, "Stop waiting for the deployment after this duration (e.g. 30m). The deployment itself continues in CloudFormation")
+}
+
+func executeChangeset(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *changeset_StackName == "" || *changeset_ChangesetName == "" {
+		failWithError(fmt.Errorf("both the stackname and changeset flags are required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	deployment := lib.DeployInfo{StackName: *changeset_StackName, ChangesetName: *changeset_ChangesetName}
+	rawchangeset, err := deployment.GetChangeset(svc)
+	if err != nil {
+		failWithError(err)
+	}
+	changeset := deployment.AddChangeset(rawchangeset)
+	if err := changeset.CheckExecutable(); err != nil {
+		failWithError(err)
+	}
+	if err := changeset.DeployChangeset(svc); err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Unable to execute change set %v", changeset.Name)))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !*changesetexecute_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Change set %v is being executed", changeset.Name)))
+		return
+	}
+	followDeployment(deployment, awsConfig, *changesetexecute_Timeout)
+	stack, err := deployment.GetFreshStack(svc)
+	if err != nil {
+		failWithError(err)
+	}
+	switch stack.StackStatus {
+	case types.StackStatusCreateComplete, types.StackStatusUpdateComplete, types.StackStatusImportComplete:
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Change set %v has been deployed", changeset.Name)))
+	default:
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The deployment of change set %v failed, stack %v is in status %v", changeset.Name, deployment.StackName, stack.StackStatus)))
+		os.Exit(1)
+	}
+}
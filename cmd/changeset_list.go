@@ -0,0 +1,74 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+// changesetListCmd represents the changeset list command
+var changesetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the change sets of a stack",
+	Long: `Lists the change sets that currently exist for a stack, newest first.
+
+Examples:
+
+$ fog changeset list --stackname my-awesome-stack
+$ fog changeset list --stackname my-awesome-stack --output json
+`,
+	Run: listChangesets,
+}
+
+func init() {
+	changesetCmd.AddCommand(changesetListCmd)
+}
+
+func listChangesets(cmd *cobra.Command, args []string) {
+	if *changeset_StackName == "" {
+		failWithError(fmt.Errorf("the stackname flag is required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	changesets, err := lib.ListChangesets(*changeset_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	output := format.OutputArray{Keys: []string{"Name", "Status", "Execution status", "Created"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Change sets for stack %v", *changeset_StackName)
+	for _, changeset := range changesets {
+		output.AddContents(map[string]interface{}{
+			"Name":             changeset.Name,
+			"Status":           changeset.Status,
+			"Execution status": changeset.ExecutionStatus,
+			"Created":          formatStackTime(&changeset.CreationTime),
+		})
+	}
+	output.Write()
+}
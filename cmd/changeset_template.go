@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/cobra"
+)
+
+var changesettemplate_Format *string
+
+// changesetTemplateCmd represents the changeset template command
+var changesetTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Show the template a change set will deploy",
+	Long: `Shows the template that will be deployed when the change set is executed.
+
+This is the processed template, meaning that any transforms such as
+AWS::Serverless or macros have already been applied. By default the template
+is shown in the format it was provided in, but you can convert it using the
+--format flag.
+
+Examples:
+
+$ fog changeset template --stackname my-awesome-stack --changeset fog-2024-01-01T12-00-00
+$ fog changeset template --stackname my-awesome-stack --changeset fog-2024-01-01T12-00-00 --format json
+`,
+	Run: showChangesetTemplate,
+}
+
+func init() {
+	changesetCmd.AddCommand(changesetTemplateCmd)
+	changesettemplate_Format = changesetTemplateCmd.Flags().String("format", "", "Convert the template to this format, either yaml or json")
+}
+
+func showChangesetTemplate(cmd *cobra.Command, args []string) {
+	if *changeset_StackName == "" || *changeset_ChangesetName == "" {
+		failWithError(fmt.Errorf("both the stackname and changeset flags are required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	template, err := lib.GetTemplateBodyFromChangeset(*changeset_ChangesetName, *changeset_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	template, err = lib.ConvertTemplateFormat(template, *changesettemplate_Format)
+	if err != nil {
+		failWithError(err)
+	}
+	fmt.Println(template)
+}
@@ -71,7 +71,7 @@ func showDependencies(cmd *cobra.Command, args []string) {
 	if *dependencies_stackName != "" {
 		subtitle = fmt.Sprintf("Stacks filtered by for %v", *dependencies_stackName)
 	}
-	title := fmt.Sprintf("%v in account %v for region %v", subtitle, awsConfig.AccountID, awsConfig.Region)
+	title := fmt.Sprintf("%v in account %v for region %v", subtitle, formatAccountDisplay(awsConfig), awsConfig.Region)
 	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
 	output.Settings.Title = title
 	output.Settings.SortKey = "Stack"
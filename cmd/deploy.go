@@ -22,6 +22,8 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -66,8 +68,11 @@ Examples:
 
 var deploy_StackName *string
 var deploy_Template *string
+var deploy_TemplateUrl *string
 var deploy_Parameters *string
 var deploy_Bucket *string
+var deploy_Package *bool
+var deploy_S3Prefix *string
 var deploy_Tags *string
 var deploy_ChangesetName *string
 var deploy_Dryrun *bool
@@ -76,28 +81,110 @@ var deploy_CreateChangeset *bool
 var deploy_DeployChangeset *bool
 var deploy_DefaultTags *bool
 var deploy_DeploymentFile *string
+var deploy_RequiredTags *string
+var deploy_Environment *string
+var deploy_DiffTemplate *bool
+var deploy_SkipDestroy *bool
+var deploy_InheritParameters *bool
+var deploy_AssumeRole *string
+var deploy_RoleSessionName *string
+var deploy_RoleExternalId *string
+var deploy_BlockOnHighRisk *bool
+var deploy_DryRunReport *string
+var deploy_GenerateDeploymentFile *string
+var deploy_SkipIfUnchanged *bool
+var deploy_LastHashFile *string
+var deploy_SopsDecrypt *bool
+var deploy_Timeout *time.Duration
+var deploy_NotificationArns *string
+var deploy_ChangesetDescription *string
+var deploy_RoleArn *string
+var deploy_RollbackMonitoringTime *int32
+var deploy_RollbackAlarmArns *string
+var deploy_DetectDriftAfter *bool
+var deploy_SkipParameterValidation *bool
+var deploy_CostEstimate *bool
+var deploy_ParameterOverrides *[]string
+
+// dryRunReportOutput is where the dry run report is written to when using --dry-run-report -
+var dryRunReportOutput = os.Stdout
+var deploy_EventLogFile *string
+var deploy_RequireApproval *bool
+var deploy_ApprovalAPI *string
+var deploy_ApprovalAPIKey *string
 var deployment lib.DeployInfo
+var deployEventLog *lib.EventLogWriter
 
 func init() {
 	rootCmd.AddCommand(deployCmd)
 	deploy_StackName = deployCmd.Flags().StringP("stackname", "n", "", "The name for the stack")
 	deploy_Template = deployCmd.Flags().StringP("template", "f", "", "The filename for the template")
+	deploy_TemplateUrl = deployCmd.Flags().String("template-url", "", "The S3 URL of an already uploaded template, used instead of --template")
 	deploy_Parameters = deployCmd.Flags().StringP("parameters", "p", "", "The file(s) containing the parameter values, comma-separated for multiple")
+	deploy_ParameterOverrides = deployCmd.Flags().StringArray("parameter-overrides", []string{}, "Parameter values in the Key=Value format, overriding those from the parameter or deployment files. Can be provided multiple times")
+	deploy_InheritParameters = deployCmd.Flags().Bool("inherit-parameters", false, "Reuse the parameter values of the existing stack. Parameters from files and overrides take precedence")
 	deploy_Tags = deployCmd.Flags().StringP("tags", "t", "", "The file(s) containing the tags, comma-separated for multiple")
 	deploy_Bucket = deployCmd.Flags().StringP("bucket", "b", "", "The S3 bucket where the template should be uploaded to (optional)")
+	deploy_Package = deployCmd.Flags().Bool("package", false, "Upload local Lambda code and nested templates referenced by the template to the S3 bucket provided with --bucket")
+	deploy_S3Prefix = deployCmd.Flags().String("s3-prefix", "", "The prefix for the artifacts uploaded with --package, e.g. artifacts/")
 	deploy_ChangesetName = deployCmd.Flags().StringP("changeset", "c", "", "The name of the changeset, when not provided it will be autogenerated")
 	deploy_Dryrun = deployCmd.Flags().Bool("dry-run", false, "Do a dry run: create the changeset and immediately delete")
 	deploy_NonInteractive = deployCmd.Flags().Bool("non-interactive", false, "Run in non-interactive mode: automatically approve the changeset and deploy")
 	deploy_CreateChangeset = deployCmd.Flags().Bool("create-changeset", false, "Only create a change set")
 	deploy_DeployChangeset = deployCmd.Flags().Bool("deploy-changeset", false, "Deploy a specific change set")
 	deploy_DefaultTags = deployCmd.Flags().Bool("default-tags", true, "Add any default tags that are specified in your config file")
-	deploy_DeploymentFile = deployCmd.Flags().StringP("deployment-file", "d", "", "The file to use for the deployment")
+	deploy_DeploymentFile = deployCmd.Flags().StringP("deployment-file", "d", "", "The file to use for the deployment. Can be a local path, an S3 URL (s3://bucket/key), or an HTTPS URL")
+	deploy_RequiredTags = deployCmd.Flags().String("require-tag", "", "Comma-separated list of tags that need to have a value, additional to any configured in the config file")
+	deploy_Environment = deployCmd.Flags().StringP("environment", "e", "", "The environment to use from a deployment file with per-environment overrides")
+	deploy_RequireApproval = deployCmd.Flags().Bool("require-changeset-approval", false, "Require the change set to be approved through the approval API before it can be deployed")
+	deploy_ApprovalAPI = deployCmd.Flags().String("approval-api", "", "The URL of the approval API used with --require-changeset-approval")
+	deploy_ApprovalAPIKey = deployCmd.Flags().String("approval-api-key", "", "The name of the environment variable that contains the key for the approval API")
+	deploy_EventLogFile = deployCmd.Flags().String("event-log-file", "", "Write the events of the deployment as JSON lines to this file")
+	deploy_DryRunReport = deployCmd.Flags().String("dry-run-report", "", "Write the result of the dry run as JSON to this file. Use - to write it to stdout, in which case all other output goes to stderr")
+	deploy_SkipDestroy = deployCmd.Flags().Bool("skip-destroy", false, "Abort the deployment if the change set removes any resources")
+	deploy_BlockOnHighRisk = deployCmd.Flags().Bool("block-on-high-risk", false, "Abort the deployment if the change set removes or replaces any resources")
+	deploy_GenerateDeploymentFile = deployCmd.Flags().String("generate-deployment-file", "", "Write the provided template, parameters, and tags to this path as a deployment file instead of deploying")
+	deploy_SkipIfUnchanged = deployCmd.Flags().Bool("skip-if-unchanged", false, "Skip the deployment if the template, parameters, and tags haven't changed since the last successful deployment")
+	deploy_LastHashFile = deployCmd.Flags().String("last-hash-file", "", "The file storing the template hash of the last successful deployment. Defaults to .fog/template-hashes/<account>/<region>/<stackname>.sha256")
+	deploy_SopsDecrypt = deployCmd.Flags().Bool("sops-decrypt", false, "Decrypt the parameter, tag, or deployment files with SOPS before using them")
+	deploy_Timeout = deployCmd.Flags().Duration("timeout", 0, "Stop waiting for the deployment after this duration (e.g. 30m). The deployment itself continues in CloudFormation")
+	deploy_NotificationArns = deployCmd.Flags().String("notification-arns", "", "The ARNs of the SNS topics that receive the stack events, comma-separated for multiple")
+	deploy_ChangesetDescription = deployCmd.Flags().String("changeset-description", "", "The description of the change set, e.g. a reference to a ticket")
+	deploy_RoleArn = deployCmd.Flags().String("iam-role-arn", "", "The ARN of the service role CloudFormation uses to deploy the stack")
+	deploy_AssumeRole = deployCmd.Flags().String("assume-role", "", "The ARN of an IAM role to assume before deploying, e.g. to deploy into another account")
+	deploy_RoleSessionName = deployCmd.Flags().String("role-session-name", "", "The session name to use when assuming the role with --assume-role")
+	deploy_RoleExternalId = deployCmd.Flags().String("role-external-id", "", "The external ID to use when assuming the role with --assume-role")
+	deploy_RollbackMonitoringTime = deployCmd.Flags().Int32("rollback-monitoring-time", 0, "The number of minutes (up to 180) CloudFormation monitors the rollback alarms after the deployment")
+	deploy_RollbackAlarmArns = deployCmd.Flags().String("rollback-alarm-arns", "", "The ARNs of the CloudWatch alarms that roll back the deployment when they go into alarm, comma-separated for multiple")
+	deploy_DetectDriftAfter = deployCmd.Flags().Bool("detect-drift-after", false, "Run a drift detection after a successful deployment to record the baseline drift status")
+	deploy_SkipParameterValidation = deployCmd.Flags().Bool("skip-parameter-validation", false, "Don't check the parameter values against the constraints in the template before creating the change set")
+	deploy_CostEstimate = deployCmd.Flags().Bool("cost-estimate", false, "Show a link to the AWS Simple Monthly Calculator with the estimated cost of the template before creating the change set")
+	deploy_DiffTemplate = deployCmd.Flags().Bool("diff-template", false, "Show the differences between the deployed template and the new one before creating the change set")
 }
 
 func deployTemplate(cmd *cobra.Command, args []string) {
 	viper.Set("output", "table") //Enforce table output for deployments
 	outputsettings = settings.NewOutputSettings()
 	outputsettings.SeparateTables = true //Make table output stand out more
+	if *deploy_DryRunReport != "" {
+		if !*deploy_Dryrun {
+			fmt.Print(outputsettings.StringFailure("The --dry-run-report flag can only be used together with --dry-run"))
+			os.Exit(1)
+		}
+		if *deploy_DryRunReport == "-" {
+			// Keep stdout clean for the report by sending everything else to stderr
+			os.Stdout = os.Stderr
+		}
+	}
+	if *deploy_GenerateDeploymentFile != "" {
+		if *deploy_SopsDecrypt {
+			// Writing the file would store the decrypted values on disk
+			fmt.Print(outputsettings.StringFailure("The --generate-deployment-file flag can't be used together with --sops-decrypt"))
+			os.Exit(1)
+		}
+		generateDeploymentFile(*deploy_GenerateDeploymentFile)
+		return
+	}
 	deployment.StackName = *deploy_StackName
 	// Set the changeset name to what's provided, otherwise fall back on the generated value
 	deployment.ChangesetName = *deploy_ChangesetName
@@ -108,8 +195,17 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 	if err != nil {
 		failWithError(err)
 	}
-	if *deploy_DeploymentFile != "" && (*deploy_Template != "" || *deploy_Parameters != "" || *deploy_Tags != "") {
-		outputsettings.StringFailure("You can't provide a deployment file and other parameters at the same time")
+	assumeDeployRole(&awsConfig)
+	if *deploy_Template != "" && *deploy_TemplateUrl != "" {
+		fmt.Print(outputsettings.StringFailure("You can't provide both a template and a template URL"))
+		os.Exit(1)
+	}
+	if *deploy_DeploymentFile != "" && (*deploy_Template != "" || *deploy_TemplateUrl != "" || *deploy_Parameters != "" || *deploy_Tags != "") {
+		fmt.Print(outputsettings.StringFailure("You can't provide a deployment file and other parameters at the same time"))
+		os.Exit(1)
+	}
+	if *deploy_Environment != "" && *deploy_DeploymentFile == "" {
+		fmt.Print(outputsettings.StringFailure("The --environment flag can only be used together with a deployment file"))
 		os.Exit(1)
 	}
 	deployment.IsNew = deployment.IsNewStack(awsConfig.CloudformationClient())
@@ -117,6 +213,7 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 		if ready, status := deployment.IsReadyForUpdate(awsConfig.CloudformationClient()); !ready {
 			message := fmt.Sprintf("The stack '%v' is currently in status %v and can't be updated", *deploy_StackName, status)
 			fmt.Print(outputsettings.StringFailure(message))
+			showRemediationSteps(types.StackStatus(status))
 			os.Exit(1)
 		}
 	}
@@ -146,17 +243,64 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 		changeset := deployment.AddChangeset(rawchangeset)
 		deploymentLog.AddChangeSet(&changeset)
 		showChangeset(changeset, deployment, awsConfig)
+		if *deploy_SkipDestroy {
+			abortOnRemovals(changeset, deployment, awsConfig)
+		}
+		if *deploy_BlockOnHighRisk {
+			abortOnHighRisk(changeset, deployment, awsConfig)
+		}
 	} else {
 		if *deploy_DeploymentFile != "" {
-			err := deployment.LoadDeploymentFile(*deploy_DeploymentFile)
+			var err error
+			if *deploy_SopsDecrypt {
+				err = deployment.LoadEncryptedDeploymentFile(*deploy_DeploymentFile, *deploy_Environment, sopsDecryptor())
+			} else {
+				err = deployment.LoadDeploymentFile(*deploy_DeploymentFile, *deploy_Environment, awsConfig.S3Client())
+			}
 			if err != nil {
 				fmt.Print(outputsettings.StringFailure(err.Error()))
 				os.Exit(1)
 			}
 		}
 		setDeployTemplate(&deployment, awsConfig)
+		if *deploy_Package {
+			packageDeployTemplate(&deployment, awsConfig)
+		}
 		setDeployTags(&deployment)
+		checkRequiredTags(deployment)
 		setDeployParameters(&deployment)
+		if *deploy_InheritParameters {
+			inheritDeployParameters(&deployment, awsConfig)
+		}
+		if !*deploy_SkipParameterValidation {
+			validateDeployParameters(deployment)
+		}
+		if *deploy_SkipIfUnchanged {
+			skipIfTemplateUnchanged(deployment, awsConfig)
+		}
+		if *deploy_CostEstimate {
+			showCostEstimate(deployment, awsConfig)
+		}
+		deployment.NotificationARNs = lib.SelectNotificationARNs(*deploy_NotificationArns, deployment.StackDeploymentFile, viper.GetStringSlice("notifications.arns"))
+		deployment.ChangesetDescription, err = lib.SelectChangesetDescription(*deploy_ChangesetDescription, deployment.StackDeploymentFile, viper.GetString("changeset.description"))
+		if err != nil {
+			fmt.Print(outputsettings.StringFailure(err.Error()))
+			os.Exit(1)
+		}
+		deployment.RoleARN, err = lib.SelectRoleARN(*deploy_RoleArn, deployment.StackDeploymentFile, viper.GetString("deployment.role-arn"))
+		if err != nil {
+			fmt.Print(outputsettings.StringFailure(err.Error()))
+			os.Exit(1)
+		}
+		deployment.RollbackConfiguration, err = lib.SelectRollbackConfiguration(*deploy_RollbackMonitoringTime, *deploy_RollbackAlarmArns, deployment.StackDeploymentFile)
+		if err != nil {
+			fmt.Print(outputsettings.StringFailure(err.Error()))
+			os.Exit(1)
+		}
+		if warning := lib.RollbackConfigurationWarning(deployment.RollbackConfiguration); warning != "" {
+			fmt.Print(outputsettings.StringWarning(warning))
+		}
+		uploadDeployTemplate(&deployment, awsConfig)
 		if viper.GetStringSlice("templates.prechecks") != nil {
 			precheckmessage := fmt.Sprintf(string(texts.FilePrecheckStarted), len(viper.GetStringSlice("templates.prechecks")))
 			fmt.Print(outputsettings.StringInfo(precheckmessage))
@@ -184,10 +328,20 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 				fmt.Print(outputsettings.StringPositive(string(texts.FilePrecheckSuccess)))
 			}
 		}
-		changeset := createChangeset(&deployment, awsConfig)
+		if *deploy_DiffTemplate {
+			showTemplateDiff(deployment, awsConfig)
+		}
+		changeset := createChangeset(&deployment, &deploymentLog, awsConfig)
 		deploymentLog.AddChangeSet(changeset)
 		showChangeset(*changeset, deployment, awsConfig)
+		if *deploy_SkipDestroy {
+			abortOnRemovals(*changeset, deployment, awsConfig)
+		}
+		if *deploy_BlockOnHighRisk {
+			abortOnHighRisk(*changeset, deployment, awsConfig)
+		}
 		if *deploy_Dryrun {
+			writeDryRunReport(deployment, *changeset, awsConfig)
 			fmt.Print(outputsettings.StringSuccess(texts.DeployChangesetMessageDryrunSuccess))
 			deleteChangeset(deployment, awsConfig)
 			os.Exit(0)
@@ -198,6 +352,16 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 			os.Exit(0)
 		}
 	}
+	if *deploy_RequireApproval {
+		requestChangesetApproval(deployment, &deploymentLog, awsConfig)
+	}
+	if *deploy_EventLogFile != "" {
+		deployEventLog, err = lib.NewEventLogWriter(*deploy_EventLogFile)
+		if err != nil {
+			failWithError(err)
+		}
+		defer deployEventLog.Close()
+	}
 	var deployChangesetConfirmation bool
 	if *deploy_NonInteractive {
 		deployChangesetConfirmation = true
@@ -219,6 +383,9 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 	case types.StackStatusCreateComplete, types.StackStatusUpdateComplete:
 		deploymentLog.Success()
 		fmt.Print(outputsettings.StringSuccess(texts.DeployStackMessageSuccess))
+		if *deploy_SkipIfUnchanged && deployment.Template != "" {
+			storeTemplateHash(deployment, awsConfig)
+		}
 		if len(resultStack.Outputs) > 0 {
 			outputkeys := []string{"Key", "Value", "Description", "ExportName"}
 			outputtitle := fmt.Sprintf("Outputs for stack %v", *resultStack.StackName)
@@ -243,8 +410,12 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 			}
 			output.Write()
 		}
+		if *deploy_DetectDriftAfter || viper.GetBool("deployment.detect-drift-after") {
+			detectDriftAfterDeployment(deployment, awsConfig.CloudformationClient())
+		}
 	case types.StackStatusRollbackComplete, types.StackStatusRollbackFailed, types.StackStatusUpdateRollbackComplete, types.StackStatusUpdateRollbackFailed:
 		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageFailed))
+		showFailureReason(deployment, awsConfig)
 		failures := showFailedEvents(deployment, awsConfig)
 		deploymentLog.Failed(failures)
 		if deployment.IsNew {
@@ -254,6 +425,91 @@ func deployTemplate(cmd *cobra.Command, args []string) {
 	}
 }
 
+// detectDriftAfterDeployment runs a drift detection for the deployed stack and
+// shows the result. As the deployment itself succeeded, problems with the drift
+// detection are only shown as a warning.
+func detectDriftAfterDeployment(deployment lib.DeployInfo, svc lib.CloudFormationDetectStackDriftAPI) {
+	fmt.Print(outputsettings.StringInfo("Running drift detection for the deployed stack"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(viper.GetInt("drift.detection-timeout"))*time.Second)
+	defer cancel()
+	status, err := lib.DetectStackDrift(ctx, deployment.StackArn, svc)
+	if err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to detect drift for stack %v: %v", deployment.StackName, err)))
+		return
+	}
+	message := fmt.Sprintf("Drift status of stack %v: %v", deployment.StackName, status)
+	if status == types.StackDriftStatusDrifted {
+		fmt.Print(outputsettings.StringWarning(message))
+		return
+	}
+	fmt.Print(outputsettings.StringSuccess(message))
+}
+
+// showRemediationSteps shows the steps that can be taken to get a stack in the provided status ready for updates again
+func showRemediationSteps(status types.StackStatus) {
+	steps := lib.GetRemediationSteps(status)
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Print(outputsettings.StringBold("How to resolve this:"))
+	for _, step := range steps {
+		fmt.Printf("  - %v\n", step)
+	}
+	fmt.Println("")
+}
+
+// generateDeploymentFile writes the template, parameters, and tags provided as
+// flags to a deployment file at the provided path
+func generateDeploymentFile(path string) {
+	if *deploy_DeploymentFile != "" {
+		fmt.Print(outputsettings.StringFailure("The --generate-deployment-file flag can't be used together with a deployment file"))
+		os.Exit(1)
+	}
+	if *deploy_Template == "" {
+		fmt.Print(outputsettings.StringFailure("Please provide the template to use in the deployment file with --template"))
+		os.Exit(1)
+	}
+	if _, err := os.Stat(path); err == nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The file %v already exists", path)))
+		os.Exit(1)
+	}
+	_, templatePath, err := lib.ReadTemplate(deploy_Template)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
+		log.Fatalln(err)
+	}
+	// The template path in a deployment file is relative to the deployment file itself
+	relativePath, err := filepath.Rel(filepath.Dir(path), templatePath)
+	if err != nil {
+		failWithError(err)
+	}
+	generated := lib.DeployInfo{
+		StackName:            *deploy_StackName,
+		TemplateRelativePath: relativePath,
+	}
+	// Default and git tags are added when deploying, so they shouldn't be stored in the file
+	*deploy_DefaultTags = false
+	viper.Set("deployment.auto-git-tags", false)
+	setDeployTags(&generated)
+	setDeployParameters(&generated)
+	generated.NotificationARNs = lib.SelectNotificationARNs(*deploy_NotificationArns, nil, nil)
+	generated.ChangesetDescription = *deploy_ChangesetDescription
+	generated.RoleARN = *deploy_RoleArn
+	generated.RollbackConfiguration, err = lib.SelectRollbackConfiguration(*deploy_RollbackMonitoringTime, *deploy_RollbackAlarmArns, nil)
+	if err != nil {
+		failWithError(err)
+	}
+	deploymentFile := generated.ToDeploymentFile()
+	contents, err := deploymentFile.ToCommentedYAML()
+	if err != nil {
+		failWithError(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		failWithError(err)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Deployment file written to %v", path)))
+}
+
 // showDeploymentInfo shows what kind of deployment this (New/Update) and where it's happening
 func showDeploymentInfo(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 	bold := color.New(color.Bold).SprintFunc()
@@ -262,28 +518,32 @@ func showDeploymentInfo(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 		if *deploy_Dryrun {
 			method = fmt.Sprintf("Doing a %v for", bold("dry run"))
 		}
-		account := awsConfig.AccountID
-		if awsConfig.AccountAlias != "" {
-			account = fmt.Sprintf("%v (%v)", awsConfig.AccountAlias, awsConfig.AccountID)
-		}
-		fmt.Printf("%v new stack '%v' to region %v of account %v\n", method, bold(*deploy_StackName), awsConfig.Region, account)
+		fmt.Printf("%v new stack '%v' to region %v of account %v\n", method, bold(deployment.StackName), awsConfig.Region, formatAccountDisplay(awsConfig))
 	} else {
 		method := "Updating"
 		if *deploy_Dryrun {
 			method = fmt.Sprintf("Doing a %v for updating", bold("dry run"))
 		}
-		fmt.Printf("%v stack '%v' in region %v of account %v\n", method, bold(*deploy_StackName), awsConfig.Region, awsConfig.AccountID)
+		fmt.Printf("%v stack '%v' in region %v of account %v\n", method, bold(deployment.StackName), awsConfig.Region, formatAccountDisplay(awsConfig))
 	}
 	printBasicStackInfo(deployment, true, awsConfig)
 }
 
 func setDeployTemplate(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
+	templateURL := *deploy_TemplateUrl
+	if deployment.StackDeploymentFile != nil {
+		templateURL = deployment.StackDeploymentFile.TemplateURL
+	}
+	if templateURL != "" {
+		setDeployTemplateFromURL(deployment, templateURL, awsConfig)
+		return
+	}
 	var template string
 	var path string
 	var err error
 	if deployment.StackDeploymentFile != nil {
 		// The deployment file has the path relative to that file
-		template, path, err = lib.ReadFile(&deployment.StackDeploymentFile.TemplateFilePath, "templates")
+		template, path, err = lib.ReadDeploymentTemplate(deployment.StackDeploymentFile.TemplateFilePath, deployment.DeploymentFilePath)
 	} else {
 		template, path, err = lib.ReadTemplate(deploy_Template)
 	}
@@ -292,15 +552,6 @@ func setDeployTemplate(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
 		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
 		log.Fatalln(err)
 	}
-	if *deploy_Bucket != "" {
-		objectname, err := lib.UploadTemplate(deploy_Template, template, deploy_Bucket, awsConfig.S3Client())
-		if err != nil {
-			fmt.Print(outputsettings.StringFailure("this failed"))
-			log.Fatalln(err)
-		}
-		url := fmt.Sprintf("https://%v.s3-%v.amazonaws.com/%v", *deploy_Bucket, awsConfig.Region, objectname)
-		deployment.TemplateUrl = url
-	}
 	// Use the root path to correctly get the relative path of the templates
 	if cfgFile != "" {
 		confdir := filepath.Dir(cfgFile)
@@ -316,6 +567,256 @@ func setDeployTemplate(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
 	deployment.Template = template
 }
 
+// setDeployTemplateFromURL uses the template that's already in S3. The
+// template is downloaded so it can still be checked before deploying, but the
+// change set is created from the URL.
+func setDeployTemplateFromURL(deployment *lib.DeployInfo, templateURL string, awsConfig config.AWSConfig) {
+	if *deploy_Package {
+		fmt.Print(outputsettings.StringFailure("The --package flag can't be used with a template URL, as it needs a local template"))
+		os.Exit(1)
+	}
+	template, err := lib.DownloadTemplate(templateURL, awsConfig.S3Client())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
+		failWithError(err)
+	}
+	deployment.Template = template
+	deployment.TemplateUrl = templateURL
+}
+
+// packageDeployTemplate uploads the local artifacts referenced by the template
+// to the S3 bucket and updates the template to use the uploaded artifacts
+func packageDeployTemplate(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
+	if *deploy_Bucket == "" {
+		fmt.Print(outputsettings.StringFailure("Please provide the S3 bucket for the packaged artifacts with --bucket"))
+		os.Exit(1)
+	}
+	region, err := lib.GetBucketRegion(*deploy_Bucket, awsConfig.S3Client())
+	if err != nil {
+		failWithError(err)
+	}
+	packageSettings := lib.PackageSettings{
+		Bucket: *deploy_Bucket,
+		Prefix: *deploy_S3Prefix,
+		Region: region,
+	}
+	template, artifacts, err := lib.PackageTemplate(deployment.Template, filepath.Dir(deployment.TemplateRelativePath), packageSettings, awsConfig.S3Client())
+	if err != nil {
+		failWithError(err)
+	}
+	for _, artifact := range artifacts {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Uploaded %v for %v to s3://%v/%v", artifact.LocalPath, artifact.LogicalID, *deploy_Bucket, artifact.S3Key)))
+	}
+	deployment.Template = template
+}
+
+// templateHashFile returns the path of the file that stores the deployment hash of the last successful deployment.
+// The default path includes the account and region, as the same stack name can be used in each of them.
+func templateHashFile(stackname string, awsConfig config.AWSConfig) string {
+	if *deploy_LastHashFile != "" {
+		return *deploy_LastHashFile
+	}
+	return filepath.Join(".fog", "template-hashes", awsConfig.AccountID, awsConfig.Region, stackname+".sha256")
+}
+
+// skipIfTemplateUnchanged stops the deployment if the template, parameters, and tags are the same as in the last successful deployment
+func skipIfTemplateUnchanged(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	stored, err := os.ReadFile(templateHashFile(deployment.StackName, awsConfig))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			failWithError(err)
+		}
+		return
+	}
+	if strings.TrimSpace(string(stored)) == lib.GetDeploymentHash(deployment.Template, deployment.Parameters, deployment.Tags, timestampTagKeys(deployment)) {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("The template, parameters, and tags for stack %v haven't changed since the last successful deployment, skipping the deployment", deployment.StackName)))
+		os.Exit(0)
+	}
+}
+
+// timestampTagKeys returns the keys of the default and deployment file tags
+// that use the $TIMESTAMP placeholder, as their value differs on every run
+func timestampTagKeys(deployment lib.DeployInfo) []string {
+	keys := make([]string, 0)
+	if *deploy_DefaultTags {
+		for key, value := range viper.GetStringMapString("tags.default") {
+			if strings.Contains(value, "$TIMESTAMP") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if deployment.StackDeploymentFile != nil {
+		for key, value := range deployment.StackDeploymentFile.Tags {
+			if strings.Contains(value, "$TIMESTAMP") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// storeTemplateHash writes the deployment hash of the deployed template, parameters, and tags to the hash file
+func storeTemplateHash(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	path := templateHashFile(deployment.StackName, awsConfig)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to store the template hash: %v", err)))
+		return
+	}
+	if err := os.WriteFile(path, []byte(lib.GetDeploymentHash(deployment.Template, deployment.Parameters, deployment.Tags, timestampTagKeys(deployment))+"\n"), 0644); err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to store the template hash: %v", err)))
+	}
+}
+
+// uploadDeployTemplate uploads the template to the S3 bucket if one was provided
+func uploadDeployTemplate(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
+	// Templates from a URL are already in S3
+	if *deploy_Bucket == "" || deployment.TemplateUrl != "" {
+		return
+	}
+	objectname, err := lib.UploadTemplate(deploy_Template, deployment.Template, deploy_Bucket, awsConfig.S3Client())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure("this failed"))
+		log.Fatalln(err)
+	}
+	url := fmt.Sprintf("https://%v.s3-%v.amazonaws.com/%v", *deploy_Bucket, awsConfig.Region, objectname)
+	deployment.TemplateUrl = url
+}
+
+// checkRequiredTags stops the deployment if any of the tags that are required
+// through --require-tag or the deployment.required-tags setting are missing
+func checkRequiredTags(deployment lib.DeployInfo) {
+	required := viper.GetStringSlice("deployment.required-tags")
+	if *deploy_RequiredTags != "" {
+		for _, tag := range strings.Split(*deploy_RequiredTags, ",") {
+			required = append(required, strings.TrimSpace(tag))
+		}
+	}
+	missing := lib.GetMissingRequiredTags(deployment.Tags, required, viper.GetBool("deployment.required-tags-ignore-case"))
+	if len(missing) == 0 {
+		return
+	}
+	message := fmt.Sprintf(string(texts.DeployStackMessageMissingTags), strings.Join(missing, ", "))
+	fmt.Print(outputsettings.StringFailure(message))
+	os.Exit(1)
+}
+
+// showTemplateDiff shows the differences between the currently deployed template
+// and the new one, and asks whether to continue with the deployment
+func showTemplateDiff(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	currentTemplate := ""
+	if !deployment.IsNew {
+		var err error
+		currentTemplate, err = lib.GetCurrentTemplateBody(deployment.StackName, awsConfig.CloudformationClient())
+		if err != nil {
+			fmt.Print(outputsettings.StringFailure(texts.DeployTemplateDiffRetrieveFailed))
+			log.Fatalln(err)
+		}
+	}
+	diff := lib.DiffTemplates(currentTemplate, deployment.Template)
+	if !lib.HasChanges(diff) {
+		fmt.Print(outputsettings.StringInfo(texts.DeployTemplateDiffNoChanges))
+		return
+	}
+	fmt.Print(outputsettings.StringBold(string(texts.DeployTemplateDiffHeader)))
+	printTemplateDiff(diff, 3)
+	if *deploy_NonInteractive {
+		return
+	}
+	if !askForConfirmation(string(texts.DeployTemplateDiffContinueConfirm)) {
+		os.Exit(0)
+	}
+}
+
+// printTemplateDiff prints the changed lines of the diff, surrounded by the
+// provided number of unchanged lines for context
+func printTemplateDiff(diff []lib.DiffLine, contextLines int) {
+	show := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Type == lib.DiffLineUnchanged {
+			continue
+		}
+		for j := i - contextLines; j <= i+contextLines; j++ {
+			if j >= 0 && j < len(diff) {
+				show[j] = true
+			}
+		}
+	}
+	skipped := false
+	for i, line := range diff {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			fmt.Println("...")
+			skipped = false
+		}
+		text := fmt.Sprintf("%v %v", line.Type, line.Text)
+		switch line.Type {
+		case lib.DiffLineAdded:
+			fmt.Println(outputsettings.StringPositiveInline(text))
+		case lib.DiffLineRemoved:
+			fmt.Println(outputsettings.StringWarningInline(text))
+		default:
+			fmt.Println(text)
+		}
+	}
+	if skipped {
+		fmt.Println("...")
+	}
+	fmt.Println("")
+}
+
+// requestChangesetApproval submits the change set to the approval API and waits
+// until it's approved. If it's rejected or not approved in time, the change set
+// is deleted and fog exits.
+func requestChangesetApproval(deployment lib.DeployInfo, deploymentLog *lib.DeploymentLog, awsConfig config.AWSConfig) {
+	if *deploy_ApprovalAPI == "" {
+		fmt.Print(outputsettings.StringFailure(texts.DeployApprovalMessageNoAPI))
+		os.Exit(1)
+	}
+	apiKey := ""
+	if *deploy_ApprovalAPIKey != "" {
+		var ok bool
+		apiKey, ok = os.LookupEnv(*deploy_ApprovalAPIKey)
+		if !ok || apiKey == "" {
+			fmt.Print(outputsettings.StringFailure(fmt.Sprintf(string(texts.DeployApprovalMessageNoAPIKey), *deploy_ApprovalAPIKey)))
+			deleteChangeset(deployment, awsConfig)
+			os.Exit(1)
+		}
+	}
+	client := lib.NewHTTPApprovalClient(*deploy_ApprovalAPI, apiKey)
+	request := lib.NewApprovalRequest(*deployment.Changeset, awsConfig.AccountID, awsConfig.Region, deployment.Changeset.GenerateChangesetUrl(awsConfig))
+	timeout := time.Duration(settings.GetInt("approval.timeout")) * time.Minute
+	interval := time.Duration(settings.GetInt("approval.poll-interval")) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	changeRequestID, err := client.RequestApproval(ctx, request)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployApprovalMessageRequestFailed))
+		fmt.Println(err)
+		deleteChangeset(deployment, awsConfig)
+		os.Exit(1)
+	}
+	deploymentLog.ChangeRequestID = changeRequestID
+	fmt.Print(outputsettings.StringInfo(fmt.Sprintf(string(texts.DeployApprovalMessageWaiting), changeRequestID)))
+	status, err := lib.WaitForApproval(ctx, client, changeRequestID, interval)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployApprovalMessageNotApproved))
+		fmt.Println(err)
+		deleteChangeset(deployment, awsConfig)
+		os.Exit(1)
+	}
+	if status == lib.ApprovalStatusRejected {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf(string(texts.DeployApprovalMessageRejected), changeRequestID)))
+		deploymentLog.StatusDescription = fmt.Sprintf(string(texts.DeployApprovalMessageRejected), changeRequestID)
+		deploymentLog.Failed(nil)
+		deleteChangeset(deployment, awsConfig)
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf(string(texts.DeployApprovalMessageApproved), changeRequestID)))
+}
+
 func setDeployTags(deployment *lib.DeployInfo) {
 	tagresult := make([]types.Tag, 0)
 	if *deploy_DefaultTags {
@@ -337,7 +838,7 @@ func setDeployTags(deployment *lib.DeployInfo) {
 		}
 	} else if *deploy_Tags != "" {
 		for _, tagfile := range strings.Split(*deploy_Tags, ",") {
-			tags, _, err := lib.ReadTagsfile(tagfile)
+			tags, err := readDeployFile(tagfile, "tags")
 			if err != nil {
 				message := fmt.Sprintf("%v '%v'", texts.FileTagsReadFailure, tagfile)
 				fmt.Print(outputsettings.StringFailure(message))
@@ -352,6 +853,14 @@ func setDeployTags(deployment *lib.DeployInfo) {
 			tagresult = append(tagresult, parsedtags...)
 		}
 	}
+	if viper.GetBool("deployment.auto-git-tags") {
+		metadata, err := lib.GetGitMetadata(lib.ExecGitRunner{})
+		if err != nil {
+			fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to add the git tags: %v", err)))
+		} else {
+			tagresult = lib.MergeTags(tagresult, metadata.ToTags(time.Now()))
+		}
+	}
 	deployment.Tags = tagresult
 }
 
@@ -376,7 +885,7 @@ func setDeployParameters(deployment *lib.DeployInfo) {
 		}
 	} else if *deploy_Parameters != "" {
 		for _, parameterfile := range strings.Split(*deploy_Parameters, ",") {
-			parameters, _, err := lib.ReadParametersfile(parameterfile)
+			parameters, err := readDeployFile(parameterfile, "parameters")
 			if err != nil {
 				message := fmt.Sprintf("%v '%v'", texts.FileParametersReadFailure, parameterfile)
 				fmt.Print(outputsettings.StringFailure(message))
@@ -391,10 +900,123 @@ func setDeployParameters(deployment *lib.DeployInfo) {
 			parameterresult = append(parameterresult, parsedparameters...)
 		}
 	}
+	if len(*deploy_ParameterOverrides) != 0 {
+		overrides, err := lib.ParseParameterOverrides(*deploy_ParameterOverrides)
+		if err != nil {
+			fmt.Print(outputsettings.StringFailure(err.Error()))
+			os.Exit(1)
+		}
+		parameterresult = lib.MergeParameters(parameterresult, overrides)
+	}
 	deployment.Parameters = parameterresult
 }
 
-func createChangeset(deployment *lib.DeployInfo, awsConfig config.AWSConfig) *lib.ChangesetInfo {
+// assumeDeployRole assumes the role provided with --assume-role or in the
+// config file, so all further calls use its credentials
+func assumeDeployRole(awsConfig *config.AWSConfig) {
+	role := config.AssumeRoleSettings{
+		RoleARN:     *deploy_AssumeRole,
+		SessionName: *deploy_RoleSessionName,
+		ExternalID:  *deploy_RoleExternalId,
+	}
+	if role.RoleARN == "" {
+		role.RoleARN = viper.GetString("deployment.assume-role")
+	}
+	if role.RoleARN == "" {
+		return
+	}
+	if role.SessionName == "" {
+		role.SessionName = viper.GetString("deployment.assume-role-session-name")
+	}
+	if role.ExternalID == "" {
+		role.ExternalID = viper.GetString("deployment.assume-role-external-id")
+	}
+	if err := awsConfig.AssumeRole(role, awsConfig.StsClient()); err != nil {
+		failWithError(err)
+	}
+}
+
+// inheritDeployParameters uses the parameter values of the existing stack for
+// any parameters that weren't provided. Inherited parameters that no longer
+// exist in the template are dropped, as CloudFormation rejects them.
+func inheritDeployParameters(deployment *lib.DeployInfo, awsConfig config.AWSConfig) {
+	if deployment.IsNew {
+		fmt.Print(outputsettings.StringWarning("This is a new stack, so there are no parameters to inherit"))
+		return
+	}
+	stack, err := deployment.GetStack(awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	parameters := lib.InheritParameters(stack.Parameters, deployment.Parameters)
+	templateparameters, err := lib.ParseTemplateParameters(deployment.Template)
+	if err != nil {
+		deployment.Parameters = parameters
+		return
+	}
+	deployment.Parameters = make([]types.Parameter, 0, len(parameters))
+	for _, parameter := range parameters {
+		if _, ok := templateparameters[aws.ToString(parameter.ParameterKey)]; !ok && aws.ToBool(parameter.UsePreviousValue) {
+			continue
+		}
+		deployment.Parameters = append(deployment.Parameters, parameter)
+	}
+}
+
+// validateDeployParameters checks the parameter values against the constraints
+// in the template and stops the deployment if any of them aren't met
+func validateDeployParameters(deployment lib.DeployInfo) {
+	parameters, err := lib.ParseTemplateParameters(deployment.Template)
+	if err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to read the parameters from the template, skipping parameter validation: %v", err)))
+		return
+	}
+	violations := lib.ValidateParameterConstraints(deployment.Parameters, lib.CfnTemplateBody{Parameters: parameters})
+	if len(violations) == 0 {
+		return
+	}
+	output := format.OutputArray{Keys: []string{"Parameter", "Constraint", "Message"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Parameter values that don't meet the constraints of the template"
+	for _, violation := range violations {
+		output.AddContents(map[string]interface{}{
+			"Parameter":  violation.Parameter,
+			"Constraint": string(violation.Violation),
+			"Message":    violation.Message,
+		})
+	}
+	output.Write()
+	fmt.Print(outputsettings.StringFailure("The parameters aren't valid for this template. Use --skip-parameter-validation to deploy anyway"))
+	os.Exit(1)
+}
+
+// showCostEstimate shows the link to the cost estimate of the template. Failing
+// to get the estimate doesn't stop the deployment.
+func showCostEstimate(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	url, err := lib.GetStackCostEstimate(deployment.Template, deployment.TemplateUrl, deployment.Parameters, awsConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to estimate the cost of the template: %v", err)))
+		return
+	}
+	fmt.Print(outputsettings.StringInfo(fmt.Sprintf("The estimated cost of the template is available at %v", url)))
+}
+
+// sopsDecryptor returns the decryptor for SOPS encrypted files using the configured binary
+func sopsDecryptor() lib.SopsDecryptor {
+	return lib.SopsDecryptor{Binary: viper.GetString("sops.binary")}
+}
+
+// readDeployFile reads a parameter or tag file, decrypting it first when --sops-decrypt is used
+func readDeployFile(fileName string, fileType string) (string, error) {
+	if !*deploy_SopsDecrypt {
+		contents, _, err := lib.ReadFile(&fileName, fileType)
+		return contents, err
+	}
+	// SOPS returns the file in its original format, which is handled when parsing the parameters or tags
+	contents, _, err := lib.ReadEncryptedFile(&fileName, fileType, sopsDecryptor())
+	return contents, err
+}
+
+func createChangeset(deployment *lib.DeployInfo, deploymentLog *lib.DeploymentLog, awsConfig config.AWSConfig) *lib.ChangesetInfo {
 	if deployment.TemplateUrl != "" {
 		text := fmt.Sprintf("Using template uploaded as %v", deployment.TemplateUrl)
 		fmt.Print(outputsettings.StringInfo(text))
@@ -404,19 +1026,22 @@ func createChangeset(deployment *lib.DeployInfo, awsConfig config.AWSConfig) *li
 		fmt.Print(outputsettings.StringFailure(texts.DeployChangesetMessageCreationFailed))
 		log.Fatalln(err)
 	}
-	changeset, err := deployment.WaitUntilChangesetDone(awsConfig.CloudformationClient())
+	changeset, err := deployment.WaitUntilChangesetDone(context.TODO(), awsConfig.CloudformationClient())
 	if err != nil {
 		fmt.Print(outputsettings.StringFailure(texts.DeployChangesetMessageCreationFailed))
 		log.Fatalln(err)
 	}
 	if changeset.Status != string(types.ChangeSetStatusCreateComplete) {
 		// When the creation fails because there are no changes, say so and complete successfully
-		if changeset.StatusReason == string(texts.DeployReceivedErrorMessagesNoChanges) || changeset.StatusReason == string(texts.DeployReceivedErrorMessagesNoUpdates) {
+		if lib.IsNoOpChangeset(*changeset) {
+			writeDryRunReport(*deployment, *changeset, awsConfig)
 			message := fmt.Sprintf(string(texts.DeployChangesetMessageNoChanges), deployment.StackName)
 			fmt.Print(outputsettings.StringSuccess(message))
+			deploymentLog.NoOp()
 			os.Exit(0)
 		}
 		// Otherwise, show the error and clean up
+		writeDryRunReport(*deployment, *changeset, awsConfig)
 		fmt.Print(outputsettings.StringFailure(texts.DeployChangesetMessageCreationFailed))
 		fmt.Println(changeset.StatusReason)
 		fmt.Printf("\r\n%v %v \r\n", texts.DeployChangesetMessageConsole, changeset.GenerateChangesetUrl(awsConfig))
@@ -434,6 +1059,58 @@ func createChangeset(deployment *lib.DeployInfo, awsConfig config.AWSConfig) *li
 	return changeset
 }
 
+// writeDryRunReport writes the result of the dry run as JSON to the file provided with --dry-run-report.
+// It's called on every exit of a dry run and does nothing when no report was requested.
+func writeDryRunReport(deployment lib.DeployInfo, changeset lib.ChangesetInfo, awsConfig config.AWSConfig) {
+	if !*deploy_Dryrun || *deploy_DryRunReport == "" {
+		return
+	}
+	report := lib.NewDryRunReport(deployment, changeset, awsConfig)
+	target := dryRunReportOutput
+	if *deploy_DryRunReport != "-" {
+		file, err := os.Create(*deploy_DryRunReport)
+		if err != nil {
+			failWithError(err)
+		}
+		defer file.Close()
+		target = file
+	}
+	if err := report.Write(target); err != nil {
+		failWithError(err)
+	}
+	if *deploy_DryRunReport != "-" {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Dry run report written to %v", *deploy_DryRunReport)))
+	}
+}
+
+// abortOnHighRisk stops the deployment and deletes the change set if it has a high risk
+func abortOnHighRisk(changeset lib.ChangesetInfo, deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	if lib.ComputeChangesetRisk(changeset) != lib.ChangesetRiskHigh {
+		return
+	}
+	fmt.Print(outputsettings.StringFailure(texts.DeployChangesetMessageHighRisk))
+	writeDryRunReport(deployment, changeset, awsConfig)
+	deleteChangeset(deployment, awsConfig)
+	os.Exit(1)
+}
+
+// abortOnRemovals stops the deployment and deletes the change set if it removes any resources
+func abortOnRemovals(changeset lib.ChangesetInfo, deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	removals := changeset.GetRemovals()
+	if len(removals) == 0 {
+		return
+	}
+	message := fmt.Sprintf(string(texts.DeployChangesetMessageSkipDestroy), len(removals))
+	fmt.Print(outputsettings.StringFailure(message))
+	for _, removal := range removals {
+		fmt.Printf("  %v (%v) %v\r\n", removal.LogicalID, removal.Type, removal.ResourceID)
+	}
+	fmt.Println("")
+	writeDryRunReport(deployment, changeset, awsConfig)
+	deleteChangeset(deployment, awsConfig)
+	os.Exit(1)
+}
+
 func deleteChangeset(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 	if *deploy_Dryrun {
 		fmt.Print(outputsettings.StringInfo(texts.DeployChangesetMessageDryrunDelete))
@@ -442,9 +1119,9 @@ func deleteChangeset(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 	} else {
 		fmt.Print(outputsettings.StringSuccess(texts.DeployChangesetMessageWillDelete))
 	}
-	deleteAttempt := deployment.Changeset.DeleteChangeset(awsConfig.CloudformationClient())
-	if !deleteAttempt {
+	if err := deployment.Changeset.DeleteChangeset(awsConfig.CloudformationClient()); err != nil {
 		fmt.Print(outputsettings.StringFailure(texts.DeployChangesetMessageDeleteFailed))
+		fmt.Println(err)
 	}
 	// Likely a new deployment. Check if the stack is in status REVIEW_IN_PROGRESS and offer to delete
 	if deployment.IsNew {
@@ -494,21 +1171,39 @@ func deployChangeset(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 		fmt.Print(outputsettings.StringFailure("Could not execute changeset! See details below"))
 		fmt.Println(err)
 	}
+	followDeployment(deployment, awsConfig, *deploy_Timeout)
+}
+
+// followDeployment shows the events of an executed change set until the
+// deployment is finished. When the timeout is larger than 0, fog stops waiting
+// after the timeout and exits.
+func followDeployment(deployment lib.DeployInfo, awsConfig config.AWSConfig, timeout time.Duration) {
 	latest := deployment.Changeset.CreationTime
 	time.Sleep(3 * time.Second)
 	fmt.Print(outputsettings.StringBold("Showing the events for the deployment:"))
-	ongoing := true
-	for ongoing {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := deployment.WaitUntilDone(ctx, awsConfig.CloudformationClient(), 3*time.Second, func() {
 		latest = showEvents(deployment, latest, awsConfig)
-		time.Sleep(3 * time.Second)
-		ongoing = deployment.IsOngoing(awsConfig.CloudformationClient())
+	})
+	if err != nil {
+		if errors.Is(err, lib.ErrDeploymentTimeout) {
+			fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stopped waiting for the deployment after %v. The stack hasn't been cancelled and the deployment continues in CloudFormation, use fog report or the console to follow its progress.", timeout)))
+		} else {
+			fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stopped following the deployment: %v. The stack hasn't been cancelled and the deployment continues in CloudFormation, use fog report or the console to follow its progress.", err)))
+		}
+		os.Exit(1)
 	}
 	// One last time after the deployment finished in case of a timing mismatch
 	showEvents(deployment, latest, awsConfig)
 }
 
 func showEvents(deployment lib.DeployInfo, latest time.Time, awsConfig config.AWSConfig) time.Time {
-	events, err := deployment.GetEvents(awsConfig.CloudformationClient())
+	events, err := deployment.GetEvents(context.TODO(), awsConfig.CloudformationClient())
 	if err != nil {
 		fmt.Print(outputsettings.StringFailure("Something went wrong trying to get the events of the stack"))
 		fmt.Println(err)
@@ -517,22 +1212,19 @@ func showEvents(deployment lib.DeployInfo, latest time.Time, awsConfig config.AW
 	for _, event := range events {
 		if event.Timestamp.After(latest) {
 			latest = *event.Timestamp
-			message := fmt.Sprintf("%v: %v %v in status %v", event.Timestamp.In(settings.GetTimezoneLocation()).Format(time.RFC3339), *event.ResourceType, *event.LogicalResourceId, event.ResourceStatus)
-			switch event.ResourceStatus {
-			case types.ResourceStatusCreateFailed, types.ResourceStatusImportFailed, types.ResourceStatusDeleteFailed, types.ResourceStatusUpdateFailed, types.ResourceStatusImportRollbackComplete, types.ResourceStatus(types.StackStatusRollbackComplete), types.ResourceStatus(types.StackStatusUpdateRollbackComplete):
-				fmt.Print(outputsettings.StringWarning(message))
-			case types.ResourceStatusCreateComplete, types.ResourceStatusImportComplete, types.ResourceStatusUpdateComplete, types.ResourceStatusDeleteComplete:
-				fmt.Print(outputsettings.StringPositive(message))
-			default:
-				fmt.Println(message)
+			if deployEventLog != nil {
+				if err := deployEventLog.WriteEvent(deployment.StackName, deployment.ChangesetName, event); err != nil {
+					fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to write to the event log: %v", err)))
+				}
 			}
+			printStackEvent(event)
 		}
 	}
 	return latest
 }
 
 func showFailedEvents(deployment lib.DeployInfo, awsConfig config.AWSConfig) []map[string]interface{} {
-	events, err := deployment.GetEvents(awsConfig.CloudformationClient())
+	events, err := deployment.GetEvents(context.TODO(), awsConfig.CloudformationClient())
 	if err != nil {
 		fmt.Print(outputsettings.StringFailure("Something went wrong trying to get the events of the stack"))
 		fmt.Println(err)
@@ -562,6 +1254,21 @@ func showFailedEvents(deployment lib.DeployInfo, awsConfig config.AWSConfig) []m
 	return result
 }
 
+// showFailureReason shows which resource caused the deployment to fail and why
+func showFailureReason(deployment lib.DeployInfo, awsConfig config.AWSConfig) {
+	stack := lib.CfnStack{Name: deployment.GetCleanedStackName(), Id: deployment.StackArn}
+	events, err := stack.GetEvents(context.TODO(), awsConfig.CloudformationClient())
+	if err != nil {
+		return
+	}
+	logicalID, reason := lib.GetRecentFailureReason(events)
+	if logicalID == "" {
+		return
+	}
+	fmt.Print(outputsettings.StringBold(fmt.Sprintf("%v failed: %v", logicalID, outputsettings.StringWarningInline(reason))))
+	fmt.Println("")
+}
+
 type ReverseEvents []types.StackEvent
 
 func (a ReverseEvents) Len() int           { return len(a) }
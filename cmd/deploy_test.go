@@ -0,0 +1,160 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/viper"
+)
+
+func TestReadDeployFile_SopsFormats(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops binary is a shell script")
+	}
+	dir := t.TempDir()
+	// The fake sops binary outputs the file as is, like sops does for a decrypted file
+	binary := filepath.Join(dir, "sops")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\ncat \"$2\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("sops.binary", binary)
+	defer viper.Set("sops.binary", nil)
+	sopsDecrypt := true
+	original := deploy_SopsDecrypt
+	deploy_SopsDecrypt = &sopsDecrypt
+	defer func() { deploy_SopsDecrypt = original }()
+
+	tests := map[string]string{
+		"params.json": `[{"ParameterKey": "Password", "ParameterValue": "secret"}]`,
+		"params.yaml": "- ParameterKey: Password\n  ParameterValue: secret\n",
+		"params.toml": "# Database credentials\n[parameters]\nPassword = \"secret\"\n",
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			decrypted, err := readDeployFile(path, "parameters")
+			if err != nil {
+				t.Fatalf("readDeployFile() error = %v", err)
+			}
+			parameters, err := lib.ParseParameterString(decrypted)
+			if err != nil {
+				t.Fatalf("ParseParameterString() error = %v", err)
+			}
+			if len(parameters) != 1 || aws.ToString(parameters[0].ParameterKey) != "Password" || aws.ToString(parameters[0].ParameterValue) != "secret" {
+				t.Errorf("readDeployFile() = %v, want the Password parameter", decrypted)
+			}
+		})
+	}
+}
+
+// mockDeployDriftClient mocks the CloudFormation calls made when executing a
+// change set, following the deployment, and detecting drift afterwards
+type mockDeployDriftClient struct {
+	*testutil.MockCFNClient
+	executed    []string
+	driftStatus types.StackDriftStatus
+	driftErr    error
+	driftStack  string
+}
+
+func (m *mockDeployDriftClient) ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	m.executed = append(m.executed, aws.ToString(params.ChangeSetName))
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func (m *mockDeployDriftClient) DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error) {
+	if m.driftErr != nil {
+		return nil, m.driftErr
+	}
+	m.driftStack = aws.ToString(params.StackName)
+	return &cloudformation.DetectStackDriftOutput{StackDriftDetectionId: aws.String("detection-1")}, nil
+}
+
+func (m *mockDeployDriftClient) DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	return &cloudformation.DescribeStackDriftDetectionStatusOutput{
+		StackDriftDetectionId: params.StackDriftDetectionId,
+		DetectionStatus:       types.StackDriftDetectionStatusDetectionComplete,
+		StackDriftStatus:      m.driftStatus,
+	}, nil
+}
+
+func TestDetectDriftAfterDeployment(t *testing.T) {
+	viper.Set("drift.detection-timeout", 60)
+	defer viper.Set("drift.detection-timeout", nil)
+	outputsettings = settings.NewOutputSettings()
+	tests := map[string]struct {
+		driftStatus types.StackDriftStatus
+		driftErr    error
+		want        string
+	}{
+		"in sync":          {driftStatus: types.StackDriftStatusInSync, want: "Drift status of stack app: IN_SYNC"},
+		"drifted":          {driftStatus: types.StackDriftStatusDrifted, want: "Drift status of stack app: DRIFTED"},
+		"detection failed": {driftErr: errors.New("throttled"), want: "Unable to detect drift for stack app: throttled"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfn := testutil.NewScenarioBuilder().WithStack("app", func(stack *testutil.StackBuilder) {}).Build()
+			cfn.StatusSequences = map[string][]types.StackStatus{"app": {types.StackStatusUpdateInProgress, types.StackStatusUpdateComplete}}
+			svc := &mockDeployDriftClient{MockCFNClient: cfn, driftStatus: tc.driftStatus, driftErr: tc.driftErr}
+			deployment := lib.DeployInfo{
+				StackName: "app",
+				StackArn:  aws.ToString(cfn.Stacks[0].StackId),
+				Changeset: &lib.ChangesetInfo{StackName: "app", Name: "fog-change-set"},
+			}
+			if err := deployment.Changeset.DeployChangeset(svc); err != nil {
+				t.Fatalf("DeployChangeset() error = %v", err)
+			}
+			if err := deployment.WaitUntilDone(context.Background(), svc, 0, func() {}); err != nil {
+				t.Fatalf("WaitUntilDone() error = %v", err)
+			}
+			stack, err := deployment.GetFreshStack(svc)
+			if err != nil || stack.StackStatus != types.StackStatusUpdateComplete {
+				t.Fatalf("GetFreshStack() = %v, %v, want a completed update", stack.StackStatus, err)
+			}
+			got := captureStdout(t, func() { detectDriftAfterDeployment(deployment, svc) })
+			if !strings.Contains(got, tc.want) {
+				t.Errorf("detectDriftAfterDeployment() printed %q, want it to contain %q", got, tc.want)
+			}
+			if len(svc.executed) != 1 || svc.executed[0] != "fog-change-set" {
+				t.Errorf("executed change sets = %v, want [fog-change-set]", svc.executed)
+			}
+			if tc.driftErr == nil && svc.driftStack != deployment.StackArn {
+				t.Errorf("drift detection ran for %q, want %q", svc.driftStack, deployment.StackArn)
+			}
+		})
+	}
+}
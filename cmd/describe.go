@@ -31,6 +31,7 @@ var describe_StackName *string
 var describe_ChangesetName *string
 var describe_ChangesetUrl *string
 var describe_Template *string
+var describe_ShowOrder *bool
 
 // describeCmd represents the describe command
 var describeCmd = &cobra.Command{
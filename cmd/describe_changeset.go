@@ -24,6 +24,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ArjenSchwarz/fog/config"
 	"github.com/ArjenSchwarz/fog/lib"
@@ -34,21 +35,25 @@ import (
 	"github.com/spf13/viper"
 )
 
-// changesetCmd represents the changeset command
-var changesetCmd = &cobra.Command{
+// describeChangesetCmd represents the describe changeset command
+var describeChangesetCmd = &cobra.Command{
 	Use:   "changeset",
 	Short: "Show the details of a changeset",
 	Long: `Using this command you get a tabular overview of the provided changeset.
 
 	You can provide the changeset either as the name of the stack + the name of the changeset,
-	or you can provide the url using the url parameter.`,
+	or you can provide the url using the url parameter.
+
+	Using --show-order you also get the changes in the order CloudFormation needs to
+	apply them, based on the references between the resources in the changeset.`,
 	Run: describeChangeset,
 }
 
 func init() {
-	describeCmd.AddCommand(changesetCmd)
-	describe_ChangesetName = changesetCmd.Flags().StringP("changeset", "c", "", "The name of the changeset")
-	describe_ChangesetUrl = changesetCmd.Flags().StringP("url", "u", "", "The URL of the changeset, will be parsed to get the stack and template name")
+	describeCmd.AddCommand(describeChangesetCmd)
+	describe_ChangesetName = describeChangesetCmd.Flags().StringP("changeset", "c", "", "The name of the changeset")
+	describe_ChangesetUrl = describeChangesetCmd.Flags().StringP("url", "u", "", "The URL of the changeset, will be parsed to get the stack and template name")
+	describe_ShowOrder = describeChangesetCmd.Flags().Bool("show-order", false, "Show the changes in the order of their dependencies")
 }
 
 func describeChangeset(cmd *cobra.Command, args []string) {
@@ -82,6 +87,28 @@ func describeChangeset(cmd *cobra.Command, args []string) {
 	changeset := deployment.AddChangeset(rawchangeset)
 	printBasicStackInfo(deployment, false, awsConfig)
 	showChangeset(changeset, deployment, awsConfig)
+	if *describe_ShowOrder {
+		printChangesetOrder(changeset)
+	}
+}
+
+// printChangesetOrder shows the changes in the order of their dependencies
+func printChangesetOrder(changeset lib.ChangesetInfo) {
+	if len(changeset.Changes) == 0 {
+		return
+	}
+	output := format.OutputArray{Keys: []string{"Order", "Action", "CfnName", "Type", "Depends on"}, Settings: outputsettings}
+	output.Settings.Title = fmt.Sprintf("Order of changes for %v", changeset.Name)
+	for i, change := range changeset.OrderedChanges() {
+		output.AddContents(map[string]interface{}{
+			"Order":      i + 1,
+			"Action":     change.Action,
+			"CfnName":    change.LogicalID,
+			"Type":       change.Type,
+			"Depends on": strings.Join(change.GetDependencies(), ", "),
+		})
+	}
+	output.Write()
 }
 
 func printBasicStackInfo(deployment lib.DeployInfo, showDryRunInfo bool, awsConfig config.AWSConfig) {
@@ -90,13 +117,17 @@ func printBasicStackInfo(deployment lib.DeployInfo, showDryRunInfo bool, awsConf
 	if showDryRunInfo {
 		keys = append(keys, "Is dry run")
 	}
+	showChangesetURL := deployment.Changeset != nil && deployment.Changeset.ID != "" && !deployment.IsDryRun
+	if showChangesetURL {
+		keys = append(keys, "Changeset URL")
+	}
 	// TODO decide if I want to include the below fields in the output
 	// , "StackStatus", "StackStatusReason", "CreationTime", "StackDescription"
 	output := format.OutputArray{Keys: keys, Settings: outputsettings}
 	output.Settings.Title = stacktitle
 	content := make(map[string]interface{})
 	content["StackName"] = deployment.GetCleanedStackName()
-	content["Account"] = awsConfig.GetAccountAliasID()
+	content["Account"] = formatAccountDisplay(awsConfig)
 	content["Region"] = awsConfig.Region
 	action := "Update"
 	if deployment.IsNew {
@@ -106,6 +137,9 @@ func printBasicStackInfo(deployment lib.DeployInfo, showDryRunInfo bool, awsConf
 	if showDryRunInfo {
 		content["Is dry run"] = deployment.IsDryRun
 	}
+	if showChangesetURL {
+		content["Changeset URL"] = lib.GetChangesetPreviewURL(*deployment.Changeset, awsConfig.Region)
+	}
 	output.AddContents(content)
 	output.Write()
 }
@@ -113,27 +147,26 @@ func printBasicStackInfo(deployment lib.DeployInfo, showDryRunInfo bool, awsConf
 func showChangeset(changeset lib.ChangesetInfo, deployment lib.DeployInfo, awsConfig config.AWSConfig) {
 	changesettitle := fmt.Sprintf("%v %v", texts.DeployChangesetMessageChanges, changeset.Name)
 	changesetsummarytitle := fmt.Sprintf("Summary for %v", changeset.Name)
-	printChangeset(changesettitle, changesetsummarytitle, changeset.Changes, changeset.HasModule)
+	printChangeset(changesettitle, changesetsummarytitle, changeset)
 
 	if !deployment.IsDryRun {
 		fmt.Printf("%v %v \r\n", texts.DeployChangesetMessageConsole, changeset.GenerateChangesetUrl(awsConfig))
 	}
 }
 
-func printChangeset(title string, summaryTitle string, changes []lib.ChangesetChanges, hasModule bool) {
+func printChangeset(title string, summaryTitle string, changeset lib.ChangesetInfo) {
 	bold := color.New(color.Bold).SprintFunc()
 	changesetkeys := []string{"Action", "CfnName", "Type", "ID", "Replacement"}
-	if hasModule {
+	if changeset.HasModule {
 		changesetkeys = append(changesetkeys, "Module")
 	}
-	summarykeys, summaryContent := getChangesetSummaryTable()
 	output := format.OutputArray{Keys: changesetkeys, Settings: outputsettings}
 	output.Settings.Title = title
 	output.Settings.SortKey = "Type"
-	if len(changes) == 0 {
+	if len(changeset.Changes) == 0 {
 		fmt.Println(texts.DeployChangesetMessageNoResourceChanges)
 	} else {
-		for _, change := range changes {
+		for _, change := range changeset.Changes {
 			content := make(map[string]interface{})
 			action := change.Action
 			if action == "Remove" {
@@ -144,48 +177,41 @@ func printChangeset(title string, summaryTitle string, changes []lib.ChangesetCh
 			content["CfnName"] = change.LogicalID
 			content["Type"] = change.Type
 			content["ID"] = change.ResourceID
-			if hasModule {
+			if changeset.HasModule {
 				content["Module"] = change.Module
 			}
-			addToChangesetSummary(&summaryContent, change)
 			output.AddContents(content)
 		}
+		printChangesetRisk(lib.ComputeChangesetRisk(changeset))
 		output.AddToBuffer()
 		destructivechanges := "Potentially destructive changes"
-		printDangerTable(destructivechanges, changes, hasModule)
-		summaryOutput := format.OutputArray{Keys: summarykeys, Settings: outputsettings}
+		printDangerTable(destructivechanges, changeset.Changes, changeset.HasModule)
+		summary := changeset.GetSummary()
+		summaryOutput := format.OutputArray{Keys: []string{"Total", "Added", "Removed", "Modified", "Replacements", "Conditionals"}, Settings: outputsettings}
 		summaryOutput.Settings.Title = summaryTitle
-		summaryOutput.AddContents(summaryContent)
+		summaryOutput.AddContents(map[string]interface{}{
+			"Total":        summary.Total,
+			"Added":        summary.Added,
+			"Removed":      summary.Removed,
+			"Modified":     summary.Modified,
+			"Replacements": summary.Replacements,
+			"Conditionals": summary.Conditionals,
+		})
 		summaryOutput.AddToBuffer()
 		output.Write()
 	}
 }
 
-func addToChangesetSummary(summaryContent *map[string]interface{}, change lib.ChangesetChanges) {
-	addToField(summaryContent, "Total", 1)
-	switch change.Action {
-	case "Add":
-		addToField(summaryContent, "Added", 1)
-	case "Remove":
-		addToField(summaryContent, "Removed", 1)
-	case "Modify":
-		addToField(summaryContent, "Modified", 1)
-	}
-	switch change.Replacement {
-	case "True":
-		addToField(summaryContent, "Replacements", 1)
-	case "Conditional":
-		addToField(summaryContent, "Conditionals", 1)
-	}
-}
-
-func getChangesetSummaryTable() ([]string, map[string]interface{}) {
-	summarykeys := []string{"Total", "Added", "Removed", "Modified", "Replacements", "Conditionals"}
-	summaryContent := make(map[string]interface{})
-	for _, key := range summarykeys {
-		summaryContent[key] = 0
+// printChangesetRisk shows the overall risk of the change set
+func printChangesetRisk(risk lib.ChangesetRisk) {
+	label := string(risk)
+	switch risk {
+	case lib.ChangesetRiskHigh:
+		label = outputsettings.StringWarningInline(label)
+	case lib.ChangesetRiskLow:
+		label = outputsettings.StringPositiveInline(label)
 	}
-	return summarykeys, summaryContent
+	fmt.Printf("Risk of this change set: %v\r\n\r\n", label)
 }
 
 func printDangerTable(title string, changes []lib.ChangesetChanges, hasModule bool) {
@@ -23,11 +23,13 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ArjenSchwarz/fog/config"
 	"github.com/ArjenSchwarz/fog/lib"
@@ -42,6 +44,11 @@ var drift_StackName *string
 var drift_resultsOnly *bool
 var drift_separateProperties *bool
 var drift_IgnoreTags *string
+var drift_Since *string
+var drift_ClearCache *bool
+var drift_Coverage *bool
+var drift_ResourceType *string
+var drift_Parallel *int
 
 // driftCmd represents the drift command
 var driftCmd = &cobra.Command{
@@ -51,22 +58,56 @@ var driftCmd = &cobra.Command{
 
 Including checking certain values that aren't currently
 supported natively by CloudFormation drift detection.
-In particular it will show NACLs and Routes changes.
+In particular it will show NACLs, Routes, and static Transit
+Gateway route changes, as well as changes to the CIDR blocks, DNS
+settings, and tenancy of VPCs and the CIDR blocks, public IP
+auto-assignment, and tags of subnets. For VPC endpoints the service
+name, policy, subnets, and security groups are checked.
+Rules of security groups that were added or removed outside of
+CloudFormation are shown as well. This can be turned off by setting
+drift.detect-security-groups to false in your config file.
+Changes to the policy documents of S3 bucket, SNS topic, and SQS
+queue policies, as well as the inline policies of IAM roles, are shown as
+a diff of the formatted JSON.
 
 Due to limitations in CloudFormation, prefix lists in routes don't
 show up by default as they can't be managed using CloudFormation and
 therefore we can't see if they've drifted.
 If you wish these to be shown, you can use the --verbose flag. This
 will still exclude AWS managed prefix lists, as these are automatically
-assigned.`,
+assigned.
+
+Not every resource type supports drift detection, use --coverage to see
+which of the resource types in the stack are covered.
+
+Use --resource-type to only show the drift of resources with specific types,
+e.g. --resource-type AWS::IAM::Role,AWS::EC2::Route. This also skips the
+additional checks for other types. A filter that should always be applied can
+be set with drift.resource-type-filter in your config file.
+
+The results of every run are stored in ~/.fog/drift-cache/<stack-name>.json.
+Using --since last will only show the drift that is new or changed since
+the previous run. Use --clear-cache to delete the stored results.
+
+A wildcard in --stackname, e.g. --stackname "my-app-*", runs the drift
+detection for every matching stack and shows the results in a single table,
+followed by a summary of how many stacks have drifted. This only shows the
+drift found by CloudFormation itself, without the additional checks or the
+drift cache. Use --parallel to run multiple drift detections at the same time,
+keeping in mind that CloudFormation may throttle these.`,
 	Run: detectDrift,
 }
 
 func init() {
 	rootCmd.AddCommand(driftCmd)
-	drift_StackName = driftCmd.Flags().StringP("stackname", "n", "", "The name of the stack")
+	drift_StackName = driftCmd.Flags().StringP("stackname", "n", "", "The name of the stack, accepts wildcards to check multiple stacks")
 	drift_resultsOnly = driftCmd.Flags().BoolP("results-only", "r", false, "Don't trigger a new drift detection")
 	drift_separateProperties = driftCmd.Flags().BoolP("separate-properties", "s", false, "Put every property on its own line")
+	drift_Since = driftCmd.Flags().String("since", "", "Only show drift that is new or changed since the previous run. Only supports \"last\"")
+	drift_ClearCache = driftCmd.Flags().Bool("clear-cache", false, "Delete the stored results of previous runs for the stack")
+	drift_Coverage = driftCmd.Flags().Bool("coverage", false, "Show which resource types in the stack support native drift detection before running it")
+	drift_ResourceType = driftCmd.Flags().String("resource-type", "", "Comma separated list of resource types to show the drift for, replacing the drift.resource-type-filter from the config file")
+	drift_Parallel = driftCmd.Flags().Int("parallel", 1, "The number of drift detections to run at the same time when checking multiple stacks")
 	drift_IgnoreTags = driftCmd.Flags().StringP("ignore-tags", "i", "", "Comma separated list of tags to ignore, additional to any configured in the config file")
 }
 
@@ -75,6 +116,24 @@ func detectDrift(cmd *cobra.Command, args []string) {
 	if err != nil {
 		failWithError(err)
 	}
+	if strings.Contains(*drift_StackName, "*") {
+		detectDriftForStacks(awsConfig)
+		return
+	}
+	cachePath, err := lib.GetDriftCachePath(*drift_StackName)
+	if err != nil {
+		failWithError(err)
+	}
+	if *drift_ClearCache {
+		if err := lib.ClearDriftCache(cachePath); err != nil {
+			failWithError(err)
+		}
+		fmt.Print(settings.NewOutputSettings().StringSuccess(fmt.Sprintf("Cleared the drift cache for stack %v", *drift_StackName)))
+		return
+	}
+	if *drift_Since != "" && *drift_Since != "last" {
+		failWithError(fmt.Errorf("unsupported value %q for --since, only \"last\" is supported", *drift_Since))
+	}
 	svc := awsConfig.CloudformationClient()
 	resultTitle := "Drift results for stack " + *drift_StackName
 	keys := []string{"LogicalId", "Type", "ChangeType", "Details"}
@@ -82,19 +141,33 @@ func detectDrift(cmd *cobra.Command, args []string) {
 	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
 	output.Settings.Title = resultTitle
 	output.Settings.SortKey = "LogicalId"
+	stack, err := lib.GetStack(drift_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	params := lib.GetParametersMap(stack.Parameters)
+	exports, err := lib.GetExportsByName(svc)
+	if err != nil {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Unable to list the exports: %v", err)))
+		exports = make(map[string]types.Export)
+	}
+	template := lib.GetTemplateBody(drift_StackName, params, exports, svc)
+	if len(template.UnresolvedImports) != 0 {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("The following exports used with Fn::ImportValue couldn't be found, so properties using them can't be compared: %v", strings.Join(template.UnresolvedImports, ", "))))
+	}
+	if *drift_Coverage {
+		showDriftCoverage(template)
+	}
 	if !*drift_resultsOnly {
 		driftid := lib.StartDriftDetection(drift_StackName, awsConfig.CloudformationClient())
 		lib.WaitForDriftDetectionToFinish(driftid, awsConfig.CloudformationClient())
 	}
+	resourceTypes := getDriftResourceTypeFilter()
 	defaultDrift := lib.GetDefaultStackDrift(drift_StackName, svc)
-	naclResources, routetableResources, logicalToPhysical := separateSpecialCases(defaultDrift)
+	specialCases := separateSpecialCases(defaultDrift, resourceTypes)
 	checkedResources := []string{}
-	stack, err := lib.GetStack(drift_StackName, svc)
-	if err != nil {
-		failWithError(err)
-	}
 
-	for _, drift := range defaultDrift {
+	for _, drift := range lib.FilterDriftByResourceType(defaultDrift, resourceTypes) {
 		checkedResources = append(checkedResources, *drift.LogicalResourceId)
 		if drift.StackResourceDriftStatus == types.StackResourceDriftStatusInSync {
 			continue
@@ -119,8 +192,26 @@ func detectDrift(cmd *cobra.Command, args []string) {
 
 		properties := []string{}
 		handledtags := []string{}
+		// Policy documents are shown as a diff instead of through the separate property differences
+		policyProperty, isPolicy := lib.GetPolicyDocumentProperty(aws.ToString(drift.ResourceType))
+		policyDiffShown := false
+		if isPolicy {
+			if policyDiff := getPolicyDrift(*drift.LogicalResourceId, policyProperty, template, *params, expectedProperties, actualProperties); policyDiff != "" {
+				properties = append(properties, policyDiff)
+				policyDiffShown = true
+			}
+		} else if aws.ToString(drift.ResourceType) == "AWS::IAM::Role" && drift.StackResourceDriftStatus == types.StackResourceDriftStatusModified {
+			policyProperty = "Policies"
+			if inlineChanges := getInlinePolicyDrift(aws.ToString(drift.PhysicalResourceId), expectedProperties, awsConfig); len(inlineChanges) != 0 {
+				properties = append(properties, inlineChanges...)
+				policyDiffShown = true
+			}
+		}
 
 		for _, property := range drift.PropertyDifferences {
+			if policyDiffShown && strings.HasPrefix(aws.ToString(property.PropertyPath), "/"+policyProperty) {
+				continue
+			}
 			pathsplit := strings.Split(*property.PropertyPath, "/")
 			if stringInSlice("Tags", pathsplit) {
 				tagprop, taghandled := tagDifferences(property, handledtags, tagMap, properties, &drift)
@@ -163,29 +254,403 @@ func detectDrift(cmd *cobra.Command, args []string) {
 			}
 		}
 	}
-	params := lib.GetParametersMap(stack.Parameters)
-	template := lib.GetTemplateBody(drift_StackName, params, svc)
-	checkNaclEntries(naclResources, template, stack.Parameters, &output, awsConfig)
-	checkRouteTableRoutes(routetableResources, template, stack.Parameters, logicalToPhysical, &output, awsConfig)
+	checkNaclEntries(specialCases.nacls, template, stack.Parameters, &output, awsConfig)
+	if settings.GetBool("drift.detect-security-groups") {
+		checkSecurityGroupRules(specialCases.securityGroups, template, stack.Parameters, specialCases.logicalToPhysical, &output, awsConfig)
+	}
+	checkRouteTableRoutes(specialCases.routetables, template, stack.Parameters, specialCases.logicalToPhysical, &output, awsConfig)
+	checkTransitGatewayRoutes(specialCases.tgwRoutetables, template, stack.Parameters, specialCases.logicalToPhysical, &output, awsConfig)
+	checkVpcs(specialCases.vpcs, template, stack.Parameters, &output, awsConfig)
+	checkSubnets(specialCases.subnets, template, stack.Parameters, stack.Tags, &output, awsConfig)
+	checkVPCEndpoints(specialCases.vpcEndpoints, template, stack.Parameters, specialCases.logicalToPhysical, &output, awsConfig)
+	updateDriftCache(cachePath, &output, len(resourceTypes) == 0)
 	output.Write()
 }
 
-func separateSpecialCases(defaultDrift []types.StackResourceDrift) (map[string]string, map[string]string, map[string]string) {
-	naclResources := make(map[string]string)
-	routetableResources := make(map[string]string)
-	logicalToPhysical := make(map[string]string)
+// detectDriftForStacks runs the drift detection for all stacks matching the
+// wildcard in --stackname and shows the combined results
+func detectDriftForStacks(awsConfig config.AWSConfig) {
+	outputsettings = settings.NewOutputSettings()
+	if *drift_resultsOnly || *drift_Since != "" || *drift_ClearCache || *drift_Coverage {
+		failWithError(fmt.Errorf("--results-only, --since, --clear-cache, and --coverage can only be used for a single stack"))
+	}
+	svc := awsConfig.CloudformationClient()
+	stacks, err := lib.GetMatchingStacks(*drift_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	if len(stacks) == 0 {
+		failWithError(fmt.Errorf("no stacks found matching %v", *drift_StackName))
+	}
+	stackNames := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		stackNames = append(stackNames, aws.ToString(stack.StackName))
+	}
+	sort.Strings(stackNames)
+	results := lib.BatchDetectDrift(context.Background(), stackNames, *drift_Parallel, svc)
+	resourceTypes := getDriftResourceTypeFilter()
+	output := format.OutputArray{Keys: []string{"StackName", "LogicalId", "Type", "ChangeType", "Details"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Drift results for stacks matching " + *drift_StackName
+	for _, result := range results {
+		if result.Err != nil {
+			output.AddContents(map[string]interface{}{
+				"StackName":  result.StackName,
+				"ChangeType": outputsettings.StringWarningInline("DETECTION_FAILED"),
+				"Details":    result.Err.Error(),
+			})
+			continue
+		}
+		for _, drift := range lib.FilterDriftByResourceType(result.Drifts, resourceTypes) {
+			changetype := string(drift.StackResourceDriftStatus)
+			if drift.StackResourceDriftStatus == types.StackResourceDriftStatusDeleted {
+				changetype = outputsettings.StringWarningInline(changetype)
+			}
+			properties := make([]string, 0, len(drift.PropertyDifferences))
+			for _, property := range drift.PropertyDifferences {
+				properties = append(properties, fmt.Sprintf("%s: %s - %s => %s", property.DifferenceType, aws.ToString(property.PropertyPath), aws.ToString(property.ExpectedValue), aws.ToString(property.ActualValue)))
+			}
+			sort.Strings(properties)
+			output.AddContents(map[string]interface{}{
+				"StackName":  result.StackName,
+				"LogicalId":  aws.ToString(drift.LogicalResourceId),
+				"Type":       aws.ToString(drift.ResourceType),
+				"ChangeType": changetype,
+				"Details":    properties,
+			})
+		}
+	}
+	output.AddToBuffer()
+	summary := lib.SummarizeDriftResults(results)
+	summaryOutput := format.OutputArray{Keys: []string{"Total", "Drifted", "InSync", "Failed"}, Settings: settings.NewOutputSettings()}
+	summaryOutput.Settings.Title = "Summary"
+	summaryOutput.AddContents(map[string]interface{}{
+		"Total":   summary.Total,
+		"Drifted": summary.Drifted,
+		"InSync":  summary.InSync,
+		"Failed":  summary.Failed,
+	})
+	summaryOutput.AddToBuffer()
+	output.Write()
+}
+
+// getDriftResourceTypeFilter returns the resource types to show the drift for.
+// The --resource-type flag takes precedence over the config file.
+func getDriftResourceTypeFilter() []string {
+	if *drift_ResourceType == "" {
+		return settings.GetStringSlice("drift.resource-type-filter")
+	}
+	result := []string{}
+	for _, resourceType := range strings.Split(*drift_ResourceType, ",") {
+		if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+			result = append(result, resourceType)
+		}
+	}
+	return result
+}
+
+// showDriftCoverage shows which resource types in the template support native drift detection
+func showDriftCoverage(template lib.CfnTemplateBody) {
+	report := lib.GetDriftCoverage(template)
+	output := format.OutputArray{Keys: []string{"Type", "Drift detection"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Drift detection coverage for stack " + *drift_StackName
+	output.Settings.SortKey = "Type"
+	for _, resourceType := range report.Supported {
+		output.AddContents(map[string]interface{}{"Type": resourceType, "Drift detection": "Supported"})
+	}
+	for _, resourceType := range report.Unsupported {
+		output.AddContents(map[string]interface{}{"Type": resourceType, "Drift detection": outputsettings.StringWarningInline("Not supported")})
+	}
+	output.Write()
+	fmt.Print(outputsettings.StringInfo(fmt.Sprintf("%.0f%% of the resources in the stack support drift detection", report.CoveragePercent)))
+}
+
+// getPolicyDrift returns the drift of a resource policy as a line by line diff
+// of the formatted policy documents. The expected policy is taken from the drift
+// results, where CloudFormation has resolved all intrinsic functions, and falls
+// back to the policy in the template.
+func getPolicyDrift(logicalId string, property string, template lib.CfnTemplateBody, params map[string]interface{}, expectedProperties map[string]interface{}, actualProperties map[string]interface{}) string {
+	expected, ok := "", false
+	if document, found := expectedProperties[property]; found {
+		formatted, err := lib.FormatPolicyDocument(document)
+		expected, ok = formatted, err == nil
+	}
+	if !ok {
+		expected, ok = lib.GetResourcePolicyFromTemplate(logicalId, template, params)
+	}
+	if !ok {
+		return ""
+	}
+	actual, err := lib.FormatPolicyDocument(actualProperties[property])
+	if err != nil {
+		return ""
+	}
+	return formatPolicyDiff("/"+property, expected, actual)
+}
+
+// getInlinePolicyDrift returns the drift of the inline policies of an IAM role,
+// comparing the policies that CloudFormation expects with the ones attached to
+// the role in IAM. Changed policies are shown as a diff of the policy documents.
+func getInlinePolicyDrift(roleName string, expectedProperties map[string]interface{}, awsConfig config.AWSConfig) []string {
+	expected := lib.GetExpectedInlinePolicies(expectedProperties)
+	actual, err := lib.GetIAMInlinePolicies(roleName, awsConfig.IAMClient())
+	if err != nil {
+		failWithError(err)
+	}
+	names := make([]string, 0, len(expected)+len(actual))
+	for name := range expected {
+		names = append(names, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := []string{}
+	for _, name := range names {
+		path := "/Policies/" + name
+		expectedPolicy, inExpected := expected[name]
+		actualPolicy, inActual := actual[name]
+		switch {
+		case !inActual:
+			result = append(result, outputsettings.StringWarningInline(fmt.Sprintf("%s: %s - %s", types.DifferenceTypeRemove, path, expectedPolicy)))
+		case !inExpected:
+			result = append(result, outputsettings.StringPositiveInline(fmt.Sprintf("%s: %s - %s", types.DifferenceTypeAdd, path, actualPolicy)))
+		default:
+			if diff := formatPolicyDiff(path, expectedPolicy, actualPolicy); diff != "" {
+				result = append(result, diff)
+			}
+		}
+	}
+	return result
+}
+
+// formatPolicyDiff returns a line by line diff of the two policy documents with
+// the changed lines highlighted, or an empty string if they're the same
+func formatPolicyDiff(path string, expected string, actual string) string {
+	diff := lib.DiffTemplates(expected, actual)
+	if !lib.HasChanges(diff) {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("%s: %s", types.DifferenceTypeNotEqual, path)}
+	for _, line := range diff {
+		text := fmt.Sprintf("%v %v", line.Type, line.Text)
+		switch line.Type {
+		case lib.DiffLineAdded:
+			text = outputsettings.StringPositiveInline(text)
+		case lib.DiffLineRemoved:
+			text = outputsettings.StringWarningInline(text)
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// updateDriftCache stores the drift results in the cache. When using --since last,
+// results that were already found in the previous run are removed from the output.
+// Filtered results don't show the full drift of the stack, so they're only
+// compared against the cache and not stored when store is false.
+func updateDriftCache(cachePath string, output *format.OutputArray, store bool) {
+	previous, found, err := lib.ReadDriftCache(cachePath)
+	if err != nil {
+		failWithError(err)
+	}
+	cache := lib.DriftCache{StackName: *drift_StackName, DetectedAt: time.Now().UTC(), Items: []lib.DriftCacheItem{}}
+	newContents := make([]format.OutputHolder, 0)
+	for _, holder := range output.Contents {
+		item := driftCacheItemFromContents(holder.Contents)
+		cache.Items = append(cache.Items, item)
+		if !found || previous.IsNew(item) {
+			newContents = append(newContents, holder)
+		}
+	}
+	if *drift_Since == "last" {
+		if found {
+			output.Settings.Title = fmt.Sprintf("New drift results for stack %v since %v", *drift_StackName, formatStackTime(&previous.DetectedAt))
+		}
+		output.Contents = newContents
+	}
+	if !store {
+		return
+	}
+	if err := cache.Write(cachePath); err != nil {
+		failWithError(err)
+	}
+}
+
+func driftCacheItemFromContents(contents map[string]interface{}) lib.DriftCacheItem {
+	details := []string{}
+	switch value := contents["Details"].(type) {
+	case []string:
+		details = value
+	case string:
+		details = []string{value}
+	case nil:
+	default:
+		details = []string{fmt.Sprint(value)}
+	}
+	return lib.NewDriftCacheItem(fmt.Sprint(contents["LogicalId"]), fmt.Sprint(contents["Type"]), fmt.Sprint(contents["ChangeType"]), details)
+}
+
+// specialCaseResources holds the physical IDs, keyed by logical ID, of the
+// resources that get additional drift checks beyond those of CloudFormation
+type specialCaseResources struct {
+	nacls             map[string]string
+	routetables       map[string]string
+	tgwRoutetables    map[string]string
+	vpcs              map[string]string
+	subnets           map[string]string
+	vpcEndpoints      map[string]string
+	securityGroups    map[string]string
+	logicalToPhysical map[string]string
+}
+
+// separateSpecialCases collects the resources that need additional drift checks.
+// Resources that aren't needed for the resource type filter are skipped, but
+// all resources are included in logicalToPhysical to resolve references.
+func separateSpecialCases(defaultDrift []types.StackResourceDrift, resourceTypes []string) specialCaseResources {
+	result := specialCaseResources{
+		nacls:             make(map[string]string),
+		routetables:       make(map[string]string),
+		tgwRoutetables:    make(map[string]string),
+		vpcs:              make(map[string]string),
+		subnets:           make(map[string]string),
+		vpcEndpoints:      make(map[string]string),
+		securityGroups:    make(map[string]string),
+		logicalToPhysical: make(map[string]string),
+	}
 	for _, drift := range defaultDrift {
-		logicalToPhysical[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		result.logicalToPhysical[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		if !lib.NeedsDriftCheck(*drift.ResourceType, resourceTypes) {
+			continue
+		}
 		switch *drift.ResourceType {
 		case "AWS::EC2::NetworkAcl":
-			naclResources[*drift.LogicalResourceId] = *drift.PhysicalResourceId
-			break
+			result.nacls[*drift.LogicalResourceId] = *drift.PhysicalResourceId
 		case "AWS::EC2::RouteTable":
-			routetableResources[*drift.LogicalResourceId] = *drift.PhysicalResourceId
-			break
+			result.routetables[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		case "AWS::EC2::TransitGatewayRouteTable":
+			result.tgwRoutetables[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		case "AWS::EC2::VPC":
+			result.vpcs[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		case "AWS::EC2::Subnet":
+			result.subnets[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		case "AWS::EC2::VPCEndpoint":
+			result.vpcEndpoints[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		case "AWS::EC2::SecurityGroup":
+			result.securityGroups[*drift.LogicalResourceId] = *drift.PhysicalResourceId
+		}
+	}
+	return result
+}
+
+// checkSubnets verifies the configuration of the subnets and if there are differences adds those to the provided output array
+func checkSubnets(subnetResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, tags []types.Tag, output *format.OutputArray, awsConfig config.AWSConfig) {
+	params := *lib.GetParametersMap(parameters)
+	stackTags := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		stackTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for logicalId, physicalId := range subnetResources {
+		expected := lib.GetSubnetFromTemplate(logicalId, template, params)
+		actual, err := lib.GetSubnetConfig(physicalId, awsConfig.EC2Client())
+		if err != nil {
+			failWithError(err)
+		}
+		changes := lib.CompareSubnetConfigs(expected, actual, stackTags)
+		if len(changes) == 0 {
+			continue
+		}
+		if *drift_separateProperties {
+			for _, change := range changes {
+				content := make(map[string]interface{})
+				content["LogicalId"] = logicalId
+				content["Type"] = "AWS::EC2::Subnet"
+				content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+				content["Details"] = change
+				output.AddContents(content)
+			}
+		} else {
+			content := make(map[string]interface{})
+			content["LogicalId"] = logicalId
+			content["Type"] = "AWS::EC2::Subnet"
+			content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+			content["Details"] = changes
+			output.AddContents(content)
+		}
+	}
+}
+
+// checkVPCEndpoints verifies the configuration of the VPC endpoints and if there are differences adds those to the provided output array
+func checkVPCEndpoints(endpointResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, logicalToPhysical map[string]string, output *format.OutputArray, awsConfig config.AWSConfig) {
+	params := *lib.GetParametersMap(parameters)
+	for logicalId, physicalId := range endpointResources {
+		diffs, err := lib.GetVPCEndpointDrift(physicalId, template, logicalId, params, logicalToPhysical, awsConfig.EC2Client())
+		if err != nil {
+			failWithError(err)
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		changes := make([]string, 0, len(diffs))
+		for _, diff := range diffs {
+			if diff.Property == "PolicyDocument" {
+				changes = append(changes, formatPolicyDiff(diff.Property, diff.Expected, diff.Actual))
+				continue
+			}
+			changes = append(changes, diff.String())
+		}
+		if *drift_separateProperties {
+			for _, change := range changes {
+				content := make(map[string]interface{})
+				content["LogicalId"] = logicalId
+				content["Type"] = "AWS::EC2::VPCEndpoint"
+				content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+				content["Details"] = change
+				output.AddContents(content)
+			}
+		} else {
+			content := make(map[string]interface{})
+			content["LogicalId"] = logicalId
+			content["Type"] = "AWS::EC2::VPCEndpoint"
+			content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+			content["Details"] = changes
+			output.AddContents(content)
+		}
+	}
+}
+
+// checkVpcs verifies the configuration of the VPCs and if there are differences adds those to the provided output array
+func checkVpcs(vpcResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, output *format.OutputArray, awsConfig config.AWSConfig) {
+	params := *lib.GetParametersMap(parameters)
+	for logicalId, physicalId := range vpcResources {
+		expected := lib.GetVpcFromTemplate(logicalId, template, params)
+		actual, err := lib.GetVpcConfig(physicalId, awsConfig.EC2Client())
+		if err != nil {
+			failWithError(err)
+		}
+		changes := lib.CompareVpcConfigs(expected, actual)
+		if len(changes) == 0 {
+			continue
+		}
+		if *drift_separateProperties {
+			for _, change := range changes {
+				content := make(map[string]interface{})
+				content["LogicalId"] = logicalId
+				content["Type"] = "AWS::EC2::VPC"
+				content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+				content["Details"] = change
+				output.AddContents(content)
+			}
+		} else {
+			content := make(map[string]interface{})
+			content["LogicalId"] = logicalId
+			content["Type"] = "AWS::EC2::VPC"
+			content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+			content["Details"] = changes
+			output.AddContents(content)
 		}
 	}
-	return naclResources, routetableResources, logicalToPhysical
 }
 
 // checkNaclEntries verifies the NACL entries and if there are differences adds those to the provided output array
@@ -249,6 +714,56 @@ func checkNaclEntries(naclResources map[string]string, template lib.CfnTemplateB
 	}
 }
 
+// checkSecurityGroupRules verifies the rules of the security groups and if there are differences adds those to the provided output array
+func checkSecurityGroupRules(securityGroupResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, logicalToPhysical map[string]string, output *format.OutputArray, awsConfig config.AWSConfig) {
+	for logicalId, physicalId := range securityGroupResources {
+		rulechanges := []string{}
+		actualRules, err := lib.GetSecurityGroupRules(physicalId, awsConfig.EC2Client())
+		if err != nil {
+			failWithError(err)
+		}
+		attachedRules, complete := lib.FilterSecurityGroupRulesByLogicalId(logicalId, template, parameters, logicalToPhysical)
+		for key, rule := range actualRules {
+			if cfnrule, ok := attachedRules[key]; ok {
+				if cfnrule.Description != rule.Description {
+					ruledetails := fmt.Sprintf("Expected: %s%sActual: %s", cfnrule.String(), outputsettings.GetSeparator(), rule.String())
+					rulechanges = append(rulechanges, ruledetails)
+				}
+				delete(attachedRules, key)
+			} else if complete {
+				// Only report unmanaged rules if every rule in the template could be linked to its security group
+				ruledetails := fmt.Sprintf("Unmanaged rule: %s", rule.String())
+				rulechanges = append(rulechanges, outputsettings.StringPositiveInline(ruledetails))
+			}
+		}
+		// Leftover rules only exist in CloudFormation
+		for _, cfnrule := range attachedRules {
+			ruledetails := fmt.Sprintf("Removed rule: %s", cfnrule.String())
+			rulechanges = append(rulechanges, outputsettings.StringWarningInline(ruledetails))
+		}
+		if len(rulechanges) != 0 {
+			sort.Strings(rulechanges)
+			if *drift_separateProperties {
+				for _, change := range rulechanges {
+					content := make(map[string]interface{})
+					content["LogicalId"] = fmt.Sprintf("Rule for security group %s", logicalId)
+					content["Type"] = "AWS::EC2::SecurityGroup"
+					content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+					content["Details"] = change
+					output.AddContents(content)
+				}
+			} else {
+				content := make(map[string]interface{})
+				content["LogicalId"] = fmt.Sprintf("Rules for security group %s", logicalId)
+				content["Type"] = "AWS::EC2::SecurityGroup"
+				content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+				content["Details"] = rulechanges
+				output.AddContents(content)
+			}
+		}
+	}
+}
+
 // checkRouteTableRoutes verifies the routes and if there are differences adds those to the provided output array
 func checkRouteTableRoutes(routetableResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, logicalToPhysical map[string]string, output *format.OutputArray, awsConfig config.AWSConfig) {
 	// Create a list of all AWS managed prefixes
@@ -320,6 +835,54 @@ func checkRouteTableRoutes(routetableResources map[string]string, template lib.C
 	}
 }
 
+// checkTransitGatewayRoutes verifies the static routes of the transit gateway route tables and if there are differences adds those to the provided output array
+func checkTransitGatewayRoutes(tgwRoutetableResources map[string]string, template lib.CfnTemplateBody, parameters []types.Parameter, logicalToPhysical map[string]string, output *format.OutputArray, awsConfig config.AWSConfig) {
+	for logicalId, physicalId := range tgwRoutetableResources {
+		attachedRoutes := lib.FilterTGWRoutesByLogicalId(logicalId, template, parameters, logicalToPhysical)
+		diffs, err := lib.GetTransitGatewayRouteDrift(physicalId, attachedRoutes, awsConfig.EC2Client())
+		if err != nil {
+			failWithError(err)
+		}
+		rulechanges := []string{}
+		for _, diff := range diffs {
+			switch diff.Type {
+			case lib.TGWRouteModified:
+				ruledetails := fmt.Sprintf("Expected: %s%sActual: %s", tgwRouteToString(*diff.Expected), outputsettings.GetSeparator(), tgwRouteToString(*diff.Actual))
+				rulechanges = append(rulechanges, ruledetails)
+			case lib.TGWRouteUnmanaged:
+				ruledetails := fmt.Sprintf("Unmanaged route: %s", tgwRouteToString(*diff.Actual))
+				rulechanges = append(rulechanges, outputsettings.StringPositiveInline(ruledetails))
+			case lib.TGWRouteRemoved:
+				// An empty destination implies it wasn't created, likely due to a condition
+				if diff.Destination == "" {
+					continue
+				}
+				ruledetails := fmt.Sprintf("Removed route: %s", tgwRouteToString(*diff.Expected))
+				rulechanges = append(rulechanges, outputsettings.StringWarningInline(ruledetails))
+			}
+		}
+		if len(rulechanges) != 0 {
+			if *drift_separateProperties {
+				for _, change := range rulechanges {
+					content := make(map[string]interface{})
+					content["LogicalId"] = fmt.Sprintf("Route for TransitGatewayRouteTable %s", logicalId)
+					content["Type"] = "AWS::EC2::TransitGatewayRoute"
+					content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+					content["Details"] = change
+					output.AddContents(content)
+				}
+			} else {
+				content := make(map[string]interface{})
+				content["LogicalId"] = fmt.Sprintf("Routes for TransitGatewayRouteTable %s", logicalId)
+				content["Type"] = "AWS::EC2::TransitGatewayRoute"
+				content["ChangeType"] = string(types.StackResourceDriftStatusModified)
+				content["Details"] = rulechanges
+				output.AddContents(content)
+			}
+		}
+	}
+}
+
 func verifyTagOrder(properties []types.PropertyDifference) (map[string]string, map[string]string) {
 	type tagprop struct {
 		ID       string
@@ -352,25 +915,15 @@ func verifyTagOrder(properties []types.PropertyDifference) (map[string]string, m
 }
 
 func getExpectedAndActualTags(expectedResources map[string]interface{}, actualResources map[string]interface{}) map[string]map[string]string {
-	// if Tags exists in expectedResources, compare the list of tags with those in actualResources
 	tags := make(map[string]map[string]string)
-	// go through expectedResources["Tags"] and add each item in expectedTags
-	if expectedResources["Tags"] != nil {
-		for _, tag := range expectedResources["Tags"].([]interface{}) {
-			tagMap := tag.(map[string]interface{})
-			tags[tagMap["Key"].(string)] = map[string]string{"Expected": tagMap["Value"].(string)}
-		}
-	}
-	// go through actualResources["Tags"] and add each item in actualTags
-	if actualResources["Tags"] != nil {
-		for _, tag := range actualResources["Tags"].([]interface{}) {
-			fmt.Println(tag)
-			tagMap := tag.(map[string]interface{})
-			if tags[tagMap["Key"].(string)] == nil {
-				tags[tagMap["Key"].(string)] = map[string]string{"Expected": "", "Actual": tagMap["Value"].(string)}
-			}
-			tags[tagMap["Key"].(string)]["Actual"] = tagMap["Value"].(string)
+	for key, value := range lib.ParseTagsFromAttributes(expectedResources) {
+		tags[key] = map[string]string{"Expected": value}
+	}
+	for key, value := range lib.ParseTagsFromAttributes(actualResources) {
+		if tags[key] == nil {
+			tags[key] = map[string]string{"Expected": ""}
 		}
+		tags[key]["Actual"] = value
 	}
 	return tags
 }
@@ -488,6 +1041,14 @@ func naclEntryToString(entry ec2types.NetworkAclEntry) string {
 	return fmt.Sprintf("%s #%v %v: %s, %s %s", direction, *entry.RuleNumber, entry.RuleAction, *entry.Protocol, cidr, ports)
 }
 
+func tgwRouteToString(route ec2types.TransitGatewayRoute) string {
+	destination := lib.GetTransitGatewayRouteDestination(route)
+	if route.State == ec2types.TransitGatewayRouteStateBlackhole {
+		return fmt.Sprintf("%s: (%s)", destination, string(route.State))
+	}
+	return fmt.Sprintf("%s: %s", destination, lib.GetTransitGatewayRouteTarget(route))
+}
+
 func routeToString(route ec2types.Route) string {
 	destination := lib.GetRouteDestination(route)
 	target := lib.GetRouteTarget(route)
@@ -0,0 +1,83 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var driftaccountscan_StackName *string
+
+// driftAccountScanCmd represents the drift account-scan command
+var driftAccountScanCmd = &cobra.Command{
+	Use:   "account-scan",
+	Short: "Show all drifted stacks in the account",
+	Long: `Shows all stacks in the account and region that are drifted.
+
+This only uses the result of the most recent drift detection of each stack, it
+doesn't start a new drift detection as doing so for every stack can be slow
+and expensive. Stacks whose drift was never detected are therefore not shown.
+Use --stackname with a wildcard to only check a subset of the stacks.
+
+Examples:
+
+$ fog drift account-scan
+$ fog drift account-scan --stackname "app-*"
+`,
+	Run: scanAccountForDrift,
+}
+
+func init() {
+	driftCmd.AddCommand(driftAccountScanCmd)
+	driftaccountscan_StackName = driftAccountScanCmd.Flags().StringP("stackname", "n", "", "Only check stacks matching this name, accepts wildcards")
+}
+
+func scanAccountForDrift(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetStacksWithDrift(driftaccountscan_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	if len(stacks) == 0 {
+		fmt.Print(outputsettings.StringSuccess("No drifted stacks found"))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"StackName", "DriftStatus", "LastChecked"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Drifted stacks in account %v (%v)", awsConfig.AccountID, awsConfig.Region)
+	for _, stack := range stacks {
+		output.AddContents(map[string]interface{}{
+			"StackName":   stack.Name,
+			"DriftStatus": string(stack.RawInfo.DriftInformation.StackDriftStatus),
+			"LastChecked": formatStackTime(stack.RawInfo.DriftInformation.LastCheckTimestamp),
+		})
+	}
+	output.Write()
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2023 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/viper"
+)
+
+func TestSeparateSpecialCases(t *testing.T) {
+	drift := func(logicalId, physicalId, resourceType string) types.StackResourceDrift {
+		return types.StackResourceDrift{
+			LogicalResourceId:  aws.String(logicalId),
+			PhysicalResourceId: aws.String(physicalId),
+			ResourceType:       aws.String(resourceType),
+		}
+	}
+	defaultDrift := []types.StackResourceDrift{
+		drift("Nacl", "acl-123", "AWS::EC2::NetworkAcl"),
+		drift("RouteTable", "rtb-123", "AWS::EC2::RouteTable"),
+		drift("TgwRouteTable", "tgw-rtb-123", "AWS::EC2::TransitGatewayRouteTable"),
+		drift("Vpc", "vpc-123", "AWS::EC2::VPC"),
+		drift("Subnet", "subnet-123", "AWS::EC2::Subnet"),
+		drift("Endpoint", "vpce-123", "AWS::EC2::VPCEndpoint"),
+		drift("SecurityGroup", "sg-123", "AWS::EC2::SecurityGroup"),
+		drift("Bucket", "my-bucket", "AWS::S3::Bucket"),
+	}
+	got := separateSpecialCases(defaultDrift, nil)
+	tests := []struct {
+		name string
+		got  map[string]string
+		want map[string]string
+	}{
+		{"NACLs", got.nacls, map[string]string{"Nacl": "acl-123"}},
+		{"Route tables", got.routetables, map[string]string{"RouteTable": "rtb-123"}},
+		{"Transit gateway route tables", got.tgwRoutetables, map[string]string{"TgwRouteTable": "tgw-rtb-123"}},
+		{"VPCs", got.vpcs, map[string]string{"Vpc": "vpc-123"}},
+		{"Subnets", got.subnets, map[string]string{"Subnet": "subnet-123"}},
+		{"VPC endpoints", got.vpcEndpoints, map[string]string{"Endpoint": "vpce-123"}},
+		{"Security groups", got.securityGroups, map[string]string{"SecurityGroup": "sg-123"}},
+		{"All resources", got.logicalToPhysical, map[string]string{
+			"Nacl":          "acl-123",
+			"RouteTable":    "rtb-123",
+			"TgwRouteTable": "tgw-rtb-123",
+			"Vpc":           "vpc-123",
+			"Subnet":        "subnet-123",
+			"Endpoint":      "vpce-123",
+			"SecurityGroup": "sg-123",
+			"Bucket":        "my-bucket",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("separateSpecialCases() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeparateSpecialCases_ResourceTypeFilter(t *testing.T) {
+	drift := func(logicalId, physicalId, resourceType string) types.StackResourceDrift {
+		return types.StackResourceDrift{
+			LogicalResourceId:  aws.String(logicalId),
+			PhysicalResourceId: aws.String(physicalId),
+			ResourceType:       aws.String(resourceType),
+		}
+	}
+	defaultDrift := []types.StackResourceDrift{
+		drift("Nacl", "acl-123", "AWS::EC2::NetworkAcl"),
+		drift("RouteTable", "rtb-123", "AWS::EC2::RouteTable"),
+		drift("Vpc", "vpc-123", "AWS::EC2::VPC"),
+		drift("Gateway", "igw-123", "AWS::EC2::InternetGateway"),
+	}
+	got := separateSpecialCases(defaultDrift, []string{"AWS::EC2::Route"})
+	if !reflect.DeepEqual(got.routetables, map[string]string{"RouteTable": "rtb-123"}) {
+		t.Errorf("separateSpecialCases() routetables = %v, want the route table", got.routetables)
+	}
+	if len(got.nacls) != 0 || len(got.vpcs) != 0 {
+		t.Errorf("separateSpecialCases() should skip the NACLs and VPCs, got %v and %v", got.nacls, got.vpcs)
+	}
+	if len(got.logicalToPhysical) != len(defaultDrift) {
+		t.Errorf("separateSpecialCases() logicalToPhysical = %v, want all resources", got.logicalToPhysical)
+	}
+}
+
+func TestGetExpectedAndActualTags(t *testing.T) {
+	tags := func(pairs ...string) map[string]interface{} {
+		result := []interface{}{}
+		for i := 0; i < len(pairs); i += 2 {
+			result = append(result, map[string]interface{}{"Key": pairs[i], "Value": pairs[i+1]})
+		}
+		return map[string]interface{}{"Tags": result}
+	}
+	tests := []struct {
+		name     string
+		expected map[string]interface{}
+		actual   map[string]interface{}
+		want     map[string]map[string]string
+	}{
+		{"Both populated", tags("Owner", "team-a"), tags("Owner", "team-b"), map[string]map[string]string{
+			"Owner": {"Expected": "team-a", "Actual": "team-b"},
+		}},
+		{"Only expected", tags("Owner", "team-a"), map[string]interface{}{}, map[string]map[string]string{
+			"Owner": {"Expected": "team-a"},
+		}},
+		{"Only actual", map[string]interface{}{}, tags("Owner", "team-b"), map[string]map[string]string{
+			"Owner": {"Expected": "", "Actual": "team-b"},
+		}},
+		{"Empty tags", tags(), tags(), map[string]map[string]string{}},
+		{"Mismatched keys", tags("Owner", "team-a"), tags("Team", "team-b"), map[string]map[string]string{
+			"Owner": {"Expected": "team-a"},
+			"Team":  {"Expected": "", "Actual": "team-b"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getExpectedAndActualTags(tt.expected, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getExpectedAndActualTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldTagBeHandled(t *testing.T) {
+	originalIgnoreTags := *drift_IgnoreTags
+	defer func() { *drift_IgnoreTags = originalIgnoreTags }()
+	*drift_IgnoreTags = "ignored-tag,AWS::S3::Bucket:bucket-tag,MyQueue:queue-tag"
+	viper.Set("drift.ignore-tags", []string{"config-tag"})
+	defer viper.Set("drift.ignore-tags", nil)
+
+	bucket := types.StackResourceDrift{LogicalResourceId: aws.String("MyBucket"), ResourceType: aws.String("AWS::S3::Bucket")}
+	queue := types.StackResourceDrift{LogicalResourceId: aws.String("MyQueue"), ResourceType: aws.String("AWS::SQS::Queue")}
+	tests := []struct {
+		name  string
+		tag   string
+		drift types.StackResourceDrift
+		want  bool
+	}{
+		{"Simple ignore", "ignored-tag", bucket, false},
+		{"Simple ignore from config", "config-tag", queue, false},
+		{"Resource type qualified ignore", "bucket-tag", bucket, false},
+		{"Resource type qualified ignore for other type", "bucket-tag", queue, true},
+		{"Logical ID qualified ignore", "queue-tag", queue, false},
+		{"Logical ID qualified ignore for other resource", "queue-tag", bucket, true},
+		{"Tag not in ignore list", "Owner", bucket, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldTagBeHandled(tt.tag, tt.drift); got != tt.want {
+				t.Errorf("shouldTagBeHandled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
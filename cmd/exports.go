@@ -73,7 +73,7 @@ func listExports(cmd *cobra.Command, args []string) {
 	if *exports_stackName != "" {
 		subtitle = fmt.Sprintf("Exports for %v", *exports_stackName)
 	}
-	title := fmt.Sprintf("%v in account %v for region %v", subtitle, awsConfig.AccountID, awsConfig.Region)
+	title := fmt.Sprintf("%v in account %v for region %v", subtitle, formatAccountDisplay(awsConfig), awsConfig.Region)
 	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
 	output.Settings.Title = title
 	output.Settings.SortKey = "Export"
@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
 	"github.com/spf13/viper"
 )
 
@@ -58,11 +60,6 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-// addToField increases the integer value of the field by the provided value
-func addToField(field *map[string]interface{}, key string, value int) {
-	(*field)[key] = (*field)[key].(int) + value
-}
-
 func failWithError(err error) {
 	fmt.Print(settings.NewOutputSettings().StringFailure(fmt.Sprintf("Error: %v", err)))
 	if viper.GetBool("debug") {
@@ -70,3 +67,29 @@ func failWithError(err error) {
 	}
 	os.Exit(1)
 }
+
+// accountAliases caches the aliases of the accounts that have been looked up
+var accountAliases = make(map[string]string)
+
+// getAccountAlias returns the alias of the account, looking it up the first
+// time it's needed. If the alias can't be retrieved, for example due to missing
+// permissions, an empty string is returned.
+func getAccountAlias(awsConfig config.AWSConfig) string {
+	if alias, ok := accountAliases[awsConfig.AccountID]; ok {
+		return alias
+	}
+	alias, err := lib.GetAccountAlias(awsConfig.IAMClient())
+	if err != nil {
+		alias = ""
+	}
+	accountAliases[awsConfig.AccountID] = alias
+	return alias
+}
+
+// formatAccountDisplay returns the account ID, prefixed with the alias of the account if it has one
+func formatAccountDisplay(awsConfig config.AWSConfig) string {
+	if alias := getAccountAlias(awsConfig); alias != "" {
+		return fmt.Sprintf("%s (%s)", alias, awsConfig.AccountID)
+	}
+	return awsConfig.AccountID
+}
@@ -74,9 +74,9 @@ func history(cmd *cobra.Command, args []string) {
 	}
 	output := format.OutputArray{Keys: []string{}, Settings: settings.NewOutputSettings()}
 	if *history_StackName == "" {
-		output.Settings.Title = fmt.Sprintf("Deployments in account %s for region %s", awsConfig.GetAccountAliasID(), awsConfig.Region)
+		output.Settings.Title = fmt.Sprintf("Deployments in account %s for region %s", formatAccountDisplay(awsConfig), awsConfig.Region)
 	} else {
-		output.Settings.Title = fmt.Sprintf("Deployments for stack(s) %s in account %s for region %s", *history_StackName, awsConfig.GetAccountAliasID(), awsConfig.Region)
+		output.Settings.Title = fmt.Sprintf("Deployments for stack(s) %s in account %s for region %s", *history_StackName, formatAccountDisplay(awsConfig), awsConfig.Region)
 	}
 	output.Write()
 }
@@ -89,7 +89,7 @@ func printLog(log lib.DeploymentLog) {
 	logtitle := "Details about the deployment"
 	output := format.OutputArray{Keys: logkeys, Settings: settings.NewOutputSettings()}
 	output.Settings.Title = logtitle
-	if log.Status == lib.DeploymentLogStatusSuccess {
+	if log.Status == lib.DeploymentLogStatusSuccess || log.Status == lib.DeploymentLogStatusNoOp {
 		output.AddHeader(outputsettings.StringPositive("📋 " + header))
 	} else {
 		output.AddHeader(outputsettings.StringWarning("📋 " + header))
@@ -109,14 +109,11 @@ func printLog(log lib.DeploymentLog) {
 	//print change set info
 	changesettitle := "Deployed change set"
 	summaryTitle := "Summary of changes"
-	hasModule := false
+	changeset := lib.ChangesetInfo{}
 	for _, change := range log.Changes {
-		if change.Module != "" {
-			hasModule = true
-			break
-		}
+		changeset.AddChange(change)
 	}
-	printChangeset(changesettitle, summaryTitle, log.Changes, hasModule)
+	printChangeset(changesettitle, summaryTitle, changeset)
 
 	if log.Status == lib.DeploymentLogStatusFailed {
 		//print error info
@@ -22,6 +22,7 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -159,7 +160,7 @@ func generateReport() {
 		latestText = "Single event."
 	}
 	if *report_StackName == "" {
-		mainoutput.Settings.Title = fmt.Sprintf("Fog report for account %s. %s", awsConfig.GetAccountAliasID(), latestText)
+		mainoutput.Settings.Title = fmt.Sprintf("Fog report for account %s. %s", formatAccountDisplay(awsConfig), latestText)
 	} else if strings.Contains(*report_StackName, "*") {
 		mainoutput.Settings.Title = fmt.Sprintf("Fog report for stacks matching '%s'. %s", *report_StackName, latestText)
 	} else {
@@ -207,7 +208,7 @@ func getReportMermaidSettings() *format.OutputSettings {
 // generateStackReport creates the report for the provided stack
 func generateStackReport(stack lib.CfnStack, mainoutput format.OutputArray, awsConfig config.AWSConfig) {
 	mainoutput.AddHeader(fmt.Sprintf("Stack %s", stack.Name))
-	events, err := stack.GetEvents(awsConfig.CloudformationClient())
+	events, err := stack.GetEvents(context.TODO(), awsConfig.CloudformationClient())
 	if err != nil {
 		failWithError(err)
 	}
@@ -240,13 +241,13 @@ func generateStackReport(stack lib.CfnStack, mainoutput format.OutputArray, awsC
 func generateFrontMatter(stacks map[string]lib.CfnStack, awsConfig config.AWSConfig) map[string]string {
 	result := make(map[string]string)
 	for _, stack := range stacks {
-		events, err := stack.GetEvents(awsConfig.CloudformationClient())
+		events, err := stack.GetEvents(context.TODO(), awsConfig.CloudformationClient())
 		if err != nil {
 			failWithError(err)
 		}
 		for _, event := range events {
 			result["account"] = awsConfig.AccountID
-			result["accountalias"] = awsConfig.GetAccountAliasID()
+			result["accountalias"] = formatAccountDisplay(awsConfig)
 			result["region"] = awsConfig.Region
 			result["stack"] = stack.Name
 			result["date"] = event.StartDate.In(settings.GetTimezoneLocation()).Format(time.RFC3339)
@@ -311,7 +312,7 @@ func createMetadataTable(stack lib.CfnStack, event lib.StackEvent, awsConfig con
 	}
 	contents := make(map[string]interface{})
 	contents["Stack"] = stack.Name
-	contents["Account"] = awsConfig.GetAccountAliasID()
+	contents["Account"] = formatAccountDisplay(awsConfig)
 	contents["Region"] = awsConfig.Region
 	contents["Type"] = event.Type
 	contents["Start time"] = event.StartDate.In(settings.GetTimezoneLocation()).Format(time.RFC3339)
@@ -68,7 +68,7 @@ func listResources(cmd *cobra.Command, args []string) {
 	if *resource_stackname != "" {
 		subtitle = fmt.Sprintf("Resources for %v", *resource_stackname)
 	}
-	title := fmt.Sprintf("%v in account %v for region %v", subtitle, awsConfig.AccountID, awsConfig.Region)
+	title := fmt.Sprintf("%v in account %v for region %v", subtitle, formatAccountDisplay(awsConfig), awsConfig.Region)
 	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
 	output.Settings.Title = title
 	output.Settings.SortKey = "Type"
@@ -81,10 +81,10 @@ func init() {
 	// Default file structure settings
 	viper.SetDefault("templates.extensions", []string{"", ".yaml", ".yml", ".templ", ".tmpl", ".template", ".json"})
 	viper.SetDefault("templates.directory", "templates")
-	viper.SetDefault("tags.extensions", []string{"", ".json"})
+	viper.SetDefault("tags.extensions", []string{"", ".json", ".yaml", ".yml", ".toml"})
 	viper.SetDefault("tags.directory", "tags")
 	viper.SetDefault("tags.default", map[string]string{})
-	viper.SetDefault("parameters.extensions", []string{"", ".json"})
+	viper.SetDefault("parameters.extensions", []string{"", ".json", ".yaml", ".yml", ".toml"})
 	viper.SetDefault("parameters.directory", "parameters")
 	viper.SetDefault("deployments.extensions", []string{"", ".yaml", ".yml", ".json"})
 	viper.SetDefault("deployments.directory", []string{"."})
@@ -92,6 +92,31 @@ func init() {
 	viper.SetDefault("rootdir", ".")
 
 	viper.SetDefault("changeset.name-format", "fog-$TIMESTAMP")
+	viper.SetDefault("changeset.description", "")
+
+	viper.SetDefault("deployment.assume-role", "")
+	viper.SetDefault("deployment.assume-role-external-id", "")
+	viper.SetDefault("deployment.assume-role-session-name", config.DefaultRoleSessionName)
+	viper.SetDefault("deployment.detect-drift-after", false)
+	viper.SetDefault("deployment.required-tags", []string{})
+	viper.SetDefault("deployment.required-tags-ignore-case", false)
+	viper.SetDefault("deployment.role-arn", "")
+
+	viper.SetDefault("sops.binary", "sops")
+
+	viper.SetDefault("drift.detect-security-groups", true)
+	viper.SetDefault("drift.detection-timeout", 300)
+	viper.SetDefault("drift.resource-type-filter", []string{})
+
+	viper.SetDefault("graph.max-nodes", 50)
+
+	viper.SetDefault("notifications.arns", []string{})
+
+	viper.SetDefault("retry.max-delay", 60)
+	viper.SetDefault("retry.max-retries", 20)
+
+	viper.SetDefault("approval.poll-interval", 30)
+	viper.SetDefault("approval.timeout", 60)
 
 	viper.SetDefault("logging.enabled", true)
 	viper.SetDefault("logging.filename", "fog-deployments.log")
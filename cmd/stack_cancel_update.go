@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var cancelupdate_NonInteractive *bool
+var cancelupdate_Wait *bool
+var cancelupdate_PollInterval *time.Duration
+
+// stackCancelUpdateCmd represents the stack cancel-update command
+var stackCancelUpdateCmd = &cobra.Command{
+	Use:   "cancel-update",
+	Short: "Cancel the update of a stack",
+	Long: `Cancels the update of a stack that is in status UPDATE_IN_PROGRESS.
+
+Cancelling an update makes CloudFormation roll the stack back to its previous
+state. With --wait fog shows the events of the rollback until it's finished.
+
+Examples:
+
+$ fog stack cancel-update --stackname my-awesome-stack
+$ fog stack cancel-update --stackname my-awesome-stack --non-interactive --wait
+`,
+	Run: cancelStackUpdate,
+}
+
+func init() {
+	stackCmd.AddCommand(stackCancelUpdateCmd)
+	cancelupdate_NonInteractive = stackCancelUpdateCmd.Flags().Bool("non-interactive", false, "Cancel the update without asking for confirmation")
+	cancelupdate_Wait = stackCancelUpdateCmd.Flags().Bool("wait", false, "Wait until the rollback is finished")
+	cancelupdate_PollInterval = stackCancelUpdateCmd.Flags().Duration("poll-interval", 10*time.Second, "How often to check the stack when using --wait")
+}
+
+func cancelStackUpdate(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), StackArn: aws.ToString(stack.StackId)}
+	if stack.StackStatus == types.StackStatusUpdateInProgress && !*cancelupdate_NonInteractive &&
+		!askForConfirmation(fmt.Sprintf("Stack %v is in status %v. Are you sure you want to cancel the update?", deployment.StackName, stack.StackStatus)) {
+		fmt.Println("No problem. I have left the update running.")
+		return
+	}
+	latest := time.Now()
+	if err := lib.CancelUpdateStack(stack, svc); err != nil {
+		failWithError(err)
+	}
+	if !*cancelupdate_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The update of stack %v has been cancelled and is being rolled back", deployment.StackName)))
+		return
+	}
+	finished, err := deployment.WaitUntilRollbackDone(context.Background(), svc, *cancelupdate_PollInterval, func(types.Stack) {
+		latest = showEvents(deployment, latest, awsConfig)
+	})
+	if errors.Is(err, lib.ErrNotRolledBack) {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The update of stack %v finished in status %v before it could be cancelled", deployment.StackName, finished.StackStatus)))
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The rollback of stack %v didn't complete", deployment.StackName)))
+		if errors.Is(err, lib.ErrRollbackFailed) {
+			fmt.Println("Use fog stack continue-rollback to retry the rollback")
+		} else {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The update of stack %v has been rolled back", deployment.StackName)))
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var stackclone_Source *string
+var stackclone_Target *string
+var stackclone_TargetRegion *string
+var stackclone_OverrideParameters *[]string
+var stackclone_NonInteractive *bool
+
+// stackCloneCmd represents the stack clone command
+var stackCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Deploy a copy of a stack under a new name",
+	Long: `Deploys a copy of an existing stack as a new stack.
+
+The new stack gets the template, parameters, and tags of the source stack.
+Parameter values can be replaced using --override-parameter Key=Value, which
+can be provided multiple times. The values of NoEcho parameters aren't returned
+by CloudFormation, so these use their default value unless they are overridden.
+
+By default the new stack is created in the same region as the source stack, use
+--target-region to create it in a different region.
+
+Exports aren't shared with the clone. As export names need to be unique, the
+template of the source stack needs to generate them from for example the stack
+name for the clone to be created successfully.
+
+Examples:
+
+$ fog stack clone --source my-stack-staging --target my-stack-test
+$ fog stack clone --source my-stack-staging --target my-stack-test --override-parameter Environment=test
+$ fog stack clone --source my-stack --target my-stack --target-region eu-west-1
+$ fog stack clone --source my-stack-staging --target my-stack-test --non-interactive
+`,
+	Run: cloneStack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackCloneCmd)
+	stackclone_Source = stackCloneCmd.Flags().String("source", "", "The name of the stack that is cloned")
+	stackclone_Target = stackCloneCmd.Flags().String("target", "", "The name of the new stack")
+	stackclone_TargetRegion = stackCloneCmd.Flags().String("target-region", "", "The region to create the new stack in, defaults to the region of the source stack")
+	stackclone_OverrideParameters = stackCloneCmd.Flags().StringArray("override-parameter", []string{}, "Parameter value in the Key=Value format that replaces the value of the source stack. Can be provided multiple times")
+	stackclone_NonInteractive = stackCloneCmd.Flags().Bool("non-interactive", false, "Deploy the change set without asking for confirmation")
+}
+
+func cloneStack(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table") //Enforce table output for deployments
+	outputsettings = settings.NewOutputSettings()
+	outputsettings.SeparateTables = true //Make table output stand out more
+	// The shared deployment functions check the deploy flag
+	*deploy_NonInteractive = *stackclone_NonInteractive
+	if *stackclone_Source == "" || *stackclone_Target == "" {
+		failWithError(fmt.Errorf("please provide both the --source and --target stacks"))
+	}
+	if *stackclone_Source == *stackclone_Target && *stackclone_TargetRegion == "" {
+		failWithError(fmt.Errorf("the --source and --target stacks need to be different when cloning within the same region"))
+	}
+	overrides, err := lib.ParseParameterOverrides(*stackclone_OverrideParameters)
+	if err != nil {
+		failWithError(err)
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	source, err := lib.GetStack(stackclone_Source, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	template, err := lib.GetCurrentTemplateBody(*stackclone_Source, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	targetConfig := awsConfig
+	if *stackclone_TargetRegion != "" {
+		targetConfig.Config = awsConfig.Config.Copy()
+		targetConfig.Config.Region = *stackclone_TargetRegion
+		targetConfig.Region = *stackclone_TargetRegion
+	}
+	clone := lib.DeployInfo{
+		StackName: *stackclone_Target,
+		Template:  template,
+		Tags:      source.Tags,
+	}
+	clone.ChangesetName = placeholderParser(viper.GetString("changeset.name-format"), &clone)
+	clone.IsNew = clone.IsNewStack(targetConfig.CloudformationClient())
+	if !clone.IsNew {
+		failWithError(fmt.Errorf("stack %v already exists in region %v", clone.StackName, targetConfig.Region))
+	}
+	parameters, unknown := lib.GetPromotionParameters(lib.PromoteParameters(source.Parameters, nil, map[string]string{}), false)
+	clone.Parameters = lib.MergeParameters(parameters, overrides)
+	unknown = notOverridden(unknown, overrides)
+	if len(unknown) > 0 {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("The values of the following NoEcho parameters aren't known and will use their default values: %v", strings.Join(unknown, ", "))))
+	}
+	for _, output := range lib.GetImportedOutputs(source, awsConfig.CloudformationClient()) {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("Export %v of stack %v is imported by %v. The clone doesn't share this export with them", output.ExportName, *stackclone_Source, strings.Join(output.ImportedBy, ", "))))
+	}
+	showDeploymentInfo(clone, targetConfig)
+	deploymentLog := lib.NewDeploymentLog(targetConfig, clone)
+	changeset := createChangeset(&clone, &deploymentLog, targetConfig)
+	deploymentLog.AddChangeSet(changeset)
+	showChangeset(*changeset, clone, targetConfig)
+	if !*stackclone_NonInteractive && !askForConfirmation(string(texts.DeployChangesetMessageDeployConfirm)) {
+		deleteChangeset(clone, targetConfig)
+		os.Exit(0)
+	}
+	deployChangeset(clone, targetConfig)
+	resultStack, err := clone.GetFreshStack(targetConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageRetrievePostFailed))
+		log.Fatalln(err.Error())
+	}
+	switch resultStack.StackStatus {
+	case types.StackStatusCreateComplete:
+		deploymentLog.Success()
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v has been cloned to %v", *stackclone_Source, clone.StackName)))
+	default:
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageFailed))
+		showFailureReason(clone, targetConfig)
+		deploymentLog.Failed(showFailedEvents(clone, targetConfig))
+		deleteStackIfNew(clone, targetConfig)
+	}
+}
+
+// notOverridden returns the parameter keys that don't have an override
+func notOverridden(keys []string, overrides []types.Parameter) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		overridden := false
+		for _, override := range overrides {
+			if *override.ParameterKey == key {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var continuerollback_SkipResources *string
+var continuerollback_Wait *bool
+var continuerollback_PollInterval *time.Duration
+
+// stackContinueRollbackCmd represents the stack continue-rollback command
+var stackContinueRollbackCmd = &cobra.Command{
+	Use:   "continue-rollback",
+	Short: "Continue the rollback of a stack stuck in UPDATE_ROLLBACK_FAILED",
+	Long: `Continues the rollback of a stack whose rollback failed.
+
+When an update fails and the rollback fails as well, the stack ends up in
+UPDATE_ROLLBACK_FAILED and can't be updated until the rollback is completed.
+This command retries the rollback. If resources can't be rolled back, for
+example because they were deleted outside of CloudFormation, you can skip them
+with --skip-resources. With --wait fog shows the events of the rollback until
+it either completes or fails again.
+
+Examples:
+
+$ fog stack continue-rollback --stackname my-stuck-stack
+$ fog stack continue-rollback --stackname my-stuck-stack --skip-resources MyBucket,MyQueue --wait
+`,
+	Run: continueRollback,
+}
+
+func init() {
+	stackCmd.AddCommand(stackContinueRollbackCmd)
+	continuerollback_SkipResources = stackContinueRollbackCmd.Flags().String("skip-resources", "", "The logical IDs of resources to skip during the rollback, comma-separated for multiple")
+	continuerollback_Wait = stackContinueRollbackCmd.Flags().Bool("wait", false, "Wait until the rollback is finished")
+	continuerollback_PollInterval = stackContinueRollbackCmd.Flags().Duration("poll-interval", 10*time.Second, "How often to check the stack when using --wait")
+}
+
+func continueRollback(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	resourcesToSkip := make([]string, 0)
+	if *continuerollback_SkipResources != "" {
+		for _, resource := range strings.Split(*continuerollback_SkipResources, ",") {
+			resourcesToSkip = append(resourcesToSkip, strings.TrimSpace(resource))
+		}
+	}
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), StackArn: aws.ToString(stack.StackId)}
+	latest := time.Now()
+	if err := lib.ContinueUpdateRollback(stack, resourcesToSkip, svc); err != nil {
+		failWithError(err)
+	}
+	if !*continuerollback_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The rollback of stack %v has been continued", deployment.StackName)))
+		return
+	}
+	_, err = deployment.WaitUntilRollbackDone(context.Background(), svc, *continuerollback_PollInterval, func(types.Stack) {
+		latest = showEvents(deployment, latest, awsConfig)
+	})
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The rollback of stack %v didn't complete", deployment.StackName)))
+		if !errors.Is(err, lib.ErrRollbackFailed) {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The rollback of stack %v has completed", deployment.StackName)))
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/cobra"
+)
+
+var stackcost_Bucket *string
+
+// stackCostCmd represents the stack cost command
+var stackCostCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Show the estimated cost of a stack",
+	Long: `Shows a link to the AWS Simple Monthly Calculator, filled in with the
+resources of the currently deployed template of the stack. The parameters use
+their current values, including NoEcho parameters. Templates larger than
+51,200 bytes need to be uploaded to S3 first, for those you need to provide a
+bucket.
+
+To see the estimated cost of a template before deploying it, use
+fog deploy --cost-estimate.
+
+Examples:
+
+$ fog stack cost --stackname my-awesome-stack
+$ fog stack cost --stackname my-large-stack --bucket my-template-bucket
+`,
+	Run: showStackCost,
+}
+
+func init() {
+	stackCmd.AddCommand(stackCostCmd)
+	stackcost_Bucket = stackCostCmd.Flags().StringP("bucket", "b", "", "The S3 bucket where the template should be uploaded to if it's too large to estimate directly")
+}
+
+func showStackCost(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	template, err := lib.GetCurrentTemplateBody(*stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	templateURL := ""
+	if len(template) > lib.MaxTemplateBodySize {
+		if *stackcost_Bucket == "" {
+			failWithError(fmt.Errorf("the template is %d bytes, which is more than the %d bytes that can be estimated directly. Please provide a bucket to upload it to", len(template), lib.MaxTemplateBodySize))
+		}
+		objectname, err := lib.UploadTemplate(stack.StackName, template, stackcost_Bucket, awsConfig.S3Client())
+		if err != nil {
+			failWithError(err)
+		}
+		templateURL = lib.S3ObjectURL(*stackcost_Bucket, awsConfig.Region, objectname)
+	}
+	// The API only returns masked values for NoEcho parameters, so all parameters use their previous value
+	url, err := lib.GetStackCostEstimate(template, templateURL, lib.InheritParameters(stack.Parameters, nil), svc)
+	if err != nil {
+		failWithError(err)
+	}
+	fmt.Print(outputsettings.StringInfo(fmt.Sprintf("The estimated cost of stack %v is available at %v", *stack_StackName, url)))
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stackdelete_Wait *bool
+var stackdelete_PollInterval *time.Duration
+var stackdelete_NonInteractive *bool
+
+// stackDeleteCmd represents the stack delete command
+var stackDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a stack",
+	Long: `Deletes a CloudFormation stack.
+
+By default fog only starts the deletion. With --wait it keeps checking the
+stack until the deletion is finished. If the deletion fails, the reason is
+shown and fog exits with an error. Stacks with termination protection enabled
+can't be deleted until the protection is disabled.
+
+Examples:
+
+$ fog stack delete --stackname my-old-stack
+$ fog stack delete --stackname my-old-stack --wait --poll-interval 5s
+$ fog stack delete --stackname my-old-stack --wait --non-interactive
+`,
+	Run: deleteStack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackDeleteCmd)
+	stackdelete_Wait = stackDeleteCmd.Flags().Bool("wait", false, "Wait until the stack is deleted")
+	stackdelete_PollInterval = stackDeleteCmd.Flags().Duration("poll-interval", 10*time.Second, "How often to check the stack when using --wait")
+	stackdelete_NonInteractive = stackDeleteCmd.Flags().Bool("non-interactive", false, "Delete the stack without asking for confirmation")
+}
+
+func deleteStack(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), StackArn: aws.ToString(stack.StackId)}
+	if aws.ToBool(stack.EnableTerminationProtection) {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stack %v has termination protection enabled. Disable termination protection before deleting the stack.", deployment.StackName)))
+		os.Exit(1)
+	}
+	if !*stackdelete_NonInteractive && !askForConfirmation(fmt.Sprintf("Are you sure you want to delete stack %v?", deployment.StackName)) {
+		fmt.Println("No problem. I have left the stack intact.")
+		return
+	}
+	if err := deployment.StartStackDeletion(svc); err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Unable to delete stack %v", deployment.StackName)))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !*stackdelete_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Deletion of stack %v has started", deployment.StackName)))
+		return
+	}
+	_, err = deployment.WaitUntilStackDeleted(context.Background(), svc, *stackdelete_PollInterval, func(stack types.Stack) {
+		fmt.Printf("%v: stack %v is in status %v\n", time.Now().In(settings.GetTimezoneLocation()).Format(time.RFC3339), deployment.StackName, stack.StackStatus)
+	})
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stack %v could not be deleted", deployment.StackName)))
+		if errors.Is(err, lib.ErrStackDeleteFailed) {
+			if reason := lastDeleteFailureReason(deployment, awsConfig); reason != "" {
+				fmt.Println(reason)
+			}
+		} else {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v has been deleted", deployment.StackName)))
+}
+
+// lastDeleteFailureReason returns the reason of the most recent DELETE_FAILED event of the stack
+func lastDeleteFailureReason(deployment lib.DeployInfo, awsConfig config.AWSConfig) string {
+	events, err := deployment.GetEvents(context.TODO(), awsConfig.CloudformationClient())
+	if err != nil {
+		return ""
+	}
+	// Events are sorted newest first, so the first failed resource that isn't the stack itself has the actual reason
+	for _, event := range events {
+		if event.ResourceStatus == types.ResourceStatusDeleteFailed && aws.ToString(event.ResourceType) != "AWS::CloudFormation::Stack" {
+			return fmt.Sprintf("%v (%v): %v", aws.ToString(event.LogicalResourceId), aws.ToString(event.ResourceType), aws.ToString(event.ResourceStatusReason))
+		}
+	}
+	for _, event := range events {
+		if event.ResourceStatus == types.ResourceStatusDeleteFailed {
+			return aws.ToString(event.ResourceStatusReason)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stackevents_Follow *bool
+var stackevents_FilterStatus *string
+
+// stackEventsCmd represents the stack events command
+var stackEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the events of a stack",
+	Long: `Shows the events of a stack, oldest first.
+
+With --follow, fog instead waits for new events and prints them as they
+happen until the stack reaches a terminal status. This is useful to watch a
+deployment from a second terminal. Use --filter-status to only show events
+with specific statuses, provided as a comma separated list.
+
+Examples:
+
+$ fog stack events --stackname my-awesome-stack
+$ fog stack events --stackname my-awesome-stack --follow
+$ fog stack events --stackname my-awesome-stack --filter-status CREATE_FAILED,UPDATE_FAILED
+`,
+	Run: showStackEvents,
+}
+
+func init() {
+	stackCmd.AddCommand(stackEventsCmd)
+	stackevents_Follow = stackEventsCmd.Flags().BoolP("follow", "f", false, "Keep printing new events until the stack reaches a terminal status")
+	stackevents_FilterStatus = stackEventsCmd.Flags().String("filter-status", "", "Only show events with these statuses, e.g. \"CREATE_FAILED,UPDATE_FAILED\"")
+}
+
+func showStackEvents(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" {
+		failWithError(fmt.Errorf("the stackname flag is required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	statuses := []string{}
+	for _, status := range strings.Split(*stackevents_FilterStatus, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			statuses = append(statuses, strings.ToUpper(status))
+		}
+	}
+	svc := awsConfig.CloudformationClient()
+	if *stackevents_Follow {
+		events, errs := lib.WatchStackEvents(context.Background(), *stack_StackName, svc)
+		for event := range events {
+			if len(statuses) == 0 || slices.Contains(statuses, string(event.ResourceStatus)) {
+				printStackEvent(event)
+			}
+		}
+		if err := <-errs; err != nil {
+			failWithError(err)
+		}
+		return
+	}
+	events, err := lib.GetAllEvents(context.TODO(), *stack_StackName, lib.EventFetchOptions{EventTypes: statuses}, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	sort.Sort(ReverseEvents(events))
+	for _, event := range events {
+		printStackEvent(event)
+	}
+}
+
+// printStackEvent prints a single line for the event, highlighting failures and successes
+func printStackEvent(event types.StackEvent) {
+	message := fmt.Sprintf("%v: %v %v in status %v", event.Timestamp.In(settings.GetTimezoneLocation()).Format(time.RFC3339), *event.ResourceType, *event.LogicalResourceId, event.ResourceStatus)
+	switch event.ResourceStatus {
+	case types.ResourceStatusCreateFailed, types.ResourceStatusImportFailed, types.ResourceStatusDeleteFailed, types.ResourceStatusUpdateFailed, types.ResourceStatusImportRollbackComplete, types.ResourceStatus(types.StackStatusRollbackComplete), types.ResourceStatus(types.StackStatusUpdateRollbackComplete):
+		fmt.Print(outputsettings.StringWarning(message))
+	case types.ResourceStatusCreateComplete, types.ResourceStatusImportComplete, types.ResourceStatusUpdateComplete, types.ResourceStatusDeleteComplete:
+		fmt.Print(outputsettings.StringPositive(message))
+	default:
+		fmt.Println(message)
+	}
+}
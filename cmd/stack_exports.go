@@ -0,0 +1,61 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var stackexports_ExportName *string
+
+// stackExportsCmd is an alias for the exports command, using the stack name
+// from the stack command
+var stackExportsCmd = &cobra.Command{
+	Use:   "exports",
+	Short: "Get a list of CloudFormation exports, an alias for fog exports",
+	Long: `Provides the same overview of exports as fog exports, so the exports can be
+found alongside the other stack commands.
+
+Without --stackname the exports of all stacks are shown. The stack name can
+contain wildcards to show the exports of all matching stacks. Use --export to
+look for a specific export, which can also contain wildcards. Use --verbose to
+show which stacks import each export.
+
+Examples:
+
+$ fog stack exports --stackname my-awesome-stack
+$ fog stack exports --stackname "*network*" --verbose
+$ fog stack exports --export network-VpcId
+`,
+	Run: listStackExports,
+}
+
+func init() {
+	stackCmd.AddCommand(stackExportsCmd)
+	stackexports_ExportName = stackExportsCmd.Flags().StringP("export", "e", "", "Filter for the export name")
+}
+
+func listStackExports(cmd *cobra.Command, args []string) {
+	exports_stackName = stack_StackName
+	export_exportName = stackexports_ExportName
+	listExports(cmd, args)
+}
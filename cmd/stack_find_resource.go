@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var findresource_PhysicalId *string
+var findresource_Type *string
+var findresource_AccountWide *bool
+
+// stackFindResourceCmd represents the stack find-resource command
+var stackFindResourceCmd = &cobra.Command{
+	Use:   "find-resource",
+	Short: "Find the stack that manages a resource",
+	Long: `Finds the stack that manages the resource with the provided physical ID,
+and shows its logical ID and type.
+
+CloudFormation can't look up which stack a resource belongs to, so fog scans
+the resources of every stack matching --stackname, which can contain
+wildcards. As this can be slow in accounts with many stacks, scanning all the
+stacks in the region requires the --account-wide flag. Use --type to skip any
+resources of other types.
+
+Examples:
+
+$ fog stack find-resource --physical-id i-0abc123 --stackname "app-*"
+$ fog stack find-resource --physical-id i-0abc123 --type AWS::EC2::Instance --account-wide
+`,
+	Run: findStackResource,
+}
+
+func init() {
+	stackCmd.AddCommand(stackFindResourceCmd)
+	findresource_PhysicalId = stackFindResourceCmd.Flags().String("physical-id", "", "The physical ID of the resource")
+	findresource_Type = stackFindResourceCmd.Flags().String("type", "", "Only check resources of this type, e.g. AWS::EC2::Instance")
+	findresource_AccountWide = stackFindResourceCmd.Flags().Bool("account-wide", false, "Scan all stacks in the region when no --stackname is provided")
+}
+
+func findStackResource(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *findresource_PhysicalId == "" {
+		failWithError(fmt.Errorf("please provide the physical ID of the resource with --physical-id"))
+	}
+	if *stack_StackName == "" && !*findresource_AccountWide {
+		failWithError(fmt.Errorf("please limit the stacks to scan with --stackname, or use --account-wide to scan all stacks in the region"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	resources, err := lib.FindResourceByPhysicalId(*findresource_PhysicalId, *stack_StackName, *findresource_Type, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	if len(resources) == 0 {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("No stack manages a resource with physical ID %v", *findresource_PhysicalId)))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"Stack", "LogicalId", "Type", "Status"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Stacks managing %v", *findresource_PhysicalId)
+	output.Settings.SortKey = "Stack"
+	for _, resource := range resources {
+		output.AddContents(map[string]interface{}{
+			"Stack":     resource.StackName,
+			"LogicalId": resource.LogicalID,
+			"Type":      resource.Type,
+			"Status":    resource.Status,
+		})
+	}
+	output.Write()
+}
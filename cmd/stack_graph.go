@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var stackgraph_Format *string
+
+// stackGraphCmd represents the stack graph command
+var stackGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show the export/import dependencies between stacks as a diagram",
+	Long: `Generates a diagram of the dependencies between your stacks.
+
+Every stack is a node and an arrow points from a stack that exports values to
+the stacks importing them, labelled with the names of the exports. By default
+the diagram is a Mermaid flowchart, use --format dot for Graphviz instead.
+
+Large diagrams quickly become hard to read, so fog warns when the graph has
+more nodes than the graph.max-nodes setting in your config file.
+
+Examples:
+
+$ fog stack graph
+$ fog stack graph --stackname "*dev*"
+$ fog stack graph --format dot | dot -Tpng -o stacks.png
+`,
+	Run: showStackGraph,
+}
+
+func init() {
+	stackCmd.AddCommand(stackGraphCmd)
+	stackgraph_Format = stackGraphCmd.Flags().String("format", "mermaid", "The format of the diagram, either mermaid or dot")
+}
+
+func showStackGraph(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stackgraph_Format != "mermaid" && *stackgraph_Format != "dot" {
+		failWithError(fmt.Errorf("unsupported format %v, please use mermaid or dot", *stackgraph_Format))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetCfnStacks(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	graph, err := lib.BuildStackDependencyGraph(stacks, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	if maxNodes := viper.GetInt("graph.max-nodes"); maxNodes > 0 && len(graph.Nodes) > maxNodes {
+		fmt.Fprint(os.Stderr, outputsettings.StringWarning(fmt.Sprintf("The graph contains %d stacks, which may be hard to read. Use --stackname to limit the stacks shown", len(graph.Nodes))))
+	}
+	switch *stackgraph_Format {
+	case "dot":
+		fmt.Print(graph.Dot())
+	default:
+		fmt.Print(graph.Mermaid())
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var stackhistory_Last *int
+var stackhistory_Format *string
+
+// stackHistoryCmd represents the stack history command
+var stackHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show all deployments of a stack based on its events",
+	Long: `Shows the deployments of a stack, newest first, based on the events of the stack.
+
+Unlike fog history, this doesn't depend on the deployment logs of fog and
+therefore also shows deployments done outside of fog. For each deployment you
+can see the type, when it started, how long it took, whether it succeeded, and
+how many resources were affected. Use --last to only show the most recent
+deployments and --format timeline for a compact text timeline.
+
+Examples:
+
+$ fog stack history --stackname my-awesome-stack
+$ fog stack history --stackname my-awesome-stack --last 5
+$ fog stack history --stackname my-awesome-stack --format timeline
+`,
+	Run: showStackHistory,
+}
+
+func init() {
+	stackCmd.AddCommand(stackHistoryCmd)
+	stackhistory_Last = stackHistoryCmd.Flags().Int("last", 0, "Only show this many of the most recent deployments")
+	stackhistory_Format = stackHistoryCmd.Flags().String("format", "table", "Show the history as a table (using the output setting) or as a timeline")
+}
+
+func showStackHistory(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	if *stackhistory_Format != "table" && *stackhistory_Format != "timeline" {
+		failWithError(fmt.Errorf("unsupported format %v, please use table or timeline", *stackhistory_Format))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetCfnStacks(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	for _, stack := range stacks {
+		events, err := stack.GetEvents(context.TODO(), awsConfig.CloudformationClient())
+		if err != nil {
+			failWithError(err)
+		}
+		events = recentStackEvents(events, *stackhistory_Last)
+		if *stackhistory_Format == "timeline" {
+			fmt.Print(stackHistoryTimeline(events))
+			continue
+		}
+		output := stackHistoryTable(events)
+		output.Settings.Title = fmt.Sprintf("Deployment history of stack %v", stack.Name)
+		output.Write()
+	}
+}
+
+// recentStackEvents returns the events newest first, limited to the last ones when last is positive
+func recentStackEvents(events []lib.StackEvent, last int) []lib.StackEvent {
+	result := make([]lib.StackEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		if last > 0 && len(result) == last {
+			break
+		}
+		result = append(result, events[i])
+	}
+	return result
+}
+
+// stackHistoryTable creates the table with a row for each of the events
+func stackHistoryTable(events []lib.StackEvent) format.OutputArray {
+	output := format.OutputArray{Keys: []string{"Type", "Start time", "Duration", "Success", "Resources"}, Settings: settings.NewOutputSettings()}
+	for _, event := range events {
+		content := map[string]interface{}{
+			"Type":       event.Type,
+			"Start time": event.StartDate.In(settings.GetTimezoneLocation()).Format(time.RFC3339),
+			"Duration":   "",
+			"Success":    "In progress",
+			"Resources":  len(event.ResourceEvents),
+		}
+		if !event.EndDate.IsZero() {
+			content["Duration"] = event.GetDuration().Round(time.Second).String()
+			content["Success"] = event.Success
+		}
+		output.AddContents(content)
+	}
+	return output
+}
+
+// stackHistoryTimeline shows the events as a line each
+func stackHistoryTimeline(events []lib.StackEvent) string {
+	var builder strings.Builder
+	for _, event := range events {
+		result := "in progress"
+		if !event.EndDate.IsZero() {
+			result = fmt.Sprintf("failed after %v", event.GetDuration().Round(time.Second))
+			if event.Success {
+				result = fmt.Sprintf("succeeded after %v", event.GetDuration().Round(time.Second))
+			}
+		}
+		builder.WriteString(fmt.Sprintf("%v  %-6v %v (%d resources)\n", event.StartDate.In(settings.GetTimezoneLocation()).Format(time.RFC3339), event.Type, result, len(event.ResourceEvents)))
+	}
+	return builder.String()
+}
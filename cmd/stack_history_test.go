@@ -0,0 +1,85 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/viper"
+)
+
+func testStackHistoryEvents() []lib.StackEvent {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return []lib.StackEvent{
+		{Type: "Create", StartDate: start, EndDate: start.Add(90 * time.Second), Success: true, ResourceEvents: make([]lib.ResourceEvent, 3)},
+		{Type: "Update", StartDate: start.Add(time.Hour), EndDate: start.Add(time.Hour + 2*time.Minute), Success: false, ResourceEvents: make([]lib.ResourceEvent, 1)},
+		{Type: "Update", StartDate: start.Add(2 * time.Hour), ResourceEvents: make([]lib.ResourceEvent, 2)},
+	}
+}
+
+func TestRecentStackEvents(t *testing.T) {
+	events := testStackHistoryEvents()
+	all := recentStackEvents(events, 0)
+	if len(all) != 3 || !all[0].StartDate.Equal(events[2].StartDate) {
+		t.Errorf("recentStackEvents() should return all events newest first, got %v", all)
+	}
+	last := recentStackEvents(events, 2)
+	if len(last) != 2 || !last[1].StartDate.Equal(events[1].StartDate) {
+		t.Errorf("recentStackEvents() with last 2 = %v", last)
+	}
+}
+
+func TestStackHistoryRendering(t *testing.T) {
+	viper.Set("timezone", "UTC")
+	defer viper.Set("timezone", "Local")
+	events := recentStackEvents(testStackHistoryEvents(), 0)
+
+	output := stackHistoryTable(events)
+	if len(output.Contents) != 3 {
+		t.Fatalf("stackHistoryTable() has %d rows, want 3", len(output.Contents))
+	}
+	wants := []map[string]interface{}{
+		{"Type": "Update", "Duration": "", "Success": "In progress", "Resources": 2},
+		{"Type": "Update", "Duration": "2m0s", "Success": false, "Resources": 1},
+		{"Type": "Create", "Duration": "1m30s", "Success": true, "Resources": 3},
+	}
+	for i, want := range wants {
+		for key, value := range want {
+			if output.Contents[i].Contents[key] != value {
+				t.Errorf("stackHistoryTable() row %d %v = %v, want %v", i, key, output.Contents[i].Contents[key], value)
+			}
+		}
+	}
+
+	timeline := stackHistoryTimeline(events)
+	wantTimeline := []string{
+		"2024-01-01T14:00:00Z  Update in progress (2 resources)",
+		"2024-01-01T13:00:00Z  Update failed after 2m0s (1 resources)",
+		"2024-01-01T12:00:00Z  Create succeeded after 1m30s (3 resources)",
+	}
+	if got := strings.Split(strings.TrimSuffix(timeline, "\n"), "\n"); strings.Join(got, "\n") != strings.Join(wantTimeline, "\n") {
+		t.Errorf("stackHistoryTimeline() = %q, want %q", got, wantTimeline)
+	}
+}
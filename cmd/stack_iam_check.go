@@ -0,0 +1,96 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var stackiamcheck_RequireBoundary *bool
+
+// stackIamCheckCmd represents the stack iam-check command
+var stackIamCheckCmd = &cobra.Command{
+	Use:   "iam-check",
+	Short: "Check the permissions boundaries of the IAM roles in a stack",
+	Long: `Shows the IAM roles managed by the stack and the permissions boundary
+attached to each of them.
+
+When using --require-boundary, roles without a permissions boundary are
+highlighted and the command exits with an error if any are found. This makes
+it suitable for use in pipelines.
+
+Examples:
+
+$ fog stack iam-check --stackname my-awesome-stack
+$ fog stack iam-check --stackname my-awesome-stack --require-boundary
+`,
+	Run: checkStackIam,
+}
+
+func init() {
+	stackCmd.AddCommand(stackIamCheckCmd)
+	stackiamcheck_RequireBoundary = stackIamCheckCmd.Flags().Bool("require-boundary", false, "Fail if any of the roles doesn't have a permissions boundary")
+}
+
+func checkStackIam(cmd *cobra.Command, args []string) {
+	if *stack_StackName == "" {
+		failWithError(fmt.Errorf("please provide the name of the stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	roles, err := lib.GetStackPermissionsBoundary(*stack_StackName, awsConfig.CloudformationClient(), awsConfig.IAMClient())
+	if err != nil {
+		failWithError(err)
+	}
+	output := format.OutputArray{Keys: []string{"LogicalId", "Role", "Permissions boundary"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Permissions boundaries of the IAM roles in stack %v", *stack_StackName)
+	output.Settings.SortKey = "LogicalId"
+	missing := 0
+	for _, role := range roles {
+		boundary := role.BoundaryARN
+		if !role.HasBoundary {
+			missing++
+			boundary = "None"
+			if *stackiamcheck_RequireBoundary {
+				boundary = output.Settings.StringWarningInline("Missing")
+			}
+		}
+		output.AddContents(map[string]interface{}{
+			"LogicalId":            role.LogicalID,
+			"Role":                 role.RoleName,
+			"Permissions boundary": boundary,
+		})
+	}
+	output.Write()
+	if *stackiamcheck_RequireBoundary && missing > 0 {
+		fmt.Print(output.Settings.StringFailure(fmt.Sprintf("%v of %v roles don't have a permissions boundary", missing, len(roles))))
+		os.Exit(1)
+	}
+}
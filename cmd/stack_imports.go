@@ -0,0 +1,96 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+// stackImportsCmd represents the stack imports command
+var stackImportsCmd = &cobra.Command{
+	Use:   "imports",
+	Short: "Show the exports a stack imports",
+	Long: `Shows the exports that are imported in the template of a stack using
+Fn::ImportValue, together with the stack that exports them and their current
+value. This shows which stacks need to be updated before this one.
+
+Imported exports that no longer exist are flagged.
+
+To see which stacks import the exports of a stack, use fog stack exports.
+
+Examples:
+
+$ fog stack imports --stackname my-awesome-stack
+`,
+	Run: showStackImports,
+}
+
+func init() {
+	stackCmd.AddCommand(stackImportsCmd)
+}
+
+func showStackImports(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	exports, err := lib.GetExportsByName(svc)
+	if err != nil {
+		failWithError(err)
+	}
+	template := lib.GetTemplateBody(stack_StackName, lib.GetParametersMap(stack.Parameters), exports, svc)
+	imports := lib.GetStackImports(template, exports)
+	if len(imports) == 0 {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Stack %v doesn't import any exports", *stack_StackName)))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"ImportedExportName", "ExportingStack", "CurrentValue"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Exports imported by stack %v", *stack_StackName)
+	output.Settings.SortKey = "ImportedExportName"
+	for _, imported := range imports {
+		exportingStack := imported.ExportingStack
+		if !imported.Found {
+			exportingStack = outputsettings.StringWarningInline("Export not found")
+		}
+		output.AddContents(map[string]interface{}{
+			"ImportedExportName": imported.ExportName,
+			"ExportingStack":     exportingStack,
+			"CurrentValue":       imported.Value,
+		})
+	}
+	output.Write()
+}
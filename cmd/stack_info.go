@@ -0,0 +1,193 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stackinfo_ShowParams *bool
+
+// stackInfoCmd represents the stack info command
+var stackInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show all information about a stack",
+	Long: `Shows an overview of everything known about a CloudFormation stack.
+
+This includes the basic information of the stack, its configuration
+(capabilities, notification ARNs, role, and termination protection),
+its drift status, outputs, and tags. Parameters are only shown when
+requested with the --show-params flag.
+
+Examples:
+
+$ fog stack info --stackname my-awesome-stack
+$ fog stack info --stackname my-awesome-stack --show-params
+`,
+	Run: showStackInfo,
+}
+
+func init() {
+	stackCmd.AddCommand(stackInfoCmd)
+	stackinfo_ShowParams = stackInfoCmd.Flags().Bool("show-params", false, "Include the parameters of the stack")
+}
+
+func showStackInfo(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetCfnStacks(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	for _, stack := range stacks {
+		printStackInfo(stack)
+	}
+}
+
+// printStackInfo renders each section of the stack's information as a separate table
+func printStackInfo(stack lib.CfnStack) {
+	outputsettings.SeparateTables = true
+	raw := stack.RawInfo
+	separator := settings.GetSeparator()
+
+	basic := format.OutputArray{Keys: []string{"Name", "ID", "Status", "Created", "Last updated", "Description"}, Settings: settings.NewOutputSettings()}
+	basic.Settings.Title = fmt.Sprintf("Stack %v", stack.Name)
+	basic.Settings.SeparateTables = true
+	basicContent := map[string]interface{}{
+		"Name":         stack.Name,
+		"ID":           stack.Id,
+		"Status":       string(raw.StackStatus),
+		"Created":      formatStackTime(raw.CreationTime),
+		"Last updated": formatStackTime(raw.LastUpdatedTime),
+		"Description":  stack.Description,
+	}
+	if raw.StackStatusReason != nil {
+		basicContent["Status"] = fmt.Sprintf("%v (%v)", raw.StackStatus, *raw.StackStatusReason)
+	}
+	basic.AddContents(basicContent)
+	basic.AddToBuffer()
+
+	configuration := format.OutputArray{Keys: []string{"Capabilities", "Notification ARNs", "Role ARN", "Termination protection"}, Settings: settings.NewOutputSettings()}
+	configuration.Settings.Title = "Configuration"
+	configuration.Settings.SeparateTables = true
+	capabilities := make([]string, 0, len(raw.Capabilities))
+	for _, capability := range raw.Capabilities {
+		capabilities = append(capabilities, string(capability))
+	}
+	roleArn := ""
+	if raw.RoleARN != nil {
+		roleArn = *raw.RoleARN
+	}
+	terminationProtection := false
+	if raw.EnableTerminationProtection != nil {
+		terminationProtection = *raw.EnableTerminationProtection
+	}
+	configuration.AddContents(map[string]interface{}{
+		"Capabilities":           strings.Join(capabilities, separator),
+		"Notification ARNs":      strings.Join(raw.NotificationARNs, separator),
+		"Role ARN":               roleArn,
+		"Termination protection": terminationProtection,
+	})
+	configuration.AddToBuffer()
+
+	drift := format.OutputArray{Keys: []string{"Drift status", "Last checked"}, Settings: settings.NewOutputSettings()}
+	drift.Settings.Title = "Drift"
+	drift.Settings.SeparateTables = true
+	driftContent := map[string]interface{}{
+		"Drift status": string(types.StackDriftStatusNotChecked),
+		"Last checked": "",
+	}
+	if raw.DriftInformation != nil {
+		driftContent["Drift status"] = string(raw.DriftInformation.StackDriftStatus)
+		driftContent["Last checked"] = formatStackTime(raw.DriftInformation.LastCheckTimestamp)
+	}
+	drift.AddContents(driftContent)
+	drift.AddToBuffer()
+
+	if *stackinfo_ShowParams && len(raw.Parameters) > 0 {
+		parameters := format.OutputArray{Keys: []string{"Key", "Value", "Resolved value"}, Settings: settings.NewOutputSettings()}
+		parameters.Settings.Title = "Parameters"
+		parameters.Settings.SortKey = "Key"
+		parameters.Settings.SeparateTables = true
+		for _, parameter := range raw.Parameters {
+			content := map[string]interface{}{
+				"Key":            aws.ToString(parameter.ParameterKey),
+				"Value":          aws.ToString(parameter.ParameterValue),
+				"Resolved value": aws.ToString(parameter.ResolvedValue),
+			}
+			parameters.AddContents(content)
+		}
+		parameters.AddToBuffer()
+	}
+
+	if len(stack.Outputs) > 0 {
+		outputs := format.OutputArray{Keys: []string{"Key", "Value", "Description", "Export name"}, Settings: settings.NewOutputSettings()}
+		outputs.Settings.Title = "Outputs"
+		outputs.Settings.SortKey = "Key"
+		outputs.Settings.SeparateTables = true
+		for _, output := range stack.Outputs {
+			outputs.AddContents(map[string]interface{}{
+				"Key":         output.OutputKey,
+				"Value":       output.OutputValue,
+				"Description": output.Description,
+				"Export name": output.ExportName,
+			})
+		}
+		outputs.AddToBuffer()
+	}
+
+	tags := format.OutputArray{Keys: []string{"Key", "Value"}, Settings: settings.NewOutputSettings()}
+	tags.Settings.Title = "Tags"
+	tags.Settings.SortKey = "Key"
+	tags.Settings.SeparateTables = true
+	for _, tag := range raw.Tags {
+		tags.AddContents(map[string]interface{}{
+			"Key":   aws.ToString(tag.Key),
+			"Value": aws.ToString(tag.Value),
+		})
+	}
+	tags.Write()
+}
+
+// formatStackTime returns the time in the configured timezone, or an empty
+// string if no time was set
+func formatStackTime(moment *time.Time) string {
+	if moment == nil {
+		return ""
+	}
+	return moment.In(settings.GetTimezoneLocation()).Format(time.RFC3339)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stacklist_TagFilters *string
+var stacklist_Tags *[]string
+var stacklist_WithDrift *bool
+var stacklist_DriftStatus *string
+var stacklist_SortBy *string
+var stacklist_Reverse *bool
+
+// stackListCmd represents the stack list command
+var stackListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the CloudFormation stacks in the account",
+	Long: `Lists the CloudFormation stacks in the account and region.
+
+You can limit the stacks shown by using a wildcard filter for the name with
+--stackname, and by tags using --tag or --tag-filters. The --tag flag takes a
+single Key=Value pair and can be provided multiple times, while --tag-filters
+takes a comma separated list of them. A stack needs to have all of the tags to
+be shown. When both a name and tags are provided, only stacks matching both
+are shown. The tags are compared with the tags returned when describing the
+stacks, so no permissions for the Resource Groups Tagging API are needed.
+
+To audit drift, use --with-drift to only show stacks that were found to have
+drifted, or --drift-status to only show stacks with a specific drift status
+(DRIFTED, IN_SYNC, NOT_CHECKED, or UNKNOWN). When filtering on drift, the drift
+status and the time of the last drift check are included in the output. Fog
+doesn't run a drift detection for this, so the status is based on the last
+time drift detection was run for each stack.
+
+Stacks are sorted by name, use --sort-by to sort them by STATUS, CREATED, or
+UPDATED instead and --reverse to invert the order. When sorting by UPDATED,
+stacks that were never updated use the time they were created.
+
+Examples:
+
+$ fog stack list
+$ fog stack list --stackname "*dev*"
+$ fog stack list --tag Team=platform --tag Environment=prod
+$ fog stack list --tag-filters "Environment=prod,Team=platform"
+$ fog stack list --sort-by UPDATED --reverse
+$ fog stack list --stackname "*prod*" --with-drift
+$ fog stack list --drift-status NOT_CHECKED
+`,
+	Run: listStacks,
+}
+
+func init() {
+	stackCmd.AddCommand(stackListCmd)
+	stacklist_TagFilters = stackListCmd.Flags().String("tag-filters", "", "Only show stacks with these tags, e.g. \"Environment=prod,Team=platform\"")
+	stacklist_Tags = stackListCmd.Flags().StringArray("tag", []string{}, "Only show stacks with this tag, e.g. Team=platform. The value may contain commas. Can be provided multiple times")
+	stacklist_SortBy = stackListCmd.Flags().String("sort-by", string(lib.SortByName), "Sort the stacks by NAME, STATUS, CREATED, or UPDATED")
+	stacklist_Reverse = stackListCmd.Flags().Bool("reverse", false, "Reverse the sort order")
+	stacklist_WithDrift = stackListCmd.Flags().Bool("with-drift", false, "Only show stacks that have drifted. Shorthand for --drift-status DRIFTED")
+	stacklist_DriftStatus = stackListCmd.Flags().String("drift-status", "", "Only show stacks with this drift status: DRIFTED, IN_SYNC, NOT_CHECKED, or UNKNOWN")
+}
+
+func listStacks(cmd *cobra.Command, args []string) {
+	tagfilters, err := lib.ParseTagFilterString(*stacklist_TagFilters)
+	if err != nil {
+		failWithError(err)
+	}
+	for _, tag := range *stacklist_Tags {
+		key, value, err := lib.ParseTagFilter(tag)
+		if err != nil {
+			failWithError(err)
+		}
+		if err := lib.AddTagFilter(tagfilters, key, value); err != nil {
+			failWithError(err)
+		}
+	}
+	driftstatus, err := getDriftStatusFilter()
+	if err != nil {
+		failWithError(err)
+	}
+	sortfield, err := lib.ParseSortField(*stacklist_SortBy)
+	if err != nil {
+		failWithError(err)
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetCfnStacks(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	if len(tagfilters) > 0 {
+		stacks = lib.FilterStacksByTags(stacks, tagfilters)
+	}
+	keys := []string{"Name", "Status", "Description", "Created", "Last updated"}
+	if driftstatus != "" {
+		stacks = lib.FilterStacksByDriftStatus(stacks, driftstatus)
+		keys = append(keys, "DriftStatus", "LastDriftCheck")
+	}
+	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Stacks in account %v for region %v", formatAccountDisplay(awsConfig), awsConfig.Region)
+	stacklist := make([]lib.CfnStack, 0, len(stacks))
+	for _, stack := range stacks {
+		stacklist = append(stacklist, stack)
+	}
+	for _, stack := range lib.SortCfnStacks(stacklist, sortfield, *stacklist_Reverse) {
+		content := map[string]interface{}{
+			"Name":         stack.Name,
+			"Status":       string(stack.RawInfo.StackStatus),
+			"Description":  stack.Description,
+			"Created":      formatStackTime(stack.RawInfo.CreationTime),
+			"Last updated": formatStackTime(stack.RawInfo.LastUpdatedTime),
+		}
+		if driftstatus != "" {
+			content["DriftStatus"] = string(lib.GetStackDriftStatus(stack))
+			content["LastDriftCheck"] = ""
+			if stack.RawInfo.DriftInformation != nil {
+				content["LastDriftCheck"] = formatStackTime(stack.RawInfo.DriftInformation.LastCheckTimestamp)
+			}
+		}
+		output.AddContents(content)
+	}
+	output.Write()
+}
+
+// getDriftStatusFilter returns the drift status to filter on, or an empty
+// status if the stacks shouldn't be filtered by drift
+func getDriftStatusFilter() (types.StackDriftStatus, error) {
+	status := strings.ToUpper(strings.TrimSpace(*stacklist_DriftStatus))
+	if *stacklist_WithDrift {
+		if status != "" && status != string(types.StackDriftStatusDrifted) {
+			return "", fmt.Errorf("--with-drift can't be combined with --drift-status %v", status)
+		}
+		return types.StackDriftStatusDrifted, nil
+	}
+	if status == "" {
+		return "", nil
+	}
+	for _, valid := range types.StackDriftStatus("").Values() {
+		if string(valid) == status {
+			return valid, nil
+		}
+	}
+	return "", fmt.Errorf("invalid drift status %v, use one of DRIFTED, IN_SYNC, NOT_CHECKED, or UNKNOWN", status)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var stackorphans_Pattern *string
+var stackorphans_Type *string
+
+// stackOrphansCmd represents the stack orphans command
+var stackOrphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Find resources that aren't managed by any stack",
+	Long: `Compares the resources of a specific type in the account and region with
+the resources managed by your CloudFormation stacks, and shows the ones that
+aren't managed by any stack.
+
+Resources that AWS creates by default, such as the default VPC or the main
+route table of a VPC, are ignored. Using --pattern you can limit the results
+to resources where the ID or Name tag matches the wildcard pattern.
+
+Supported resource types: ` + strings.Join(lib.GetSupportedOrphanTypes(), ", ") + `
+
+Examples:
+
+$ fog stack orphans --type AWS::EC2::VPC
+$ fog stack orphans --type AWS::EC2::Subnet --pattern "subnet-*"
+`,
+	Run: findOrphans,
+}
+
+func init() {
+	stackCmd.AddCommand(stackOrphansCmd)
+	stackorphans_Pattern = stackOrphansCmd.Flags().String("pattern", "", "Only show resources where the ID or Name matches this wildcard pattern")
+	stackorphans_Type = stackOrphansCmd.Flags().String("type", "AWS::EC2::VPC", "The CloudFormation resource type to check")
+}
+
+func findOrphans(cmd *cobra.Command, args []string) {
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	physical, err := lib.ListPhysicalResources(context.TODO(), *stackorphans_Type, awsConfig.EC2Client())
+	if err != nil {
+		failWithError(err)
+	}
+	managed, err := lib.GetManagedResources(awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	orphans := lib.FindUnmanagedResources(managed, physical, *stackorphans_Pattern)
+	output := format.OutputArray{Keys: []string{"Type", "ID", "Name"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Unmanaged resources of type %v in account %v for region %v", *stackorphans_Type, formatAccountDisplay(awsConfig), awsConfig.Region)
+	output.Settings.SortKey = "ID"
+	for _, orphan := range orphans {
+		output.AddContents(map[string]interface{}{
+			"Type": orphan.Type,
+			"ID":   orphan.ID,
+			"Name": orphan.Name,
+		})
+	}
+	output.Write()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stackoutputs_Key *string
+
+// stackOutputsCmd represents the stack outputs command
+var stackOutputsCmd = &cobra.Command{
+	Use:   "outputs",
+	Short: "Show the outputs of a stack",
+	Long: `Shows the outputs of a stack with their descriptions and export names.
+
+All output formats are supported, so you can for example use --output json and
+pipe the result to jq. If you only need a single value, use --key to print
+just the value of that output, which is useful in shell scripts.
+
+Examples:
+
+$ fog stack outputs --stackname my-awesome-stack
+$ fog stack outputs --stackname my-awesome-stack --output json
+$ VPC_ID=$(fog stack outputs --stackname my-awesome-stack --key VpcId)
+`,
+	Run: showStackOutputs,
+}
+
+func init() {
+	stackCmd.AddCommand(stackOutputsCmd)
+	stackoutputs_Key = stackOutputsCmd.Flags().StringP("key", "k", "", "Only print the value of the output with this key")
+}
+
+func showStackOutputs(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stack, err := lib.GetStack(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	if *stackoutputs_Key != "" {
+		value, err := lib.GetStackOutputValue(stack, *stackoutputs_Key)
+		if err != nil {
+			failWithError(err)
+		}
+		fmt.Println(value)
+		return
+	}
+	printStackOutputs(stack)
+}
+
+// printStackOutputs shows the outputs of the stack in the configured output format.
+// When the stack has no outputs, JSON output is an empty array so it can still be piped to jq.
+func printStackOutputs(stack types.Stack) {
+	outputsettings = settings.NewOutputSettings()
+	if len(stack.Outputs) == 0 && outputsettings.OutputFormat != "json" {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Stack %v doesn't have any outputs", aws.ToString(stack.StackName))))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"OutputKey", "OutputValue", "Description", "ExportName"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Outputs of stack %v", aws.ToString(stack.StackName))
+	output.Settings.SortKey = "OutputKey"
+	for _, stackOutput := range stack.Outputs {
+		output.AddContents(map[string]interface{}{
+			"OutputKey":   aws.ToString(stackOutput.OutputKey),
+			"OutputValue": aws.ToString(stackOutput.OutputValue),
+			"Description": aws.ToString(stackOutput.Description),
+			"ExportName":  aws.ToString(stackOutput.ExportName),
+		})
+	}
+	output.Write()
+}
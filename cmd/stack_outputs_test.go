@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/viper"
+)
+
+// captureStdout returns everything written to stdout while running f
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	original := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = writer
+	defer func() { os.Stdout = original }()
+	f()
+	writer.Close()
+	captured, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(captured)
+}
+
+func testOutputsStack() types.Stack {
+	return types.Stack{
+		StackName: aws.String("network"),
+		Outputs: []types.Output{
+			{OutputKey: aws.String("VpcId"), OutputValue: aws.String("vpc-123"), Description: aws.String("The VPC"), ExportName: aws.String("network-VpcId")},
+			{OutputKey: aws.String("SubnetId"), OutputValue: aws.String("subnet-123")},
+		},
+	}
+}
+
+func TestPrintStackOutputs_JSON(t *testing.T) {
+	viper.Set("output", "json")
+	defer viper.Set("output", nil)
+	got := captureStdout(t, func() { printStackOutputs(testOutputsStack()) })
+	var parsed []map[string]string
+	if err := json.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("printStackOutputs() didn't print valid JSON: %v\n%v", err, got)
+	}
+	want := []map[string]string{
+		{"OutputKey": "SubnetId", "OutputValue": "subnet-123", "Description": "", "ExportName": ""},
+		{"OutputKey": "VpcId", "OutputValue": "vpc-123", "Description": "The VPC", "ExportName": "network-VpcId"},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("printStackOutputs() = %v, want %v", parsed, want)
+	}
+
+	empty := captureStdout(t, func() { printStackOutputs(types.Stack{StackName: aws.String("empty")}) })
+	if strings.TrimSpace(empty) != "[]" {
+		t.Errorf("printStackOutputs() without outputs = %q, want an empty JSON array", empty)
+	}
+}
+
+func TestPrintStackOutputs_CSV(t *testing.T) {
+	viper.Set("output", "csv")
+	defer viper.Set("output", nil)
+	got := captureStdout(t, func() { printStackOutputs(testOutputsStack()) })
+	want := "Outputs of stack network\n" +
+		"OutputKey,OutputValue,Description,ExportName\n" +
+		"SubnetId,subnet-123,,\n" +
+		"VpcId,vpc-123,The VPC,network-VpcId\n"
+	if got != want {
+		t.Errorf("printStackOutputs() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintStackOutputs_Table(t *testing.T) {
+	viper.Set("output", "table")
+	defer viper.Set("output", nil)
+	got := captureStdout(t, func() { printStackOutputs(testOutputsStack()) })
+	for _, expected := range []string{"Outputs of stack network", "OUTPUTKEY", "OUTPUTVALUE", "vpc-123", "The VPC", "network-VpcId", "subnet-123"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("printStackOutputs() table doesn't contain %q:\n%v", expected, got)
+		}
+	}
+	if strings.Index(got, "SubnetId") > strings.Index(got, "VpcId") {
+		t.Errorf("printStackOutputs() table isn't sorted by output key:\n%v", got)
+	}
+
+	empty := captureStdout(t, func() { printStackOutputs(types.Stack{StackName: aws.String("empty")}) })
+	if !strings.Contains(empty, "Stack empty doesn't have any outputs") {
+		t.Errorf("printStackOutputs() without outputs = %q, want the info message", empty)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var stackpromote_From *string
+var stackpromote_To *string
+var stackpromote_ParamMap *string
+var stackpromote_NonInteractive *bool
+
+// stackPromoteCmd represents the stack promote command
+var stackPromoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Deploy the template of a stack to another environment",
+	Long: `Deploys the template that is currently used by a stack to another stack,
+for example to promote a change from staging to production.
+
+The destination stack gets the parameters of the source stack. Values that are
+different between environments can be replaced using a parameter map, a YAML or
+JSON file that maps the values of the source environment to those of the
+destination environment. Before the change set is created, a table shows the
+parameter values of both stacks and highlights what will change.
+
+The values of NoEcho parameters aren't returned by CloudFormation. If the
+destination stack exists these keep their current value, otherwise they use the
+default value from the template. If the destination stack doesn't exist yet it
+gets the tags of the source stack, with their values replaced using the
+parameter map.
+
+Examples:
+
+$ fog stack promote --from my-stack-staging --to my-stack-production
+$ fog stack promote --from my-stack-staging --to my-stack-production --param-map promotions/production.yaml
+$ fog stack promote --from my-stack-staging --to my-stack-production --non-interactive
+`,
+	Run: promoteStack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackPromoteCmd)
+	stackpromote_From = stackPromoteCmd.Flags().String("from", "", "The name of the stack that is promoted")
+	stackpromote_To = stackPromoteCmd.Flags().String("to", "", "The name of the stack that the template is deployed to")
+	stackpromote_ParamMap = stackPromoteCmd.Flags().String("param-map", "", "A YAML or JSON file that maps parameter values of the source environment to those of the destination environment")
+	stackpromote_NonInteractive = stackPromoteCmd.Flags().Bool("non-interactive", false, "Deploy the change set without asking for confirmation")
+}
+
+func promoteStack(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table") //Enforce table output for deployments
+	outputsettings = settings.NewOutputSettings()
+	outputsettings.SeparateTables = true //Make table output stand out more
+	// The shared deployment functions check the deploy flag
+	*deploy_NonInteractive = *stackpromote_NonInteractive
+	if *stackpromote_From == "" || *stackpromote_To == "" {
+		failWithError(fmt.Errorf("please provide both the --from and --to stacks"))
+	}
+	if *stackpromote_From == *stackpromote_To {
+		failWithError(fmt.Errorf("the --from and --to stacks need to be different"))
+	}
+	valueMap := make(map[string]string)
+	if *stackpromote_ParamMap != "" {
+		var err error
+		valueMap, err = lib.ReadParameterMap(*stackpromote_ParamMap)
+		if err != nil {
+			failWithError(err)
+		}
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	source, err := lib.GetStack(stackpromote_From, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	template, err := lib.GetCurrentTemplateBody(*stackpromote_From, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	promotion := lib.DeployInfo{
+		StackName: *stackpromote_To,
+		Template:  template,
+	}
+	promotion.ChangesetName = placeholderParser(viper.GetString("changeset.name-format"), &promotion)
+	promotion.IsNew = promotion.IsNewStack(awsConfig.CloudformationClient())
+	var current []types.Parameter
+	if promotion.IsNew {
+		promotion.Tags = promoteTags(source.Tags, valueMap)
+	} else {
+		destination, err := lib.GetStack(stackpromote_To, awsConfig.CloudformationClient())
+		if err != nil {
+			failWithError(err)
+		}
+		current = destination.Parameters
+	}
+	promoted := lib.PromoteParameters(source.Parameters, current, valueMap)
+	showPromotedParameters(promoted, promotion.IsNew)
+	parameters, unknown := lib.GetPromotionParameters(promoted, !promotion.IsNew)
+	promotion.Parameters = parameters
+	if len(unknown) > 0 {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("The values of the following NoEcho parameters aren't known and will use their default values: %v", strings.Join(unknown, ", "))))
+	}
+	showDeploymentInfo(promotion, awsConfig)
+	deploymentLog := lib.NewDeploymentLog(awsConfig, promotion)
+	changeset := createChangeset(&promotion, &deploymentLog, awsConfig)
+	deploymentLog.AddChangeSet(changeset)
+	showChangeset(*changeset, promotion, awsConfig)
+	if !*stackpromote_NonInteractive && !askForConfirmation(string(texts.DeployChangesetMessageDeployConfirm)) {
+		deleteChangeset(promotion, awsConfig)
+		os.Exit(0)
+	}
+	deployChangeset(promotion, awsConfig)
+	resultStack, err := promotion.GetFreshStack(awsConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageRetrievePostFailed))
+		log.Fatalln(err.Error())
+	}
+	switch resultStack.StackStatus {
+	case types.StackStatusCreateComplete, types.StackStatusUpdateComplete:
+		deploymentLog.Success()
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v has been promoted to %v", *stackpromote_From, promotion.StackName)))
+	default:
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageFailed))
+		showFailureReason(promotion, awsConfig)
+		deploymentLog.Failed(showFailedEvents(promotion, awsConfig))
+		if promotion.IsNew {
+			deleteStackIfNew(promotion, awsConfig)
+		}
+	}
+}
+
+// promoteTags returns the tags of the source stack with their values replaced based on the value map
+func promoteTags(tags []types.Tag, valueMap map[string]string) []types.Tag {
+	result := make([]types.Tag, 0, len(tags))
+	for _, tag := range tags {
+		value := aws.ToString(tag.Value)
+		if mapped, ok := valueMap[value]; ok {
+			value = mapped
+		}
+		result = append(result, types.Tag{Key: tag.Key, Value: aws.String(value)})
+	}
+	return result
+}
+
+// showPromotedParameters shows the parameter values of both stacks, highlighting the values that change
+func showPromotedParameters(promoted []lib.PromotedParameter, isNew bool) {
+	fromTitle := fmt.Sprintf("%v value", *stackpromote_From)
+	currentTitle := fmt.Sprintf("Current %v value", *stackpromote_To)
+	newTitle := fmt.Sprintf("New %v value", *stackpromote_To)
+	keys := []string{"Parameter", fromTitle, newTitle}
+	if !isNew {
+		keys = []string{"Parameter", fromTitle, currentTitle, newTitle}
+	}
+	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Parameter changes"
+	output.Settings.SortKey = "Parameter"
+	for _, parameter := range promoted {
+		newValue := parameter.NewValue
+		if parameter.Unknown {
+			newValue = "Unknown (NoEcho)"
+			if !isNew {
+				newValue = "Unchanged (NoEcho)"
+			}
+		} else if parameter.IsChanged() {
+			newValue = outputsettings.StringWarningInline(newValue)
+		}
+		content := map[string]interface{}{
+			"Parameter":  parameter.Key,
+			fromTitle:    parameter.SourceValue,
+			currentTitle: parameter.CurrentValue,
+			newTitle:     newValue,
+		}
+		output.AddContents(content)
+	}
+	output.Write()
+}
@@ -0,0 +1,237 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var stackrecovery_Action *string
+var stackrecovery_NonInteractive *bool
+var stackrecovery_Wait *bool
+var stackrecovery_PollInterval *time.Duration
+
+const (
+	recoveryActionContinue = "continue"
+	recoveryActionDelete   = "delete"
+)
+
+// stackRecoveryCmd represents the stack recovery command
+var stackRecoveryCmd = &cobra.Command{
+	Use:   "recovery",
+	Short: "Recover a stack whose rollback failed",
+	Long: `Helps to recover a stack stuck in ROLLBACK_FAILED or UPDATE_ROLLBACK_FAILED.
+
+Fog shows the resources that failed during the latest rollback and offers to
+either continue the rollback or delete the stack. When continuing the rollback,
+the failed resources are skipped so the rollback can complete. Stacks in
+ROLLBACK_FAILED were never created successfully, so these can only be deleted.
+
+If the stack is still rolling back, fog recommends waiting for the rollback to
+finish instead. In non-interactive mode you need to provide the action to take
+with --action.
+
+Examples:
+
+$ fog stack recovery --stackname my-stuck-stack
+$ fog stack recovery --stackname my-stuck-stack --action continue --non-interactive --wait
+$ fog stack recovery --stackname my-failed-stack --action delete
+`,
+	Run: stackRecovery,
+}
+
+func init() {
+	stackCmd.AddCommand(stackRecoveryCmd)
+	stackrecovery_Action = stackRecoveryCmd.Flags().String("action", "", "The action to take: continue (the rollback) or delete (the stack). Required with --non-interactive")
+	stackrecovery_NonInteractive = stackRecoveryCmd.Flags().Bool("non-interactive", false, "Take the action without asking for confirmation")
+	stackrecovery_Wait = stackRecoveryCmd.Flags().Bool("wait", false, "Wait until the rollback or deletion is finished")
+	stackrecovery_PollInterval = stackRecoveryCmd.Flags().Duration("poll-interval", 10*time.Second, "How often to check the stack when using --wait")
+}
+
+func stackRecovery(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	action := strings.ToLower(*stackrecovery_Action)
+	if action != "" && action != recoveryActionContinue && action != recoveryActionDelete {
+		failWithError(fmt.Errorf("invalid action %v, use either %v or %v", *stackrecovery_Action, recoveryActionContinue, recoveryActionDelete))
+	}
+	if action == "" && *stackrecovery_NonInteractive {
+		failWithError(fmt.Errorf("the --action flag is required when using --non-interactive"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	stack, err := lib.GetStack(stack_StackName, svc)
+	if err != nil {
+		failWithError(err)
+	}
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), StackArn: aws.ToString(stack.StackId)}
+	switch stack.StackStatus {
+	case types.StackStatusUpdateRollbackInProgress, types.StackStatusRollbackInProgress, types.StackStatusUpdateRollbackCompleteCleanupInProgress:
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Stack %v is still rolling back (%v). Wait for the rollback to finish before taking any action.", deployment.StackName, stack.StackStatus)))
+		return
+	case types.StackStatusUpdateRollbackFailed:
+	case types.StackStatusRollbackFailed:
+		if action == recoveryActionContinue {
+			failWithError(fmt.Errorf("stack %v is in status %v, which means it was never created successfully. It can only be deleted", deployment.StackName, stack.StackStatus))
+		}
+	default:
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v is in status %v and doesn't need to be recovered", deployment.StackName, stack.StackStatus)))
+		return
+	}
+	events, err := deployment.GetEvents(context.TODO(), svc)
+	if err != nil {
+		failWithError(err)
+	}
+	failures := lib.GetRollbackFailures(events)
+	printRollbackFailures(deployment, failures)
+	if action == "" {
+		action = askForRecoveryAction(deployment, stack.StackStatus)
+	} else if !*stackrecovery_NonInteractive && !askForConfirmation(fmt.Sprintf("Are you sure you want to %v?", recoveryActionDescription(action, deployment.StackName))) {
+		action = ""
+	}
+	switch action {
+	case recoveryActionContinue:
+		recoverByContinuingRollback(deployment, stack, failures, awsConfig)
+	case recoveryActionDelete:
+		recoverByDeletingStack(deployment, stack, awsConfig)
+	default:
+		fmt.Println("No problem. I have left the stack as it is.")
+	}
+}
+
+// printRollbackFailures shows the resources that failed during the rollback
+func printRollbackFailures(deployment lib.DeployInfo, failures []types.StackEvent) {
+	if len(failures) == 0 {
+		fmt.Print(outputsettings.StringInfo("No failed resources were found for the latest rollback"))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"LogicalID", "Type", "Status", "Reason"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Resources that failed during the rollback of %v", deployment.StackName)
+	for _, failure := range failures {
+		output.AddContents(map[string]interface{}{
+			"LogicalID": aws.ToString(failure.LogicalResourceId),
+			"Type":      aws.ToString(failure.ResourceType),
+			"Status":    string(failure.ResourceStatus),
+			"Reason":    aws.ToString(failure.ResourceStatusReason),
+		})
+	}
+	output.Write()
+}
+
+// askForRecoveryAction asks which action to take. Continuing the rollback is
+// only offered if the stack supports it.
+func askForRecoveryAction(deployment lib.DeployInfo, status types.StackStatus) string {
+	if status == types.StackStatusUpdateRollbackFailed && askForConfirmation(fmt.Sprintf("Do you want to %v?", recoveryActionDescription(recoveryActionContinue, deployment.StackName))) {
+		return recoveryActionContinue
+	}
+	if askForConfirmation(fmt.Sprintf("Do you want to %v?", recoveryActionDescription(recoveryActionDelete, deployment.StackName))) {
+		return recoveryActionDelete
+	}
+	return ""
+}
+
+// recoveryActionDescription describes the action for use in a question
+func recoveryActionDescription(action string, stackname string) string {
+	if action == recoveryActionContinue {
+		return fmt.Sprintf("continue the rollback of stack %v, skipping the failed resources", stackname)
+	}
+	return fmt.Sprintf("delete stack %v", stackname)
+}
+
+// recoverByContinuingRollback continues the rollback while skipping the
+// resources that failed to roll back
+func recoverByContinuingRollback(deployment lib.DeployInfo, stack types.Stack, failures []types.StackEvent, awsConfig config.AWSConfig) {
+	svc := awsConfig.CloudformationClient()
+	resourcesToSkip := lib.GetResourcesToSkip(failures)
+	if len(resourcesToSkip) != 0 {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Skipping the resources %v", strings.Join(resourcesToSkip, ", "))))
+	}
+	latest := time.Now()
+	if err := lib.ContinueUpdateRollback(stack, resourcesToSkip, svc); err != nil {
+		failWithError(err)
+	}
+	if !*stackrecovery_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The rollback of stack %v has been continued", deployment.StackName)))
+		return
+	}
+	_, err := deployment.WaitUntilRollbackDone(context.Background(), svc, *stackrecovery_PollInterval, func(types.Stack) {
+		latest = showEvents(deployment, latest, awsConfig)
+	})
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("The rollback of stack %v didn't complete", deployment.StackName)))
+		if !errors.Is(err, lib.ErrRollbackFailed) {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The rollback of stack %v has completed", deployment.StackName)))
+}
+
+// recoverByDeletingStack deletes the stack
+func recoverByDeletingStack(deployment lib.DeployInfo, stack types.Stack, awsConfig config.AWSConfig) {
+	svc := awsConfig.CloudformationClient()
+	if aws.ToBool(stack.EnableTerminationProtection) {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stack %v has termination protection enabled. Disable termination protection before deleting the stack.", deployment.StackName)))
+		os.Exit(1)
+	}
+	if err := deployment.StartStackDeletion(svc); err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Unable to delete stack %v", deployment.StackName)))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !*stackrecovery_Wait {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Deletion of stack %v has started", deployment.StackName)))
+		return
+	}
+	_, err := deployment.WaitUntilStackDeleted(context.Background(), svc, *stackrecovery_PollInterval, func(stack types.Stack) {
+		fmt.Printf("%v: stack %v is in status %v\n", time.Now().In(settings.GetTimezoneLocation()).Format(time.RFC3339), deployment.StackName, stack.StackStatus)
+	})
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Stack %v could not be deleted", deployment.StackName)))
+		if errors.Is(err, lib.ErrStackDeleteFailed) {
+			if reason := lastDeleteFailureReason(deployment, awsConfig); reason != "" {
+				fmt.Println(reason)
+			}
+		} else {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v has been deleted", deployment.StackName)))
+}
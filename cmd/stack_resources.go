@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var stackresources_Type *string
+var stackresources_Status *string
+var stackresources_ShowDrift *bool
+
+// stackResourcesCmd represents the stack resources command
+var stackResourcesCmd = &cobra.Command{
+	Use:   "resources",
+	Short: "Show the resources of a stack",
+	Long: `Shows the logical and physical IDs, types, and statuses of the resources in a stack.
+
+Use --type and --status to only show resources of a specific type or with a
+specific status. With --show-drift the drift status of every resource from the
+most recent drift detection is included. This doesn't start a new drift
+detection, use fog drift for that.
+
+To see the resources of multiple stacks, use fog resources instead.
+
+Examples:
+
+$ fog stack resources --stackname my-awesome-stack
+$ fog stack resources --stackname my-awesome-stack --type AWS::S3::Bucket
+$ fog stack resources --stackname my-awesome-stack --status CREATE_FAILED --show-drift
+`,
+	Run: showStackResources,
+}
+
+func init() {
+	stackCmd.AddCommand(stackResourcesCmd)
+	stackresources_Type = stackResourcesCmd.Flags().String("type", "", "Only show resources of this type, e.g. AWS::S3::Bucket")
+	stackresources_Status = stackResourcesCmd.Flags().String("status", "", "Only show resources with this status, e.g. CREATE_FAILED")
+	stackresources_ShowDrift = stackResourcesCmd.Flags().Bool("show-drift", false, "Show the drift status of the resources from the most recent drift detection")
+}
+
+func showStackResources(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	svc := awsConfig.CloudformationClient()
+	resources, err := lib.GetStackResources(*stack_StackName, *stackresources_Type, strings.ToUpper(*stackresources_Status), svc)
+	if err != nil {
+		failWithError(err)
+	}
+	keys := []string{"LogicalId", "PhysicalId", "Type", "Status"}
+	if *stackresources_ShowDrift {
+		keys = append(keys, "DriftStatus")
+	}
+	output := format.OutputArray{Keys: keys, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Resources of stack %v", *stack_StackName)
+	output.Settings.SortKey = "LogicalId"
+	for _, resource := range resources {
+		content := map[string]interface{}{
+			"LogicalId":  resource.LogicalID,
+			"PhysicalId": resource.ResourceID,
+			"Type":       resource.Type,
+			"Status":     resource.Status,
+		}
+		if *stackresources_ShowDrift {
+			content["DriftStatus"] = resource.DriftStatus
+		}
+		output.AddContents(content)
+	}
+	output.Write()
+}
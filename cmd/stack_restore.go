@@ -0,0 +1,126 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var stackrestore_SnapshotDir *string
+var stackrestore_NonInteractive *bool
+
+// stackRestoreCmd represents the stack restore command
+var stackRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Create a new stack from a snapshot",
+	Long: `Creates a new stack from a snapshot made with "fog stack snapshot".
+
+The new stack uses the template, parameters, and tags from the snapshot. By
+default the stack gets the same name as the stack the snapshot was taken from,
+use --stackname to provide a different name. The values of NoEcho parameters
+aren't stored in a snapshot, so these will need to have a default value in the
+template.
+
+Examples:
+
+$ fog stack restore --snapshot-dir snapshots/my-awesome-stack/2024-01-01T12-00-00
+$ fog stack restore --snapshot-dir snapshots/my-awesome-stack/2024-01-01T12-00-00 --stackname my-restored-stack
+$ fog stack restore --snapshot-dir snapshots/my-awesome-stack/2024-01-01T12-00-00 --non-interactive
+`,
+	Run: restoreStack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackRestoreCmd)
+	stackrestore_SnapshotDir = stackRestoreCmd.Flags().String("snapshot-dir", "", "The directory containing the snapshot")
+	stackrestore_NonInteractive = stackRestoreCmd.Flags().Bool("non-interactive", false, "Create the stack without asking for confirmation")
+}
+
+func restoreStack(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table") //Enforce table output for deployments
+	outputsettings = settings.NewOutputSettings()
+	outputsettings.SeparateTables = true //Make table output stand out more
+	// The shared deployment functions check the deploy flag
+	*deploy_NonInteractive = *stackrestore_NonInteractive
+	if *stackrestore_SnapshotDir == "" {
+		failWithError(fmt.Errorf("please provide the directory of the snapshot"))
+	}
+	snapshot, err := lib.ReadStackSnapshot(*stackrestore_SnapshotDir)
+	if err != nil {
+		failWithError(err)
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	restore := lib.DeployInfo{
+		StackName: snapshot.StackName,
+		Template:  snapshot.Template,
+		Tags:      snapshot.GetDeploymentTags(),
+	}
+	if *stack_StackName != "" {
+		restore.StackName = *stack_StackName
+	}
+	restore.ChangesetName = placeholderParser(viper.GetString("changeset.name-format"), &restore)
+	if !restore.IsNewStack(awsConfig.CloudformationClient()) {
+		failWithError(fmt.Errorf("stack %v already exists, please provide a different name with --stackname", restore.StackName))
+	}
+	restore.IsNew = true
+	parameters, unknown := snapshot.GetDeploymentParameters()
+	restore.Parameters = parameters
+	if len(unknown) > 0 {
+		fmt.Print(outputsettings.StringWarning(fmt.Sprintf("The values of the following NoEcho parameters aren't known and will use their default values: %v", strings.Join(unknown, ", "))))
+	}
+	showDeploymentInfo(restore, awsConfig)
+	deploymentLog := lib.NewDeploymentLog(awsConfig, restore)
+	changeset := createChangeset(&restore, &deploymentLog, awsConfig)
+	deploymentLog.AddChangeSet(changeset)
+	showChangeset(*changeset, restore, awsConfig)
+	if !*stackrestore_NonInteractive && !askForConfirmation(string(texts.DeployChangesetMessageDeployConfirm)) {
+		deleteChangeset(restore, awsConfig)
+		os.Exit(0)
+	}
+	deployChangeset(restore, awsConfig)
+	resultStack, err := restore.GetFreshStack(awsConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageRetrievePostFailed))
+		log.Fatalln(err.Error())
+	}
+	if resultStack.StackStatus == types.StackStatusCreateComplete {
+		deploymentLog.Success()
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Stack %v has been restored from %v", restore.StackName, *stackrestore_SnapshotDir)))
+		return
+	}
+	fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageFailed))
+	deploymentLog.Failed(showFailedEvents(restore, awsConfig))
+	deleteStackIfNew(restore, awsConfig)
+}
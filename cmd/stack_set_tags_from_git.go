@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// stackSetTagsFromGitCmd represents the stack set-tags-from-git command
+var stackSetTagsFromGitCmd = &cobra.Command{
+	Use:   "set-tags-from-git",
+	Short: "Tag a stack with the details of the current git commit",
+	Long: `Tags a stack with the details of the git commit in the current directory.
+
+The following tags are added to the existing tags of the stack:
+- git:commit: the SHA of the current commit
+- git:branch: the current branch
+- git:author: the author of the current commit
+- git:deployed-at: the time the tags were applied
+
+The stack is updated with its current template and parameters, so only the
+tags change. To add these tags on every deployment instead, set
+deployment.auto-git-tags to true in your config file.
+
+Examples:
+
+$ fog stack set-tags-from-git --stackname my-awesome-stack
+`,
+	Run: setTagsFromGit,
+}
+
+func init() {
+	stackCmd.AddCommand(stackSetTagsFromGitCmd)
+}
+
+func setTagsFromGit(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table")
+	outputsettings = settings.NewOutputSettings()
+	outputsettings.SeparateTables = true
+	if *stack_StackName == "" {
+		failWithError(fmt.Errorf("please provide the name of the stack"))
+	}
+	metadata, err := lib.GetGitMetadata(lib.ExecGitRunner{})
+	if err != nil {
+		failWithError(err)
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stack, err := lib.GetStack(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	gitTags := metadata.ToTags(time.Now())
+	showGitTags(gitTags)
+	updateStackTags(stack, lib.MergeTags(stack.Tags, gitTags), awsConfig)
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The git tags have been applied to stack %v", aws.ToString(stack.StackName))))
+}
+
+// updateStackTags replaces the tags of the stack and shows the events of the
+// update until it's finished. It exits if the update fails.
+func updateStackTags(stack types.Stack, tags []types.Tag, awsConfig config.AWSConfig) {
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), StackArn: aws.ToString(stack.StackId)}
+	started := time.Now()
+	err := lib.UpdateStackTags(stack, tags, awsConfig.CloudformationClient())
+	if err != nil {
+		if strings.Contains(err.Error(), "No updates are to be performed") {
+			fmt.Print(outputsettings.StringInfo("The stack already has these tags"))
+			os.Exit(0)
+		}
+		failWithError(err)
+	}
+	fmt.Print(outputsettings.StringBold("Showing the events for the update:"))
+	latest := started
+	for deployment.IsOngoing(awsConfig.CloudformationClient()) {
+		latest = showEvents(deployment, latest, awsConfig)
+		time.Sleep(3 * time.Second)
+	}
+	showEvents(deployment, latest, awsConfig)
+	resultStack, err := deployment.GetFreshStack(awsConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.DeployStackMessageRetrievePostFailed))
+		failWithError(err)
+	}
+	if resultStack.StackStatus != types.StackStatusUpdateComplete {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Updating the tags of stack %v failed", deployment.StackName)))
+		showFailureReason(deployment, awsConfig)
+		os.Exit(1)
+	}
+}
+
+// showGitTags shows the git tags that will be applied
+func showGitTags(tags []types.Tag) {
+	output := format.OutputArray{Keys: []string{"Key", "Value"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Git tags"
+	for _, tag := range tags {
+		output.AddContents(map[string]interface{}{
+			"Key":   aws.ToString(tag.Key),
+			"Value": aws.ToString(tag.Value),
+		})
+	}
+	output.Write()
+}
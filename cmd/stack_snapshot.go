@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/spf13/cobra"
+)
+
+var stacksnapshot_OutputDir *string
+
+// stackSnapshotCmd represents the stack snapshot command
+var stackSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the current state of a stack to disk",
+	Long: `Saves the current state of a stack to disk, for auditing or disaster recovery.
+
+The snapshot is stored in <output-dir>/<stack-name>/<timestamp>/ and consists of:
+- template.yaml: the currently deployed template
+- parameters.yaml: the parameters, with NoEcho parameters set to UsePreviousValue
+- tags.yaml: the tags of the stack
+- outputs.yaml: the outputs of the stack
+- resources.json: the resources in the stack
+- deployment.yaml: a deployment file that combines the template, parameters, and tags
+
+A snapshot can be used to create a new stack with the "fog stack restore" command.
+
+Examples:
+
+$ fog stack snapshot --stackname my-awesome-stack
+$ fog stack snapshot --stackname my-awesome-stack --output-dir backups
+`,
+	Run: snapshotStack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackSnapshotCmd)
+	stacksnapshot_OutputDir = stackSnapshotCmd.Flags().String("output-dir", "snapshots", "The directory to store the snapshot in")
+}
+
+func snapshotStack(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" {
+		failWithError(fmt.Errorf("please provide the name of the stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	snapshot, err := lib.CreateStackSnapshot(*stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	directory := lib.SnapshotDirectory(*stacksnapshot_OutputDir, snapshot.StackName, time.Now().In(settings.GetTimezoneLocation()))
+	if err := snapshot.Write(directory); err != nil {
+		failWithError(err)
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Snapshot of stack %v saved to %v", snapshot.StackName, directory)))
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var stacktag_Key *string
+var stacktag_Value *string
+var stacktagremove_Key *string
+
+// stackTagCmd represents the stack tag command
+var stackTagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage the tags of a stack",
+	Long: `Shows or changes the tags of a stack without redeploying it.
+
+Changing the tags updates the stack with its current template and parameters,
+so only the tags change. CloudFormation propagates stack tags to the resources
+in the stack that support tags.
+
+Examples:
+
+$ fog stack tag list --stackname my-awesome-stack
+$ fog stack tag add --stackname my-awesome-stack --key Team --value Platform
+$ fog stack tag remove --stackname my-awesome-stack --key OldTag
+`,
+}
+
+// stackTagListCmd represents the stack tag list command
+var stackTagListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the tags of a stack",
+	Run:   listStackTags,
+}
+
+// stackTagAddCmd represents the stack tag add command
+var stackTagAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a tag to a stack, or change its value",
+	Run:   addStackTag,
+}
+
+// stackTagRemoveCmd represents the stack tag remove command
+var stackTagRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a tag from a stack",
+	Run:   removeStackTag,
+}
+
+func init() {
+	stackCmd.AddCommand(stackTagCmd)
+	stackTagCmd.AddCommand(stackTagListCmd)
+	stackTagCmd.AddCommand(stackTagAddCmd)
+	stackTagCmd.AddCommand(stackTagRemoveCmd)
+	stacktag_Key = stackTagAddCmd.Flags().String("key", "", "The key of the tag")
+	stacktag_Value = stackTagAddCmd.Flags().String("value", "", "The value of the tag")
+	stacktagremove_Key = stackTagRemoveCmd.Flags().String("key", "", "The key of the tag")
+}
+
+func listStackTags(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	stack, _ := getStackForTags()
+	output := format.OutputArray{Keys: []string{"Key", "Value"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Tags of stack %v", aws.ToString(stack.StackName))
+	output.Settings.SortKey = "Key"
+	for _, tag := range stack.Tags {
+		output.AddContents(map[string]interface{}{
+			"Key":   aws.ToString(tag.Key),
+			"Value": aws.ToString(tag.Value),
+		})
+	}
+	output.Write()
+}
+
+func addStackTag(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table")
+	outputsettings = settings.NewOutputSettings()
+	if *stacktag_Key == "" {
+		failWithError(fmt.Errorf("please provide the key of the tag"))
+	}
+	stack, awsConfig := getStackForTags()
+	checkReadyForTagUpdate(stack, awsConfig)
+	tag := types.Tag{Key: aws.String(*stacktag_Key), Value: aws.String(*stacktag_Value)}
+	updateStackTags(stack, lib.MergeTags(stack.Tags, []types.Tag{tag}), awsConfig)
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Tag %v has been set on stack %v", *stacktag_Key, aws.ToString(stack.StackName))))
+}
+
+func removeStackTag(cmd *cobra.Command, args []string) {
+	viper.Set("output", "table")
+	outputsettings = settings.NewOutputSettings()
+	if *stacktagremove_Key == "" {
+		failWithError(fmt.Errorf("please provide the key of the tag"))
+	}
+	stack, awsConfig := getStackForTags()
+	tags := lib.RemoveTags(stack.Tags, []string{*stacktagremove_Key})
+	if len(tags) == len(stack.Tags) {
+		fmt.Print(outputsettings.StringInfo(fmt.Sprintf("Stack %v doesn't have a tag %v", aws.ToString(stack.StackName), *stacktagremove_Key)))
+		return
+	}
+	checkReadyForTagUpdate(stack, awsConfig)
+	updateStackTags(stack, tags, awsConfig)
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Tag %v has been removed from stack %v", *stacktagremove_Key, aws.ToString(stack.StackName))))
+}
+
+// getStackForTags returns the stack provided with --stackname
+func getStackForTags() (types.Stack, config.AWSConfig) {
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stack, err := lib.GetStack(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	return stack, awsConfig
+}
+
+// checkReadyForTagUpdate stops if the stack can't be updated in its current status
+func checkReadyForTagUpdate(stack types.Stack, awsConfig config.AWSConfig) {
+	deployment := lib.DeployInfo{StackName: aws.ToString(stack.StackName), RawStack: &stack}
+	if ready, status := deployment.IsReadyForUpdate(awsConfig.CloudformationClient()); !ready {
+		failWithError(fmt.Errorf("the stack '%v' is currently in status %v and can't be updated", deployment.StackName, status))
+	}
+}
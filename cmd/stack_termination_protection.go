@@ -0,0 +1,142 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+var terminationprotection_List *bool
+var terminationprotection_NonInteractive *bool
+
+// stackTerminationProtectionCmd represents the stack termination-protection command
+var stackTerminationProtectionCmd = &cobra.Command{
+	Use:   "termination-protection",
+	Short: "Manage the termination protection of stacks",
+	Long: `Shows or changes the termination protection of stacks.
+
+Use the enable and disable subcommands to change the termination protection of
+a single stack. With --list the termination protection status is shown for all
+stacks matching the provided stackname, which supports wildcards.
+
+Examples:
+
+$ fog stack termination-protection enable --stackname my-awesome-stack
+$ fog stack termination-protection disable --stackname my-awesome-stack
+$ fog stack termination-protection --list --stackname "my-*"
+`,
+	Run: listTerminationProtection,
+}
+
+// stackTerminationProtectionEnableCmd represents the stack termination-protection enable command
+var stackTerminationProtectionEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Enable termination protection for a stack",
+	Run:   enableTerminationProtection,
+}
+
+// stackTerminationProtectionDisableCmd represents the stack termination-protection disable command
+var stackTerminationProtectionDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable termination protection for a stack",
+	Long: `Disables termination protection for a stack.
+
+As this allows the stack to be deleted, you will be asked for confirmation
+unless --non-interactive is used.`,
+	Run: disableTerminationProtection,
+}
+
+func init() {
+	stackCmd.AddCommand(stackTerminationProtectionCmd)
+	stackTerminationProtectionCmd.AddCommand(stackTerminationProtectionEnableCmd)
+	stackTerminationProtectionCmd.AddCommand(stackTerminationProtectionDisableCmd)
+	terminationprotection_List = stackTerminationProtectionCmd.Flags().Bool("list", false, "Show the termination protection status of all stacks matching the stackname")
+	terminationprotection_NonInteractive = stackTerminationProtectionDisableCmd.Flags().Bool("non-interactive", false, "Disable termination protection without asking for confirmation")
+}
+
+func listTerminationProtection(cmd *cobra.Command, args []string) {
+	if !*terminationprotection_List {
+		_ = cmd.Help()
+		return
+	}
+	outputsettings = settings.NewOutputSettings()
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	stacks, err := lib.GetCfnStacks(stack_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	output := format.OutputArray{Keys: []string{"StackName", "TerminationProtection"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = "Termination protection of stacks"
+	output.Settings.SortKey = "StackName"
+	for _, stack := range stacks {
+		output.AddContents(map[string]interface{}{
+			"StackName":             stack.Name,
+			"TerminationProtection": aws.ToBool(stack.RawInfo.EnableTerminationProtection),
+		})
+	}
+	output.Write()
+}
+
+func enableTerminationProtection(cmd *cobra.Command, args []string) {
+	setTerminationProtection(true)
+}
+
+func disableTerminationProtection(cmd *cobra.Command, args []string) {
+	setTerminationProtection(false)
+}
+
+// setTerminationProtection changes the termination protection of the stack provided with --stackname
+func setTerminationProtection(enabled bool) {
+	outputsettings = settings.NewOutputSettings()
+	if *stack_StackName == "" || strings.Contains(*stack_StackName, "*") {
+		failWithError(fmt.Errorf("please provide the exact name or ID of a single stack"))
+	}
+	if !enabled && !*terminationprotection_NonInteractive && !askForConfirmation(fmt.Sprintf("Are you sure you want to disable termination protection for stack %v?", *stack_StackName)) {
+		fmt.Println("No problem. I have left the termination protection enabled.")
+		return
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	if err := lib.SetTerminationProtection(*stack_StackName, enabled, awsConfig.CloudformationClient()); err != nil {
+		fmt.Print(outputsettings.StringFailure(fmt.Sprintf("Unable to change the termination protection of stack %v", *stack_StackName)))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("Termination protection %v for stack %v", state, *stack_StackName)))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var templatediff_StackName *string
+
+// templateDiffCmd represents the template diff command
+var templateDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare a local template with the deployed one",
+	Long: `Compares a local template with the template that is currently deployed for a stack.
+
+Both templates are normalized before comparing, so switching between YAML and
+JSON or using the short form of intrinsic functions doesn't show up as a
+change. Changes are shown per resource (or other template entry), listing the
+keys that were added, removed, or modified. With --output json the result is
+a structured diff that can be used in pipelines.
+
+Examples:
+
+$ fog template diff --stackname my-awesome-stack --template basicvpc
+$ fog template diff --stackname my-awesome-stack --template basicvpc --output json
+`,
+	Run: diffTemplate,
+}
+
+func init() {
+	templateCmd.AddCommand(templateDiffCmd)
+	templatediff_StackName = templateDiffCmd.Flags().StringP("stackname", "n", "", "The name of the deployed stack to compare against")
+}
+
+func diffTemplate(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *template_TemplateName == "" || *templatediff_StackName == "" {
+		failWithError(fmt.Errorf("both the template and stackname flags are required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	template, _, err := lib.ReadTemplate(template_TemplateName)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
+		failWithError(err)
+	}
+	updated, err := lib.NormalizeTemplate(template)
+	if err != nil {
+		failWithError(fmt.Errorf("unable to parse the local template: %w", err))
+	}
+	deployedTemplate, err := lib.GetCurrentTemplateBody(*templatediff_StackName, awsConfig.CloudformationClient())
+	if err != nil {
+		failWithError(err)
+	}
+	current, err := lib.NormalizeTemplate(deployedTemplate)
+	if err != nil {
+		failWithError(fmt.Errorf("unable to parse the deployed template: %w", err))
+	}
+	diff := lib.CompareTemplates(current, updated)
+	if outputsettings.OutputFormat == "json" {
+		result, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			failWithError(err)
+		}
+		fmt.Println(string(result))
+		return
+	}
+	if !diff.HasChanges() {
+		fmt.Print(outputsettings.StringSuccess(fmt.Sprintf("The template is the same as the one deployed for stack %v", *templatediff_StackName)))
+		return
+	}
+	output := format.OutputArray{Keys: []string{"Section", "Name", "Key", "Change", "Old", "New"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Template changes compared to stack %v", *templatediff_StackName)
+	for _, change := range diff.Changes {
+		output.AddContents(map[string]interface{}{
+			"Section": change.Section,
+			"Name":    change.Name,
+			"Key":     change.Path,
+			"Change":  string(change.Type),
+			"Old":     formatTemplateValue(change.Old),
+			"New":     formatTemplateValue(change.New),
+		})
+	}
+	output.Write()
+}
+
+// formatTemplateValue shows strings as is and everything else as compact JSON
+func formatTemplateValue(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(result)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/spf13/cobra"
+)
+
+var templateparameters_RequiredOnly *bool
+
+// templateParametersCmd represents the template parameters command
+var templateParametersCmd = &cobra.Command{
+	Use:   "parameters",
+	Short: "Show the parameters of a template",
+	Long: `Shows the parameters a template accepts.
+
+The template is read locally and found the same way as with fog deploy, so no
+AWS credentials are needed. Both JSON and YAML templates are supported. Use
+--required-only to only show the parameters without a default value, which
+are the ones that need to be provided when deploying the template. For use in
+other tools, the parameter definitions can be shown as JSON with --output json.
+
+Examples:
+
+$ fog template parameters --template basicvpc
+$ fog template parameters --template basicvpc --required-only --output json
+`,
+	Run: showTemplateParameters,
+}
+
+func init() {
+	templateCmd.AddCommand(templateParametersCmd)
+	templateparameters_RequiredOnly = templateParametersCmd.Flags().Bool("required-only", false, "Only show the parameters without a default value")
+}
+
+func showTemplateParameters(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *template_TemplateName == "" {
+		failWithError(fmt.Errorf("the template flag is required"))
+	}
+	template, _, err := lib.ReadTemplate(template_TemplateName)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
+		failWithError(err)
+	}
+	parameters, err := lib.ParseTemplateParameters(template)
+	if err != nil {
+		failWithError(err)
+	}
+	if *templateparameters_RequiredOnly {
+		parameters = lib.FilterRequiredParameters(parameters)
+	}
+	separator := settings.GetSeparator()
+	output := format.OutputArray{Keys: []string{"Name", "Type", "Default", "AllowedValues", "Description", "NoEcho"}, Settings: settings.NewOutputSettings()}
+	output.Settings.Title = fmt.Sprintf("Parameters of template %v", *template_TemplateName)
+	output.Settings.SortKey = "Name"
+	for name, parameter := range parameters {
+		defaultValue := ""
+		if !parameter.IsRequired() {
+			defaultValue = fmt.Sprint(parameter.Default)
+		}
+		allowedValues := make([]string, 0, len(parameter.AllowedValues))
+		for _, value := range parameter.AllowedValues {
+			allowedValues = append(allowedValues, fmt.Sprint(value))
+		}
+		output.AddContents(map[string]interface{}{
+			"Name":          name,
+			"Type":          parameter.Type,
+			"Default":       defaultValue,
+			"AllowedValues": strings.Join(allowedValues, separator),
+			"Description":   parameter.Description,
+			"NoEcho":        parameter.NoEcho,
+		})
+	}
+	output.Write()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Arjen Schwarz <developer@arjen.eu>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib"
+	"github.com/ArjenSchwarz/fog/lib/texts"
+	format "github.com/ArjenSchwarz/go-output"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+var templatevalidate_Bucket *string
+
+// templateValidateCmd represents the template validate command
+var templateValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a template with CloudFormation",
+	Long: `Validates a template using the CloudFormation ValidateTemplate API.
+
+This is a quick way to catch syntax errors before creating a change set. The
+template is found the same way as with fog deploy. Templates larger than 51,200
+bytes need to be uploaded to S3 first, for those you need to provide a bucket.
+
+On success the description, required capabilities, and parameters of the
+template are shown.
+
+Examples:
+
+$ fog template validate --template basicvpc
+$ fog template validate --template large-template --bucket my-template-bucket
+`,
+	Run: validateTemplate,
+}
+
+func init() {
+	templateCmd.AddCommand(templateValidateCmd)
+	templatevalidate_Bucket = templateValidateCmd.Flags().StringP("bucket", "b", "", "The S3 bucket where the template should be uploaded to if it's too large to validate directly")
+}
+
+func validateTemplate(cmd *cobra.Command, args []string) {
+	outputsettings = settings.NewOutputSettings()
+	if *template_TemplateName == "" {
+		failWithError(fmt.Errorf("the template flag is required"))
+	}
+	awsConfig, err := config.DefaultAwsConfig(*settings)
+	if err != nil {
+		failWithError(err)
+	}
+	template, _, err := lib.ReadTemplate(template_TemplateName)
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure(texts.FileTemplateReadFailure))
+		failWithError(err)
+	}
+	templateURL := ""
+	if len(template) > lib.MaxTemplateBodySize {
+		if *templatevalidate_Bucket == "" {
+			failWithError(fmt.Errorf("the template is %d bytes, which is more than the %d bytes that can be validated directly. Please provide a bucket to upload it to", len(template), lib.MaxTemplateBodySize))
+		}
+		objectname, err := lib.UploadTemplate(template_TemplateName, template, templatevalidate_Bucket, awsConfig.S3Client())
+		if err != nil {
+			failWithError(err)
+		}
+		templateURL = fmt.Sprintf("https://%v.s3-%v.amazonaws.com/%v", *templatevalidate_Bucket, awsConfig.Region, objectname)
+	}
+	result, err := lib.ValidateTemplate(template, templateURL, awsConfig.CloudformationClient())
+	if err != nil {
+		fmt.Print(outputsettings.StringFailure("The template is not valid"))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Print(outputsettings.StringSuccess("The template is valid"))
+	separator := settings.GetSeparator()
+	capabilities := make([]string, 0, len(result.Capabilities))
+	for _, capability := range result.Capabilities {
+		capabilities = append(capabilities, string(capability))
+	}
+	summary := format.OutputArray{Keys: []string{"Description", "Capabilities"}, Settings: settings.NewOutputSettings()}
+	summary.Settings.Title = fmt.Sprintf("Template %v", *template_TemplateName)
+	summary.Settings.SeparateTables = true
+	summary.AddContents(map[string]interface{}{
+		"Description":  aws.ToString(result.Description),
+		"Capabilities": strings.Join(capabilities, separator),
+	})
+	summary.AddToBuffer()
+	parameters := format.OutputArray{Keys: []string{"Name", "Default", "NoEcho", "Description"}, Settings: settings.NewOutputSettings()}
+	parameters.Settings.Title = "Parameters"
+	parameters.Settings.SeparateTables = true
+	parameters.Settings.SortKey = "Name"
+	for _, parameter := range result.Parameters {
+		parameters.AddContents(map[string]interface{}{
+			"Name":        aws.ToString(parameter.ParameterKey),
+			"Default":     aws.ToString(parameter.DefaultValue),
+			"NoEcho":      aws.ToBool(parameter.NoEcho),
+			"Description": aws.ToString(parameter.Description),
+		})
+	}
+	parameters.Write()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	external "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
@@ -16,12 +17,28 @@ import (
 
 // AWSConfig is a holder for AWS Config type information
 type AWSConfig struct {
-	AccountAlias string
-	AccountID    string
-	Config       aws.Config
-	ProfileName  string
-	Region       string
-	UserID       string
+	AccountID string
+	// AssumedRoleARN is only set after AssumeRole
+	AssumedRoleARN string
+	Config         aws.Config
+	ProfileName    string
+	Region         string
+	UserID         string
+}
+
+// DefaultRoleSessionName is the session name used when assuming a role without providing one
+const DefaultRoleSessionName = "fog-deploy"
+
+// STSAssumeRoleAPI is the subset of the STS client required to assume a role
+type STSAssumeRoleAPI interface {
+	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+}
+
+// AssumeRoleSettings contains the role to assume and how to assume it
+type AssumeRoleSettings struct {
+	RoleARN     string
+	SessionName string
+	ExternalID  string
 }
 
 // DefaultAwsConfig loads default AWS Config
@@ -52,7 +69,6 @@ func DefaultAwsConfig(config Config) (AWSConfig, error) {
 	if err != nil {
 		return awsConfig, err
 	}
-	awsConfig.setAlias()
 	return awsConfig, nil
 }
 
@@ -81,31 +97,67 @@ func (config *AWSConfig) EC2Client() *ec2.Client {
 	return ec2.NewFromConfig(config.Config)
 }
 
-func (config *AWSConfig) setCallerInfo() error {
-	c := config.StsClient()
-	result, err := c.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+// AssumeRole assumes the role and uses its temporary credentials for all
+// clients created afterwards. The credentials are refreshed by assuming the
+// role again when they expire. The account and user are updated to those of
+// the assumed role.
+func (config *AWSConfig) AssumeRole(role AssumeRoleSettings, svc STSAssumeRoleAPI) error {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(role.RoleARN),
+		RoleSessionName: aws.String(role.SessionName),
+	}
+	if role.SessionName == "" {
+		input.RoleSessionName = aws.String(DefaultRoleSessionName)
+	}
+	if role.ExternalID != "" {
+		input.ExternalId = aws.String(role.ExternalID)
+	}
+	result, err := svc.AssumeRole(context.TODO(), input)
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to assume role %v: %w", role.RoleARN, err)
 	}
-	config.AccountID = *result.Account
-	config.UserID = *result.UserId
+	credentials := assumedRoleCredentials(result)
+	roleARN, err := arn.Parse(aws.ToString(result.AssumedRoleUser.Arn))
+	if err != nil {
+		return fmt.Errorf("unable to assume role %v: %w", role.RoleARN, err)
+	}
+	initial := true
+	config.Config.Credentials = aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		if initial {
+			initial = false
+			return credentials, nil
+		}
+		refreshed, err := svc.AssumeRole(ctx, input)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+		return assumedRoleCredentials(refreshed), nil
+	}))
+	config.AssumedRoleARN = role.RoleARN
+	config.AccountID = roleARN.AccountID
+	config.UserID = aws.ToString(result.AssumedRoleUser.AssumedRoleId)
 	return nil
 }
 
-func (config *AWSConfig) setAlias() {
-	c := config.IAMClient()
-	result, err := c.ListAccountAliases(context.TODO(), &iam.ListAccountAliasesInput{})
-	if err != nil || len(result.AccountAliases) == 0 {
-		// If the user doesn't have permission to see the aliases or the account has no aliases, continue without
-		return
+// assumedRoleCredentials converts the result of AssumeRole to credentials
+func assumedRoleCredentials(result *sts.AssumeRoleOutput) aws.Credentials {
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(result.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(result.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(result.Credentials.SessionToken),
+		Source:          "AssumeRole",
+		CanExpire:       true,
+		Expires:         aws.ToTime(result.Credentials.Expiration),
 	}
-	config.AccountAlias = result.AccountAliases[0]
 }
 
-func (config *AWSConfig) GetAccountAliasID() string {
-	if config.AccountAlias != "" {
-		return fmt.Sprintf("%s (%s)", config.AccountAlias, config.AccountID)
-	} else {
-		return config.AccountID
+func (config *AWSConfig) setCallerInfo() error {
+	c := config.StsClient()
+	result, err := c.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return err
 	}
+	config.AccountID = *result.Account
+	config.UserID = *result.UserId
+	return nil
 }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// mockAssumeRoleClient returns new credentials for every call
+type mockAssumeRoleClient struct {
+	err    error
+	inputs []*sts.AssumeRoleInput
+}
+
+func (m *mockAssumeRoleClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.inputs = append(m.inputs, params)
+	return &sts.AssumeRoleOutput{
+		AssumedRoleUser: &ststypes.AssumedRoleUser{
+			Arn:           aws.String("arn:aws:sts::999999999999:assumed-role/deploy-role/" + aws.ToString(params.RoleSessionName)),
+			AssumedRoleId: aws.String("AROAEXAMPLE:" + aws.ToString(params.RoleSessionName)),
+		},
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     aws.String("ASIAEXAMPLE" + string(rune('0'+len(m.inputs)))),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			// Expire immediately so the next retrieval assumes the role again
+			Expiration: aws.Time(time.Now().Add(-time.Minute)),
+		},
+	}, nil
+}
+
+func TestAWSConfig_AssumeRole(t *testing.T) {
+	tests := map[string]struct {
+		role            AssumeRoleSettings
+		wantSessionName string
+		wantExternalID  *string
+	}{
+		"Defaults": {
+			role:            AssumeRoleSettings{RoleARN: "arn:aws:iam::999999999999:role/deploy-role"},
+			wantSessionName: DefaultRoleSessionName,
+		},
+		"Session name and external ID": {
+			role:            AssumeRoleSettings{RoleARN: "arn:aws:iam::999999999999:role/deploy-role", SessionName: "release-pipeline", ExternalID: "secret-id"},
+			wantSessionName: "release-pipeline",
+			wantExternalID:  aws.String("secret-id"),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &mockAssumeRoleClient{}
+			awsConfig := AWSConfig{AccountID: "111111111111", UserID: "AIDAEXAMPLE"}
+			if err := awsConfig.AssumeRole(tc.role, client); err != nil {
+				t.Fatalf("AssumeRole() error = %v", err)
+			}
+			input := client.inputs[0]
+			if aws.ToString(input.RoleArn) != tc.role.RoleARN || aws.ToString(input.RoleSessionName) != tc.wantSessionName || aws.ToString(input.ExternalId) != aws.ToString(tc.wantExternalID) {
+				t.Errorf("AssumeRole() input = %+v", input)
+			}
+			if awsConfig.AccountID != "999999999999" || awsConfig.UserID != "AROAEXAMPLE:"+tc.wantSessionName {
+				t.Errorf("AssumeRole() account = %v, user = %v, want those of the assumed role", awsConfig.AccountID, awsConfig.UserID)
+			}
+			if awsConfig.AssumedRoleARN != tc.role.RoleARN {
+				t.Errorf("AssumeRole() stored role %v, want %v", awsConfig.AssumedRoleARN, tc.role.RoleARN)
+			}
+			credentials, err := awsConfig.Config.Credentials.Retrieve(context.Background())
+			if err != nil || credentials.AccessKeyID != "ASIAEXAMPLE1" {
+				t.Errorf("Credentials.Retrieve() = %v, %v, want the assumed role credentials", credentials.AccessKeyID, err)
+			}
+			refreshed, err := awsConfig.Config.Credentials.Retrieve(context.Background())
+			if err != nil || refreshed.AccessKeyID != "ASIAEXAMPLE2" {
+				t.Errorf("Credentials.Retrieve() after expiry = %v, %v, want refreshed credentials", refreshed.AccessKeyID, err)
+			}
+		})
+	}
+}
+
+func TestAWSConfig_AssumeRole_Failure(t *testing.T) {
+	denied := errors.New("access denied")
+	awsConfig := AWSConfig{AccountID: "111111111111"}
+	err := awsConfig.AssumeRole(AssumeRoleSettings{RoleARN: "arn:aws:iam::999999999999:role/deploy-role"}, &mockAssumeRoleClient{err: denied})
+	if !errors.Is(err, denied) {
+		t.Errorf("AssumeRole() error = %v, want %v", err, denied)
+	}
+	if awsConfig.AccountID != "111111111111" || awsConfig.AssumedRoleARN != "" || awsConfig.Config.Credentials != nil {
+		t.Errorf("AssumeRole() changed the config after failing: %+v", awsConfig)
+	}
+}
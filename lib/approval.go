@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ApprovalStatus is the status of a change request in the approval system
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+)
+
+// ApprovalClient requests and tracks approval for a change set from an
+// external change management system
+type ApprovalClient interface {
+	// RequestApproval submits the change set for approval and returns the ID of the change request
+	RequestApproval(ctx context.Context, request ApprovalRequest) (string, error)
+	// GetApprovalStatus returns the current status of the change request
+	GetApprovalStatus(ctx context.Context, changeRequestID string) (ApprovalStatus, error)
+}
+
+// ApprovalRequest is the summary of a change set that is sent for approval
+type ApprovalRequest struct {
+	Account       string           `json:"account"`
+	Region        string           `json:"region"`
+	StackName     string           `json:"stackName"`
+	ChangesetName string           `json:"changesetName"`
+	ChangesetID   string           `json:"changesetId"`
+	ConsoleURL    string           `json:"consoleUrl"`
+	Changes       []ApprovalChange `json:"changes"`
+}
+
+// ApprovalChange is a single resource change in an ApprovalRequest
+type ApprovalChange struct {
+	Action      string `json:"action"`
+	LogicalID   string `json:"logicalId"`
+	ResourceID  string `json:"resourceId,omitempty"`
+	Type        string `json:"type"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// NewApprovalRequest creates the approval request for the change set
+func NewApprovalRequest(changeset ChangesetInfo, account string, region string, consoleURL string) ApprovalRequest {
+	request := ApprovalRequest{
+		Account:       account,
+		Region:        region,
+		StackName:     changeset.StackName,
+		ChangesetName: changeset.Name,
+		ChangesetID:   changeset.ID,
+		ConsoleURL:    consoleURL,
+		Changes:       make([]ApprovalChange, 0, len(changeset.Changes)),
+	}
+	for _, change := range changeset.Changes {
+		request.Changes = append(request.Changes, ApprovalChange{
+			Action:      change.Action,
+			LogicalID:   change.LogicalID,
+			ResourceID:  change.ResourceID,
+			Type:        change.Type,
+			Replacement: change.Replacement,
+		})
+	}
+	return request
+}
+
+// HTTPApprovalClient is an ApprovalClient for a REST API. Change requests are
+// created with a POST to <url>/approvals and their status is retrieved with a
+// GET to <url>/approvals/<id>.
+type HTTPApprovalClient struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// NewHTTPApprovalClient returns an HTTPApprovalClient for the API at the provided URL
+func NewHTTPApprovalClient(url string, apiKey string) *HTTPApprovalClient {
+	return &HTTPApprovalClient{
+		URL:    strings.TrimSuffix(url, "/"),
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// RequestApproval submits the change set for approval and returns the ID of the change request
+func (client *HTTPApprovalClient) RequestApproval(ctx context.Context, request ApprovalRequest) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		ChangeRequestID string `json:"changeRequestId"`
+	}
+	if err := client.do(ctx, http.MethodPost, client.URL+"/approvals", body, &response); err != nil {
+		return "", err
+	}
+	if response.ChangeRequestID == "" {
+		return "", fmt.Errorf("the approval API didn't return a changeRequestId")
+	}
+	return response.ChangeRequestID, nil
+}
+
+// GetApprovalStatus returns the current status of the change request
+func (client *HTTPApprovalClient) GetApprovalStatus(ctx context.Context, changeRequestID string) (ApprovalStatus, error) {
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := client.do(ctx, http.MethodGet, client.URL+"/approvals/"+url.PathEscape(changeRequestID), nil, &response); err != nil {
+		return "", err
+	}
+	return ApprovalStatus(strings.ToLower(response.Status)), nil
+}
+
+// do sends the request to the approval API and decodes the JSON response into result
+func (client *HTTPApprovalClient) do(ctx context.Context, method string, url string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+client.APIKey)
+	}
+	resp, err := client.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("the approval API returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// WaitForApproval polls the approval system until the change request is approved
+// or rejected. Use a context with a timeout to limit how long to wait.
+func WaitForApproval(ctx context.Context, client ApprovalClient, changeRequestID string, interval time.Duration) (ApprovalStatus, error) {
+	for {
+		status, err := client.GetApprovalStatus(ctx, changeRequestID)
+		if err != nil {
+			return status, err
+		}
+		if status == ApprovalStatusApproved || status == ApprovalStatusRejected {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return ApprovalStatusPending, fmt.Errorf("stopped waiting for approval of change request %s: %w", changeRequestID, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockApprovalClient struct {
+	statuses []ApprovalStatus
+	calls    int
+	err      error
+}
+
+func (m *mockApprovalClient) RequestApproval(ctx context.Context, request ApprovalRequest) (string, error) {
+	return "CR-1", m.err
+}
+
+func (m *mockApprovalClient) GetApprovalStatus(ctx context.Context, changeRequestID string) (ApprovalStatus, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	status := m.statuses[len(m.statuses)-1]
+	if m.calls < len(m.statuses) {
+		status = m.statuses[m.calls]
+	}
+	m.calls++
+	return status, nil
+}
+
+func TestWaitForApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *mockApprovalClient
+		timeout   time.Duration
+		want      ApprovalStatus
+		wantCalls int
+		wantErr   bool
+	}{
+		{"Approved after polling", &mockApprovalClient{statuses: []ApprovalStatus{ApprovalStatusPending, ApprovalStatusPending, ApprovalStatusApproved}}, time.Second, ApprovalStatusApproved, 3, false},
+		{"Rejected", &mockApprovalClient{statuses: []ApprovalStatus{ApprovalStatusRejected}}, time.Second, ApprovalStatusRejected, 1, false},
+		{"Timeout", &mockApprovalClient{statuses: []ApprovalStatus{ApprovalStatusPending}}, 20 * time.Millisecond, ApprovalStatusPending, 0, true},
+		{"API error", &mockApprovalClient{err: errors.New("unavailable")}, time.Second, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			got, err := WaitForApproval(ctx, tt.client, "CR-1", time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WaitForApproval() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("WaitForApproval() = %v, want %v", got, tt.want)
+			}
+			if tt.wantCalls != 0 && tt.client.calls != tt.wantCalls {
+				t.Errorf("WaitForApproval() made %v calls, want %v", tt.client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHTTPApprovalClient(t *testing.T) {
+	var received ApprovalRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/approvals":
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"changeRequestId": "CHG0012345"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/approvals/CHG0012345":
+			w.Write([]byte(`{"status": "Approved"}`))
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/approvals/CHG%2F0012346":
+			w.Write([]byte(`{"status": "Pending"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	changeset := ChangesetInfo{
+		Name:      "fog-changeset",
+		StackName: "my-stack",
+		Changes:   []ChangesetChanges{{Action: "Modify", LogicalID: "Bucket", Type: "AWS::S3::Bucket", Replacement: "False"}},
+	}
+	client := NewHTTPApprovalClient(server.URL+"/", "secret")
+	id, err := client.RequestApproval(context.Background(), NewApprovalRequest(changeset, "123456789012", "ap-southeast-2", "https://console"))
+	if err != nil {
+		t.Fatalf("RequestApproval() error = %v", err)
+	}
+	if id != "CHG0012345" {
+		t.Errorf("RequestApproval() = %v, want CHG0012345", id)
+	}
+	if received.StackName != "my-stack" || len(received.Changes) != 1 || received.Changes[0].LogicalID != "Bucket" {
+		t.Errorf("RequestApproval() sent %+v", received)
+	}
+	status, err := client.GetApprovalStatus(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetApprovalStatus() error = %v", err)
+	}
+	if status != ApprovalStatusApproved {
+		t.Errorf("GetApprovalStatus() = %v, want %v", status, ApprovalStatusApproved)
+	}
+	// IDs are escaped so they can't change the path of the request
+	status, err = client.GetApprovalStatus(context.Background(), "CHG/0012346")
+	if err != nil {
+		t.Fatalf("GetApprovalStatus() with an escaped ID error = %v", err)
+	}
+	if status != ApprovalStatusPending {
+		t.Errorf("GetApprovalStatus() with an escaped ID = %v, want %v", status, ApprovalStatusPending)
+	}
+	if _, err := client.GetApprovalStatus(context.Background(), "unknown"); err == nil {
+		t.Errorf("GetApprovalStatus() expected an error for an unknown change request")
+	}
+	unauthorized := NewHTTPApprovalClient(server.URL, "wrong")
+	if _, err := unauthorized.RequestApproval(context.Background(), ApprovalRequest{}); err == nil {
+		t.Errorf("RequestApproval() expected an error for an invalid API key")
+	}
+}
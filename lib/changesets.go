@@ -2,28 +2,39 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib/texts"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
 
 type ChangesetInfo struct {
-	Changes      []ChangesetChanges
-	CreationTime time.Time
-	HasModule    bool
-	ID           string
-	Name         string
-	Status       string
-	StatusReason string
-	StackID      string
-	StackName    string
+	Changes         []ChangesetChanges
+	CreationTime    time.Time
+	ExecutionStatus string
+	HasModule       bool
+	ID              string
+	Name            string
+	Status          string
+	StatusReason    string
+	StackID         string
+	StackName       string
+	// Running counts of the changes added through AddChange
+	addCount         int
+	removeCount      int
+	modifyCount      int
+	replacementCount int
+	conditionalCount int
+	changeCount      int
 }
 
 type ChangesetChanges struct {
@@ -36,17 +47,104 @@ type ChangesetChanges struct {
 	Details     []types.ResourceChangeDetail
 }
 
-func (changeset *ChangesetInfo) DeleteChangeset(svc *cloudformation.Client) bool {
+// ListChangesets returns the change sets that exist for the stack, newest first
+func ListChangesets(stackname string, svc CloudFormationListChangeSetsAPI) ([]ChangesetInfo, error) {
+	paginator := cloudformation.NewListChangeSetsPaginator(svc, &cloudformation.ListChangeSetsInput{StackName: &stackname})
+	result := make([]ChangesetInfo, 0)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range output.Summaries {
+			result = append(result, ChangesetInfo{
+				CreationTime:    aws.ToTime(summary.CreationTime),
+				ExecutionStatus: string(summary.ExecutionStatus),
+				ID:              aws.ToString(summary.ChangeSetId),
+				Name:            aws.ToString(summary.ChangeSetName),
+				Status:          string(summary.Status),
+				StatusReason:    aws.ToString(summary.StatusReason),
+				StackID:         aws.ToString(summary.StackId),
+				StackName:       aws.ToString(summary.StackName),
+			})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreationTime.After(result[j].CreationTime)
+	})
+	return result, nil
+}
+
+// DeleteChangeset deletes the change set
+func (changeset *ChangesetInfo) DeleteChangeset(svc CloudFormationDeleteChangeSetAPI) error {
 	input := &cloudformation.DeleteChangeSetInput{
 		StackName:     &changeset.StackName,
 		ChangeSetName: &changeset.Name,
 	}
 	_, err := svc.DeleteChangeSet(context.TODO(), input)
+	return err
+}
 
-	return err == nil
+// SelectChangesetsForDeletion picks the change sets that should be deleted
+// from the change sets of a stack, which are expected to be sorted newest
+// first as returned by ListChangesets. When a name is provided only that
+// change set is selected, with all set every change set that was created or
+// failed is selected, and otherwise the most recent change set is selected.
+func SelectChangesetsForDeletion(changesets []ChangesetInfo, stackname string, name string, all bool) ([]ChangesetInfo, error) {
+	if name != "" {
+		for _, changeset := range changesets {
+			if changeset.Name == name || changeset.ID == name {
+				return []ChangesetInfo{changeset}, nil
+			}
+		}
+		return nil, fmt.Errorf("change set %v doesn't exist for stack %v", name, stackname)
+	}
+	if len(changesets) == 0 {
+		return nil, fmt.Errorf("stack %v doesn't have any change sets", stackname)
+	}
+	if !all {
+		return changesets[:1], nil
+	}
+	result := make([]ChangesetInfo, 0)
+	for _, changeset := range changesets {
+		if changeset.Status == string(types.ChangeSetStatusCreateComplete) || changeset.Status == string(types.ChangeSetStatusFailed) {
+			result = append(result, changeset)
+		}
+	}
+	return result, nil
+}
+
+// DeleteChangesets deletes all of the change sets and returns the names of
+// those that were deleted, and the error for each change set that couldn't
+// be deleted keyed by its name
+func DeleteChangesets(changesets []ChangesetInfo, svc CloudFormationDeleteChangeSetAPI) ([]string, map[string]error) {
+	deleted := make([]string, 0, len(changesets))
+	failed := make(map[string]error)
+	for _, changeset := range changesets {
+		if err := changeset.DeleteChangeset(svc); err != nil {
+			failed[changeset.Name] = err
+			continue
+		}
+		deleted = append(deleted, changeset.Name)
+	}
+	return deleted, failed
 }
 
-func (changeset *ChangesetInfo) DeployChangeset(svc *cloudformation.Client) error {
+// ErrChangesetNotExecutable is returned when a change set can't be executed
+var ErrChangesetNotExecutable = errors.New("the change set can't be executed")
+
+// CheckExecutable verifies that the change set is available for execution.
+// Change sets become OBSOLETE when the stack was updated after they were
+// created, and are UNAVAILABLE while they are still being created or when
+// their creation failed.
+func (changeset *ChangesetInfo) CheckExecutable() error {
+	if changeset.ExecutionStatus != string(types.ExecutionStatusAvailable) {
+		return fmt.Errorf("%w: change set %v has execution status %v", ErrChangesetNotExecutable, changeset.Name, changeset.ExecutionStatus)
+	}
+	return nil
+}
+
+func (changeset *ChangesetInfo) DeployChangeset(svc CloudFormationExecuteChangeSetAPI) error {
 	input := &cloudformation.ExecuteChangeSetInput{
 		ChangeSetName: &changeset.Name,
 		StackName:     &changeset.StackName,
@@ -55,6 +153,149 @@ func (changeset *ChangesetInfo) DeployChangeset(svc *cloudformation.Client) erro
 	return err
 }
 
+// DependencyGraph returns the dependencies between the changes in the change
+// set, mapping the logical ID of a resource to the logical IDs of the resources
+// that depend on it. Dependencies are derived from the ResourceReference and
+// ResourceAttribute details of each change, and only resources that are part of
+// the change set are included.
+func (changeset *ChangesetInfo) DependencyGraph() map[string][]string {
+	inChangeset := make(map[string]bool)
+	for _, change := range changeset.Changes {
+		inChangeset[change.LogicalID] = true
+	}
+	graph := make(map[string][]string)
+	for _, change := range changeset.Changes {
+		if _, ok := graph[change.LogicalID]; !ok {
+			graph[change.LogicalID] = []string{}
+		}
+		for _, dependency := range change.GetDependencies() {
+			if !inChangeset[dependency] || stringInSlice(change.LogicalID, graph[dependency]) {
+				continue
+			}
+			graph[dependency] = append(graph[dependency], change.LogicalID)
+		}
+	}
+	return graph
+}
+
+// OrderedChanges returns the changes in the order they depend on each other,
+// so a resource is always listed after the resources it depends on. Changes
+// without a dependency between them keep their original order. Changes that
+// are part of a circular dependency are added at the end.
+func (changeset *ChangesetInfo) OrderedChanges() []ChangesetChanges {
+	graph := changeset.DependencyGraph()
+	remaining := make(map[string]int)
+	for _, dependents := range graph {
+		for _, dependent := range dependents {
+			remaining[dependent]++
+		}
+	}
+	result := make([]ChangesetChanges, 0, len(changeset.Changes))
+	added := make(map[int]bool)
+	for len(result) < len(changeset.Changes) {
+		progress := false
+		for i, change := range changeset.Changes {
+			if added[i] || remaining[change.LogicalID] > 0 {
+				continue
+			}
+			result = append(result, change)
+			added[i] = true
+			progress = true
+			for _, dependent := range graph[change.LogicalID] {
+				remaining[dependent]--
+			}
+			break
+		}
+		if !progress {
+			for i, change := range changeset.Changes {
+				if !added[i] {
+					result = append(result, change)
+				}
+			}
+			break
+		}
+	}
+	return result
+}
+
+// GetSummary returns the number of changes per type in the change set. The
+// counts are kept up to date by AddChange, so this doesn't need to go through
+// all the changes. Changes that weren't added through AddChange aren't counted.
+func (changeset *ChangesetInfo) GetSummary() ChangesetSummary {
+	return ChangesetSummary{
+		Total:        changeset.changeCount,
+		Added:        changeset.addCount,
+		Removed:      changeset.removeCount,
+		Modified:     changeset.modifyCount,
+		Replacements: changeset.replacementCount,
+		Conditionals: changeset.conditionalCount,
+	}
+}
+
+// GetChangesetChangeCount returns the number of changes in the change set
+// without going through all the changes
+func GetChangesetChangeCount(changeset *ChangesetInfo) int {
+	return changeset.GetSummary().Total
+}
+
+// countChange adds the change to the running counts
+func (changeset *ChangesetInfo) countChange(change ChangesetChanges) {
+	changeset.changeCount++
+	switch change.Action {
+	case string(types.ChangeActionAdd):
+		changeset.addCount++
+	case string(types.ChangeActionRemove):
+		changeset.removeCount++
+	case string(types.ChangeActionModify):
+		changeset.modifyCount++
+	}
+	switch change.Replacement {
+	case string(types.ReplacementTrue):
+		changeset.replacementCount++
+	case string(types.ReplacementConditional):
+		changeset.conditionalCount++
+	}
+}
+
+// ChangesetRisk is the overall risk of deploying a change set
+type ChangesetRisk string
+
+const (
+	// ChangesetRiskHigh means resources are removed or (possibly) replaced
+	ChangesetRiskHigh ChangesetRisk = "HIGH"
+	// ChangesetRiskMedium means resources are modified without being replaced
+	ChangesetRiskMedium ChangesetRisk = "MEDIUM"
+	// ChangesetRiskLow means resources are only added
+	ChangesetRiskLow ChangesetRisk = "LOW"
+)
+
+// ComputeChangesetRisk returns the risk of deploying the change set. It's high
+// when any resources are removed or replaced, including conditional
+// replacements, medium when resources are otherwise changed, and low when
+// resources are only added.
+func ComputeChangesetRisk(changeset ChangesetInfo) ChangesetRisk {
+	summary := changeset.GetSummary()
+	switch {
+	case summary.Removed > 0 || summary.Replacements > 0 || summary.Conditionals > 0:
+		return ChangesetRiskHigh
+	case summary.Total > summary.Added:
+		return ChangesetRiskMedium
+	default:
+		return ChangesetRiskLow
+	}
+}
+
+// GetRemovals returns the changes that remove a resource
+func (changeset *ChangesetInfo) GetRemovals() []ChangesetChanges {
+	result := make([]ChangesetChanges, 0)
+	for _, change := range changeset.Changes {
+		if change.Action == string(types.ChangeActionRemove) {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
 func (changeset *ChangesetInfo) AddChange(changes ChangesetChanges) {
 	var contents []ChangesetChanges
 	if changeset.Changes != nil {
@@ -62,6 +303,7 @@ func (changeset *ChangesetInfo) AddChange(changes ChangesetChanges) {
 	}
 	contents = append(contents, changes)
 	changeset.Changes = contents
+	changeset.countChange(changes)
 	if changes.Module != "" {
 		changeset.HasModule = true
 	}
@@ -72,8 +314,13 @@ func (changeset *ChangesetInfo) GetStack(svc *cloudformation.Client) (types.Stac
 }
 
 func (changeset *ChangesetInfo) GenerateChangesetUrl(settings config.AWSConfig) string {
-	return fmt.Sprintf("https://console.aws.amazon.com/cloudformation/home?region=%v#/stacks/changesets/changes?stackId=%v&changeSetId=%v",
-		settings.Region, changeset.StackID, changeset.ID)
+	return GetChangesetPreviewURL(*changeset, settings.Region)
+}
+
+// GetChangesetPreviewURL returns the URL of the change set in the regional AWS console
+func GetChangesetPreviewURL(changeset ChangesetInfo, region string) string {
+	return fmt.Sprintf("https://%v.console.aws.amazon.com/cloudformation/home?region=%v#/stacks/changesets/changes?stackId=%v&changeSetId=%v",
+		region, region, url.QueryEscape(changeset.StackID), url.QueryEscape(changeset.ID))
 }
 
 func GetStackAndChangesetFromURL(changeseturl string, region string) (string, string) {
@@ -98,6 +345,23 @@ func GetStackAndChangesetFromURL(changeseturl string, region string) (string, st
 	return stackid, changesetid
 }
 
+// IsNoOpChangeset checks whether the change set failed only because it didn't contain
+// any changes. CloudFormation marks these as FAILED, but for a deployment this is a
+// successful outcome.
+func IsNoOpChangeset(changeset ChangesetInfo) bool {
+	if changeset.Status != string(types.ChangeSetStatusFailed) {
+		return false
+	}
+	return strings.Contains(changeset.StatusReason, string(texts.DeployReceivedErrorMessagesNoChanges)) ||
+		strings.Contains(changeset.StatusReason, string(texts.DeployReceivedErrorMessagesNoUpdates))
+}
+
+// ShouldRetryChangesetCreation returns whether a failed change set should be created again.
+// A failed change set will fail again with the same input, so this is always false.
+func ShouldRetryChangesetCreation(changeset ChangesetInfo) bool {
+	return false
+}
+
 func (changes *ChangesetChanges) GetDangerDetails() []string {
 	details := []string{}
 	for _, detail := range changes.Details {
@@ -109,3 +373,20 @@ func (changes *ChangesetChanges) GetDangerDetails() []string {
 	}
 	return details
 }
+
+// GetDependencies returns the logical IDs of the resources this change refers to
+func (changes *ChangesetChanges) GetDependencies() []string {
+	result := make([]string, 0)
+	for _, detail := range changes.Details {
+		if detail.ChangeSource != types.ChangeSourceResourceReference && detail.ChangeSource != types.ChangeSourceResourceAttribute {
+			continue
+		}
+		// Resource attributes are referred to as LogicalId.Attribute
+		dependency, _, _ := strings.Cut(aws.ToString(detail.CausingEntity), ".")
+		if dependency == "" || dependency == changes.LogicalID || stringInSlice(dependency, result) {
+			continue
+		}
+		result = append(result, dependency)
+	}
+	return result
+}
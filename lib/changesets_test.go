@@ -1,10 +1,18 @@
 package lib
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ArjenSchwarz/fog/config"
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
 
 // func TestChangesetInfo_DeleteChangeset(t *testing.T) {
@@ -203,7 +211,15 @@ func TestChangesetInfo_GenerateChangesetUrl(t *testing.T) {
 		args   args
 		want   string
 	}{
-		// TODO: Add test cases.
+		{
+			name: "Regional console URL",
+			fields: fields{
+				ID:      "arn:aws:cloudformation:ap-southeast-2:123456789012:changeSet/fog-2024/abc",
+				StackID: "arn:aws:cloudformation:ap-southeast-2:123456789012:stack/my-stack/def",
+			},
+			args: args{settings: config.AWSConfig{Region: "ap-southeast-2"}},
+			want: "https://ap-southeast-2.console.aws.amazon.com/cloudformation/home?region=ap-southeast-2#/stacks/changesets/changes?stackId=arn%3Aaws%3Acloudformation%3Aap-southeast-2%3A123456789012%3Astack%2Fmy-stack%2Fdef&changeSetId=arn%3Aaws%3Acloudformation%3Aap-southeast-2%3A123456789012%3AchangeSet%2Ffog-2024%2Fabc",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -288,3 +304,350 @@ func Test_GetStackAndChangesetFromURL(t *testing.T) {
 // 		})
 // 	}
 // }
+
+func TestIsNoOpChangeset(t *testing.T) {
+	tests := []struct {
+		name      string
+		changeset ChangesetInfo
+		want      bool
+	}{
+		{"No changes", ChangesetInfo{Status: "FAILED", StatusReason: "The submitted information didn't contain changes. Submit different information to create a change set."}, true},
+		{"No updates", ChangesetInfo{Status: "FAILED", StatusReason: "No updates are to be performed."}, true},
+		{"Real failure", ChangesetInfo{Status: "FAILED", StatusReason: "Template format error: Unresolved resource dependencies [MyBucket] in the Resources block of the template"}, false},
+		{"Successful change set", ChangesetInfo{Status: "CREATE_COMPLETE"}, false},
+		{"Not failed but matching reason", ChangesetInfo{Status: "CREATE_COMPLETE", StatusReason: "No updates are to be performed."}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNoOpChangeset(tt.changeset); got != tt.want {
+				t.Errorf("IsNoOpChangeset() = %v, want %v", got, tt.want)
+			}
+			if got := ShouldRetryChangesetCreation(tt.changeset); got {
+				t.Errorf("ShouldRetryChangesetCreation() = %v, want false", got)
+			}
+		})
+	}
+}
+
+func TestChangesetStageErrors(t *testing.T) {
+	stageErr := errors.New("stage failed")
+	tests := []struct {
+		name          string
+		client        *testutil.MockCFNClient
+		wantCreateErr bool
+		wantDescErr   bool
+		wantExecErr   bool
+		wantDeleted   bool
+	}{
+		{"No errors", &testutil.MockCFNClient{}, false, false, false, true},
+		{"Create fails", (&testutil.MockCFNClient{}).WithCreateChangesetError(stageErr), true, false, false, true},
+		{"Describe fails", (&testutil.MockCFNClient{}).WithDescribeChangesetError(stageErr), false, true, false, true},
+		{"Execute fails", (&testutil.MockCFNClient{}).WithExecuteChangesetError(stageErr), false, false, true, true},
+		{"Delete fails", (&testutil.MockCFNClient{}).WithDeleteChangesetError(stageErr), false, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := DeployInfo{StackName: "test-stack", ChangesetName: "test-changeset"}
+			if _, err := deployment.CreateChangeSet(tt.client); (err != nil) != tt.wantCreateErr {
+				t.Errorf("CreateChangeSet() error = %v, wantErr %v", err, tt.wantCreateErr)
+			}
+			resp, err := deployment.GetChangeset(tt.client)
+			if (err != nil) != tt.wantDescErr {
+				t.Errorf("GetChangeset() error = %v, wantErr %v", err, tt.wantDescErr)
+			}
+			if err == nil && len(resp) != 1 {
+				t.Errorf("GetChangeset() returned %v results, want 1", len(resp))
+			}
+			changeset := ChangesetInfo{StackName: deployment.StackName, Name: deployment.ChangesetName}
+			if err := changeset.DeployChangeset(tt.client); (err != nil) != tt.wantExecErr {
+				t.Errorf("DeployChangeset() error = %v, wantErr %v", err, tt.wantExecErr)
+			}
+			if err := changeset.DeleteChangeset(tt.client); (err == nil) != tt.wantDeleted {
+				t.Errorf("DeleteChangeset() error = %v, want deleted %v", err, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestChangesetInfo_DependencyGraph(t *testing.T) {
+	reference := func(entity string, source types.ChangeSource) types.ResourceChangeDetail {
+		return types.ResourceChangeDetail{ChangeSource: source, CausingEntity: aws.String(entity)}
+	}
+	changeset := ChangesetInfo{Changes: []ChangesetChanges{
+		{LogicalID: "Function", Details: []types.ResourceChangeDetail{
+			reference("Role.Arn", types.ChangeSourceResourceAttribute),
+			reference("Bucket", types.ChangeSourceResourceReference),
+			reference("Environment", types.ChangeSourceParameterReference),
+		}},
+		{LogicalID: "Alarm", Details: []types.ResourceChangeDetail{
+			reference("Function", types.ChangeSourceResourceReference),
+			reference("ExternalTopic", types.ChangeSourceResourceReference),
+		}},
+		{LogicalID: "Role"},
+		{LogicalID: "Bucket"},
+	}}
+	wantGraph := map[string][]string{
+		"Function": {"Alarm"},
+		"Alarm":    {},
+		"Role":     {"Function"},
+		"Bucket":   {"Function"},
+	}
+	if got := changeset.DependencyGraph(); !reflect.DeepEqual(got, wantGraph) {
+		t.Errorf("DependencyGraph() = %v, want %v", got, wantGraph)
+	}
+	wantOrder := []string{"Role", "Bucket", "Function", "Alarm"}
+	gotOrder := make([]string, 0)
+	for _, change := range changeset.OrderedChanges() {
+		gotOrder = append(gotOrder, change.LogicalID)
+	}
+	if !reflect.DeepEqual(gotOrder, wantOrder) {
+		t.Errorf("OrderedChanges() = %v, want %v", gotOrder, wantOrder)
+	}
+}
+
+func TestChangesetInfo_OrderedChanges_Cycle(t *testing.T) {
+	changeset := ChangesetInfo{Changes: []ChangesetChanges{
+		{LogicalID: "A", Details: []types.ResourceChangeDetail{{ChangeSource: types.ChangeSourceResourceReference, CausingEntity: aws.String("B")}}},
+		{LogicalID: "B", Details: []types.ResourceChangeDetail{{ChangeSource: types.ChangeSourceResourceReference, CausingEntity: aws.String("A")}}},
+		{LogicalID: "C"},
+	}}
+	gotOrder := make([]string, 0)
+	for _, change := range changeset.OrderedChanges() {
+		gotOrder = append(gotOrder, change.LogicalID)
+	}
+	wantOrder := []string{"C", "A", "B"}
+	if !reflect.DeepEqual(gotOrder, wantOrder) {
+		t.Errorf("OrderedChanges() = %v, want %v", gotOrder, wantOrder)
+	}
+}
+
+func TestChangesetInfo_GetRemovals(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []ChangesetChanges
+		want    []string
+	}{
+		{"No changes", nil, []string{}},
+		{"No removals", []ChangesetChanges{{Action: "Add", LogicalID: "Bucket"}, {Action: "Modify", LogicalID: "Role"}}, []string{}},
+		{"Removals", []ChangesetChanges{{Action: "Remove", LogicalID: "Bucket"}, {Action: "Modify", LogicalID: "Role"}, {Action: "Remove", LogicalID: "Queue"}}, []string{"Bucket", "Queue"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changeset := ChangesetInfo{Changes: tt.changes}
+			got := make([]string, 0)
+			for _, change := range changeset.GetRemovals() {
+				got = append(got, change.LogicalID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRemovals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangesetInfo_GetSummary(t *testing.T) {
+	changes := []ChangesetChanges{
+		{Action: "Add", LogicalID: "Bucket"},
+		{Action: "Modify", LogicalID: "Role", Replacement: "True"},
+		{Action: "Modify", LogicalID: "Function", Replacement: "Conditional"},
+		{Action: "Remove", LogicalID: "Queue"},
+		{Action: "Import", LogicalID: "Table"},
+	}
+	want := ChangesetSummary{Total: 5, Added: 1, Removed: 1, Modified: 2, Replacements: 1, Conditionals: 1}
+
+	added := ChangesetInfo{}
+	for _, change := range changes {
+		added.AddChange(change)
+	}
+	if got := added.GetSummary(); got != want {
+		t.Errorf("GetSummary() after AddChange = %+v, want %+v", got, want)
+	}
+
+	if got := (&ChangesetInfo{}).GetSummary(); got != (ChangesetSummary{}) {
+		t.Errorf("GetSummary() without changes = %+v, want an empty summary", got)
+	}
+
+	if got := GetChangesetChangeCount(&added); got != 5 {
+		t.Errorf("GetChangesetChangeCount() = %v, want 5", got)
+	}
+}
+
+func TestComputeChangesetRisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []ChangesetChanges
+		want    ChangesetRisk
+	}{
+		{"No changes", nil, ChangesetRiskLow},
+		{"Only additions", []ChangesetChanges{{Action: "Add", LogicalID: "Bucket"}}, ChangesetRiskLow},
+		{"Modification", []ChangesetChanges{{Action: "Add", LogicalID: "Bucket"}, {Action: "Modify", LogicalID: "Role", Replacement: "False"}}, ChangesetRiskMedium},
+		{"Import", []ChangesetChanges{{Action: "Import", LogicalID: "Table"}}, ChangesetRiskMedium},
+		{"Conditional replacement", []ChangesetChanges{{Action: "Modify", LogicalID: "Function", Replacement: "Conditional"}}, ChangesetRiskHigh},
+		{"Replacement", []ChangesetChanges{{Action: "Modify", LogicalID: "Role", Replacement: "True"}}, ChangesetRiskHigh},
+		{"Removal", []ChangesetChanges{{Action: "Add", LogicalID: "Bucket"}, {Action: "Remove", LogicalID: "Queue"}}, ChangesetRiskHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changeset := ChangesetInfo{}
+			for _, change := range tt.changes {
+				changeset.AddChange(change)
+			}
+			if got := ComputeChangesetRisk(changeset); got != tt.want {
+				t.Errorf("ComputeChangesetRisk() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockListChangeSetsClient returns each page of summaries in turn
+type mockListChangeSetsClient struct {
+	pages [][]types.ChangeSetSummary
+	err   error
+}
+
+func (m *mockListChangeSetsClient) ListChangeSets(ctx context.Context, params *cloudformation.ListChangeSetsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListChangeSetsOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(*params.NextToken, "%d", &page)
+	}
+	if page >= len(m.pages) {
+		return &cloudformation.ListChangeSetsOutput{}, nil
+	}
+	output := &cloudformation.ListChangeSetsOutput{Summaries: m.pages[page]}
+	if page+1 < len(m.pages) {
+		output.NextToken = aws.String(fmt.Sprintf("%d", page+1))
+	}
+	return output, nil
+}
+
+func TestListChangesets(t *testing.T) {
+	older := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	tests := map[string]struct {
+		svc       *mockListChangeSetsClient
+		wantNames []string
+		wantErr   bool
+	}{
+		"no change sets": {
+			svc:       &mockListChangeSetsClient{},
+			wantNames: []string{},
+		},
+		"sorted newest first across pages": {
+			svc: &mockListChangeSetsClient{pages: [][]types.ChangeSetSummary{
+				{{ChangeSetName: aws.String("first"), CreationTime: &older, Status: types.ChangeSetStatusCreateComplete, ExecutionStatus: types.ExecutionStatusAvailable}},
+				{{ChangeSetName: aws.String("second"), CreationTime: &newer, Status: types.ChangeSetStatusFailed, ExecutionStatus: types.ExecutionStatusUnavailable}},
+			}},
+			wantNames: []string{"second", "first"},
+		},
+		"api error": {
+			svc:     &mockListChangeSetsClient{err: errors.New("access denied")},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ListChangesets("my-stack", tc.svc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ListChangesets() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			names := make([]string, 0, len(got))
+			for _, changeset := range got {
+				names = append(names, changeset.Name)
+			}
+			if !reflect.DeepEqual(names, tc.wantNames) {
+				t.Errorf("ListChangesets() names = %v, want %v", names, tc.wantNames)
+			}
+		})
+	}
+}
+
+type mockCloudFormationDeleteChangeSetAPI func(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error)
+
+func (m mockCloudFormationDeleteChangeSetAPI) DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestSelectChangesetsForDeletion(t *testing.T) {
+	// Sorted newest first, as returned by ListChangesets
+	changesets := []ChangesetInfo{
+		{Name: "newest", Status: string(types.ChangeSetStatusCreateInProgress)},
+		{Name: "ready", Status: string(types.ChangeSetStatusCreateComplete)},
+		{Name: "failed", Status: string(types.ChangeSetStatusFailed)},
+	}
+	tests := map[string]struct {
+		changesets []ChangesetInfo
+		name       string
+		all        bool
+		want       []string
+		wantErr    bool
+	}{
+		"named":                  {changesets: changesets, name: "failed", want: []string{"failed"}},
+		"named but non-existent": {changesets: changesets, name: "missing", wantErr: true},
+		"latest":                 {changesets: changesets, want: []string{"newest"}},
+		"all":                    {changesets: changesets, all: true, want: []string{"ready", "failed"}},
+		"no change sets":         {changesets: []ChangesetInfo{}, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SelectChangesetsForDeletion(tc.changesets, "my-stack", tc.name, tc.all)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SelectChangesetsForDeletion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			names := []string{}
+			for _, changeset := range got {
+				names = append(names, changeset.Name)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("SelectChangesetsForDeletion() = %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeleteChangesets(t *testing.T) {
+	requested := []string{}
+	svc := mockCloudFormationDeleteChangeSetAPI(func(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+		requested = append(requested, aws.ToString(params.ChangeSetName))
+		if aws.ToString(params.ChangeSetName) == "locked" {
+			return nil, errors.New("ChangeSet is being executed")
+		}
+		return &cloudformation.DeleteChangeSetOutput{}, nil
+	})
+	changesets := []ChangesetInfo{{Name: "one", StackName: "my-stack"}, {Name: "locked", StackName: "my-stack"}, {Name: "two", StackName: "my-stack"}}
+	deleted, failed := DeleteChangesets(changesets, svc)
+	if !reflect.DeepEqual(requested, []string{"one", "locked", "two"}) {
+		t.Errorf("DeleteChangesets() requested %v", requested)
+	}
+	if !reflect.DeepEqual(deleted, []string{"one", "two"}) || len(failed) != 1 {
+		t.Errorf("DeleteChangesets() = %v, %v", deleted, failed)
+	}
+	if err := failed["locked"]; err == nil || err.Error() != "ChangeSet is being executed" {
+		t.Errorf("DeleteChangesets() error for locked = %v, want the error of the API", err)
+	}
+}
+
+func TestChangesetInfo_CheckExecutable(t *testing.T) {
+	tests := map[string]struct {
+		executionStatus types.ExecutionStatus
+		wantErr         error
+	}{
+		"available":   {executionStatus: types.ExecutionStatusAvailable},
+		"obsolete":    {executionStatus: types.ExecutionStatusObsolete, wantErr: ErrChangesetNotExecutable},
+		"unavailable": {executionStatus: types.ExecutionStatusUnavailable, wantErr: ErrChangesetNotExecutable},
+		"executed":    {executionStatus: types.ExecutionStatusExecuteComplete, wantErr: ErrChangesetNotExecutable},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changeset := ChangesetInfo{Name: "fog-changeset", ExecutionStatus: string(tc.executionStatus)}
+			if err := changeset.CheckExecutable(); !errors.Is(err, tc.wantErr) {
+				t.Errorf("CheckExecutable() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// DiffLineType describes what happened to a line in a diff
+type DiffLineType string
+
+const (
+	DiffLineUnchanged DiffLineType = " "
+	DiffLineAdded     DiffLineType = "+"
+	DiffLineRemoved   DiffLineType = "-"
+)
+
+// DiffLine is a single line in a diff
+type DiffLine struct {
+	Type DiffLineType
+	Text string
+}
+
+// GetCurrentTemplateBody returns the template that is currently deployed for the stack, as it was provided
+func GetCurrentTemplateBody(stackName string, svc CloudFormationGetTemplateAPI) (string, error) {
+	input := cloudformation.GetTemplateInput{
+		StackName:     &stackName,
+		TemplateStage: types.TemplateStageOriginal,
+	}
+	result, err := svc.GetTemplate(context.TODO(), &input)
+	if err != nil {
+		return "", err
+	}
+	if result.TemplateBody == nil {
+		return "", nil
+	}
+	return *result.TemplateBody, nil
+}
+
+// DiffTemplates compares two templates line by line and returns the full diff
+func DiffTemplates(current string, updated string) []DiffLine {
+	return diffLines(splitLines(current), splitLines(updated))
+}
+
+// HasChanges returns true if any of the lines in the diff were added or removed
+func HasChanges(diff []DiffLine) bool {
+	for _, line := range diff {
+		if line.Type != DiffLineUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines splits a text into lines, ignoring a trailing newline
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines calculates the shortest edit script between the two slices using
+// the Myers diff algorithm
+func diffLines(a []string, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace holds a copy of v before each step d, limited to the diagonals that step can reach
+	trace := make([][]int, 0)
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	// Walk back through the trace to build the diff in reverse
+	reversed := make([]DiffLine, 0, max)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		snapshot := trace[d]
+		value := func(k int) int { return snapshot[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && value(k-1) < value(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := value(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Type: DiffLineUnchanged, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffLine{Type: DiffLineAdded, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, DiffLine{Type: DiffLineRemoved, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	result := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		result[len(reversed)-1-i] = line
+	}
+	return result
+}
+
+// TemplateChangeType describes what happened to a key in a template
+type TemplateChangeType string
+
+const (
+	TemplateChangeAdded    TemplateChangeType = "added"
+	TemplateChangeRemoved  TemplateChangeType = "removed"
+	TemplateChangeModified TemplateChangeType = "modified"
+)
+
+// TemplateChange is a single difference between two templates
+type TemplateChange struct {
+	// Section is the top level section of the template, e.g. Resources
+	Section string `json:"section"`
+	// Name is the name of the entry within the section, e.g. the logical ID of a resource
+	Name string `json:"name,omitempty"`
+	// Path is the dot separated path of the changed key within the entry
+	Path string             `json:"path,omitempty"`
+	Type TemplateChangeType `json:"type"`
+	Old  interface{}        `json:"old,omitempty"`
+	New  interface{}        `json:"new,omitempty"`
+}
+
+// TemplateDiff holds all differences between two templates
+type TemplateDiff struct {
+	Changes []TemplateChange `json:"changes"`
+}
+
+// HasChanges returns true if the templates are different
+func (diff TemplateDiff) HasChanges() bool {
+	return len(diff.Changes) != 0
+}
+
+// CompareTemplates compares two templates as normalized by NormalizeTemplate.
+// Entries in a section are compared individually, so a modified resource
+// shows the specific properties that changed. Changes are sorted by section,
+// name, and path.
+func CompareTemplates(current map[string]interface{}, updated map[string]interface{}) TemplateDiff {
+	diff := TemplateDiff{Changes: make([]TemplateChange, 0)}
+	for _, section := range sortedUnionKeys(current, updated) {
+		oldSection, inCurrent := current[section]
+		newSection, inUpdated := updated[section]
+		oldMap, oldIsMap := oldSection.(map[string]interface{})
+		newMap, newIsMap := newSection.(map[string]interface{})
+		if !oldIsMap || !newIsMap {
+			// Scalar sections like Description, or sections that were added or removed
+			if inCurrent && inUpdated && reflect.DeepEqual(oldSection, newSection) {
+				continue
+			}
+			diff.Changes = append(diff.Changes, newTemplateChange(section, "", "", oldSection, inCurrent, newSection, inUpdated))
+			continue
+		}
+		for _, name := range sortedUnionKeys(oldMap, newMap) {
+			oldEntry, inOld := oldMap[name]
+			newEntry, inNew := newMap[name]
+			if !inOld || !inNew {
+				diff.Changes = append(diff.Changes, newTemplateChange(section, name, "", oldEntry, inOld, newEntry, inNew))
+				continue
+			}
+			diff.Changes = append(diff.Changes, compareTemplateValues(section, name, "", oldEntry, newEntry)...)
+		}
+	}
+	return diff
+}
+
+// compareTemplateValues recursively compares the values, descending into maps
+// so only the changed keys are reported. Intrinsic functions are compared as
+// a whole.
+func compareTemplateValues(section string, name string, path string, old interface{}, new interface{}) []TemplateChange {
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if !oldIsMap || !newIsMap || isIntrinsicFunction(oldMap) || isIntrinsicFunction(newMap) {
+		return []TemplateChange{newTemplateChange(section, name, path, old, true, new, true)}
+	}
+	changes := make([]TemplateChange, 0)
+	for _, key := range sortedUnionKeys(oldMap, newMap) {
+		keyPath := key
+		if path != "" {
+			keyPath = fmt.Sprintf("%v.%v", path, key)
+		}
+		oldValue, inOld := oldMap[key]
+		newValue, inNew := newMap[key]
+		if !inOld || !inNew {
+			changes = append(changes, newTemplateChange(section, name, keyPath, oldValue, inOld, newValue, inNew))
+			continue
+		}
+		changes = append(changes, compareTemplateValues(section, name, keyPath, oldValue, newValue)...)
+	}
+	return changes
+}
+
+// isIntrinsicFunction checks if the map is a call to an intrinsic function like Ref or Fn::Sub
+func isIntrinsicFunction(value map[string]interface{}) bool {
+	if len(value) != 1 {
+		return false
+	}
+	for key := range value {
+		return key == "Ref" || strings.HasPrefix(key, "Fn::")
+	}
+	return false
+}
+
+func newTemplateChange(section string, name string, path string, old interface{}, hasOld bool, new interface{}, hasNew bool) TemplateChange {
+	change := TemplateChange{Section: section, Name: name, Path: path, Old: old, New: new, Type: TemplateChangeModified}
+	if !hasOld {
+		change.Type = TemplateChangeAdded
+	} else if !hasNew {
+		change.Type = TemplateChangeRemoved
+	}
+	return change
+}
+
+// sortedUnionKeys returns the keys that exist in either map, sorted
+func sortedUnionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
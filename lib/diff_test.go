@@ -0,0 +1,166 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+)
+
+func TestDiffTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		updated string
+		want    []DiffLine
+	}{
+		{"Identical", "a\nb\n", "a\nb\n", []DiffLine{{DiffLineUnchanged, "a"}, {DiffLineUnchanged, "b"}}},
+		{"New template", "", "a\nb", []DiffLine{{DiffLineAdded, "a"}, {DiffLineAdded, "b"}}},
+		{"Removed template", "a\nb", "", []DiffLine{{DiffLineRemoved, "a"}, {DiffLineRemoved, "b"}}},
+		{"Changed line", "a\nb\nc", "a\nx\nc", []DiffLine{{DiffLineUnchanged, "a"}, {DiffLineRemoved, "b"}, {DiffLineAdded, "x"}, {DiffLineUnchanged, "c"}}},
+		{"Added line", "a\nc", "a\nb\nc", []DiffLine{{DiffLineUnchanged, "a"}, {DiffLineAdded, "b"}, {DiffLineUnchanged, "c"}}},
+		{"Removed line", "a\nb\nc", "a\nc", []DiffLine{{DiffLineUnchanged, "a"}, {DiffLineRemoved, "b"}, {DiffLineUnchanged, "c"}}},
+		{"Windows line endings", "a\r\nb\r\n", "a\nb\n", []DiffLine{{DiffLineUnchanged, "a"}, {DiffLineUnchanged, "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffTemplates(tt.current, tt.updated)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffTemplates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTemplates_Reconstructs(t *testing.T) {
+	current := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n  Queue:\n    Type: AWS::SQS::Queue\nOutputs:\n  BucketName:\n    Value: !Ref Bucket\n"
+	updated := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n    Properties:\n      VersioningConfiguration:\n        Status: Enabled\nOutputs:\n  BucketName:\n    Value: !Ref Bucket\n  BucketArn:\n    Value: !GetAtt Bucket.Arn\n"
+	diff := DiffTemplates(current, updated)
+	var before, after []string
+	changes := 0
+	for _, line := range diff {
+		switch line.Type {
+		case DiffLineUnchanged:
+			before = append(before, line.Text)
+			after = append(after, line.Text)
+		case DiffLineRemoved:
+			before = append(before, line.Text)
+			changes++
+		case DiffLineAdded:
+			after = append(after, line.Text)
+			changes++
+		}
+	}
+	if strings.Join(before, "\n")+"\n" != current {
+		t.Errorf("DiffTemplates() does not reconstruct the current template")
+	}
+	if strings.Join(after, "\n")+"\n" != updated {
+		t.Errorf("DiffTemplates() does not reconstruct the updated template")
+	}
+	// 2 lines removed (Queue), 3 added (Properties), 2 added (BucketArn)
+	if changes != 7 {
+		t.Errorf("DiffTemplates() has %v changed lines, want 7", changes)
+	}
+	if !HasChanges(diff) {
+		t.Errorf("HasChanges() = false, want true")
+	}
+	if HasChanges(DiffTemplates(current, current)) {
+		t.Errorf("HasChanges() = true for identical templates, want false")
+	}
+}
+
+func TestGetCurrentTemplateBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		svc     CloudFormationGetTemplateAPI
+		want    string
+		wantErr bool
+	}{
+		{"Template found", mockCloudFormationGetTemplateAPI(func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+			return &cloudformation.GetTemplateOutput{TemplateBody: aws.String("Resources: {}")}, nil
+		}), "Resources: {}", false},
+		{"API error", mockCloudFormationGetTemplateAPI(func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+			return nil, errors.New("Stack does not exist")
+		}), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCurrentTemplateBody("test-stack", tt.svc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetCurrentTemplateBody() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetCurrentTemplateBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareTemplates(t *testing.T) {
+	yamlTemplate := `Description: My stack
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Name
+  Queue:
+    Type: AWS::SQS::Queue
+`
+	jsonTemplate := `{"Description": "My stack", "Resources": {"Queue": {"Type": "AWS::SQS::Queue"}, "Bucket": {"Type": "AWS::S3::Bucket", "Properties": {"BucketName": {"Ref": "Name"}}}}}`
+	tests := []struct {
+		name    string
+		current string
+		updated string
+		want    []TemplateChange
+	}{
+		{"YAML and JSON versions are equal", yamlTemplate, jsonTemplate, []TemplateChange{}},
+		{"Resource added and removed", yamlTemplate, `Description: My stack
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Name
+  Topic:
+    Type: AWS::SNS::Topic
+`, []TemplateChange{
+			{Section: "Resources", Name: "Queue", Type: TemplateChangeRemoved, Old: map[string]interface{}{"Type": "AWS::SQS::Queue"}},
+			{Section: "Resources", Name: "Topic", Type: TemplateChangeAdded, New: map[string]interface{}{"Type": "AWS::SNS::Topic"}},
+		}},
+		{"Property and description modified", yamlTemplate, `Description: My updated stack
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${Name}-bucket"
+      VersioningConfiguration:
+        Status: Enabled
+  Queue:
+    Type: AWS::SQS::Queue
+`, []TemplateChange{
+			{Section: "Description", Type: TemplateChangeModified, Old: "My stack", New: "My updated stack"},
+			{Section: "Resources", Name: "Bucket", Path: "Properties.BucketName", Type: TemplateChangeModified, Old: map[string]interface{}{"Ref": "Name"}, New: map[string]interface{}{"Fn::Sub": "${Name}-bucket"}},
+			{Section: "Resources", Name: "Bucket", Path: "Properties.VersioningConfiguration", Type: TemplateChangeAdded, New: map[string]interface{}{"Status": "Enabled"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := NormalizeTemplate(tt.current)
+			if err != nil {
+				t.Fatalf("NormalizeTemplate() error = %v", err)
+			}
+			updated, err := NormalizeTemplate(tt.updated)
+			if err != nil {
+				t.Fatalf("NormalizeTemplate() error = %v", err)
+			}
+			got := CompareTemplates(current, updated)
+			if !reflect.DeepEqual(got.Changes, tt.want) {
+				t.Errorf("CompareTemplates() = %+v, want %+v", got.Changes, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,13 @@ package lib
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
@@ -34,6 +39,44 @@ func WaitForDriftDetectionToFinish(driftDetectionId *string, svc *cloudformation
 	return result.DetectionStatus
 }
 
+// driftDetectionPollInterval is how long DetectStackDrift waits between checks of the detection status
+var driftDetectionPollInterval = 5 * time.Second
+
+// ErrDriftDetectionFailed is returned when CloudFormation couldn't finish the drift detection
+var ErrDriftDetectionFailed = errors.New("the drift detection failed")
+
+// DetectStackDrift runs a drift detection for the stack and waits until it's
+// finished. It returns the drift status of the stack, which is DRIFTED,
+// IN_SYNC, or NOT_CHECKED. Unlike StartDriftDetection it returns errors
+// instead of panicking, so callers can treat a failed detection as a warning.
+func DetectStackDrift(ctx context.Context, stackName string, svc CloudFormationDetectStackDriftAPI) (types.StackDriftStatus, error) {
+	started, err := svc.DetectStackDrift(ctx, &cloudformation.DetectStackDriftInput{StackName: &stackName})
+	if err != nil {
+		return "", err
+	}
+	input := &cloudformation.DescribeStackDriftDetectionStatusInput{StackDriftDetectionId: started.StackDriftDetectionId}
+	for {
+		result, err := svc.DescribeStackDriftDetectionStatus(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		switch result.DetectionStatus {
+		case types.StackDriftDetectionStatusDetectionComplete:
+			return result.StackDriftStatus, nil
+		case types.StackDriftDetectionStatusDetectionFailed:
+			if result.DetectionStatusReason != nil {
+				return result.StackDriftStatus, fmt.Errorf("%w: %v", ErrDriftDetectionFailed, *result.DetectionStatusReason)
+			}
+			return result.StackDriftStatus, ErrDriftDetectionFailed
+		}
+		select {
+		case <-time.After(driftDetectionPollInterval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
 func GetDefaultStackDrift(stackName *string, svc *cloudformation.Client) []types.StackResourceDrift {
 	input := &cloudformation.DescribeStackResourceDriftsInput{
 		StackName: stackName,
@@ -46,6 +89,141 @@ func GetDefaultStackDrift(stackName *string, svc *cloudformation.Client) []types
 	return result.StackResourceDrifts
 }
 
+// derivedDriftTypes maps resource types to the types of the resources fog
+// checks for drift through them, like the routes of a route table
+var derivedDriftTypes = map[string][]string{
+	"AWS::EC2::NetworkAcl":               {"AWS::EC2::NetworkAclEntry"},
+	"AWS::EC2::RouteTable":               {"AWS::EC2::Route"},
+	"AWS::EC2::TransitGatewayRouteTable": {"AWS::EC2::TransitGatewayRoute"},
+}
+
+// MatchesResourceTypeFilter returns whether the resource type is one of the
+// types in the filter, ignoring case. An empty filter matches every type.
+func MatchesResourceTypeFilter(resourceType string, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, filterType := range filter {
+		if strings.EqualFold(resourceType, filterType) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsDriftCheck returns whether the additional drift checks for a resource
+// of this type are needed with the filter. This is the case when the type
+// itself matches, or when the resources checked through it do.
+func NeedsDriftCheck(resourceType string, filter []string) bool {
+	if MatchesResourceTypeFilter(resourceType, filter) {
+		return true
+	}
+	for _, derivedType := range derivedDriftTypes[resourceType] {
+		if MatchesResourceTypeFilter(derivedType, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDriftByResourceType returns only the drift results for resources with
+// a type in the filter. An empty filter returns all results.
+func FilterDriftByResourceType(drifts []types.StackResourceDrift, filter []string) []types.StackResourceDrift {
+	result := make([]types.StackResourceDrift, 0, len(drifts))
+	for _, drift := range drifts {
+		if MatchesResourceTypeFilter(aws.ToString(drift.ResourceType), filter) {
+			result = append(result, drift)
+		}
+	}
+	return result
+}
+
+// DriftResult is the result of the drift detection of a single stack
+type DriftResult struct {
+	StackName string
+	Status    types.StackDriftStatus
+	// Drifts only contains the resources that were modified or deleted
+	Drifts []types.StackResourceDrift
+	Err    error
+}
+
+// DriftSummary counts the stacks in a set of drift results
+type DriftSummary struct {
+	Total   int
+	Drifted int
+	InSync  int
+	Failed  int
+}
+
+// BatchDetectDrift runs a drift detection for each of the stacks and returns
+// the results in the same order as the stack names. At most parallel
+// detections run at the same time, as CloudFormation throttles these calls.
+// A failure for one stack is stored in its result and doesn't stop the others.
+func BatchDetectDrift(ctx context.Context, stackNames []string, parallel int, svc CloudFormationBatchDriftAPI) []DriftResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]DriftResult, len(stackNames))
+	semaphore := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, stackName := range stackNames {
+		wg.Add(1)
+		go func(i int, stackName string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = detectDriftForStack(ctx, stackName, svc)
+		}(i, stackName)
+	}
+	wg.Wait()
+	return results
+}
+
+// detectDriftForStack runs the drift detection for a single stack and
+// retrieves the drifted resources
+func detectDriftForStack(ctx context.Context, stackName string, svc CloudFormationBatchDriftAPI) DriftResult {
+	result := DriftResult{StackName: stackName}
+	result.Status, result.Err = DetectStackDrift(ctx, stackName, svc)
+	if result.Err != nil || result.Status != types.StackDriftStatusDrifted {
+		return result
+	}
+	input := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: aws.String(stackName),
+		StackResourceDriftStatusFilters: []types.StackResourceDriftStatus{
+			types.StackResourceDriftStatusModified,
+			types.StackResourceDriftStatusDeleted,
+		},
+	}
+	for {
+		output, err := svc.DescribeStackResourceDrifts(ctx, input)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Drifts = append(result.Drifts, output.StackResourceDrifts...)
+		if output.NextToken == nil {
+			return result
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// SummarizeDriftResults counts the drifted, in sync, and failed stacks
+func SummarizeDriftResults(results []DriftResult) DriftSummary {
+	summary := DriftSummary{Total: len(results)}
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			summary.Failed++
+		case result.Status == types.StackDriftStatusDrifted:
+			summary.Drifted++
+		case result.Status == types.StackDriftStatusInSync:
+			summary.InSync++
+		}
+	}
+	return summary
+}
+
 func GetUncheckedStackResources(stackName *string, checkedResources []string, svc *cloudformation.Client) []CfnResource {
 	resources := GetResources(stackName, svc)
 	uncheckedresources := []CfnResource{}
@@ -0,0 +1,261 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// mockDriftDetectionClient returns the detection statuses in order
+type mockDriftDetectionClient struct {
+	startErr  error
+	statuses  []cloudformation.DescribeStackDriftDetectionStatusOutput
+	stackName string
+}
+
+func (m *mockDriftDetectionClient) DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error) {
+	if m.startErr != nil {
+		return nil, m.startErr
+	}
+	m.stackName = aws.ToString(params.StackName)
+	return &cloudformation.DetectStackDriftOutput{StackDriftDetectionId: aws.String("detection-1")}, nil
+}
+
+func (m *mockDriftDetectionClient) DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	status := m.statuses[0]
+	if len(m.statuses) > 1 {
+		m.statuses = m.statuses[1:]
+	}
+	return &status, nil
+}
+
+func TestDetectStackDrift(t *testing.T) {
+	originalInterval := driftDetectionPollInterval
+	driftDetectionPollInterval = time.Millisecond
+	defer func() { driftDetectionPollInterval = originalInterval }()
+
+	inProgress := cloudformation.DescribeStackDriftDetectionStatusOutput{DetectionStatus: types.StackDriftDetectionStatusDetectionInProgress}
+	startErr := errors.New("access denied")
+	tests := map[string]struct {
+		client  *mockDriftDetectionClient
+		want    types.StackDriftStatus
+		wantErr error
+	}{
+		"in sync": {
+			client: &mockDriftDetectionClient{statuses: []cloudformation.DescribeStackDriftDetectionStatusOutput{
+				inProgress,
+				{DetectionStatus: types.StackDriftDetectionStatusDetectionComplete, StackDriftStatus: types.StackDriftStatusInSync},
+			}},
+			want: types.StackDriftStatusInSync,
+		},
+		"drifted": {
+			client: &mockDriftDetectionClient{statuses: []cloudformation.DescribeStackDriftDetectionStatusOutput{
+				{DetectionStatus: types.StackDriftDetectionStatusDetectionComplete, StackDriftStatus: types.StackDriftStatusDrifted},
+			}},
+			want: types.StackDriftStatusDrifted,
+		},
+		"detection failed": {
+			client: &mockDriftDetectionClient{statuses: []cloudformation.DescribeStackDriftDetectionStatusOutput{
+				{DetectionStatus: types.StackDriftDetectionStatusDetectionFailed, StackDriftStatus: types.StackDriftStatusNotChecked, DetectionStatusReason: aws.String("unsupported resources")},
+			}},
+			want:    types.StackDriftStatusNotChecked,
+			wantErr: ErrDriftDetectionFailed,
+		},
+		"unable to start": {
+			client:  &mockDriftDetectionClient{startErr: startErr},
+			wantErr: startErr,
+		},
+		"timed out": {
+			client:  &mockDriftDetectionClient{statuses: []cloudformation.DescribeStackDriftDetectionStatusOutput{inProgress}},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			got, err := DetectStackDrift(ctx, "my-stack", tc.client)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("DetectStackDrift() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("DetectStackDrift() = %v, want %v", got, tc.want)
+			}
+			if tc.client.startErr == nil && tc.client.stackName != "my-stack" {
+				t.Errorf("DetectStackDrift() started detection for %v", tc.client.stackName)
+			}
+		})
+	}
+}
+
+func TestFilterDriftByResourceType(t *testing.T) {
+	drift := func(logicalID, resourceType string) types.StackResourceDrift {
+		return types.StackResourceDrift{LogicalResourceId: aws.String(logicalID), ResourceType: aws.String(resourceType)}
+	}
+	drifts := []types.StackResourceDrift{
+		drift("Role", "AWS::IAM::Role"),
+		drift("Bucket", "AWS::S3::Bucket"),
+		drift("Queue", "AWS::SQS::Queue"),
+		drift("RouteTable", "AWS::EC2::RouteTable"),
+	}
+	tests := map[string]struct {
+		filter []string
+		want   []string
+	}{
+		"no filter":        {want: []string{"Role", "Bucket", "Queue", "RouteTable"}},
+		"single type":      {filter: []string{"AWS::S3::Bucket"}, want: []string{"Bucket"}},
+		"multiple types":   {filter: []string{"AWS::IAM::Role", "AWS::S3::Bucket"}, want: []string{"Role", "Bucket"}},
+		"ignores case":     {filter: []string{"aws::iam::role"}, want: []string{"Role"}},
+		"derived type":     {filter: []string{"AWS::EC2::Route"}, want: []string{}},
+		"no matching type": {filter: []string{"AWS::SNS::Topic"}, want: []string{}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			for _, result := range FilterDriftByResourceType(drifts, tc.filter) {
+				got = append(got, aws.ToString(result.LogicalResourceId))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("FilterDriftByResourceType() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNeedsDriftCheck(t *testing.T) {
+	tests := map[string]struct {
+		resourceType string
+		filter       []string
+		want         bool
+	}{
+		"no filter":               {resourceType: "AWS::EC2::VPC", want: true},
+		"matching type":           {resourceType: "AWS::EC2::VPC", filter: []string{"AWS::EC2::VPC"}, want: true},
+		"other type":              {resourceType: "AWS::EC2::VPC", filter: []string{"AWS::EC2::Subnet"}, want: false},
+		"routes of route table":   {resourceType: "AWS::EC2::RouteTable", filter: []string{"AWS::EC2::Route"}, want: true},
+		"entries of NACL":         {resourceType: "AWS::EC2::NetworkAcl", filter: []string{"AWS::EC2::NetworkAclEntry"}, want: true},
+		"transit gateway routes":  {resourceType: "AWS::EC2::TransitGatewayRouteTable", filter: []string{"AWS::EC2::TransitGatewayRoute"}, want: true},
+		"routes for another type": {resourceType: "AWS::EC2::NetworkAcl", filter: []string{"AWS::EC2::Route"}, want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := NeedsDriftCheck(tc.resourceType, tc.filter); got != tc.want {
+				t.Errorf("NeedsDriftCheck() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// mockBatchDriftClient returns the drift status and drifted resources per
+// stack, and tracks how many detections run at the same time
+type mockBatchDriftClient struct {
+	mu         sync.Mutex
+	statuses   map[string]types.StackDriftStatus
+	drifts     map[string][]types.StackResourceDrift
+	errs       map[string]error
+	running    int
+	maxRunning int
+}
+
+func (m *mockBatchDriftClient) DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error) {
+	stackName := aws.ToString(params.StackName)
+	if err := m.errs[stackName]; err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.running++
+	if m.running > m.maxRunning {
+		m.maxRunning = m.running
+	}
+	m.mu.Unlock()
+	return &cloudformation.DetectStackDriftOutput{StackDriftDetectionId: aws.String(stackName)}, nil
+}
+
+func (m *mockBatchDriftClient) DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	time.Sleep(5 * time.Millisecond)
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	return &cloudformation.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus:  types.StackDriftDetectionStatusDetectionComplete,
+		StackDriftStatus: m.statuses[aws.ToString(params.StackDriftDetectionId)],
+	}, nil
+}
+
+func (m *mockBatchDriftClient) DescribeStackResourceDrifts(ctx context.Context, params *cloudformation.DescribeStackResourceDriftsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourceDriftsOutput, error) {
+	drifts := m.drifts[aws.ToString(params.StackName)]
+	// Return one drift per page to exercise the pagination
+	page := 0
+	if params.NextToken != nil {
+		page = len(aws.ToString(params.NextToken))
+	}
+	output := &cloudformation.DescribeStackResourceDriftsOutput{}
+	if page < len(drifts) {
+		output.StackResourceDrifts = drifts[page : page+1]
+	}
+	if page+1 < len(drifts) {
+		output.NextToken = aws.String(strings.Repeat("x", page+1))
+	}
+	return output, nil
+}
+
+func TestBatchDetectDrift(t *testing.T) {
+	modified := func(logicalID string) types.StackResourceDrift {
+		return types.StackResourceDrift{LogicalResourceId: aws.String(logicalID), StackResourceDriftStatus: types.StackResourceDriftStatusModified}
+	}
+	detectErr := errors.New("access denied")
+	stackNames := []string{"app-api", "app-web", "app-worker", "app-db"}
+	for _, parallel := range []int{0, 1, 2, 4} {
+		t.Run(fmt.Sprintf("parallel %d", parallel), func(t *testing.T) {
+			client := &mockBatchDriftClient{
+				statuses: map[string]types.StackDriftStatus{
+					"app-api":    types.StackDriftStatusDrifted,
+					"app-web":    types.StackDriftStatusInSync,
+					"app-worker": types.StackDriftStatusDrifted,
+				},
+				drifts: map[string][]types.StackResourceDrift{
+					"app-api":    {modified("Function"), modified("Role")},
+					"app-worker": {modified("Queue")},
+				},
+				errs: map[string]error{"app-db": detectErr},
+			}
+			results := BatchDetectDrift(context.Background(), stackNames, parallel, client)
+			if len(results) != len(stackNames) {
+				t.Fatalf("BatchDetectDrift() returned %d results, want %d", len(results), len(stackNames))
+			}
+			for i, result := range results {
+				if result.StackName != stackNames[i] {
+					t.Errorf("BatchDetectDrift() result %d is for %v, want %v", i, result.StackName, stackNames[i])
+				}
+			}
+			if got := len(results[0].Drifts); got != 2 {
+				t.Errorf("BatchDetectDrift() found %d drifted resources for app-api, want 2", got)
+			}
+			if got := len(results[1].Drifts); got != 0 {
+				t.Errorf("BatchDetectDrift() found %d drifted resources for app-web, want 0", got)
+			}
+			if !errors.Is(results[3].Err, detectErr) {
+				t.Errorf("BatchDetectDrift() error for app-db = %v, want %v", results[3].Err, detectErr)
+			}
+			limit := parallel
+			if limit < 1 {
+				limit = 1
+			}
+			if client.maxRunning > limit {
+				t.Errorf("BatchDetectDrift() ran %d detections at the same time, want at most %d", client.maxRunning, limit)
+			}
+			want := DriftSummary{Total: 4, Drifted: 2, InSync: 1, Failed: 1}
+			if got := SummarizeDriftResults(results); got != want {
+				t.Errorf("SummarizeDriftResults() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DriftCache holds the results of the previous drift detection for a stack
+type DriftCache struct {
+	StackName  string           `json:"stackName"`
+	DetectedAt time.Time        `json:"detectedAt"`
+	Items      []DriftCacheItem `json:"items"`
+}
+
+// DriftCacheItem is a single drifted resource as it was reported
+type DriftCacheItem struct {
+	LogicalID  string   `json:"logicalId"`
+	Type       string   `json:"type"`
+	ChangeType string   `json:"changeType"`
+	Details    []string `json:"details"`
+}
+
+var ansiEscapeRegex = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// NewDriftCacheItem creates a cache item, stripping any colours from the values
+func NewDriftCacheItem(logicalID, resourceType, changeType string, details []string) DriftCacheItem {
+	item := DriftCacheItem{
+		LogicalID:  ansiEscapeRegex.ReplaceAllString(logicalID, ""),
+		Type:       ansiEscapeRegex.ReplaceAllString(resourceType, ""),
+		ChangeType: ansiEscapeRegex.ReplaceAllString(changeType, ""),
+		Details:    make([]string, 0, len(details)),
+	}
+	for _, detail := range details {
+		item.Details = append(item.Details, ansiEscapeRegex.ReplaceAllString(detail, ""))
+	}
+	return item
+}
+
+// Key uniquely identifies the drift of the item, so a change in the drift of
+// a resource results in a different key
+func (item DriftCacheItem) Key() string {
+	return strings.Join(append([]string{item.LogicalID, item.Type, item.ChangeType}, item.Details...), "\n")
+}
+
+// GetDriftCachePath returns the location of the drift cache for the stack, which
+// is ~/.fog/drift-cache/<stack-name>.json
+func GetDriftCachePath(stackName string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	filename := strings.NewReplacer("/", "-", ":", "-").Replace(stackName) + ".json"
+	return filepath.Join(home, ".fog", "drift-cache", filename), nil
+}
+
+// ReadDriftCache reads the drift cache from the path. The boolean shows whether
+// a cache was found.
+func ReadDriftCache(path string) (DriftCache, bool, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DriftCache{}, false, nil
+	}
+	if err != nil {
+		return DriftCache{}, false, err
+	}
+	cache := DriftCache{}
+	if err := json.Unmarshal(contents, &cache); err != nil {
+		return DriftCache{}, false, err
+	}
+	return cache, true, nil
+}
+
+// Write stores the drift cache at the path, creating the directory if needed
+func (cache DriftCache) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	contents, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// ClearDriftCache deletes the drift cache at the path if it exists
+func ClearDriftCache(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// IsNew returns whether the item is new or changed compared to the cached results
+func (cache DriftCache) IsNew(item DriftCacheItem) bool {
+	key := item.Key()
+	for _, cached := range cache.Items {
+		if cached.Key() == key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDriftCache_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift-cache", "my-stack.json")
+	if _, found, err := ReadDriftCache(path); found || err != nil {
+		t.Fatalf("ReadDriftCache() on missing file = found %v, error %v", found, err)
+	}
+	cache := DriftCache{
+		StackName:  "my-stack",
+		DetectedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Items:      []DriftCacheItem{NewDriftCacheItem("Bucket", "AWS::S3::Bucket", "MODIFIED", []string{"NOT_EQUAL: /Versioning"})},
+	}
+	if err := cache.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, found, err := ReadDriftCache(path)
+	if err != nil || !found {
+		t.Fatalf("ReadDriftCache() = found %v, error %v", found, err)
+	}
+	if !reflect.DeepEqual(got, cache) {
+		t.Errorf("ReadDriftCache() = %v, want %v", got, cache)
+	}
+	if err := ClearDriftCache(path); err != nil {
+		t.Fatalf("ClearDriftCache() error = %v", err)
+	}
+	if _, found, _ := ReadDriftCache(path); found {
+		t.Errorf("ReadDriftCache() found a cache after clearing it")
+	}
+	if err := ClearDriftCache(path); err != nil {
+		t.Errorf("ClearDriftCache() on missing file error = %v", err)
+	}
+}
+
+func TestDriftCache_IsNew(t *testing.T) {
+	cache := DriftCache{Items: []DriftCacheItem{
+		NewDriftCacheItem("Bucket", "AWS::S3::Bucket", "MODIFIED", []string{"NOT_EQUAL: /Versioning"}),
+		NewDriftCacheItem("Queue", "AWS::SQS::Queue", "DELETED", nil),
+	}}
+	tests := []struct {
+		name string
+		item DriftCacheItem
+		want bool
+	}{
+		{"Unchanged", NewDriftCacheItem("Bucket", "AWS::S3::Bucket", "MODIFIED", []string{"NOT_EQUAL: /Versioning"}), false},
+		{"Unchanged with colours", NewDriftCacheItem("Queue", "AWS::SQS::Queue", "\x1b[31mDELETED\x1b[0m", nil), false},
+		{"Changed details", NewDriftCacheItem("Bucket", "AWS::S3::Bucket", "MODIFIED", []string{"NOT_EQUAL: /Encryption"}), true},
+		{"New resource", NewDriftCacheItem("Role", "AWS::IAM::Role", "MODIFIED", []string{"ADD: /Policies"}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.IsNew(tt.item); got != tt.want {
+				t.Errorf("IsNew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	_ "embed"
+	"sort"
+	"strings"
+)
+
+//go:embed data/drift-supported-types.txt
+var driftSupportedTypesData string
+
+// DriftCoverageReport shows which resource types in a template are covered by
+// CloudFormation's native drift detection
+type DriftCoverageReport struct {
+	// Supported holds the resource types that support drift detection
+	Supported []string
+	// Unsupported holds the resource types that don't support drift detection
+	Unsupported []string
+	// CoveragePercent is the percentage of resources in the template that support drift detection
+	CoveragePercent float64
+}
+
+// GetDriftSupportedTypes returns the resource types that support drift detection
+func GetDriftSupportedTypes() map[string]bool {
+	result := make(map[string]bool)
+	for _, line := range strings.Split(driftSupportedTypesData, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result[line] = true
+	}
+	return result
+}
+
+// GetDriftCoverage returns which of the resource types in the template support
+// drift detection. Resources that won't be created due to a condition are
+// ignored. The coverage is calculated over the number of resources, not the
+// number of resource types.
+func GetDriftCoverage(template CfnTemplateBody) DriftCoverageReport {
+	supportedTypes := GetDriftSupportedTypes()
+	supported := make(map[string]bool)
+	unsupported := make(map[string]bool)
+	total, covered := 0, 0
+	for _, resource := range template.Resources {
+		if !template.ShouldHaveResource(resource) {
+			continue
+		}
+		total++
+		if supportedTypes[resource.Type] {
+			covered++
+			supported[resource.Type] = true
+		} else {
+			unsupported[resource.Type] = true
+		}
+	}
+	report := DriftCoverageReport{
+		Supported:   sortedKeys(supported),
+		Unsupported: sortedKeys(unsupported),
+	}
+	if total > 0 {
+		report.CoveragePercent = float64(covered) / float64(total) * 100
+	}
+	return report
+}
+
+// sortedKeys returns the keys of the map in alphabetical order
+func sortedKeys(values map[string]bool) []string {
+	result := make([]string, 0, len(values))
+	for key := range values {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
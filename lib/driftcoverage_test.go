@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDriftCoverage(t *testing.T) {
+	template := `
+Conditions:
+  CreateQueue: !Equals [true, false]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+  OtherBucket:
+    Type: AWS::S3::Bucket
+  Role:
+    Type: AWS::IAM::Role
+  Lookup:
+    Type: Custom::Lookup
+  Queue:
+    Type: AWS::SQS::Queue
+    Condition: CreateQueue
+`
+	params := map[string]interface{}{}
+	parsed := ParseTemplateString(template, &params, nil)
+	got := GetDriftCoverage(parsed)
+	if !reflect.DeepEqual(got.Supported, []string{"AWS::IAM::Role", "AWS::S3::Bucket"}) {
+		t.Errorf("GetDriftCoverage() Supported = %v", got.Supported)
+	}
+	if !reflect.DeepEqual(got.Unsupported, []string{"Custom::Lookup"}) {
+		t.Errorf("GetDriftCoverage() Unsupported = %v", got.Unsupported)
+	}
+	if got.CoveragePercent != 75 {
+		t.Errorf("GetDriftCoverage() CoveragePercent = %v, want 75", got.CoveragePercent)
+	}
+	if empty := GetDriftCoverage(CfnTemplateBody{}); empty.CoveragePercent != 0 || len(empty.Supported) != 0 {
+		t.Errorf("GetDriftCoverage() for an empty template = %+v", empty)
+	}
+}
+
+func TestGetDriftSupportedTypes(t *testing.T) {
+	types := GetDriftSupportedTypes()
+	if !types["AWS::EC2::VPC"] {
+		t.Errorf("GetDriftSupportedTypes() doesn't include AWS::EC2::VPC")
+	}
+	for key := range types {
+		if key == "" || key[0] == '#' {
+			t.Errorf("GetDriftSupportedTypes() includes invalid entry %q", key)
+		}
+	}
+}
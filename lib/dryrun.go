@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// DryRunReport is the machine readable result of a dry run
+type DryRunReport struct {
+	StackName       string           `json:"stackName"`
+	Account         string           `json:"account"`
+	Region          string           `json:"region"`
+	IsNew           bool             `json:"isNew"`
+	ChangesetName   string           `json:"changesetName"`
+	ChangesetStatus string           `json:"changesetStatus"`
+	StatusReason    string           `json:"statusReason,omitempty"`
+	GeneratedAt     time.Time        `json:"generatedAt"`
+	Summary         ChangesetSummary `json:"summary"`
+	Changes         []DryRunChange   `json:"changes"`
+}
+
+// DryRunChange is a single resource change in a DryRunReport
+type DryRunChange struct {
+	Action      string               `json:"action"`
+	LogicalID   string               `json:"logicalId"`
+	ResourceID  string               `json:"resourceId,omitempty"`
+	Type        string               `json:"type"`
+	Replacement string               `json:"replacement,omitempty"`
+	Module      string               `json:"module,omitempty"`
+	Details     []DryRunChangeDetail `json:"details,omitempty"`
+}
+
+// DryRunChangeDetail describes what causes a change to a resource
+type DryRunChangeDetail struct {
+	Attribute          string `json:"attribute"`
+	Name               string `json:"name,omitempty"`
+	RequiresRecreation string `json:"requiresRecreation,omitempty"`
+	ChangeSource       string `json:"changeSource,omitempty"`
+	CausingEntity      string `json:"causingEntity,omitempty"`
+	Evaluation         string `json:"evaluation,omitempty"`
+}
+
+// ChangesetSummary contains the number of changes per type in a change set
+type ChangesetSummary struct {
+	Total        int `json:"total"`
+	Added        int `json:"added"`
+	Removed      int `json:"removed"`
+	Modified     int `json:"modified"`
+	Replacements int `json:"replacements"`
+	Conditionals int `json:"conditionals"`
+}
+
+// NewDryRunReport creates a report for the change set of a deployment. A change
+// set without changes results in an empty list of changes.
+func NewDryRunReport(deployment DeployInfo, changeset ChangesetInfo, awsConfig config.AWSConfig) DryRunReport {
+	changes := make([]DryRunChange, 0, len(changeset.Changes))
+	for _, change := range changeset.Changes {
+		changes = append(changes, newDryRunChange(change))
+	}
+	return DryRunReport{
+		StackName:       deployment.GetCleanedStackName(),
+		Account:         awsConfig.AccountID,
+		Region:          awsConfig.Region,
+		IsNew:           deployment.IsNew,
+		ChangesetName:   changeset.Name,
+		ChangesetStatus: changeset.Status,
+		StatusReason:    changeset.StatusReason,
+		GeneratedAt:     time.Now().UTC(),
+		Summary:         changeset.GetSummary(),
+		Changes:         changes,
+	}
+}
+
+func newDryRunChange(change ChangesetChanges) DryRunChange {
+	result := DryRunChange{
+		Action:      change.Action,
+		LogicalID:   change.LogicalID,
+		ResourceID:  change.ResourceID,
+		Type:        change.Type,
+		Replacement: change.Replacement,
+		Module:      change.Module,
+	}
+	for _, detail := range change.Details {
+		resultDetail := DryRunChangeDetail{
+			ChangeSource:  string(detail.ChangeSource),
+			CausingEntity: aws.ToString(detail.CausingEntity),
+			Evaluation:    string(detail.Evaluation),
+		}
+		if detail.Target != nil {
+			resultDetail.Attribute = string(detail.Target.Attribute)
+			resultDetail.Name = aws.ToString(detail.Target.Name)
+			resultDetail.RequiresRecreation = string(detail.Target.RequiresRecreation)
+		}
+		result.Details = append(result.Details, resultDetail)
+	}
+	return result
+}
+
+// Write writes the report as indented JSON
+func (report DryRunReport) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
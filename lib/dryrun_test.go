@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ArjenSchwarz/fog/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestDryRunReport_Write(t *testing.T) {
+	deployment := DeployInfo{StackName: "my-stack", IsNew: true}
+	changeset := ChangesetInfo{Name: "fog-dryrun", Status: "CREATE_COMPLETE"}
+	for _, change := range []ChangesetChanges{
+		{Action: "Add", LogicalID: "Bucket", Type: "AWS::S3::Bucket"},
+		{Action: "Modify", LogicalID: "Role", Type: "AWS::IAM::Role", Replacement: "Conditional", Details: []types.ResourceChangeDetail{
+			{ChangeSource: types.ChangeSourceParameterReference, CausingEntity: aws.String("RoleName"), Evaluation: types.EvaluationTypeStatic,
+				Target: &types.ResourceTargetDefinition{Attribute: types.ResourceAttributeProperties, Name: aws.String("RoleName"), RequiresRecreation: types.RequiresRecreationAlways}},
+		}},
+		{Action: "Remove", LogicalID: "Queue", Type: "AWS::SQS::Queue"},
+	} {
+		changeset.AddChange(change)
+	}
+	awsConfig := config.AWSConfig{AccountID: "123456789012", Region: "ap-southeast-2"}
+	report := NewDryRunReport(deployment, changeset, awsConfig)
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("Write() didn't produce valid JSON: %v", err)
+	}
+	if parsed["stackName"] != "my-stack" || parsed["account"] != "123456789012" || parsed["isNew"] != true {
+		t.Errorf("Write() has unexpected metadata: %v", parsed)
+	}
+	wantSummary := ChangesetSummary{Total: 3, Added: 1, Removed: 1, Modified: 1, Conditionals: 1}
+	if report.Summary != wantSummary {
+		t.Errorf("Summary = %+v, want %+v", report.Summary, wantSummary)
+	}
+	changes, ok := parsed["changes"].([]interface{})
+	if !ok || len(changes) != 3 {
+		t.Fatalf("Write() changes = %v, want 3 changes", parsed["changes"])
+	}
+	// All keys, including those of the change details, use camelCase
+	modify := changes[1].(map[string]interface{})
+	if modify["logicalId"] != "Role" || modify["replacement"] != "Conditional" {
+		t.Errorf("Write() change = %v", modify)
+	}
+	details, ok := modify["details"].([]interface{})
+	if !ok || len(details) != 1 {
+		t.Fatalf("Write() details = %v, want 1 detail", modify["details"])
+	}
+	want := map[string]interface{}{
+		"attribute":          "Properties",
+		"name":               "RoleName",
+		"requiresRecreation": "Always",
+		"changeSource":       "ParameterReference",
+		"causingEntity":      "RoleName",
+		"evaluation":         "Static",
+	}
+	if !reflect.DeepEqual(details[0], want) {
+		t.Errorf("Write() detail = %v, want %v", details[0], want)
+	}
+}
+
+func TestDryRunReport_NoChanges(t *testing.T) {
+	changeset := ChangesetInfo{Status: "FAILED", StatusReason: "The submitted information didn't contain changes."}
+	report := NewDryRunReport(DeployInfo{StackName: "my-stack"}, changeset, config.AWSConfig{})
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"changes": []`)) {
+		t.Errorf("Write() should render an empty list of changes, got %v", buf.String())
+	}
+}
@@ -2,6 +2,12 @@ package lib
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -33,6 +39,405 @@ func GetRouteTable(routetableId string, svc EC2DescribeRouteTablesAPI) (types.Ro
 	return result.RouteTables[0], nil
 }
 
+// VpcConfig holds the configuration of a VPC that is checked for drift
+type VpcConfig struct {
+	CidrBlock          string
+	EnableDNSSupport   bool
+	EnableDNSHostnames bool
+	InstanceTenancy    string
+	IPv6CIDRBlocks     []string
+	// AmazonProvidedIPv6 shows an Amazon provided IPv6 CIDR block is expected,
+	// the actual block is only known after it has been assigned
+	AmazonProvidedIPv6 bool
+}
+
+// GetVpcConfig returns the actual configuration of the VPC with the given ID
+func GetVpcConfig(vpcId string, svc EC2DescribeVpcConfigAPI) (VpcConfig, error) {
+	result, err := svc.DescribeVpcs(context.TODO(), &ec2.DescribeVpcsInput{VpcIds: []string{vpcId}})
+	if err != nil {
+		return VpcConfig{}, err
+	}
+	if len(result.Vpcs) == 0 {
+		return VpcConfig{}, fmt.Errorf("VPC %v not found", vpcId)
+	}
+	vpc := result.Vpcs[0]
+	config := VpcConfig{
+		CidrBlock:       aws.ToString(vpc.CidrBlock),
+		InstanceTenancy: string(vpc.InstanceTenancy),
+		IPv6CIDRBlocks:  []string{},
+	}
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && association.Ipv6CidrBlockState.State != types.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		config.IPv6CIDRBlocks = append(config.IPv6CIDRBlocks, aws.ToString(association.Ipv6CidrBlock))
+	}
+	sort.Strings(config.IPv6CIDRBlocks)
+	dnsSupport, err := svc.DescribeVpcAttribute(context.TODO(), &ec2.DescribeVpcAttributeInput{VpcId: &vpcId, Attribute: types.VpcAttributeNameEnableDnsSupport})
+	if err != nil {
+		return VpcConfig{}, err
+	}
+	if dnsSupport.EnableDnsSupport != nil {
+		config.EnableDNSSupport = aws.ToBool(dnsSupport.EnableDnsSupport.Value)
+	}
+	dnsHostnames, err := svc.DescribeVpcAttribute(context.TODO(), &ec2.DescribeVpcAttributeInput{VpcId: &vpcId, Attribute: types.VpcAttributeNameEnableDnsHostnames})
+	if err != nil {
+		return VpcConfig{}, err
+	}
+	if dnsHostnames.EnableDnsHostnames != nil {
+		config.EnableDNSHostnames = aws.ToBool(dnsHostnames.EnableDnsHostnames.Value)
+	}
+	return config, nil
+}
+
+// CompareVpcConfigs returns a description of every difference between the expected and actual VPC configuration
+func CompareVpcConfigs(expected VpcConfig, actual VpcConfig) []string {
+	differences := []string{}
+	if expected.CidrBlock != "" && expected.CidrBlock != actual.CidrBlock {
+		differences = append(differences, fmt.Sprintf("CidrBlock: %v => %v", expected.CidrBlock, actual.CidrBlock))
+	}
+	if expected.EnableDNSSupport != actual.EnableDNSSupport {
+		differences = append(differences, fmt.Sprintf("EnableDnsSupport: %v => %v", expected.EnableDNSSupport, actual.EnableDNSSupport))
+	}
+	if expected.EnableDNSHostnames != actual.EnableDNSHostnames {
+		differences = append(differences, fmt.Sprintf("EnableDnsHostnames: %v => %v", expected.EnableDNSHostnames, actual.EnableDNSHostnames))
+	}
+	if expected.InstanceTenancy != "" && expected.InstanceTenancy != actual.InstanceTenancy {
+		differences = append(differences, fmt.Sprintf("InstanceTenancy: %v => %v", expected.InstanceTenancy, actual.InstanceTenancy))
+	}
+	for _, block := range expected.IPv6CIDRBlocks {
+		if !stringInSlice(block, actual.IPv6CIDRBlocks) {
+			differences = append(differences, fmt.Sprintf("Removed IPv6 CIDR block: %v", block))
+		}
+	}
+	unexpected := []string{}
+	for _, block := range actual.IPv6CIDRBlocks {
+		if !stringInSlice(block, expected.IPv6CIDRBlocks) {
+			unexpected = append(unexpected, block)
+		}
+	}
+	// The value of an Amazon provided block can't be predicted, so only check that there is one
+	if expected.AmazonProvidedIPv6 {
+		switch {
+		case len(unexpected) == 0:
+			differences = append(differences, "Removed Amazon provided IPv6 CIDR block")
+		case len(unexpected) > 1:
+			differences = append(differences, fmt.Sprintf("Unmanaged IPv6 CIDR blocks: expected 1 Amazon provided block, found %v", strings.Join(unexpected, ", ")))
+		}
+	} else {
+		for _, block := range unexpected {
+			differences = append(differences, fmt.Sprintf("Unmanaged IPv6 CIDR block: %v", block))
+		}
+	}
+	return differences
+}
+
+// SubnetConfig holds the configuration of a subnet that is checked for drift
+type SubnetConfig struct {
+	CidrBlock                   string
+	AvailabilityZone            string
+	MapPublicIPOnLaunch         bool
+	AssignIPv6AddressOnCreation bool
+	IPv6CIDRBlock               string
+	Tags                        map[string]string
+}
+
+// GetSubnetConfig returns the actual configuration of the subnet with the given ID
+func GetSubnetConfig(subnetId string, svc EC2DescribeSubnetsAPI) (SubnetConfig, error) {
+	result, err := svc.DescribeSubnets(context.TODO(), &ec2.DescribeSubnetsInput{SubnetIds: []string{subnetId}})
+	if err != nil {
+		return SubnetConfig{}, err
+	}
+	if len(result.Subnets) == 0 {
+		return SubnetConfig{}, fmt.Errorf("subnet %v not found", subnetId)
+	}
+	subnet := result.Subnets[0]
+	config := SubnetConfig{
+		CidrBlock:                   aws.ToString(subnet.CidrBlock),
+		AvailabilityZone:            aws.ToString(subnet.AvailabilityZone),
+		MapPublicIPOnLaunch:         aws.ToBool(subnet.MapPublicIpOnLaunch),
+		AssignIPv6AddressOnCreation: aws.ToBool(subnet.AssignIpv6AddressOnCreation),
+		Tags:                        ParseTagsFromAWSTags(subnet.Tags),
+	}
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && association.Ipv6CidrBlockState.State != types.SubnetCidrBlockStateCodeAssociated {
+			continue
+		}
+		config.IPv6CIDRBlock = aws.ToString(association.Ipv6CidrBlock)
+	}
+	return config, nil
+}
+
+// CompareSubnetConfigs returns a description of every difference between the
+// expected and actual subnet configuration. The availability zone isn't
+// compared, as it can't be changed after creation and often comes from
+// Fn::GetAZs, which can't be resolved reliably from the template. Tags that
+// are set by AWS are ignored, as are the expected tags where the value
+// couldn't be resolved. CloudFormation copies the stack's tags onto the
+// subnet, so those aren't reported as unmanaged tags.
+func CompareSubnetConfigs(expected SubnetConfig, actual SubnetConfig, stackTags map[string]string) []string {
+	differences := []string{}
+	if expected.CidrBlock != "" && expected.CidrBlock != actual.CidrBlock {
+		differences = append(differences, fmt.Sprintf("CidrBlock: %v => %v", expected.CidrBlock, actual.CidrBlock))
+	}
+	if expected.MapPublicIPOnLaunch != actual.MapPublicIPOnLaunch {
+		differences = append(differences, fmt.Sprintf("MapPublicIpOnLaunch: %v => %v", expected.MapPublicIPOnLaunch, actual.MapPublicIPOnLaunch))
+	}
+	if expected.AssignIPv6AddressOnCreation != actual.AssignIPv6AddressOnCreation {
+		differences = append(differences, fmt.Sprintf("AssignIpv6AddressOnCreation: %v => %v", expected.AssignIPv6AddressOnCreation, actual.AssignIPv6AddressOnCreation))
+	}
+	if expected.IPv6CIDRBlock != "" && expected.IPv6CIDRBlock != actual.IPv6CIDRBlock {
+		differences = append(differences, fmt.Sprintf("Ipv6CidrBlock: %v => %v", expected.IPv6CIDRBlock, actual.IPv6CIDRBlock))
+	}
+	tagDifferences := []string{}
+	for key, value := range expected.Tags {
+		if strings.HasPrefix(value, "REF: ") {
+			continue
+		}
+		actualValue, ok := actual.Tags[key]
+		switch {
+		case !ok:
+			tagDifferences = append(tagDifferences, fmt.Sprintf("Removed tag: %v", key))
+		case actualValue != value:
+			tagDifferences = append(tagDifferences, fmt.Sprintf("Tag %v: %v => %v", key, value, actualValue))
+		}
+	}
+	for key := range actual.Tags {
+		if _, ok := expected.Tags[key]; ok || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		if _, ok := stackTags[key]; !ok {
+			tagDifferences = append(tagDifferences, fmt.Sprintf("Unmanaged tag: %v", key))
+		}
+	}
+	sort.Strings(tagDifferences)
+	return append(differences, tagDifferences...)
+}
+
+// VPCEndpointConfig holds the configuration of a VPC endpoint that is checked for drift
+type VPCEndpointConfig struct {
+	ServiceName      string
+	PolicyDocument   string
+	SubnetIds        []string
+	SecurityGroupIds []string
+}
+
+// VPCEndpointDiff describes a property of a VPC endpoint that differs from the template
+type VPCEndpointDiff struct {
+	Property string
+	Expected string
+	Actual   string
+}
+
+// String returns the difference in the same format as the other drift details
+func (diff VPCEndpointDiff) String() string {
+	return fmt.Sprintf("%v: %v => %v", diff.Property, diff.Expected, diff.Actual)
+}
+
+// GetVPCEndpointConfig returns the actual configuration of the VPC endpoint with the given ID
+func GetVPCEndpointConfig(endpointId string, svc EC2DescribeVpcEndpointsAPI) (VPCEndpointConfig, error) {
+	result, err := svc.DescribeVpcEndpoints(context.TODO(), &ec2.DescribeVpcEndpointsInput{VpcEndpointIds: []string{endpointId}})
+	if err != nil {
+		return VPCEndpointConfig{}, err
+	}
+	if len(result.VpcEndpoints) == 0 {
+		return VPCEndpointConfig{}, fmt.Errorf("VPC endpoint %v not found", endpointId)
+	}
+	endpoint := result.VpcEndpoints[0]
+	config := VPCEndpointConfig{
+		ServiceName:      aws.ToString(endpoint.ServiceName),
+		SubnetIds:        endpoint.SubnetIds,
+		SecurityGroupIds: make([]string, 0, len(endpoint.Groups)),
+	}
+	if endpoint.PolicyDocument != nil {
+		policy, err := FormatPolicyDocument(aws.ToString(endpoint.PolicyDocument))
+		if err != nil {
+			return config, err
+		}
+		config.PolicyDocument = policy
+	}
+	for _, group := range endpoint.Groups {
+		config.SecurityGroupIds = append(config.SecurityGroupIds, aws.ToString(group.GroupId))
+	}
+	return config, nil
+}
+
+// GetVPCEndpointDrift returns the differences between the VPC endpoint as
+// defined in the template and its actual configuration
+func GetVPCEndpointDrift(endpointId string, template CfnTemplateBody, logicalId string, params map[string]any, logicalToPhysical map[string]string, svc EC2DescribeVpcEndpointsAPI) ([]VPCEndpointDiff, error) {
+	actual, err := GetVPCEndpointConfig(endpointId, svc)
+	if err != nil {
+		return nil, err
+	}
+	expected := GetVPCEndpointFromTemplate(logicalId, template, params, logicalToPhysical)
+	return CompareVPCEndpointConfigs(expected, actual), nil
+}
+
+// CompareVPCEndpointConfigs returns the differences between the expected and
+// actual VPC endpoint configuration. Values that aren't set in the template, or
+// that contain references that couldn't be resolved, aren't compared. The
+// region in the service name is ignored, as it can't be resolved reliably from
+// the template.
+func CompareVPCEndpointConfigs(expected VPCEndpointConfig, actual VPCEndpointConfig) []VPCEndpointDiff {
+	differences := []VPCEndpointDiff{}
+	if expected.ServiceName != "" && vpcEndpointServiceSuffix(expected.ServiceName) != vpcEndpointServiceSuffix(actual.ServiceName) {
+		differences = append(differences, VPCEndpointDiff{Property: "ServiceName", Expected: expected.ServiceName, Actual: actual.ServiceName})
+	}
+	if expected.PolicyDocument != "" && !strings.Contains(expected.PolicyDocument, "REF: ") && expected.PolicyDocument != actual.PolicyDocument {
+		differences = append(differences, VPCEndpointDiff{Property: "PolicyDocument", Expected: expected.PolicyDocument, Actual: actual.PolicyDocument})
+	}
+	if expected.SubnetIds != nil && !stringSetsMatch(expected.SubnetIds, actual.SubnetIds) {
+		differences = append(differences, VPCEndpointDiff{Property: "SubnetIds", Expected: strings.Join(sortedCopy(expected.SubnetIds), ", "), Actual: strings.Join(sortedCopy(actual.SubnetIds), ", ")})
+	}
+	if expected.SecurityGroupIds != nil && !stringSetsMatch(expected.SecurityGroupIds, actual.SecurityGroupIds) {
+		differences = append(differences, VPCEndpointDiff{Property: "SecurityGroupIds", Expected: strings.Join(sortedCopy(expected.SecurityGroupIds), ", "), Actual: strings.Join(sortedCopy(actual.SecurityGroupIds), ", ")})
+	}
+	return differences
+}
+
+// vpcEndpointServiceSuffix returns the service name without the region for AWS services
+func vpcEndpointServiceSuffix(serviceName string) string {
+	parts := strings.SplitN(serviceName, ".", 4)
+	if len(parts) == 4 && parts[0] == "com" && parts[1] == "amazonaws" {
+		return parts[3]
+	}
+	return serviceName
+}
+
+// stringSetsMatch returns true if both slices contain the same values, regardless of order
+func stringSetsMatch(first []string, second []string) bool {
+	return slices.Equal(sortedCopy(first), sortedCopy(second))
+}
+
+// sortedCopy returns a sorted copy of the slice
+func sortedCopy(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+// SecurityGroupRule is a single rule of a security group, with one source or destination
+type SecurityGroupRule struct {
+	Egress      bool
+	Protocol    string
+	FromPort    int32
+	ToPort      int32
+	Target      string
+	Description string
+}
+
+// Key returns the identifier of the rule, which is everything but the description
+func (rule SecurityGroupRule) Key() string {
+	direction := "ingress"
+	if rule.Egress {
+		direction = "egress"
+	}
+	return fmt.Sprintf("%s %s %d-%d %s", direction, rule.Protocol, rule.FromPort, rule.ToPort, rule.Target)
+}
+
+// String returns a readable description of the rule
+func (rule SecurityGroupRule) String() string {
+	direction := "ingress"
+	if rule.Egress {
+		direction = "egress"
+	}
+	ports := "Ports: All"
+	switch {
+	case rule.Protocol == "icmp" || rule.Protocol == "icmpv6":
+		ports = fmt.Sprintf("ICMP: %v-%v", rule.FromPort, rule.ToPort)
+		if rule.FromPort == -1 {
+			ports = "ICMP: All"
+		}
+	case rule.FromPort == -1:
+	case rule.FromPort == rule.ToPort:
+		ports = fmt.Sprintf("Port: %v", rule.FromPort)
+	default:
+		ports = fmt.Sprintf("Ports: %v-%v", rule.FromPort, rule.ToPort)
+	}
+	protocol := rule.Protocol
+	if protocol == "-1" {
+		protocol = "all"
+	}
+	result := fmt.Sprintf("%s: %s, %s %s", direction, protocol, rule.Target, ports)
+	if rule.Description != "" {
+		result += fmt.Sprintf(" (%s)", rule.Description)
+	}
+	return result
+}
+
+// securityGroupProtocols maps the protocol numbers that AWS returns as names
+var securityGroupProtocols = map[string]string{
+	"1":  "icmp",
+	"6":  "tcp",
+	"17": "udp",
+	"58": "icmpv6",
+}
+
+// normalizeSecurityGroupRule makes the protocol and ports of a rule match how AWS returns them
+func normalizeSecurityGroupRule(rule SecurityGroupRule) SecurityGroupRule {
+	rule.Protocol = strings.ToLower(rule.Protocol)
+	if name, ok := securityGroupProtocols[rule.Protocol]; ok {
+		rule.Protocol = name
+	}
+	// Ports don't apply when all traffic is allowed
+	if rule.Protocol == "-1" {
+		rule.FromPort = -1
+		rule.ToPort = -1
+	}
+	return rule
+}
+
+// GetSecurityGroupRules returns the rules of the security group, keyed by SecurityGroupRule.Key
+func GetSecurityGroupRules(groupId string, svc EC2DescribeSecurityGroupsAPI) (map[string]SecurityGroupRule, error) {
+	result, err := svc.DescribeSecurityGroups(context.TODO(), &ec2.DescribeSecurityGroupsInput{GroupIds: []string{groupId}})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.SecurityGroups) == 0 {
+		return nil, fmt.Errorf("security group %v not found", groupId)
+	}
+	group := result.SecurityGroups[0]
+	rules := make(map[string]SecurityGroupRule)
+	addPermissions := func(permissions []types.IpPermission, egress bool) {
+		for _, permission := range permissions {
+			base := SecurityGroupRule{
+				Egress:   egress,
+				Protocol: aws.ToString(permission.IpProtocol),
+				FromPort: -1,
+				ToPort:   -1,
+			}
+			if permission.FromPort != nil {
+				base.FromPort = *permission.FromPort
+			}
+			if permission.ToPort != nil {
+				base.ToPort = *permission.ToPort
+			}
+			add := func(target string, description *string) {
+				rule := base
+				rule.Target = target
+				rule.Description = aws.ToString(description)
+				rule = normalizeSecurityGroupRule(rule)
+				rules[rule.Key()] = rule
+			}
+			for _, iprange := range permission.IpRanges {
+				add(aws.ToString(iprange.CidrIp), iprange.Description)
+			}
+			for _, iprange := range permission.Ipv6Ranges {
+				add(aws.ToString(iprange.CidrIpv6), iprange.Description)
+			}
+			for _, prefixlist := range permission.PrefixListIds {
+				add(aws.ToString(prefixlist.PrefixListId), prefixlist.Description)
+			}
+			for _, pair := range permission.UserIdGroupPairs {
+				add(aws.ToString(pair.GroupId), pair.Description)
+			}
+		}
+	}
+	addPermissions(group.IpPermissions, false)
+	addPermissions(group.IpPermissionsEgress, true)
+	return rules, nil
+}
+
 // GetManagedPrefixLists returns all managed prefix lists for the region/account
 func GetManagedPrefixLists(svc EC2DescribeManagedPrefixListsAPI) []types.ManagedPrefixList {
 	input := ec2.DescribeManagedPrefixListsInput{}
@@ -120,7 +525,10 @@ func CompareRoutes(route1 types.Route, route2 types.Route) bool {
 	if !stringPointerValueMatch(route1.InstanceId, route2.InstanceId) {
 		return false
 	}
-	if !stringPointerValueMatch(route1.InstanceOwnerId, route2.InstanceOwnerId) {
+	// For routes to an instance, AWS adds the owner and network interface of the
+	// instance. These can't be set in a template, so only compare them if both are set.
+	instanceRoute := route1.InstanceId != nil
+	if !stringPointerValueMatch(route1.InstanceOwnerId, route2.InstanceOwnerId) && !(instanceRoute && (route1.InstanceOwnerId == nil || route2.InstanceOwnerId == nil)) {
 		return false
 	}
 	if !stringPointerValueMatch(route1.LocalGatewayId, route2.LocalGatewayId) {
@@ -129,7 +537,7 @@ func CompareRoutes(route1 types.Route, route2 types.Route) bool {
 	if !stringPointerValueMatch(route1.NatGatewayId, route2.NatGatewayId) {
 		return false
 	}
-	if !stringPointerValueMatch(route1.NetworkInterfaceId, route2.NetworkInterfaceId) {
+	if !stringPointerValueMatch(route1.NetworkInterfaceId, route2.NetworkInterfaceId) && !(instanceRoute && (route1.NetworkInterfaceId == nil || route2.NetworkInterfaceId == nil)) {
 		return false
 	}
 	if !stringPointerValueMatch(route1.TransitGatewayId, route2.TransitGatewayId) {
@@ -206,3 +614,115 @@ func stringPointerValueMatch(pointer1 *string, pointer2 *string) bool {
 	// otherwise the values need to match
 	return *pointer1 == *pointer2
 }
+
+// TGWRouteDiffType describes how a transit gateway route differs from the template
+type TGWRouteDiffType string
+
+const (
+	// TGWRouteModified is a route that exists in both, but with different values
+	TGWRouteModified TGWRouteDiffType = "Modified"
+	// TGWRouteUnmanaged is a route that only exists in the route table
+	TGWRouteUnmanaged TGWRouteDiffType = "Unmanaged"
+	// TGWRouteRemoved is a route that only exists in the template
+	TGWRouteRemoved TGWRouteDiffType = "Removed"
+)
+
+// TGWRouteDiff is a difference between a static transit gateway route in the
+// template and the route table. Expected is nil for unmanaged routes and
+// Actual is nil for removed routes.
+type TGWRouteDiff struct {
+	Destination string
+	Type        TGWRouteDiffType
+	Expected    *types.TransitGatewayRoute
+	Actual      *types.TransitGatewayRoute
+}
+
+// maxTransitGatewayRouteResults is the largest number of routes a single
+// SearchTransitGatewayRoutes call can return
+const maxTransitGatewayRouteResults = 1000
+
+// GetTransitGatewayRouteDrift compares the static routes in the transit gateway
+// route table with the routes from the template, which are keyed by their
+// destination. Propagated routes are ignored as these aren't managed through
+// CloudFormation.
+//
+// The SearchTransitGatewayRoutes API doesn't offer a continuation token, so
+// the search asks for the maximum number of routes and returns an error when
+// the route table holds more than that instead of reporting partial results.
+func GetTransitGatewayRouteDrift(routeTableId string, templateRoutes map[string]types.TransitGatewayRoute, svc EC2SearchTransitGatewayRoutesAPI) ([]TGWRouteDiff, error) {
+	resp, err := svc.SearchTransitGatewayRoutes(context.TODO(), &ec2.SearchTransitGatewayRoutesInput{
+		TransitGatewayRouteTableId: aws.String(routeTableId),
+		Filters: []types.Filter{
+			{Name: aws.String("type"), Values: []string{string(types.TransitGatewayRouteTypeStatic)}},
+			{Name: aws.String("state"), Values: []string{string(types.TransitGatewayRouteStateActive), string(types.TransitGatewayRouteStateBlackhole)}},
+		},
+		MaxResults: aws.Int32(maxTransitGatewayRouteResults),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToBool(resp.AdditionalRoutesAvailable) {
+		return nil, fmt.Errorf("transit gateway route table %v has more than %d static routes, which can't be retrieved in a single search", routeTableId, maxTransitGatewayRouteResults)
+	}
+	remaining := make(map[string]types.TransitGatewayRoute, len(templateRoutes))
+	for destination, route := range templateRoutes {
+		remaining[destination] = route
+	}
+	result := make([]TGWRouteDiff, 0)
+	for _, route := range resp.Routes {
+		actual := route
+		destination := GetTransitGatewayRouteDestination(actual)
+		expected, ok := remaining[destination]
+		if !ok {
+			result = append(result, TGWRouteDiff{Destination: destination, Type: TGWRouteUnmanaged, Actual: &actual})
+			continue
+		}
+		delete(remaining, destination)
+		if !CompareTransitGatewayRoutes(expected, actual) {
+			result = append(result, TGWRouteDiff{Destination: destination, Type: TGWRouteModified, Expected: &expected, Actual: &actual})
+		}
+	}
+	for destination, route := range remaining {
+		expected := route
+		result = append(result, TGWRouteDiff{Destination: destination, Type: TGWRouteRemoved, Expected: &expected})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Destination < result[j].Destination
+	})
+	return result, nil
+}
+
+// CompareTransitGatewayRoutes compares two transit gateway routes and returns
+// true if they have the same destination, state, and attachment
+func CompareTransitGatewayRoutes(route1 types.TransitGatewayRoute, route2 types.TransitGatewayRoute) bool {
+	if GetTransitGatewayRouteDestination(route1) != GetTransitGatewayRouteDestination(route2) {
+		return false
+	}
+	if route1.State != route2.State {
+		return false
+	}
+	return GetTransitGatewayRouteTarget(route1) == GetTransitGatewayRouteTarget(route2)
+}
+
+// GetTransitGatewayRouteDestination returns the destination of a transit gateway
+// route. Either DestinationCidrBlock or PrefixListId
+func GetTransitGatewayRouteDestination(route types.TransitGatewayRoute) string {
+	if route.DestinationCidrBlock != nil {
+		return *route.DestinationCidrBlock
+	}
+	return aws.ToString(route.PrefixListId)
+}
+
+// GetTransitGatewayRouteTarget returns the attachment ID of a transit gateway
+// route, or an empty string for blackhole routes
+func GetTransitGatewayRouteTarget(route types.TransitGatewayRoute) string {
+	if route.State == types.TransitGatewayRouteStateBlackhole {
+		return ""
+	}
+	attachments := make([]string, 0, len(route.TransitGatewayAttachments))
+	for _, attachment := range route.TransitGatewayAttachments {
+		attachments = append(attachments, aws.ToString(attachment.TransitGatewayAttachmentId))
+	}
+	sort.Strings(attachments)
+	return strings.Join(attachments, ",")
+}
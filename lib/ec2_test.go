@@ -262,6 +262,23 @@ func TestCompareRoutes(t *testing.T) {
 	state.State = types.RouteStateBlackhole
 	origin := fullRoute
 	origin.Origin = types.RouteOriginCreateRouteTable
+	templateInstanceRoute := types.Route{
+		DestinationCidrBlock: aws.String("Cidr"),
+		InstanceId:           aws.String("Instance"),
+		State:                types.RouteStateActive,
+		Origin:               types.RouteOriginCreateRoute,
+	}
+	actualInstanceRoute := templateInstanceRoute
+	actualInstanceRoute.InstanceOwnerId = aws.String("InstanceOwner")
+	actualInstanceRoute.NetworkInterfaceId = aws.String("ENI")
+	otherInstanceRoute := actualInstanceRoute
+	otherInstanceRoute.InstanceId = aws.String("Different")
+	templateEniRoute := types.Route{
+		DestinationCidrBlock: aws.String("Cidr"),
+		NetworkInterfaceId:   aws.String("ENI"),
+	}
+	actualEniRoute := templateEniRoute
+	actualEniRoute.NetworkInterfaceId = nil
 	tests := []struct {
 		name string
 		args args
@@ -286,6 +303,10 @@ func TestCompareRoutes(t *testing.T) {
 		{"Different value VpcPeeringConnectionId", args{route1: fullRoute, route2: peer}, false},
 		{"Different value State", args{route1: fullRoute, route2: state}, false},
 		{"Different value Origin", args{route1: fullRoute, route2: origin}, false},
+		{"Instance route with owner and network interface added by AWS", args{route1: actualInstanceRoute, route2: templateInstanceRoute}, true},
+		{"Instance route with owner and network interface added by AWS reversed", args{route1: templateInstanceRoute, route2: actualInstanceRoute}, true},
+		{"Instance route to a different instance", args{route1: otherInstanceRoute, route2: templateInstanceRoute}, false},
+		{"Missing network interface for a route without instance", args{route1: actualEniRoute, route2: templateEniRoute}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -403,3 +424,404 @@ func TestGetRouteTarget(t *testing.T) {
 		})
 	}
 }
+
+type mockEC2DescribeVpcConfigAPI struct {
+	vpc        types.Vpc
+	attributes map[types.VpcAttributeName]bool
+}
+
+func (m mockEC2DescribeVpcConfigAPI) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	if params.VpcIds[0] != aws.ToString(m.vpc.VpcId) {
+		return &ec2.DescribeVpcsOutput{}, nil
+	}
+	return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{m.vpc}}, nil
+}
+
+func (m mockEC2DescribeVpcConfigAPI) DescribeVpcAttribute(ctx context.Context, params *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error) {
+	value := &types.AttributeBooleanValue{Value: aws.Bool(m.attributes[params.Attribute])}
+	switch params.Attribute {
+	case types.VpcAttributeNameEnableDnsSupport:
+		return &ec2.DescribeVpcAttributeOutput{EnableDnsSupport: value}, nil
+	case types.VpcAttributeNameEnableDnsHostnames:
+		return &ec2.DescribeVpcAttributeOutput{EnableDnsHostnames: value}, nil
+	}
+	return nil, errors.New("unexpected attribute")
+}
+
+func TestGetVpcConfig(t *testing.T) {
+	svc := mockEC2DescribeVpcConfigAPI{
+		vpc: types.Vpc{
+			VpcId:           aws.String("vpc-123"),
+			CidrBlock:       aws.String("10.0.0.0/16"),
+			InstanceTenancy: types.TenancyDefault,
+			Ipv6CidrBlockAssociationSet: []types.VpcIpv6CidrBlockAssociation{
+				{Ipv6CidrBlock: aws.String("2001:db8::/56"), Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated}},
+				{Ipv6CidrBlock: aws.String("2001:db9::/56"), Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeDisassociated}},
+			},
+		},
+		attributes: map[types.VpcAttributeName]bool{types.VpcAttributeNameEnableDnsSupport: true},
+	}
+	got, err := GetVpcConfig("vpc-123", svc)
+	if err != nil {
+		t.Fatalf("GetVpcConfig() error = %v", err)
+	}
+	want := VpcConfig{
+		CidrBlock:          "10.0.0.0/16",
+		EnableDNSSupport:   true,
+		EnableDNSHostnames: false,
+		InstanceTenancy:    "default",
+		IPv6CIDRBlocks:     []string{"2001:db8::/56"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetVpcConfig() = %+v, want %+v", got, want)
+	}
+	if _, err := GetVpcConfig("vpc-unknown", svc); err == nil {
+		t.Errorf("GetVpcConfig() expected an error for an unknown VPC")
+	}
+}
+
+func TestCompareVpcConfigs(t *testing.T) {
+	base := VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{}}
+	tests := []struct {
+		name     string
+		expected VpcConfig
+		actual   VpcConfig
+		want     []string
+	}{
+		{"No differences", base, base, []string{}},
+		{"DNS and tenancy changed", base, VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: false, EnableDNSHostnames: true, InstanceTenancy: "dedicated", IPv6CIDRBlocks: []string{}}, []string{
+			"EnableDnsSupport: true => false",
+			"EnableDnsHostnames: false => true",
+			"InstanceTenancy: default => dedicated",
+		}},
+		{"Unmanaged IPv6 block", base, VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{"2001:db8::/56"}}, []string{
+			"Unmanaged IPv6 CIDR block: 2001:db8::/56",
+		}},
+		{"Amazon provided IPv6 block present", VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{}, AmazonProvidedIPv6: true}, VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{"2600:1f18::/56"}}, []string{}},
+		{"Amazon provided IPv6 block removed", VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{}, AmazonProvidedIPv6: true}, base, []string{
+			"Removed Amazon provided IPv6 CIDR block",
+		}},
+		{"Explicit IPv6 block removed", VpcConfig{CidrBlock: "10.0.0.0/16", EnableDNSSupport: true, InstanceTenancy: "default", IPv6CIDRBlocks: []string{"2001:db8::/56"}}, base, []string{
+			"Removed IPv6 CIDR block: 2001:db8::/56",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareVpcConfigs(tt.expected, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareVpcConfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockEC2SearchTransitGatewayRoutesAPI func(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error)
+
+func (m mockEC2SearchTransitGatewayRoutesAPI) SearchTransitGatewayRoutes(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetTransitGatewayRouteDrift(t *testing.T) {
+	tgwRoute := func(destination string, attachment string, state types.TransitGatewayRouteState) types.TransitGatewayRoute {
+		route := types.TransitGatewayRoute{
+			DestinationCidrBlock: aws.String(destination),
+			State:                state,
+			Type:                 types.TransitGatewayRouteTypeStatic,
+		}
+		if attachment != "" {
+			route.TransitGatewayAttachments = []types.TransitGatewayRouteAttachment{{TransitGatewayAttachmentId: aws.String(attachment)}}
+		}
+		return route
+	}
+	templateRoutes := map[string]types.TransitGatewayRoute{
+		"10.0.0.0/16": tgwRoute("10.0.0.0/16", "tgw-attach-1", types.TransitGatewayRouteStateActive),
+		"10.1.0.0/16": tgwRoute("10.1.0.0/16", "tgw-attach-2", types.TransitGatewayRouteStateActive),
+		"10.2.0.0/16": tgwRoute("10.2.0.0/16", "", types.TransitGatewayRouteStateBlackhole),
+		"10.3.0.0/16": tgwRoute("10.3.0.0/16", "tgw-attach-3", types.TransitGatewayRouteStateActive),
+	}
+	var input *ec2.SearchTransitGatewayRoutesInput
+	svc := mockEC2SearchTransitGatewayRoutesAPI(func(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+		input = params
+		return &ec2.SearchTransitGatewayRoutesOutput{Routes: []types.TransitGatewayRoute{
+			tgwRoute("10.0.0.0/16", "tgw-attach-1", types.TransitGatewayRouteStateActive),
+			tgwRoute("10.1.0.0/16", "tgw-attach-9", types.TransitGatewayRouteStateActive),
+			tgwRoute("10.2.0.0/16", "", types.TransitGatewayRouteStateBlackhole),
+			tgwRoute("192.168.0.0/16", "tgw-attach-4", types.TransitGatewayRouteStateActive),
+		}}, nil
+	})
+	got, err := GetTransitGatewayRouteDrift("tgw-rtb-123", templateRoutes, svc)
+	if err != nil {
+		t.Fatalf("GetTransitGatewayRouteDrift() error = %v", err)
+	}
+	if aws.ToString(input.TransitGatewayRouteTableId) != "tgw-rtb-123" {
+		t.Errorf("GetTransitGatewayRouteDrift() searched route table %v", aws.ToString(input.TransitGatewayRouteTableId))
+	}
+	if aws.ToInt32(input.MaxResults) != 1000 {
+		t.Errorf("GetTransitGatewayRouteDrift() asked for %v routes, want 1000", aws.ToInt32(input.MaxResults))
+	}
+	want := []struct {
+		destination string
+		diffType    TGWRouteDiffType
+	}{
+		{"10.1.0.0/16", TGWRouteModified},
+		{"10.3.0.0/16", TGWRouteRemoved},
+		{"192.168.0.0/16", TGWRouteUnmanaged},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetTransitGatewayRouteDrift() returned %d differences, want %d: %+v", len(got), len(want), got)
+	}
+	for i, diff := range got {
+		if diff.Destination != want[i].destination || diff.Type != want[i].diffType {
+			t.Errorf("GetTransitGatewayRouteDrift()[%d] = %v %v, want %v %v", i, diff.Destination, diff.Type, want[i].destination, want[i].diffType)
+		}
+	}
+	if got[1].Actual != nil || got[2].Expected != nil {
+		t.Errorf("GetTransitGatewayRouteDrift() set the wrong side for removed or unmanaged routes")
+	}
+
+	failing := mockEC2SearchTransitGatewayRoutesAPI(func(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+		return nil, errors.New("access denied")
+	})
+	if _, err := GetTransitGatewayRouteDrift("tgw-rtb-123", templateRoutes, failing); err == nil {
+		t.Errorf("GetTransitGatewayRouteDrift() expected an error")
+	}
+
+	truncated := mockEC2SearchTransitGatewayRoutesAPI(func(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+		return &ec2.SearchTransitGatewayRoutesOutput{
+			Routes:                    []types.TransitGatewayRoute{tgwRoute("10.0.0.0/16", "tgw-attach-1", types.TransitGatewayRouteStateActive)},
+			AdditionalRoutesAvailable: aws.Bool(true),
+		}, nil
+	})
+	if _, err := GetTransitGatewayRouteDrift("tgw-rtb-123", templateRoutes, truncated); err == nil {
+		t.Errorf("GetTransitGatewayRouteDrift() expected an error when more routes are available")
+	}
+}
+
+type mockEC2DescribeSubnetsAPI func(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+
+func (m mockEC2DescribeSubnetsAPI) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetSubnetConfig(t *testing.T) {
+	svc := mockEC2DescribeSubnetsAPI(func(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+		if len(params.SubnetIds) != 1 || params.SubnetIds[0] != "subnet-123" {
+			return &ec2.DescribeSubnetsOutput{}, nil
+		}
+		return &ec2.DescribeSubnetsOutput{Subnets: []types.Subnet{{
+			SubnetId:            aws.String("subnet-123"),
+			CidrBlock:           aws.String("10.0.0.0/24"),
+			AvailabilityZone:    aws.String("ap-southeast-2a"),
+			MapPublicIpOnLaunch: aws.Bool(true),
+			Ipv6CidrBlockAssociationSet: []types.SubnetIpv6CidrBlockAssociation{
+				{Ipv6CidrBlock: aws.String("2001:db8::/64"), Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated}},
+			},
+			Tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String("public")}},
+		}}}, nil
+	})
+	got, err := GetSubnetConfig("subnet-123", svc)
+	if err != nil {
+		t.Fatalf("GetSubnetConfig() error = %v", err)
+	}
+	want := SubnetConfig{
+		CidrBlock:           "10.0.0.0/24",
+		AvailabilityZone:    "ap-southeast-2a",
+		MapPublicIPOnLaunch: true,
+		IPv6CIDRBlock:       "2001:db8::/64",
+		Tags:                map[string]string{"Name": "public"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSubnetConfig() = %+v, want %+v", got, want)
+	}
+	if _, err := GetSubnetConfig("subnet-missing", svc); err == nil {
+		t.Errorf("GetSubnetConfig() expected an error for a missing subnet")
+	}
+}
+
+func TestCompareSubnetConfigs(t *testing.T) {
+	expected := SubnetConfig{
+		CidrBlock:        "10.0.0.0/24",
+		AvailabilityZone: "us-east-1a",
+		Tags:             map[string]string{"Name": "private", "Owner": "team-a", "Stack": "REF: AWS::StackName"},
+	}
+	actual := SubnetConfig{
+		CidrBlock:           "10.0.0.0/24",
+		AvailabilityZone:    "ap-southeast-2a",
+		MapPublicIPOnLaunch: true,
+		Tags:                map[string]string{"Name": "public", "Stack": "my-stack", "Extra": "value", "CostCenter": "1234", "aws:cloudformation:stack-name": "my-stack"},
+	}
+	got := CompareSubnetConfigs(expected, actual, map[string]string{"CostCenter": "1234", "Name": "stack-name"})
+	want := []string{
+		"MapPublicIpOnLaunch: false => true",
+		"Removed tag: Owner",
+		"Tag Name: private => public",
+		"Unmanaged tag: Extra",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareSubnetConfigs() = %v, want %v", got, want)
+	}
+	if got := CompareSubnetConfigs(actual, actual, nil); len(got) != 0 {
+		t.Errorf("CompareSubnetConfigs() for identical configs = %v", got)
+	}
+}
+
+type mockEC2DescribeVpcEndpointsAPI func(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error)
+
+func (m mockEC2DescribeVpcEndpointsAPI) DescribeVpcEndpoints(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetVPCEndpointDrift(t *testing.T) {
+	template := `Resources:
+  Endpoint:
+    Type: AWS::EC2::VPCEndpoint
+    Properties:
+      ServiceName: !Sub com.amazonaws.${AWS::Region}.s3
+      VpcEndpointType: Interface
+      SubnetIds:
+        - !Ref SubnetA
+        - !Ref SubnetB
+      SecurityGroupIds:
+        - !Ref SecurityGroup
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal: "*"
+            Action: s3:GetObject
+            Resource: "*"
+`
+	logicalToPhysical := map[string]string{
+		"Endpoint":      "vpce-123",
+		"SubnetA":       "subnet-a",
+		"SubnetB":       "subnet-b",
+		"SecurityGroup": "sg-123",
+	}
+	params := map[string]interface{}{}
+	body := ParseTemplateString(template, &params, nil)
+	svc := mockEC2DescribeVpcEndpointsAPI(func(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+		if len(params.VpcEndpointIds) != 1 || params.VpcEndpointIds[0] != "vpce-123" {
+			return &ec2.DescribeVpcEndpointsOutput{}, nil
+		}
+		return &ec2.DescribeVpcEndpointsOutput{VpcEndpoints: []types.VpcEndpoint{{
+			VpcEndpointId:  aws.String("vpce-123"),
+			ServiceName:    aws.String("com.amazonaws.us-east-1.s3"),
+			SubnetIds:      []string{"subnet-c", "subnet-a"},
+			Groups:         []types.SecurityGroupIdentifier{{GroupId: aws.String("sg-123")}},
+			PolicyDocument: aws.String(`{"Statement":[{"Action":"s3:*","Effect":"Allow","Principal":"*","Resource":"*"}],"Version":"2012-10-17"}`),
+		}}}, nil
+	})
+	got, err := GetVPCEndpointDrift("vpce-123", body, "Endpoint", params, logicalToPhysical, svc)
+	if err != nil {
+		t.Fatalf("GetVPCEndpointDrift() error = %v", err)
+	}
+	properties := make([]string, 0, len(got))
+	for _, diff := range got {
+		properties = append(properties, diff.Property)
+	}
+	if !reflect.DeepEqual(properties, []string{"PolicyDocument", "SubnetIds"}) {
+		t.Fatalf("GetVPCEndpointDrift() returned differences for %v, want PolicyDocument and SubnetIds", properties)
+	}
+	if got[1].String() != "SubnetIds: subnet-a, subnet-b => subnet-a, subnet-c" {
+		t.Errorf("GetVPCEndpointDrift() subnet difference = %q", got[1].String())
+	}
+	if _, err := GetVPCEndpointDrift("vpce-missing", body, "Endpoint", params, logicalToPhysical, svc); err == nil {
+		t.Errorf("GetVPCEndpointDrift() expected an error for a missing endpoint")
+	}
+}
+
+func TestCompareVPCEndpointConfigs(t *testing.T) {
+	actual := VPCEndpointConfig{
+		ServiceName:      "com.amazonaws.us-east-1.s3",
+		SubnetIds:        []string{"subnet-a"},
+		SecurityGroupIds: []string{"sg-123"},
+	}
+	tests := []struct {
+		name     string
+		expected VPCEndpointConfig
+		want     []string
+	}{
+		{"Region in service name is ignored", VPCEndpointConfig{ServiceName: "com.amazonaws.ap-southeast-2.s3"}, []string{}},
+		{"Different service", VPCEndpointConfig{ServiceName: "com.amazonaws.ap-southeast-2.dynamodb"}, []string{"ServiceName"}},
+		{"Unset values aren't compared", VPCEndpointConfig{}, []string{}},
+		{"Unresolved policy references aren't compared", VPCEndpointConfig{PolicyDocument: `{"Resource": "REF: Bucket"}`}, []string{}},
+		{"Different security groups", VPCEndpointConfig{SecurityGroupIds: []string{"sg-123", "sg-456"}}, []string{"SecurityGroupIds"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, diff := range CompareVPCEndpointConfigs(tt.expected, actual) {
+				got = append(got, diff.Property)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareVPCEndpointConfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockEC2DescribeSecurityGroupsAPI func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+
+func (m mockEC2DescribeSecurityGroupsAPI) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetSecurityGroupRules(t *testing.T) {
+	svc := mockEC2DescribeSecurityGroupsAPI(func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+		if len(params.GroupIds) != 1 || params.GroupIds[0] != "sg-123" {
+			return &ec2.DescribeSecurityGroupsOutput{}, nil
+		}
+		return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{
+			GroupId: aws.String("sg-123"),
+			IpPermissions: []types.IpPermission{{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int32(443),
+				ToPort:     aws.Int32(443),
+				IpRanges: []types.IpRange{
+					{CidrIp: aws.String("10.0.0.0/8"), Description: aws.String("HTTPS")},
+					{CidrIp: aws.String("192.168.0.0/16")},
+				},
+				UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String("sg-456")}},
+			}},
+			IpPermissionsEgress: []types.IpPermission{{
+				IpProtocol: aws.String("-1"),
+				IpRanges:   []types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			}},
+		}}}, nil
+	})
+	got, err := GetSecurityGroupRules("sg-123", svc)
+	if err != nil {
+		t.Fatalf("GetSecurityGroupRules() error = %v", err)
+	}
+	want := map[string]SecurityGroupRule{
+		"ingress tcp 443-443 10.0.0.0/8":     {Protocol: "tcp", FromPort: 443, ToPort: 443, Target: "10.0.0.0/8", Description: "HTTPS"},
+		"ingress tcp 443-443 192.168.0.0/16": {Protocol: "tcp", FromPort: 443, ToPort: 443, Target: "192.168.0.0/16"},
+		"ingress tcp 443-443 sg-456":         {Protocol: "tcp", FromPort: 443, ToPort: 443, Target: "sg-456"},
+		"egress -1 -1--1 0.0.0.0/0":          {Egress: true, Protocol: "-1", FromPort: -1, ToPort: -1, Target: "0.0.0.0/0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSecurityGroupRules() = %v, want %v", got, want)
+	}
+	if _, err := GetSecurityGroupRules("sg-missing", svc); err == nil {
+		t.Errorf("GetSecurityGroupRules() expected an error for a missing security group")
+	}
+}
+
+func TestSecurityGroupRule_String(t *testing.T) {
+	tests := []struct {
+		name string
+		rule SecurityGroupRule
+		want string
+	}{
+		{"Single port", SecurityGroupRule{Protocol: "tcp", FromPort: 443, ToPort: 443, Target: "10.0.0.0/8", Description: "HTTPS"}, "ingress: tcp, 10.0.0.0/8 Port: 443 (HTTPS)"},
+		{"Port range", SecurityGroupRule{Protocol: "udp", FromPort: 1000, ToPort: 2000, Target: "sg-123"}, "ingress: udp, sg-123 Ports: 1000-2000"},
+		{"All traffic", SecurityGroupRule{Egress: true, Protocol: "-1", FromPort: -1, ToPort: -1, Target: "0.0.0.0/0"}, "egress: all, 0.0.0.0/0 Ports: All"},
+		{"All ICMP", SecurityGroupRule{Protocol: "icmp", FromPort: -1, ToPort: -1, Target: "10.0.0.0/8"}, "ingress: icmp, 10.0.0.0/8 ICMP: All"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.String(); got != tt.want {
+				t.Errorf("SecurityGroupRule.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// EventLogEntry is a single stack event as written to the event log file
+type EventLogEntry struct {
+	StackName            string    `json:"StackName"`
+	DeploymentID         string    `json:"DeploymentID"`
+	WallClockTime        time.Time `json:"WallClockTime"`
+	EventID              string    `json:"EventId"`
+	Timestamp            time.Time `json:"Timestamp"`
+	LogicalResourceID    string    `json:"LogicalResourceId"`
+	PhysicalResourceID   string    `json:"PhysicalResourceId,omitempty"`
+	ResourceType         string    `json:"ResourceType"`
+	ResourceStatus       string    `json:"ResourceStatus"`
+	ResourceStatusReason string    `json:"ResourceStatusReason,omitempty"`
+}
+
+// EventLogWriter writes stack events as JSON lines to a file
+type EventLogWriter struct {
+	file *os.File
+}
+
+// NewEventLogWriter opens the file for appending, creating it if it doesn't exist
+func NewEventLogWriter(path string) (*EventLogWriter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLogWriter{file: file}, nil
+}
+
+// WriteEvent writes the event as a single JSON line. The file is synced after
+// every event so the log can be followed while the deployment runs.
+func (writer *EventLogWriter) WriteEvent(stackName string, deploymentID string, event types.StackEvent) error {
+	entry := EventLogEntry{
+		StackName:            stackName,
+		DeploymentID:         deploymentID,
+		WallClockTime:        time.Now().UTC(),
+		EventID:              aws.ToString(event.EventId),
+		LogicalResourceID:    aws.ToString(event.LogicalResourceId),
+		PhysicalResourceID:   aws.ToString(event.PhysicalResourceId),
+		ResourceType:         aws.ToString(event.ResourceType),
+		ResourceStatus:       string(event.ResourceStatus),
+		ResourceStatusReason: aws.ToString(event.ResourceStatusReason),
+	}
+	if event.Timestamp != nil {
+		entry.Timestamp = *event.Timestamp
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return writer.file.Sync()
+}
+
+// Close closes the underlying file
+func (writer *EventLogWriter) Close() error {
+	return writer.file.Close()
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestEventLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []types.StackEvent{
+		{EventId: aws.String("1"), Timestamp: aws.Time(timestamp), LogicalResourceId: aws.String("Bucket"), ResourceType: aws.String("AWS::S3::Bucket"), ResourceStatus: types.ResourceStatusCreateInProgress},
+		{EventId: aws.String("2"), Timestamp: aws.Time(timestamp.Add(time.Minute)), LogicalResourceId: aws.String("Bucket"), PhysicalResourceId: aws.String("my-bucket"), ResourceType: aws.String("AWS::S3::Bucket"), ResourceStatus: types.ResourceStatusCreateFailed, ResourceStatusReason: aws.String("Bucket already exists")},
+	}
+	writer, err := NewEventLogWriter(path)
+	if err != nil {
+		t.Fatalf("NewEventLogWriter() error = %v", err)
+	}
+	for i, event := range events {
+		if err := writer.WriteEvent("my-stack", "fog-changeset", event); err != nil {
+			t.Fatalf("WriteEvent() error = %v", err)
+		}
+		// Every event should be readable before the file is closed
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := len(splitLines(string(contents))); lines != i+1 {
+			t.Errorf("event log has %v lines after %v writes", lines, i+1)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	entries := make([]EventLogEntry, 0)
+	for scanner.Scan() {
+		var entry EventLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("event log has %v entries, want 2", len(entries))
+	}
+	last := entries[1]
+	if last.StackName != "my-stack" || last.DeploymentID != "fog-changeset" || last.ResourceStatus != "CREATE_FAILED" ||
+		last.ResourceStatusReason != "Bucket already exists" || last.PhysicalResourceID != "my-bucket" || !last.Timestamp.Equal(timestamp.Add(time.Minute)) {
+		t.Errorf("unexpected entry %+v", last)
+	}
+	if last.WallClockTime.IsZero() {
+		t.Errorf("WallClockTime should be set")
+	}
+}
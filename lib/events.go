@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// EventFetchOptions limits the events returned by GetAllEvents. Zero values
+// mean no limit is applied for that option.
+type EventFetchOptions struct {
+	// MaxEvents is the maximum number of events to return
+	MaxEvents int
+	// Since excludes events that happened before this time
+	Since time.Time
+	// Until excludes events that happened after this time
+	Until time.Time
+	// EventTypes limits the events to those with one of these resource statuses, e.g. CREATE_FAILED
+	EventTypes []string
+}
+
+// GetAllEvents retrieves the events of a stack, going through all pages of
+// results until the options are satisfied. Events are returned in the order
+// CloudFormation provides them, newest first. Cancelling the context stops
+// the retrieval of further pages.
+func GetAllEvents(ctx context.Context, stackNameOrARN string, opts EventFetchOptions, svc CloudFormationEventsFetcher) ([]types.StackEvent, error) {
+	input := &cloudformation.DescribeStackEventsInput{
+		StackName: &stackNameOrARN,
+	}
+	paginator := cloudformation.NewDescribeStackEventsPaginator(svc, input)
+	result := make([]types.StackEvent, 0)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range output.StackEvents {
+			if event.Timestamp != nil {
+				// Events are sorted newest first, so nothing older will match either
+				if !opts.Since.IsZero() && event.Timestamp.Before(opts.Since) {
+					return result, nil
+				}
+				if !opts.Until.IsZero() && event.Timestamp.After(opts.Until) {
+					continue
+				}
+			}
+			if len(opts.EventTypes) != 0 && !stringInSlice(string(event.ResourceStatus), opts.EventTypes) {
+				continue
+			}
+			result = append(result, event)
+			if opts.MaxEvents > 0 && len(result) >= opts.MaxEvents {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// stackEventsPollInterval is how long WatchStackEvents waits between polls
+var stackEventsPollInterval = 5 * time.Second
+
+// WatchStackEvents polls the events of a stack and sends every event that
+// happens after the watch started on the returned event channel, oldest first.
+// The event channel is closed when the stack itself reaches a terminal status,
+// the context is cancelled, or the events can't be retrieved. In the last case
+// the error is sent on the error channel, which is closed after the event
+// channel.
+func WatchStackEvents(ctx context.Context, stackID string, svc CloudFormationEventsFetcher) (<-chan types.StackEvent, <-chan error) {
+	events := make(chan types.StackEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		seen := make(map[string]bool)
+		started := false
+		for {
+			output, err := svc.DescribeStackEvents(ctx, &cloudformation.DescribeStackEventsInput{StackName: &stackID})
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+			// Events are returned newest first
+			newEvents := make([]types.StackEvent, 0)
+			for _, event := range output.StackEvents {
+				if seen[aws.ToString(event.EventId)] {
+					break
+				}
+				seen[aws.ToString(event.EventId)] = true
+				newEvents = append(newEvents, event)
+			}
+			if !started {
+				started = true
+				if len(newEvents) > 0 && isTerminalStackEvent(newEvents[0]) {
+					return
+				}
+				newEvents = nil
+			}
+			for i := len(newEvents) - 1; i >= 0; i-- {
+				select {
+				case events <- newEvents[i]:
+				case <-ctx.Done():
+					return
+				}
+				if isTerminalStackEvent(newEvents[i]) {
+					return
+				}
+			}
+			select {
+			case <-time.After(stackEventsPollInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, errs
+}
+
+// isTerminalStackEvent checks if the event shows the stack itself finishing an operation
+func isTerminalStackEvent(event types.StackEvent) bool {
+	if aws.ToString(event.ResourceType) != "AWS::CloudFormation::Stack" || aws.ToString(event.PhysicalResourceId) != aws.ToString(event.StackId) {
+		return false
+	}
+	return !strings.HasSuffix(string(event.ResourceStatus), "IN_PROGRESS")
+}
@@ -0,0 +1,176 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+type mockCloudFormationEventsFetcher func(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error)
+
+func (m mockCloudFormationEventsFetcher) DescribeStackEvents(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+// pagedEventsFetcher returns a mock that serves the provided pages of events in order
+func pagedEventsFetcher(pages [][]types.StackEvent) mockCloudFormationEventsFetcher {
+	return func(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+		page := 0
+		if params.NextToken != nil {
+			page, _ = strconv.Atoi(*params.NextToken)
+		}
+		output := &cloudformation.DescribeStackEventsOutput{StackEvents: pages[page]}
+		if page+1 < len(pages) {
+			output.NextToken = aws.String(strconv.Itoa(page + 1))
+		}
+		return output, nil
+	}
+}
+
+func TestGetAllEvents(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id string, minutes int, status types.ResourceStatus) types.StackEvent {
+		return types.StackEvent{
+			EventId:        aws.String(id),
+			Timestamp:      aws.Time(base.Add(time.Duration(minutes) * time.Minute)),
+			ResourceStatus: status,
+		}
+	}
+	// Newest first, as returned by CloudFormation
+	pages := [][]types.StackEvent{
+		{event("e5", 5, types.ResourceStatusUpdateComplete), event("e4", 4, types.ResourceStatusUpdateFailed)},
+		{event("e3", 3, types.ResourceStatusUpdateInProgress), event("e2", 2, types.ResourceStatusCreateComplete)},
+		{event("e1", 1, types.ResourceStatusCreateInProgress)},
+	}
+	ids := func(events []types.StackEvent) []string {
+		result := make([]string, 0, len(events))
+		for _, event := range events {
+			result = append(result, *event.EventId)
+		}
+		return result
+	}
+	tests := []struct {
+		name    string
+		opts    EventFetchOptions
+		svc     CloudFormationEventsFetcher
+		want    []string
+		wantErr bool
+	}{
+		{"All events across pages", EventFetchOptions{}, pagedEventsFetcher(pages), []string{"e5", "e4", "e3", "e2", "e1"}, false},
+		{"Max events", EventFetchOptions{MaxEvents: 3}, pagedEventsFetcher(pages), []string{"e5", "e4", "e3"}, false},
+		{"Since", EventFetchOptions{Since: base.Add(2 * time.Minute)}, pagedEventsFetcher(pages), []string{"e5", "e4", "e3", "e2"}, false},
+		{"Until", EventFetchOptions{Until: base.Add(3 * time.Minute)}, pagedEventsFetcher(pages), []string{"e3", "e2", "e1"}, false},
+		{"Event types", EventFetchOptions{EventTypes: []string{"UPDATE_FAILED", "CREATE_COMPLETE"}}, pagedEventsFetcher(pages), []string{"e4", "e2"}, false},
+		{"Combined options", EventFetchOptions{Since: base.Add(2 * time.Minute), Until: base.Add(4 * time.Minute), MaxEvents: 2}, pagedEventsFetcher(pages), []string{"e4", "e3"}, false},
+		{"API error", EventFetchOptions{}, mockCloudFormationEventsFetcher(func(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+			return nil, errors.New("stack does not exist")
+		}), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAllEvents(context.TODO(), "test-stack", tt.opts, tt.svc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAllEvents() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("GetAllEvents() = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAllEvents_CancelledContext(t *testing.T) {
+	svc := mockCloudFormationEventsFetcher(func(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+		// The real client returns the context error when the context is done
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &cloudformation.DescribeStackEventsOutput{}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetAllEvents(ctx, "test-stack", EventFetchOptions{}, svc); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAllEvents() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWatchStackEvents(t *testing.T) {
+	originalInterval := stackEventsPollInterval
+	stackEventsPollInterval = time.Millisecond
+	defer func() { stackEventsPollInterval = originalInterval }()
+
+	stackID := "arn:aws:cloudformation:us-east-1:123456789012:stack/my-stack/abc"
+	resource := func(id string, status types.ResourceStatus) types.StackEvent {
+		return types.StackEvent{EventId: aws.String(id), StackId: aws.String(stackID), ResourceType: aws.String("AWS::S3::Bucket"), PhysicalResourceId: aws.String("bucket"), ResourceStatus: status}
+	}
+	stack := func(id string, status types.ResourceStatus) types.StackEvent {
+		return types.StackEvent{EventId: aws.String(id), StackId: aws.String(stackID), ResourceType: aws.String("AWS::CloudFormation::Stack"), PhysicalResourceId: aws.String(stackID), ResourceStatus: status}
+	}
+	tests := map[string]struct {
+		responses [][]types.StackEvent
+		err       error
+		want      []string
+	}{
+		"stream events until the stack finishes": {
+			responses: [][]types.StackEvent{
+				{stack("e1", types.ResourceStatusUpdateInProgress)},
+				{resource("e3", types.ResourceStatusUpdateComplete), resource("e2", types.ResourceStatusUpdateInProgress), stack("e1", types.ResourceStatusUpdateInProgress)},
+				{stack("e4", types.ResourceStatus(types.StackStatusUpdateComplete)), resource("e3", types.ResourceStatusUpdateComplete)},
+			},
+			want: []string{"e2", "e3", "e4"},
+		},
+		"stack already finished": {
+			responses: [][]types.StackEvent{
+				{stack("e1", types.ResourceStatus(types.StackStatusCreateComplete))},
+			},
+			want: []string{},
+		},
+		"events can't be retrieved": {
+			responses: [][]types.StackEvent{
+				{stack("e1", types.ResourceStatusUpdateInProgress)},
+				{resource("e2", types.ResourceStatusUpdateInProgress), stack("e1", types.ResourceStatusUpdateInProgress)},
+			},
+			err:  errors.New("throttled"),
+			want: []string{"e2"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			svc := mockCloudFormationEventsFetcher(func(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+				if calls >= len(tc.responses) {
+					if tc.err != nil {
+						return nil, tc.err
+					}
+					return nil, errors.New("unexpected call")
+				}
+				calls++
+				return &cloudformation.DescribeStackEventsOutput{StackEvents: tc.responses[calls-1]}, nil
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			got := []string{}
+			events, errs := WatchStackEvents(ctx, stackID, svc)
+			for event := range events {
+				got = append(got, *event.EventId)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("WatchStackEvents() = %v, want %v", got, tc.want)
+			}
+			if err := <-errs; !errors.Is(err, tc.err) {
+				t.Errorf("WatchStackEvents() error = %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
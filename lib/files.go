@@ -6,13 +6,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 )
@@ -21,34 +28,91 @@ import (
 // we'll read it right away, or if not we'll try to locate it in the appropriate
 // directory with one of the configured extensions.
 func ReadFile(fileName *string, fileType string) (string, string, error) {
-	filePath := *fileName
+	filePath, err := findFile(*fileName, fileType)
+	if err != nil {
+		return "", "", err
+	}
+	dat, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	return string(dat), filePath, nil
+}
+
+// findFile returns the path of the file. If fileName is not an actual file, it
+// looks for it in the configured directory for the fileType using the configured extensions.
+func findFile(fileName string, fileType string) (string, error) {
+	filePath := fileName
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		// fileName is not an actual file. Try to find it in the right subdirectory.
 		fileFound := false
 		fileDirectory := viper.GetString(fileType + ".directory")
 		for _, extension := range viper.GetStringSlice(fileType + ".extensions") {
-			filePath = fileDirectory + "/" + *fileName + extension
+			filePath = fileDirectory + "/" + fileName + extension
 			if _, err := os.Stat(filePath); !os.IsNotExist(err) {
 				fileFound = true
 				break
 			}
 		}
 		if !fileFound {
-			errMsg := fmt.Sprintf("no file found for '%s' matching '%s'", fileType, *fileName)
-			return "", "", errors.New(errMsg)
+			errMsg := fmt.Sprintf("no file found for '%s' matching '%s'", fileType, fileName)
+			return "", errors.New(errMsg)
 		}
 	}
-	dat, err := os.ReadFile(filePath)
+	return filePath, nil
+}
+
+// SopsDecryptor decrypts files that are encrypted with SOPS
+type SopsDecryptor struct {
+	// Binary is the path of the sops binary
+	Binary string
+}
+
+// Decrypt runs sops to decrypt the file and returns the decrypted contents.
+// The decrypted contents are only kept in memory.
+func (decryptor SopsDecryptor) Decrypt(filePath string) (string, error) {
+	cmd := exec.Command(decryptor.Binary, "--decrypt", filePath)
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("sops failed to decrypt %s: %w\n%s", filePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return out.String(), nil
+}
+
+// ReadEncryptedFile works the same as ReadFile, but decrypts the file using SOPS
+func ReadEncryptedFile(fileName *string, fileType string, decryptor SopsDecryptor) (string, string, error) {
+	filePath, err := findFile(*fileName, fileType)
 	if err != nil {
 		return "", "", err
 	}
-	return string(dat), filePath, nil
+	contents, err := decryptor.Decrypt(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	return contents, filePath, nil
 }
 
 func ReadTemplate(templateName *string) (string, string, error) {
 	return ReadFile(templateName, "templates")
 }
 
+// ReadDeploymentTemplate reads the template of a deployment file. The template
+// path is relative to the deployment file. If the template can't be found there,
+// the path is resolved like --template: from the current directory or the
+// templates directory.
+func ReadDeploymentTemplate(templatePath string, deploymentFilePath string) (string, string, error) {
+	if deploymentFilePath != "" && !filepath.IsAbs(templatePath) {
+		relativePath := filepath.Join(filepath.Dir(deploymentFilePath), filepath.FromSlash(templatePath))
+		if dat, err := os.ReadFile(relativePath); err == nil {
+			return string(dat), relativePath, nil
+		}
+	}
+	return ReadFile(&templatePath, "templates")
+}
+
 func ReadTagsfile(tagsName string) (string, string, error) {
 	return ReadFile(&tagsName, "tags")
 }
@@ -61,6 +125,226 @@ func ReadDeploymentFile(deploymentmentFileName string) (string, string, error) {
 	return ReadFile(&deploymentmentFileName, "deployments")
 }
 
+// httpClient is used to download deployment files, it can be replaced in tests
+var httpClient = http.DefaultClient
+
+// ParseDeploymentFileFromURL loads and parses a deployment file from an S3 URL
+// (s3://bucket/key or https://bucket.s3.amazonaws.com/key), any other HTTPS URL,
+// or a local file path. Plain HTTP URLs aren't accepted. The environment is used to pick the overrides from
+// deployment files that contain multiple environments.
+func ParseDeploymentFileFromURL(location string, environment string, s3Svc S3GetObjectAPI) (StackDeploymentFile, error) {
+	var contents string
+	if _, _, ok := parseS3URL(location); ok {
+		resp, err := getS3Object(location, s3Svc)
+		if err != nil {
+			return StackDeploymentFile{}, fmt.Errorf("unable to download deployment file %s: %w", location, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return StackDeploymentFile{}, err
+		}
+		contents = string(body)
+	} else if strings.HasPrefix(location, "http://") {
+		return StackDeploymentFile{}, fmt.Errorf("unable to download deployment file %s: only https:// URLs are supported", location)
+	} else if strings.HasPrefix(location, "https://") {
+		resp, err := httpClient.Get(location)
+		if err != nil {
+			return StackDeploymentFile{}, fmt.Errorf("unable to download deployment file %s: %w", location, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return StackDeploymentFile{}, fmt.Errorf("unable to download deployment file %s: %s", location, resp.Status)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return StackDeploymentFile{}, err
+		}
+		contents = string(body)
+	} else {
+		var err error
+		contents, _, err = ReadDeploymentFile(location)
+		if err != nil {
+			return StackDeploymentFile{}, err
+		}
+	}
+	if strings.TrimSpace(contents) == "" {
+		return StackDeploymentFile{}, fmt.Errorf("deployment file %s is empty", location)
+	}
+	return ParseDeploymentFileV2(contents, environment)
+}
+
+// MaxTemplateURLSize is the largest template in bytes that CloudFormation
+// accepts from S3
+const MaxTemplateURLSize = 1048576
+
+// ValidateTemplateURL checks that the URL is an HTTPS URL of an object in S3,
+// as that's the only kind of template URL CloudFormation accepts
+func ValidateTemplateURL(templateURL string) error {
+	if !strings.HasPrefix(templateURL, "https://") {
+		return fmt.Errorf("invalid template URL %v, it needs to be an https:// URL of an object in S3", templateURL)
+	}
+	if _, _, ok := parseS3URL(templateURL); !ok {
+		return fmt.Errorf("invalid template URL %v, it needs to be an https:// URL of an object in S3", templateURL)
+	}
+	return nil
+}
+
+// DownloadTemplate retrieves the template at the S3 URL, so it can be checked
+// and compared like a local template. Templates larger than what
+// CloudFormation accepts from S3 return an error.
+func DownloadTemplate(templateURL string, s3Svc S3GetObjectAPI) (string, error) {
+	if err := ValidateTemplateURL(templateURL); err != nil {
+		return "", err
+	}
+	resp, err := getS3Object(templateURL, s3Svc)
+	if err != nil {
+		return "", fmt.Errorf("unable to download template %s: %w", templateURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxTemplateURLSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > MaxTemplateURLSize {
+		return "", fmt.Errorf("template %s is larger than the %d bytes CloudFormation accepts", templateURL, MaxTemplateURLSize)
+	}
+	return string(body), nil
+}
+
+// getS3Object retrieves the object at the S3 URL. The version from a versionId
+// query parameter is retrieved instead of the latest one, and the request is
+// sent to the region in the URL's host, if it has one.
+func getS3Object(location string, s3Svc S3GetObjectAPI) (*s3.GetObjectOutput, error) {
+	bucket, key, _ := parseS3URL(location)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	region := ""
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme == "https" {
+		if versionID := parsed.Query().Get("versionId"); versionID != "" {
+			input.VersionId = aws.String(versionID)
+		}
+		region = s3URLRegion(parsed.Host)
+	}
+	return s3Svc.GetObject(context.TODO(), input, func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+}
+
+// s3URLRegion returns the region in the host of an S3 HTTPS URL, such as
+// bucket.s3.region.amazonaws.com or s3-region.amazonaws.com. Global hosts like
+// bucket.s3.amazonaws.com don't have a region, which returns an empty string.
+func s3URLRegion(host string) string {
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ".cn"), ".amazonaws.com")
+	labels := strings.Split(host, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if strings.HasPrefix(labels[i], "s3-") {
+			return strings.TrimPrefix(labels[i], "s3-")
+		}
+		if labels[i] == "s3" {
+			remaining := labels[i+1:]
+			if len(remaining) > 0 && remaining[0] == "dualstack" {
+				remaining = remaining[1:]
+			}
+			if len(remaining) > 0 {
+				return remaining[0]
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// parseS3URL extracts the bucket and key from an s3:// URL or an S3 HTTPS URL in
+// either virtual-hosted (bucket.s3.region.amazonaws.com/key) or path style
+// (s3.region.amazonaws.com/bucket/key)
+func parseS3URL(location string) (string, string, bool) {
+	if strings.HasPrefix(location, "s3://") {
+		bucket, key, found := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+		if !found || bucket == "" || key == "" {
+			return "", "", false
+		}
+		return bucket, key, true
+	}
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Scheme != "https" || !(strings.HasSuffix(parsed.Host, ".amazonaws.com") || strings.HasSuffix(parsed.Host, ".amazonaws.com.cn")) {
+		return "", "", false
+	}
+	path := strings.TrimPrefix(parsed.Path, "/")
+	if strings.HasPrefix(parsed.Host, "s3.") || strings.HasPrefix(parsed.Host, "s3-") {
+		bucket, key, found := strings.Cut(path, "/")
+		if !found || bucket == "" || key == "" {
+			return "", "", false
+		}
+		return bucket, key, true
+	}
+	bucket, _, found := strings.Cut(parsed.Host, ".s3")
+	if !found || bucket == "" || path == "" {
+		return "", "", false
+	}
+	return bucket, path, true
+}
+
+// deploymentFileComments holds the comments that are added above each field
+// when generating a deployment file
+var deploymentFileComments = []struct {
+	key     string
+	comment string
+}{
+	{"template-file-path", "The path to the template, relative to the location of this deployment file"},
+	{"template-url", "The S3 URL of the template, used instead of the template file path"},
+	{"parameters", "The parameters for the stack as key: value pairs"},
+	{"tags", "The tags for the stack as key: value pairs. Default tags from the fog configuration are added during deployment"},
+	{"notification-arns", "The SNS topics that receive the events of the stack"},
+	{"changeset-description", "The description of the change sets created for the stack"},
+	{"role-arn", "The service role CloudFormation uses to deploy the stack"},
+	{"rollback-configuration", "The CloudWatch alarms that trigger a rollback and how many minutes they are monitored after the deployment"},
+}
+
+// ToCommentedYAML returns the deployment file as YAML, with a comment
+// explaining each of the fields
+func (deploymentFile StackDeploymentFile) ToCommentedYAML() (string, error) {
+	values := map[string]interface{}{
+		"template-file-path":    deploymentFile.TemplateFilePath,
+		"template-url":          deploymentFile.TemplateURL,
+		"parameters":            deploymentFile.Parameters,
+		"tags":                  deploymentFile.Tags,
+		"notification-arns":     deploymentFile.NotificationARNs,
+		"changeset-description": deploymentFile.ChangesetDescription,
+		"role-arn":              deploymentFile.RoleARN,
+	}
+	if deploymentFile.RollbackConfiguration != nil {
+		values["rollback-configuration"] = deploymentFile.RollbackConfiguration
+	}
+	var builder strings.Builder
+	for _, field := range deploymentFileComments {
+		value, ok := values[field.key]
+		if !ok {
+			continue
+		}
+		if arns, ok := value.([]string); ok && len(arns) == 0 {
+			continue
+		}
+		if text, ok := value.(string); ok && text == "" && (field.key != "template-file-path" || deploymentFile.TemplateURL != "") {
+			continue
+		}
+		if fieldMap, ok := value.(map[string]string); ok && fieldMap == nil {
+			value = map[string]string{}
+		}
+		contents, err := yaml.Marshal(map[string]interface{}{field.key: value})
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString("# " + field.comment + "\n")
+		builder.Write(contents)
+	}
+	return builder.String(), nil
+}
+
 func UploadTemplate(templateName *string, template string, bucketName *string, svc *s3.Client) (string, error) {
 	// use the template name with a timestamp that should be unique
 	// prefix with fog to make it easier to set up specific lifecycle rules
@@ -119,6 +403,61 @@ func YamlToJson(input []byte) ([]byte, error) {
 	return result, nil
 }
 
+// tomlSectionHeader matches the header of a TOML table, e.g. [parameters]
+var tomlSectionHeader = regexp.MustCompile(`^\[\s*[A-Za-z0-9_.-]+\s*\]$`)
+
+// isTomlDocument checks if the first statement of the contents is a TOML table header
+func isTomlDocument(contents string) bool {
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return tomlSectionHeader.MatchString(line)
+	}
+	return false
+}
+
+// keyValueFileToJson converts a parameter or tag file to the CloudFormation
+// JSON format of a list of objects with a key and value field. JSON files are
+// returned as is and YAML files are converted to JSON. For TOML files, the
+// Key = "Value" pairs in the provided section are used, sorted by key.
+func keyValueFileToJson(contents string, section string, keyName string, valueName string) ([]byte, error) {
+	if json.Valid([]byte(contents)) {
+		return []byte(contents), nil
+	}
+	if !isTomlDocument(contents) {
+		return YamlToJson([]byte(contents))
+	}
+	parsed := make(map[string]interface{})
+	if err := toml.Unmarshal([]byte(contents), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %s", err)
+	}
+	values, ok := parsed[section].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the TOML file doesn't have a [%v] section", section)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch typed := values[key].(type) {
+		case string:
+			value = typed
+		case int64, float64, bool:
+			value = fmt.Sprint(typed)
+		default:
+			return nil, fmt.Errorf("the value of %v in the [%v] section needs to be a string, number, or boolean", key, section)
+		}
+		result = append(result, map[string]string{keyName: key, valueName: value})
+	}
+	return json.Marshal(result)
+}
+
 // convertMapInterfaceToMapString converts a map[interface{}]interface{} to a map[string]interface{}
 // This is required for the YAML to JSON conversion as the JSON library does not support interface{} keys
 func convertMapInterfaceToMapString(i interface{}) interface{} {
@@ -0,0 +1,317 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"gopkg.in/yaml.v2"
+)
+
+type mockS3GetObjectAPI func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+
+func (m mockS3GetObjectAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func Test_parseS3URL(t *testing.T) {
+	tests := []struct {
+		name       string
+		location   string
+		wantBucket string
+		wantKey    string
+		wantOk     bool
+	}{
+		{"S3 scheme", "s3://my-bucket/deployments/prod.yaml", "my-bucket", "deployments/prod.yaml", true},
+		{"S3 scheme without key", "s3://my-bucket", "", "", false},
+		{"Virtual hosted style", "https://my-bucket.s3.amazonaws.com/deployments/prod.yaml", "my-bucket", "deployments/prod.yaml", true},
+		{"Virtual hosted style with region", "https://my-bucket.s3.ap-southeast-2.amazonaws.com/prod.yaml", "my-bucket", "prod.yaml", true},
+		{"Path style", "https://s3.ap-southeast-2.amazonaws.com/my-bucket/prod.yaml", "my-bucket", "prod.yaml", true},
+		{"Other HTTPS URL", "https://example.com/prod.yaml", "", "", false},
+		{"Local path", "deployments/prod.yaml", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, key, ok := parseS3URL(tt.location)
+			if bucket != tt.wantBucket || key != tt.wantKey || ok != tt.wantOk {
+				t.Errorf("parseS3URL() = %v, %v, %v, want %v, %v, %v", bucket, key, ok, tt.wantBucket, tt.wantKey, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseDeploymentFileFromURL(t *testing.T) {
+	deploymentFile := "template-file-path: vpc.yaml\nparameters:\n  Environment: prod\ntags:\n  Team: platform\n"
+	want := StackDeploymentFile{
+		TemplateFilePath: "vpc.yaml",
+		Parameters:       map[string]string{"Environment": "prod"},
+		Tags:             map[string]string{"Team": "platform"},
+	}
+	localFile := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(localFile, []byte(deploymentFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prod.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, deploymentFile)
+	}))
+	defer server.Close()
+	originalClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = originalClient }()
+	s3Mock := mockS3GetObjectAPI(func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+		if *params.Bucket != "my-bucket" || *params.Key != "prod.yaml" {
+			return nil, errors.New("NoSuchKey")
+		}
+		return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(deploymentFile))}, nil
+	})
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{"S3 URL", "s3://my-bucket/prod.yaml", false},
+		{"S3 HTTPS URL", "https://my-bucket.s3.amazonaws.com/prod.yaml", false},
+		{"Missing S3 object", "s3://my-bucket/missing.yaml", true},
+		{"HTTPS URL", server.URL + "/prod.yaml", false},
+		{"Missing HTTPS file", server.URL + "/missing.yaml", true},
+		{"Plain HTTP URL", strings.Replace(server.URL, "https://", "http://", 1) + "/prod.yaml", true},
+		{"Local file", localFile, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDeploymentFileFromURL(tt.location, "", s3Mock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDeploymentFileFromURL() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseDeploymentFileFromURL() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateURL(t *testing.T) {
+	tests := map[string]struct {
+		url     string
+		wantErr bool
+	}{
+		"Path style":           {url: "https://s3.amazonaws.com/my-bucket/vpc.yaml"},
+		"Regional path style":  {url: "https://s3.ap-southeast-2.amazonaws.com/my-bucket/vpc.yaml"},
+		"Virtual-hosted style": {url: "https://my-bucket.s3.ap-southeast-2.amazonaws.com/templates/vpc.yaml"},
+		"Legacy regional":      {url: "https://my-bucket.s3-ap-southeast-2.amazonaws.com/vpc.yaml"},
+		"S3 URI":               {url: "s3://my-bucket/vpc.yaml", wantErr: true},
+		"HTTP":                 {url: "http://s3.amazonaws.com/my-bucket/vpc.yaml", wantErr: true},
+		"Other host":           {url: "https://example.com/vpc.yaml", wantErr: true},
+		"Bucket without key":   {url: "https://s3.amazonaws.com/my-bucket", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateTemplateURL(tc.url); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateTemplateURL() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadTemplate(t *testing.T) {
+	template := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n"
+	var requestedVersion, requestedRegion string
+	s3Mock := mockS3GetObjectAPI(func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+		options := s3.Options{}
+		for _, optFn := range optFns {
+			optFn(&options)
+		}
+		requestedVersion = aws.ToString(params.VersionId)
+		requestedRegion = options.Region
+		switch *params.Key {
+		case "templates/vpc.yaml":
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(template))}, nil
+		case "templates/huge.yaml":
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(strings.Repeat("a", MaxTemplateURLSize+1)))}, nil
+		}
+		return nil, errors.New("NoSuchKey")
+	})
+	tests := map[string]struct {
+		url         string
+		want        string
+		wantVersion string
+		wantRegion  string
+		wantErr     bool
+	}{
+		"Template":       {url: "https://my-bucket.s3.amazonaws.com/templates/vpc.yaml", want: template},
+		"Version":        {url: "https://my-bucket.s3.amazonaws.com/templates/vpc.yaml?versionId=abc123", want: template, wantVersion: "abc123"},
+		"Bucket region":  {url: "https://my-bucket.s3.eu-west-1.amazonaws.com/templates/vpc.yaml", want: template, wantRegion: "eu-west-1"},
+		"Legacy region":  {url: "https://my-bucket.s3-ap-southeast-2.amazonaws.com/templates/vpc.yaml", want: template, wantRegion: "ap-southeast-2"},
+		"Path style":     {url: "https://s3.us-west-2.amazonaws.com/my-bucket/templates/vpc.yaml?versionId=v2", want: template, wantVersion: "v2", wantRegion: "us-west-2"},
+		"China":          {url: "https://my-bucket.s3.cn-north-1.amazonaws.com.cn/templates/vpc.yaml", want: template, wantRegion: "cn-north-1"},
+		"Too large":      {url: "https://my-bucket.s3.amazonaws.com/templates/huge.yaml", wantErr: true},
+		"Missing object": {url: "https://my-bucket.s3.amazonaws.com/templates/missing.yaml", wantErr: true},
+		"Invalid URL":    {url: "https://example.com/templates/vpc.yaml", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			requestedVersion, requestedRegion = "", ""
+			got, err := DownloadTemplate(tc.url, s3Mock)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("DownloadTemplate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("DownloadTemplate() = %q, want %q", got, tc.want)
+			}
+			if requestedVersion != tc.wantVersion || requestedRegion != tc.wantRegion {
+				t.Errorf("DownloadTemplate() requested version %q in region %q, want %q in %q", requestedVersion, requestedRegion, tc.wantVersion, tc.wantRegion)
+			}
+		})
+	}
+}
+
+func TestStackDeploymentFile_ToCommentedYAML(t *testing.T) {
+	deploymentFile := StackDeploymentFile{
+		TemplateFilePath: "../templates/vpc.yaml",
+		Parameters:       map[string]string{"VpcCidr": "10.0.0.0/16", "Environment": "dev"},
+	}
+	got, err := deploymentFile.ToCommentedYAML()
+	if err != nil {
+		t.Fatalf("ToCommentedYAML() error = %v", err)
+	}
+	want := `# The path to the template, relative to the location of this deployment file
+template-file-path: ../templates/vpc.yaml
+# The parameters for the stack as key: value pairs
+parameters:
+  Environment: dev
+  VpcCidr: 10.0.0.0/16
+# The tags for the stack as key: value pairs. Default tags from the fog configuration are added during deployment
+tags: {}
+`
+	if got != want {
+		t.Errorf("ToCommentedYAML() = %v, want %v", got, want)
+	}
+	var parsed StackDeploymentFile
+	if err := yaml.Unmarshal([]byte(got), &parsed); err != nil {
+		t.Fatalf("generated deployment file can't be parsed: %v", err)
+	}
+	if parsed.TemplateFilePath != deploymentFile.TemplateFilePath || len(parsed.Parameters) != 2 {
+		t.Errorf("generated deployment file parsed as %+v", parsed)
+	}
+}
+
+func TestReadDeploymentTemplate(t *testing.T) {
+	root := t.TempDir()
+	templatePath := filepath.Join(root, "templates", "vpc.yaml")
+	deploymentPath := filepath.Join(root, "deployments", "vpc.yaml")
+	for _, dir := range []string{filepath.Dir(templatePath), filepath.Dir(deploymentPath)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(templatePath, []byte("Resources: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Generate the deployment file the same way --generate-deployment-file does
+	relativePath, err := filepath.Rel(filepath.Dir(deploymentPath), templatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := DeployInfo{TemplateRelativePath: relativePath}.ToDeploymentFile().ToCommentedYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(deploymentPath, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var deployment DeployInfo
+	if err := deployment.LoadDeploymentFile(deploymentPath, "", nil); err != nil {
+		t.Fatalf("LoadDeploymentFile() error = %v", err)
+	}
+	if deployment.DeploymentFilePath != deploymentPath {
+		t.Errorf("LoadDeploymentFile() DeploymentFilePath = %v, want %v", deployment.DeploymentFilePath, deploymentPath)
+	}
+	template, path, err := ReadDeploymentTemplate(deployment.StackDeploymentFile.TemplateFilePath, deployment.DeploymentFilePath)
+	if err != nil {
+		t.Fatalf("ReadDeploymentTemplate() error = %v", err)
+	}
+	if template != "Resources: {}\n" || path != templatePath {
+		t.Errorf("ReadDeploymentTemplate() = %q, %v, want the template at %v", template, path, templatePath)
+	}
+	// Without a deployment file path, the path is used as is
+	if _, _, err := ReadDeploymentTemplate(templatePath, ""); err != nil {
+		t.Errorf("ReadDeploymentTemplate() without deployment file error = %v", err)
+	}
+	if _, _, err := ReadDeploymentTemplate("../templates/missing.yaml", deploymentPath); err == nil {
+		t.Errorf("ReadDeploymentTemplate() expected an error for a missing template")
+	}
+}
+
+// writeFakeSops creates a script that behaves like sops --decrypt by stripping
+// the ENC[] markers from the file, and fails for files with "broken" in the name
+func writeFakeSops(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops binary is a shell script")
+	}
+	script := `#!/bin/sh
+if [ "$1" != "--decrypt" ]; then
+  exit 2
+fi
+case "$2" in
+  *broken*) echo "Failed to get the data key required to decrypt the SOPS file." >&2; exit 128;;
+esac
+sed 's/ENC\[\([^]]*\)\]/\1/' "$2"
+`
+	path := filepath.Join(t.TempDir(), "sops")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadEncryptedFile(t *testing.T) {
+	decryptor := SopsDecryptor{Binary: writeFakeSops(t)}
+	dir := t.TempDir()
+	encrypted := filepath.Join(dir, "params.enc.json")
+	if err := os.WriteFile(encrypted, []byte(`[{"ParameterKey": "Password", "ParameterValue": "ENC[secret]"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.enc.json")
+	if err := os.WriteFile(broken, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	contents, path, err := ReadEncryptedFile(&encrypted, "parameters", decryptor)
+	if err != nil {
+		t.Fatalf("ReadEncryptedFile() error = %v", err)
+	}
+	if path != encrypted {
+		t.Errorf("ReadEncryptedFile() path = %v, want %v", path, encrypted)
+	}
+	parameters, err := ParseParameterString(contents)
+	if err != nil {
+		t.Fatalf("ParseParameterString() error = %v", err)
+	}
+	if len(parameters) != 1 || *parameters[0].ParameterValue != "secret" {
+		t.Errorf("ReadEncryptedFile() returned %v, want the decrypted parameter", contents)
+	}
+	_, _, err = ReadEncryptedFile(&broken, "parameters", decryptor)
+	if err == nil || !strings.Contains(err.Error(), "Failed to get the data key") {
+		t.Errorf("ReadEncryptedFile() error = %v, want the stderr of sops", err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+	if _, _, err := ReadEncryptedFile(&missing, "parameters", decryptor); err == nil {
+		t.Errorf("ReadEncryptedFile() expected an error for a missing file")
+	}
+}
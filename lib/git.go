@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// GitCommandRunner runs git commands and returns their output
+type GitCommandRunner interface {
+	Run(args ...string) (string, error)
+}
+
+// ExecGitRunner runs git commands using the git binary in the provided directory
+type ExecGitRunner struct {
+	Dir string
+}
+
+// Run executes git with the provided arguments and returns the trimmed output
+func (runner ExecGitRunner) Run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = runner.Dir
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %v failed: %v %v", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// GitMetadata contains the details of the current commit of a git repository
+type GitMetadata struct {
+	Commit string
+	Branch string
+	Author string
+}
+
+// GetGitMetadata retrieves the details of the latest commit using the provided runner
+func GetGitMetadata(runner GitCommandRunner) (GitMetadata, error) {
+	metadata := GitMetadata{}
+	commit, err := runner.Run("rev-parse", "HEAD")
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Commit = commit
+	branch, err := runner.Run("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Branch = branch
+	author, err := runner.Run("log", "-1", "--format=%an")
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Author = author
+	return metadata, nil
+}
+
+// GitDeployedAtTag is the key of the tag with the time of the deployment
+const GitDeployedAtTag = "git:deployed-at"
+
+// ToTags returns the git metadata as CloudFormation tags, using deployedAt for the git:deployed-at tag
+func (metadata GitMetadata) ToTags(deployedAt time.Time) []types.Tag {
+	return []types.Tag{
+		{Key: aws.String("git:commit"), Value: aws.String(metadata.Commit)},
+		{Key: aws.String("git:branch"), Value: aws.String(metadata.Branch)},
+		{Key: aws.String("git:author"), Value: aws.String(metadata.Author)},
+		{Key: aws.String(GitDeployedAtTag), Value: aws.String(deployedAt.UTC().Format(time.RFC3339))},
+	}
+}
+
+// MergeTags returns the existing tags with the additional tags added. Additional tags
+// overwrite the value of existing tags with the same key.
+func MergeTags(existing []types.Tag, additional []types.Tag) []types.Tag {
+	result := make([]types.Tag, 0, len(existing)+len(additional))
+	positions := make(map[string]int)
+	for _, tag := range append(append([]types.Tag{}, existing...), additional...) {
+		key := aws.ToString(tag.Key)
+		if position, ok := positions[key]; ok {
+			result[position] = tag
+			continue
+		}
+		positions[key] = len(result)
+		result = append(result, tag)
+	}
+	return result
+}
+
+// RemoveTags returns the existing tags without the tags with the provided keys
+func RemoveTags(existing []types.Tag, keys []string) []types.Tag {
+	result := make([]types.Tag, 0, len(existing))
+	for _, tag := range existing {
+		if stringInSlice(aws.ToString(tag.Key), keys) {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// UpdateStackTags updates the tags of the stack while keeping its template and parameters unchanged
+func UpdateStackTags(stack types.Stack, tags []types.Tag, svc CloudFormationUpdateStackAPI) error {
+	parameters := make([]types.Parameter, 0, len(stack.Parameters))
+	for _, parameter := range stack.Parameters {
+		parameters = append(parameters, types.Parameter{
+			ParameterKey:     parameter.ParameterKey,
+			UsePreviousValue: aws.Bool(true),
+		})
+	}
+	input := &cloudformation.UpdateStackInput{
+		StackName:           stack.StackName,
+		UsePreviousTemplate: aws.Bool(true),
+		Parameters:          parameters,
+		Tags:                tags,
+		Capabilities:        types.CapabilityCapabilityAutoExpand.Values(),
+	}
+	_, err := svc.UpdateStack(context.TODO(), input)
+	return err
+}
@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+type mockGitRunner map[string]string
+
+func (m mockGitRunner) Run(args ...string) (string, error) {
+	output, ok := m[strings.Join(args, " ")]
+	if !ok {
+		return "", errors.New("not a git repository")
+	}
+	return output, nil
+}
+
+type mockCloudFormationUpdateStackAPI func(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error)
+
+func (m mockCloudFormationUpdateStackAPI) UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetGitMetadata(t *testing.T) {
+	repository := mockGitRunner{
+		"rev-parse HEAD":              "0123456789abcdef",
+		"rev-parse --abbrev-ref HEAD": "main",
+		"log -1 --format=%an":         "Jane Doe",
+	}
+	tests := []struct {
+		name    string
+		runner  GitCommandRunner
+		want    GitMetadata
+		wantErr bool
+	}{
+		{"Repository", repository, GitMetadata{Commit: "0123456789abcdef", Branch: "main", Author: "Jane Doe"}, false},
+		{"Not a repository", mockGitRunner{}, GitMetadata{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetGitMetadata(tt.runner)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetGitMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetGitMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitMetadata_ToTags(t *testing.T) {
+	metadata := GitMetadata{Commit: "0123456789abcdef", Branch: "main", Author: "Jane Doe"}
+	deployedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("AEDT", 11*60*60))
+	got := metadata.ToTags(deployedAt)
+	want := map[string]string{
+		"git:commit":      "0123456789abcdef",
+		"git:branch":      "main",
+		"git:author":      "Jane Doe",
+		"git:deployed-at": "2024-03-01T01:00:00Z",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ToTags() returned %d tags, want %d", len(got), len(want))
+	}
+	for _, tag := range got {
+		if want[aws.ToString(tag.Key)] != aws.ToString(tag.Value) {
+			t.Errorf("ToTags() tag %v = %v, want %v", aws.ToString(tag.Key), aws.ToString(tag.Value), want[aws.ToString(tag.Key)])
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	existing := []types.Tag{
+		{Key: aws.String("Owner"), Value: aws.String("team")},
+		{Key: aws.String("git:commit"), Value: aws.String("old")},
+	}
+	additional := []types.Tag{
+		{Key: aws.String("git:commit"), Value: aws.String("new")},
+		{Key: aws.String("git:branch"), Value: aws.String("main")},
+	}
+	want := []types.Tag{
+		{Key: aws.String("Owner"), Value: aws.String("team")},
+		{Key: aws.String("git:commit"), Value: aws.String("new")},
+		{Key: aws.String("git:branch"), Value: aws.String("main")},
+	}
+	got := MergeTags(existing, additional)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeTags() = %v, want %v", got, want)
+	}
+	if aws.ToString(existing[1].Value) != "old" {
+		t.Errorf("MergeTags() modified the existing tags")
+	}
+}
+
+func TestStackTagChanges(t *testing.T) {
+	tag := func(key, value string) types.Tag {
+		return types.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+	existing := []types.Tag{tag("Owner", "team-a"), tag("Environment", "dev")}
+	tests := map[string]struct {
+		add    []types.Tag
+		remove []string
+		want   []types.Tag
+	}{
+		"add":              {add: []types.Tag{tag("Team", "Platform")}, want: []types.Tag{tag("Owner", "team-a"), tag("Environment", "dev"), tag("Team", "Platform")}},
+		"overwrite":        {add: []types.Tag{tag("Owner", "team-b")}, want: []types.Tag{tag("Owner", "team-b"), tag("Environment", "dev")}},
+		"remove":           {remove: []string{"Owner"}, want: []types.Tag{tag("Environment", "dev")}},
+		"remove all":       {remove: []string{"Owner", "Environment"}, want: []types.Tag{}},
+		"remove unknown":   {remove: []string{"OldTag"}, want: existing},
+		"add after remove": {remove: []string{"Owner"}, add: []types.Tag{tag("Owner", "team-c")}, want: []types.Tag{tag("Environment", "dev"), tag("Owner", "team-c")}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := MergeTags(RemoveTags(existing, tc.remove), tc.add)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("MergeTags(RemoveTags()) = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateStackTags(t *testing.T) {
+	stack := types.Stack{
+		StackName: aws.String("test-stack"),
+		Parameters: []types.Parameter{
+			{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")},
+		},
+	}
+	tags := []types.Tag{{Key: aws.String("git:branch"), Value: aws.String("main")}}
+	var input *cloudformation.UpdateStackInput
+	svc := mockCloudFormationUpdateStackAPI(func(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error) {
+		input = params
+		return &cloudformation.UpdateStackOutput{}, nil
+	})
+	if err := UpdateStackTags(stack, tags, svc); err != nil {
+		t.Fatalf("UpdateStackTags() error = %v", err)
+	}
+	if !aws.ToBool(input.UsePreviousTemplate) {
+		t.Errorf("UpdateStackTags() didn't use the previous template")
+	}
+	if len(input.Parameters) != 1 || !aws.ToBool(input.Parameters[0].UsePreviousValue) || input.Parameters[0].ParameterValue != nil {
+		t.Errorf("UpdateStackTags() parameters = %v, want UsePreviousValue for VpcCidr", input.Parameters)
+	}
+	if !reflect.DeepEqual(input.Tags, tags) {
+		t.Errorf("UpdateStackTags() tags = %v, want %v", input.Tags, tags)
+	}
+}
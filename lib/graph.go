@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependencyGraph holds the stacks and the export/import dependencies between them
+type DependencyGraph struct {
+	// Nodes contains the names of the stacks, sorted
+	Nodes []string
+	// Edges contains the dependencies, sorted by the exporting and then the importing stack
+	Edges []DependencyEdge
+}
+
+// DependencyEdge links a stack that exports values to a stack that imports them
+type DependencyEdge struct {
+	// From is the name of the stack that exports the values
+	From string
+	// To is the name of the stack that imports the values
+	To string
+	// Exports contains the names of the exports the dependency consists of
+	Exports []string
+}
+
+// BuildStackDependencyGraph creates the dependency graph for the stacks based
+// on which stacks import their exports, as returned by ListImports. Importing
+// stacks that aren't part of the provided stacks are added as nodes as well.
+func BuildStackDependencyGraph(stacks map[string]CfnStack, svc CloudFormationListImportsAPI) (DependencyGraph, error) {
+	graph := DependencyGraph{Nodes: make([]string, 0), Edges: make([]DependencyEdge, 0)}
+	nodes := make(map[string]bool)
+	for _, stack := range stacks {
+		nodes[stack.Name] = true
+		edges := make(map[string]*DependencyEdge)
+		for _, output := range stack.Outputs {
+			if output.ExportName == "" {
+				continue
+			}
+			importers, err := GetExportImporters(output.ExportName, svc)
+			if err != nil {
+				return graph, err
+			}
+			for _, importer := range importers {
+				nodes[importer] = true
+				edge, ok := edges[importer]
+				if !ok {
+					edge = &DependencyEdge{From: stack.Name, To: importer, Exports: make([]string, 0)}
+					edges[importer] = edge
+				}
+				if !stringInSlice(output.ExportName, edge.Exports) {
+					edge.Exports = append(edge.Exports, output.ExportName)
+				}
+			}
+		}
+		for _, edge := range edges {
+			sort.Strings(edge.Exports)
+			graph.Edges = append(graph.Edges, *edge)
+		}
+	}
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Strings(graph.Nodes)
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+// Mermaid returns the graph as a Mermaid flowchart
+func (graph DependencyGraph) Mermaid() string {
+	ids := make(map[string]string, len(graph.Nodes))
+	var builder strings.Builder
+	builder.WriteString("graph LR\n")
+	for index, node := range graph.Nodes {
+		ids[node] = fmt.Sprintf("stack%d", index)
+		builder.WriteString(fmt.Sprintf("    %v[\"%v\"]\n", ids[node], escapeGraphLabel(node)))
+	}
+	for _, edge := range graph.Edges {
+		if len(edge.Exports) == 0 {
+			builder.WriteString(fmt.Sprintf("    %v --> %v\n", ids[edge.From], ids[edge.To]))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("    %v -->|\"%v\"| %v\n", ids[edge.From], escapeGraphLabel(strings.Join(edge.Exports, ", ")), ids[edge.To]))
+	}
+	return builder.String()
+}
+
+// Dot returns the graph in the Graphviz DOT format
+func (graph DependencyGraph) Dot() string {
+	var builder strings.Builder
+	builder.WriteString("digraph {\n")
+	for _, node := range graph.Nodes {
+		builder.WriteString(fmt.Sprintf("    \"%v\";\n", escapeGraphLabel(node)))
+	}
+	for _, edge := range graph.Edges {
+		builder.WriteString(fmt.Sprintf("    \"%v\" -> \"%v\"", escapeGraphLabel(edge.From), escapeGraphLabel(edge.To)))
+		if len(edge.Exports) != 0 {
+			builder.WriteString(fmt.Sprintf(" [label=\"%v\"]", escapeGraphLabel(strings.Join(edge.Exports, ", "))))
+		}
+		builder.WriteString(";\n")
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// escapeGraphLabel escapes the double quotes in a label
+func escapeGraphLabel(label string) string {
+	return strings.ReplaceAll(label, "\"", "\\\"")
+}
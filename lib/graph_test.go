@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+)
+
+// testDependencyStacks returns the stacks as GetCfnStacks would before the
+// imports are looked up, together with a client that knows the imports
+func testDependencyStacks() (map[string]CfnStack, *testutil.MockCFNClient) {
+	stacks := map[string]CfnStack{
+		"network": {
+			Name: "network",
+			Outputs: []CfnOutput{
+				{ExportName: "network-VpcId"},
+				{ExportName: "network-SubnetIds"},
+				{OutputKey: "NotExported"},
+			},
+		},
+		"database": {
+			Name:    "database",
+			Outputs: []CfnOutput{{ExportName: "database-Endpoint"}},
+		},
+		"app":     {Name: "app"},
+		"logging": {Name: "logging", Outputs: []CfnOutput{{ExportName: "logging-Bucket"}}},
+	}
+	svc := &testutil.MockCFNClient{Imports: map[string][]string{
+		"network-VpcId":     {"app", "database"},
+		"network-SubnetIds": {"app"},
+		"database-Endpoint": {"app"},
+	}}
+	return stacks, svc
+}
+
+type mockCloudFormationListImportsAPI func(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error)
+
+func (m mockCloudFormationListImportsAPI) ListImports(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestBuildStackDependencyGraph(t *testing.T) {
+	want := DependencyGraph{
+		Nodes: []string{"app", "database", "logging", "network"},
+		Edges: []DependencyEdge{
+			{From: "database", To: "app", Exports: []string{"database-Endpoint"}},
+			{From: "network", To: "app", Exports: []string{"network-SubnetIds", "network-VpcId"}},
+			{From: "network", To: "database", Exports: []string{"network-VpcId"}},
+		},
+	}
+	stacks, svc := testDependencyStacks()
+	got, err := BuildStackDependencyGraph(stacks, svc)
+	if err != nil {
+		t.Fatalf("BuildStackDependencyGraph() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildStackDependencyGraph() = %+v, want %+v", got, want)
+	}
+
+	// Importing stacks outside of the provided stacks are still shown
+	partial, err := BuildStackDependencyGraph(map[string]CfnStack{"database": stacks["database"]}, svc)
+	if err != nil {
+		t.Fatalf("BuildStackDependencyGraph() error = %v", err)
+	}
+	if !reflect.DeepEqual(partial.Nodes, []string{"app", "database"}) {
+		t.Errorf("BuildStackDependencyGraph() nodes = %v", partial.Nodes)
+	}
+
+	failing := mockCloudFormationListImportsAPI(func(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error) {
+		return nil, errors.New("access denied")
+	})
+	if _, err := BuildStackDependencyGraph(stacks, failing); err == nil {
+		t.Errorf("BuildStackDependencyGraph() expected an error when the imports can't be listed")
+	}
+}
+
+func TestGetExportImporters(t *testing.T) {
+	pages := map[string]*cloudformation.ListImportsOutput{
+		"":      {Imports: []string{"app", "database"}, NextToken: aws.String("page2")},
+		"page2": {Imports: []string{"monitoring"}},
+	}
+	svc := mockCloudFormationListImportsAPI(func(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error) {
+		if aws.ToString(params.ExportName) == "unused" {
+			return nil, errors.New("api error ValidationError: Export 'unused' is not imported by any stack.")
+		}
+		return pages[aws.ToString(params.NextToken)], nil
+	})
+	got, err := GetExportImporters("network-VpcId", svc)
+	if err != nil {
+		t.Fatalf("GetExportImporters() error = %v", err)
+	}
+	if want := []string{"app", "database", "monitoring"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetExportImporters() = %v, want %v", got, want)
+	}
+	if got, err := GetExportImporters("unused", svc); err != nil || len(got) != 0 {
+		t.Errorf("GetExportImporters() for an unused export = %v, %v, want no importers", got, err)
+	}
+}
+
+func TestDependencyGraph_Render(t *testing.T) {
+	graph := DependencyGraph{
+		Nodes: []string{"app", "network"},
+		Edges: []DependencyEdge{{From: "network", To: "app", Exports: []string{"network-VpcId"}}},
+	}
+	wantMermaid := "graph LR\n" +
+		"    stack0[\"app\"]\n" +
+		"    stack1[\"network\"]\n" +
+		"    stack1 -->|\"network-VpcId\"| stack0\n"
+	if got := graph.Mermaid(); got != wantMermaid {
+		t.Errorf("DependencyGraph.Mermaid() = %q, want %q", got, wantMermaid)
+	}
+	wantDot := "digraph {\n" +
+		"    \"app\";\n" +
+		"    \"network\";\n" +
+		"    \"network\" -> \"app\" [label=\"network-VpcId\"];\n" +
+		"}\n"
+	if got := graph.Dot(); got != wantDot {
+		t.Errorf("DependencyGraph.Dot() = %q, want %q", got, wantDot)
+	}
+}
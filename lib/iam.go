@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// RoleBoundaryInfo shows whether an IAM role has a permissions boundary attached
+type RoleBoundaryInfo struct {
+	LogicalID   string
+	RoleName    string
+	BoundaryARN string
+	HasBoundary bool
+}
+
+// GetStackPermissionsBoundary returns the permissions boundary information for
+// every IAM role managed by the stack. The roles are found through the stack's
+// resources, as IAM roles don't carry the aws:cloudformation tags. Roles that
+// aren't in a completed state are skipped, as their physical ID may not point
+// to an existing role.
+func GetStackPermissionsBoundary(stackName string, cfnSvc CloudFormationListStackResourcesAPI, iamSvc IAMGetRoleAPI) ([]RoleBoundaryInfo, error) {
+	resources, err := ListStackResourceSummaries(stackName, cfnSvc)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RoleBoundaryInfo, 0)
+	for _, resource := range resources {
+		if aws.ToString(resource.ResourceType) != "AWS::IAM::Role" || resource.PhysicalResourceId == nil {
+			continue
+		}
+		status := resource.ResourceStatus
+		if !strings.HasSuffix(string(status), "_COMPLETE") || status == cfntypes.ResourceStatusDeleteComplete {
+			continue
+		}
+		role, err := iamSvc.GetRole(context.TODO(), &iam.GetRoleInput{RoleName: resource.PhysicalResourceId})
+		if err != nil {
+			return nil, err
+		}
+		info := RoleBoundaryInfo{
+			LogicalID: aws.ToString(resource.LogicalResourceId),
+			RoleName:  aws.ToString(resource.PhysicalResourceId),
+		}
+		if role.Role != nil && role.Role.PermissionsBoundary != nil {
+			info.BoundaryARN = aws.ToString(role.Role.PermissionsBoundary.PermissionsBoundaryArn)
+			info.HasBoundary = info.BoundaryARN != ""
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// GetIAMInlinePolicies returns the inline policies of the role as a map of the
+// policy name to the policy document, formatted as indented JSON
+func GetIAMInlinePolicies(roleName string, svc IAMRoleInlinePoliciesAPI) (map[string]string, error) {
+	result := make(map[string]string)
+	paginator := iam.NewListRolePoliciesPaginator(svc, &iam.ListRolePoliciesInput{RoleName: &roleName})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, policyName := range page.PolicyNames {
+			policy, err := svc.GetRolePolicy(context.TODO(), &iam.GetRolePolicyInput{
+				RoleName:   &roleName,
+				PolicyName: aws.String(policyName),
+			})
+			if err != nil {
+				return nil, err
+			}
+			// IAM returns the policy document URL encoded. Path unescaping keeps
+			// a literal + intact, where query unescaping turns it into a space.
+			document, err := url.PathUnescape(aws.ToString(policy.PolicyDocument))
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy document for %v: %w", policyName, err)
+			}
+			formatted, err := FormatPolicyDocument(document)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy document for %v: %w", policyName, err)
+			}
+			result[policyName] = formatted
+		}
+	}
+	return result, nil
+}
+
+// GetExpectedInlinePolicies returns the inline policies from the Policies
+// property of an AWS::IAM::Role as a map of the policy name to the policy
+// document, formatted as indented JSON
+func GetExpectedInlinePolicies(properties map[string]interface{}) map[string]string {
+	result := make(map[string]string)
+	policies, ok := properties["Policies"].([]interface{})
+	if !ok {
+		return result
+	}
+	for _, policy := range policies {
+		policyMap, ok := policy.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := policyMap["PolicyName"].(string)
+		if !ok {
+			continue
+		}
+		formatted, err := FormatPolicyDocument(policyMap["PolicyDocument"])
+		if err != nil {
+			continue
+		}
+		result[name] = formatted
+	}
+	return result
+}
+
+// GetAccountAlias returns the alias of the account. An account can have at most
+// one alias, an empty string is returned if it doesn't have one.
+func GetAccountAlias(svc IAMListAccountAliasesAPI) (string, error) {
+	result, err := svc.ListAccountAliases(context.TODO(), &iam.ListAccountAliasesInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(result.AccountAliases) == 0 {
+		return "", nil
+	}
+	return result.AccountAliases[0], nil
+}
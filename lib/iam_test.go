@@ -0,0 +1,207 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+type mockIAMGetRoleAPI func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+
+func (m mockIAMGetRoleAPI) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+type mockCloudFormationListStackResourcesAPI func(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error)
+
+func (m mockCloudFormationListStackResourcesAPI) ListStackResources(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetStackPermissionsBoundary(t *testing.T) {
+	boundary := "arn:aws:iam::123456789012:policy/boundary"
+	// The resources are split over two pages, and the role that failed to
+	// create has a physical ID that doesn't exist in IAM
+	resources := mockCloudFormationListStackResourcesAPI(func(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+		if params.NextToken == nil {
+			return &cloudformation.ListStackResourcesOutput{
+				StackResourceSummaries: []cfntypes.StackResourceSummary{
+					{LogicalResourceId: aws.String("BoundedRole"), PhysicalResourceId: aws.String("my-stack-BoundedRole"), ResourceType: aws.String("AWS::IAM::Role"), ResourceStatus: cfntypes.ResourceStatusCreateComplete},
+					{LogicalResourceId: aws.String("FailedRole"), PhysicalResourceId: aws.String("my-stack-FailedRole"), ResourceType: aws.String("AWS::IAM::Role"), ResourceStatus: cfntypes.ResourceStatusCreateFailed},
+				},
+				NextToken: aws.String("page2"),
+			}, nil
+		}
+		return &cloudformation.ListStackResourcesOutput{
+			StackResourceSummaries: []cfntypes.StackResourceSummary{
+				{LogicalResourceId: aws.String("OpenRole"), PhysicalResourceId: aws.String("my-stack-OpenRole"), ResourceType: aws.String("AWS::IAM::Role"), ResourceStatus: cfntypes.ResourceStatusUpdateComplete},
+				{LogicalResourceId: aws.String("Bucket"), PhysicalResourceId: aws.String("my-bucket"), ResourceType: aws.String("AWS::S3::Bucket"), ResourceStatus: cfntypes.ResourceStatusCreateComplete},
+			},
+		}, nil
+	})
+	roles := mockIAMGetRoleAPI(func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+		if *params.RoleName == "my-stack-FailedRole" {
+			return nil, errors.New("NoSuchEntity")
+		}
+		role := &types.Role{RoleName: params.RoleName}
+		if *params.RoleName == "my-stack-BoundedRole" {
+			role.PermissionsBoundary = &types.AttachedPermissionsBoundary{PermissionsBoundaryArn: aws.String(boundary)}
+		}
+		return &iam.GetRoleOutput{Role: role}, nil
+	})
+	failingRoles := mockIAMGetRoleAPI(func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+		return nil, errors.New("AccessDenied")
+	})
+	tests := []struct {
+		name    string
+		iamSvc  IAMGetRoleAPI
+		want    []RoleBoundaryInfo
+		wantErr bool
+	}{
+		{"Roles with and without boundary", roles, []RoleBoundaryInfo{
+			{LogicalID: "BoundedRole", RoleName: "my-stack-BoundedRole", BoundaryARN: boundary, HasBoundary: true},
+			{LogicalID: "OpenRole", RoleName: "my-stack-OpenRole"},
+		}, false},
+		{"IAM error", failingRoles, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStackPermissionsBoundary("my-stack", resources, tt.iamSvc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetStackPermissionsBoundary() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStackPermissionsBoundary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockIAMRoleInlinePoliciesAPI struct {
+	policies map[string]string
+	err      error
+}
+
+func (m mockIAMRoleInlinePoliciesAPI) ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	names := make([]string, 0, len(m.policies))
+	for name := range m.policies {
+		names = append(names, name)
+	}
+	return &iam.ListRolePoliciesOutput{PolicyNames: names}, nil
+}
+
+func (m mockIAMRoleInlinePoliciesAPI) GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error) {
+	return &iam.GetRolePolicyOutput{
+		RoleName:       params.RoleName,
+		PolicyName:     params.PolicyName,
+		PolicyDocument: aws.String(m.policies[aws.ToString(params.PolicyName)]),
+	}, nil
+}
+
+func TestGetIAMInlinePolicies(t *testing.T) {
+	svc := mockIAMRoleInlinePoliciesAPI{policies: map[string]string{
+		"read-bucket": "%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Action%22%3A%22s3%3AGetObject%22%2C%22Resource%22%3A%22*%22%7D%5D%7D",
+		"read-prefix": "%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Action%22%3A%22s3%3AGetObject%22%2C%22Resource%22%3A%22arn%3Aaws%3As3%3A%3A%3Abucket%2Fa+b%2F*%22%7D%5D%7D",
+	}}
+	got, err := GetIAMInlinePolicies("my-role", svc)
+	if err != nil {
+		t.Fatalf("GetIAMInlinePolicies() error = %v", err)
+	}
+	want := map[string]string{"read-bucket": `{
+  "Statement": [
+    {
+      "Action": "s3:GetObject",
+      "Effect": "Allow",
+      "Resource": "*"
+    }
+  ],
+  "Version": "2012-10-17"
+}`, "read-prefix": `{
+  "Statement": [
+    {
+      "Action": "s3:GetObject",
+      "Effect": "Allow",
+      "Resource": "arn:aws:s3:::bucket/a+b/*"
+    }
+  ],
+  "Version": "2012-10-17"
+}`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetIAMInlinePolicies() = %v, want %v", got, want)
+	}
+	if _, err := GetIAMInlinePolicies("my-role", mockIAMRoleInlinePoliciesAPI{err: errors.New("access denied")}); err == nil {
+		t.Errorf("GetIAMInlinePolicies() expected an error")
+	}
+}
+
+func TestGetExpectedInlinePolicies(t *testing.T) {
+	properties := map[string]interface{}{
+		"RoleName": "my-role",
+		"Policies": []interface{}{
+			map[string]interface{}{
+				"PolicyName": "read-bucket",
+				"PolicyDocument": map[string]interface{}{
+					"Version":   "2012-10-17",
+					"Statement": []interface{}{},
+				},
+			},
+			map[string]interface{}{"PolicyDocument": map[string]interface{}{}},
+		},
+	}
+	got := GetExpectedInlinePolicies(properties)
+	want := map[string]string{"read-bucket": "{\n  \"Statement\": [],\n  \"Version\": \"2012-10-17\"\n}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetExpectedInlinePolicies() = %v, want %v", got, want)
+	}
+	if got := GetExpectedInlinePolicies(map[string]interface{}{}); len(got) != 0 {
+		t.Errorf("GetExpectedInlinePolicies() without policies = %v", got)
+	}
+}
+
+// MockIAMClient implements IAMListAccountAliasesAPI
+type MockIAMClient struct {
+	AccountAliases []string
+	Err            error
+}
+
+func (m MockIAMClient) ListAccountAliases(ctx context.Context, params *iam.ListAccountAliasesInput, optFns ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return &iam.ListAccountAliasesOutput{AccountAliases: m.AccountAliases}, nil
+}
+
+func TestGetAccountAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  MockIAMClient
+		want    string
+		wantErr bool
+	}{
+		{"Account with alias", MockIAMClient{AccountAliases: []string{"my-account"}}, "my-account", false},
+		{"Account without alias", MockIAMClient{}, "", false},
+		{"No permission", MockIAMClient{Err: errors.New("access denied")}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAccountAlias(tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAccountAlias() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetAccountAlias() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,10 @@ package lib
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type EC2DescribeNaclsAPI interface {
@@ -17,3 +20,209 @@ type EC2DescribeRouteTablesAPI interface {
 type EC2DescribeManagedPrefixListsAPI interface {
 	DescribeManagedPrefixLists(ctx context.Context, params *ec2.DescribeManagedPrefixListsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeManagedPrefixListsOutput, error)
 }
+
+// CloudFormationEventsFetcher is the subset of the CloudFormation client required to fetch stack events
+type CloudFormationEventsFetcher interface {
+	DescribeStackEvents(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error)
+}
+
+// S3GetObjectAPI is the subset of the S3 client required to download objects
+type S3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3PutObjectAPI is the subset of the S3 client required to upload objects
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3GetBucketLocationAPI is the subset of the S3 client required to find the region of a bucket
+type S3GetBucketLocationAPI interface {
+	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+}
+
+// CloudFormationGetTemplateAPI is the subset of the CloudFormation client required to retrieve templates
+type CloudFormationGetTemplateAPI interface {
+	GetTemplate(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error)
+}
+
+// CloudFormationDescribeStacksAPI is the subset of the CloudFormation client required to describe stacks
+type CloudFormationDescribeStacksAPI interface {
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+}
+
+// CloudFormationListImportsAPI is the subset of the CloudFormation client required to find the imports of an export
+type CloudFormationListImportsAPI interface {
+	ListImports(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error)
+}
+
+// CloudFormationDescribeStacksAndImportsAPI combines the APIs required to retrieve stacks with their imports
+type CloudFormationDescribeStacksAndImportsAPI interface {
+	CloudFormationDescribeStacksAPI
+	CloudFormationListImportsAPI
+}
+
+// CloudFormationListStackResourcesAPI is the subset of the CloudFormation client required to list all resources of a stack page by page
+type CloudFormationListStackResourcesAPI interface {
+	ListStackResources(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error)
+}
+
+// CloudFormationStacksAndResourcesAPI combines the APIs required to retrieve all stacks with their resources
+type CloudFormationStacksAndResourcesAPI interface {
+	CloudFormationDescribeStacksAndImportsAPI
+	CloudFormationListStackResourcesAPI
+}
+
+// IAMGetRoleAPI is the subset of the IAM client required to retrieve roles
+type IAMGetRoleAPI interface {
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+// CloudFormationDescribeStacksAndResourcesAPI combines the APIs required to search the resources of multiple stacks
+type CloudFormationDescribeStacksAndResourcesAPI interface {
+	CloudFormationDescribeStacksAPI
+	CloudFormationListStackResourcesAPI
+}
+
+// CloudFormationCreateChangeSetAPI is the subset of the CloudFormation client required to create change sets
+type CloudFormationCreateChangeSetAPI interface {
+	CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error)
+}
+
+// CloudFormationDescribeChangeSetAPI is the subset of the CloudFormation client required to describe change sets
+type CloudFormationDescribeChangeSetAPI interface {
+	DescribeChangeSet(ctx context.Context, params *cloudformation.DescribeChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error)
+}
+
+// CloudFormationExecuteChangeSetAPI is the subset of the CloudFormation client required to execute change sets
+type CloudFormationExecuteChangeSetAPI interface {
+	ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error)
+}
+
+// CloudFormationListChangeSetsAPI is the subset of the CloudFormation client required to list the change sets of a stack
+type CloudFormationListChangeSetsAPI interface {
+	ListChangeSets(ctx context.Context, params *cloudformation.ListChangeSetsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListChangeSetsOutput, error)
+}
+
+// CloudFormationDeleteChangeSetAPI is the subset of the CloudFormation client required to delete change sets
+type CloudFormationDeleteChangeSetAPI interface {
+	DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error)
+}
+
+// EC2DescribeVpcsAPI is the subset of the EC2 client required to describe VPCs
+type EC2DescribeVpcsAPI interface {
+	DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+}
+
+// EC2DescribeSubnetsAPI is the subset of the EC2 client required to describe subnets
+type EC2DescribeSubnetsAPI interface {
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// EC2DescribeSecurityGroupsAPI is the subset of the EC2 client required to describe security groups
+type EC2DescribeSecurityGroupsAPI interface {
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+}
+
+// EC2DescribeNetworkResourcesAPI combines the APIs required to list the network resources in a region
+type EC2DescribeNetworkResourcesAPI interface {
+	EC2DescribeVpcsAPI
+	EC2DescribeSubnetsAPI
+	EC2DescribeSecurityGroupsAPI
+	EC2DescribeRouteTablesAPI
+	EC2DescribeNaclsAPI
+}
+
+// EC2DescribeVpcAttributeAPI is the subset of the EC2 client required to retrieve the attributes of a VPC
+type EC2DescribeVpcAttributeAPI interface {
+	DescribeVpcAttribute(ctx context.Context, params *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error)
+}
+
+// EC2DescribeVpcConfigAPI combines the APIs required to retrieve the configuration of a VPC
+type EC2DescribeVpcConfigAPI interface {
+	EC2DescribeVpcsAPI
+	EC2DescribeVpcAttributeAPI
+}
+
+// CloudFormationListExportsAPI is the subset of the CloudFormation client required to list exports
+type CloudFormationListExportsAPI interface {
+	ListExports(ctx context.Context, params *cloudformation.ListExportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListExportsOutput, error)
+}
+
+// EC2SearchTransitGatewayRoutesAPI is the subset of the EC2 client required to search the routes of a transit gateway route table
+type EC2SearchTransitGatewayRoutesAPI interface {
+	SearchTransitGatewayRoutes(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error)
+}
+
+// IAMListRolePoliciesAPI is the subset of the IAM client required to list the inline policies of a role
+type IAMListRolePoliciesAPI interface {
+	ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error)
+}
+
+// IAMGetRolePolicyAPI is the subset of the IAM client required to retrieve an inline policy of a role
+type IAMGetRolePolicyAPI interface {
+	GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+}
+
+// IAMRoleInlinePoliciesAPI combines the APIs required to retrieve the inline policies of a role
+type IAMRoleInlinePoliciesAPI interface {
+	IAMListRolePoliciesAPI
+	IAMGetRolePolicyAPI
+}
+
+// IAMListAccountAliasesAPI is the subset of the IAM client required to retrieve the account alias
+type IAMListAccountAliasesAPI interface {
+	ListAccountAliases(ctx context.Context, params *iam.ListAccountAliasesInput, optFns ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error)
+}
+
+// CloudFormationUpdateStackAPI is the subset of the CloudFormation client required to update stacks
+type CloudFormationUpdateStackAPI interface {
+	UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error)
+}
+
+// EC2DescribeVpcEndpointsAPI is the subset of the EC2 client required to describe VPC endpoints
+type EC2DescribeVpcEndpointsAPI interface {
+	DescribeVpcEndpoints(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error)
+}
+
+// CloudFormationValidateTemplateAPI is the subset of the CloudFormation client required to validate templates
+type CloudFormationValidateTemplateAPI interface {
+	ValidateTemplate(ctx context.Context, params *cloudformation.ValidateTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ValidateTemplateOutput, error)
+}
+
+// CloudFormationDeleteStackAPI is the subset of the CloudFormation client required to delete stacks
+type CloudFormationDeleteStackAPI interface {
+	DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error)
+}
+
+// CloudFormationUpdateTerminationProtectionAPI is the subset of the CloudFormation client required to change termination protection
+type CloudFormationUpdateTerminationProtectionAPI interface {
+	UpdateTerminationProtection(ctx context.Context, params *cloudformation.UpdateTerminationProtectionInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateTerminationProtectionOutput, error)
+}
+
+// CloudFormationContinueUpdateRollbackAPI is the subset of the CloudFormation client required to continue a failed rollback
+type CloudFormationContinueUpdateRollbackAPI interface {
+	ContinueUpdateRollback(ctx context.Context, params *cloudformation.ContinueUpdateRollbackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ContinueUpdateRollbackOutput, error)
+}
+
+// CloudFormationCancelUpdateStackAPI is the subset of the CloudFormation client required to cancel stack updates
+type CloudFormationCancelUpdateStackAPI interface {
+	CancelUpdateStack(ctx context.Context, params *cloudformation.CancelUpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CancelUpdateStackOutput, error)
+}
+
+// CloudFormationDetectStackDriftAPI is the subset of the CloudFormation client required to run a drift detection
+type CloudFormationDetectStackDriftAPI interface {
+	DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error)
+	DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error)
+}
+
+// CloudFormationBatchDriftAPI is the subset of the CloudFormation client required to detect and show the drift of multiple stacks
+type CloudFormationBatchDriftAPI interface {
+	CloudFormationDetectStackDriftAPI
+	DescribeStackResourceDrifts(ctx context.Context, params *cloudformation.DescribeStackResourceDriftsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourceDriftsOutput, error)
+}
+
+// CloudFormationEstimateTemplateCostAPI is the subset of the CloudFormation client required to estimate the cost of a template
+type CloudFormationEstimateTemplateCostAPI interface {
+	EstimateTemplateCost(ctx context.Context, params *cloudformation.EstimateTemplateCostInput, optFns ...func(*cloudformation.Options)) (*cloudformation.EstimateTemplateCostOutput, error)
+}
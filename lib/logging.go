@@ -26,6 +26,7 @@ type DeploymentLogStatus string
 const (
 	DeploymentLogStatusSuccess DeploymentLogStatus = "SUCCESS"
 	DeploymentLogStatusFailed  DeploymentLogStatus = "FAILED"
+	DeploymentLogStatusNoOp    DeploymentLogStatus = "NOOP"
 )
 
 type DeploymentLogPreChecks string
@@ -39,6 +40,8 @@ const (
 type DeploymentLog struct {
 	// The AWS Account
 	Account string
+	// The ID of the change request that approved the deployment
+	ChangeRequestID string `json:",omitempty"`
 	// The list of changes that comprise the change set
 	Changes []ChangesetChanges
 	// Deployer is the name of the user/role who deploys the stack
@@ -122,6 +125,12 @@ func (deploymentlog *DeploymentLog) Success() {
 	deploymentlog.Write()
 }
 
+// NoOp marks the deployment as not having had any changes to deploy
+func (deploymentlog *DeploymentLog) NoOp() {
+	deploymentlog.Status = DeploymentLogStatusNoOp
+	deploymentlog.Write()
+}
+
 func (deploymentlog *DeploymentLog) Failed(failures []map[string]interface{}) {
 	deploymentlog.Status = DeploymentLogStatusFailed
 	deploymentlog.Failures = failures
@@ -0,0 +1,366 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestCompareNaclEntries_ProtocolsAndPorts(t *testing.T) {
+	// expected is the entry as defined in the template, actual is the entry
+	// as returned by DescribeNetworkAcls. Each is built separately so a match
+	// is only reported when the concrete values line up.
+	tests := []struct {
+		name     string
+		expected types.NetworkAclEntry
+		actual   types.NetworkAclEntry
+		want     bool
+	}{
+		{
+			name: "TCP port range matches",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: true,
+		},
+		{
+			name: "TCP port range differs",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: false,
+		},
+		{
+			name: "UDP single port matches",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: true,
+		},
+		{
+			name: "UDP single port differs",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(123), To: aws.Int32(123)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: false,
+		},
+		{
+			name: "UDP vs TCP with same port",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: false,
+		},
+		{
+			name: "ICMP all types matches",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(-1), Type: aws.Int32(-1)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(-1), Type: aws.Int32(-1)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: true,
+		},
+		{
+			name: "ICMP echo request matches",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(8)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(8)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: true,
+		},
+		{
+			name: "ICMP all types vs echo request",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(-1), Type: aws.Int32(-1)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(8)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: false,
+		},
+		{
+			name: "ICMP echo request vs echo reply",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(8)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(0)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: false,
+		},
+		{
+			name: "ICMP vs no ICMP settings",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(-1), Type: aws.Int32(-1)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: false,
+		},
+		{
+			name: "Protocol -1 matches",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(true), Protocol: aws.String("-1"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(true), Protocol: aws.String("-1"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: true,
+		},
+		{
+			name: "Protocol -1 vs TCP",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(true), Protocol: aws.String("-1"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(true), Protocol: aws.String("6"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: false,
+		},
+		{
+			name: "IPv6 CIDR matches",
+			expected: types.NetworkAclEntry{
+				Ipv6CidrBlock: aws.String("::/0"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(443), To: aws.Int32(443)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(130),
+			},
+			actual: types.NetworkAclEntry{
+				Ipv6CidrBlock: aws.String("::/0"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(443), To: aws.Int32(443)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(130),
+			},
+			want: true,
+		},
+		{
+			name: "IPv6 CIDR vs IPv4 CIDR",
+			expected: types.NetworkAclEntry{
+				Ipv6CidrBlock: aws.String("::/0"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(443), To: aws.Int32(443)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(130),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(443), To: aws.Int32(443)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(130),
+			},
+			want: false,
+		},
+		{
+			name: "Ingress vs egress",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(true), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: false,
+		},
+		{
+			name: "Allow vs deny",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionDeny, RuleNumber: aws.Int32(100),
+			},
+			want: false,
+		},
+		{
+			name: "Port range 0-0 vs single port 0",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(0)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(0)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: true,
+		},
+		{
+			name: "Port range 0-0 vs 0-1",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(0)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(1)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: false,
+		},
+		{
+			name: "Port range 0-0 vs no port range",
+			expected: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(0), To: aws.Int32(0)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareNaclEntries(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("CompareNaclEntries() = %v, want %v", got, tt.want)
+			}
+			// The comparison should be symmetrical
+			if got := CompareNaclEntries(tt.actual, tt.expected); got != tt.want {
+				t.Errorf("CompareNaclEntries() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaclResourceToNaclEntry_MatchesDescribedEntries(t *testing.T) {
+	// The template side of the comparison is built by NaclResourceToNaclEntry,
+	// so check its output against entries as DescribeNetworkAcls returns them.
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		actual     types.NetworkAclEntry
+		want       bool
+	}{
+		{
+			name: "TCP port range",
+			properties: map[string]interface{}{
+				"CidrBlock": "10.0.0.0/16", "Egress": false, "Protocol": float64(6),
+				"PortRange":  map[string]interface{}{"From": float64(1024), "To": float64(65535)},
+				"RuleAction": "allow", "RuleNumber": float64(100),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("6"),
+				PortRange:  &types.PortRange{From: aws.Int32(1024), To: aws.Int32(65535)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(100),
+			},
+			want: true,
+		},
+		{
+			name: "UDP single port as strings",
+			properties: map[string]interface{}{
+				"CidrBlock": "10.0.0.0/16", "Egress": false, "Protocol": "17",
+				"PortRange":  map[string]interface{}{"From": "53", "To": "53"},
+				"RuleAction": "allow", "RuleNumber": float64(110),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("10.0.0.0/16"), Egress: aws.Bool(false), Protocol: aws.String("17"),
+				PortRange:  &types.PortRange{From: aws.Int32(53), To: aws.Int32(53)},
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(110),
+			},
+			want: true,
+		},
+		{
+			name: "ICMP echo request",
+			properties: map[string]interface{}{
+				"CidrBlock": "0.0.0.0/0", "Egress": false, "Protocol": float64(1),
+				"Icmp":       map[string]interface{}{"Code": float64(0), "Type": float64(8)},
+				"RuleAction": "allow", "RuleNumber": float64(120),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(false), Protocol: aws.String("1"),
+				IcmpTypeCode: &types.IcmpTypeCode{Code: aws.Int32(0), Type: aws.Int32(8)},
+				RuleAction:   types.RuleActionAllow, RuleNumber: aws.Int32(120),
+			},
+			want: true,
+		},
+		{
+			name: "Deny all egress against allow",
+			properties: map[string]interface{}{
+				"CidrBlock": "0.0.0.0/0", "Egress": true, "Protocol": float64(-1),
+				"RuleAction": "deny", "RuleNumber": float64(200),
+			},
+			actual: types.NetworkAclEntry{
+				CidrBlock: aws.String("0.0.0.0/0"), Egress: aws.Bool(true), Protocol: aws.String("-1"),
+				RuleAction: types.RuleActionAllow, RuleNumber: aws.Int32(200),
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := NaclResourceToNaclEntry(CfnTemplateResource{Type: "AWS::EC2::NetworkAclEntry", Properties: tt.properties}, nil)
+			if got := CompareNaclEntries(expected, tt.actual); got != tt.want {
+				t.Errorf("CompareNaclEntries(NaclResourceToNaclEntry()) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// PhysicalResource is a resource that exists in the account, regardless of
+// whether it's managed by CloudFormation
+type PhysicalResource struct {
+	Type string
+	ID   string
+	Name string
+}
+
+type physicalResourceLister func(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error)
+
+// physicalResourceListers contains the resource types that can be checked for
+// orphans. Resources that AWS creates by default (such as the default VPC) are
+// not returned as these can't be managed by CloudFormation.
+var physicalResourceListers = map[string]physicalResourceLister{
+	"AWS::EC2::VPC":           listVpcs,
+	"AWS::EC2::Subnet":        listSubnets,
+	"AWS::EC2::SecurityGroup": listSecurityGroups,
+	"AWS::EC2::RouteTable":    listRouteTables,
+	"AWS::EC2::NetworkAcl":    listNetworkAcls,
+}
+
+// GetSupportedOrphanTypes returns the resource types that can be checked for orphans
+func GetSupportedOrphanTypes() []string {
+	result := make([]string, 0, len(physicalResourceListers))
+	for resourceType := range physicalResourceListers {
+		result = append(result, resourceType)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// GetManagedResources returns the resources of all stacks in the region. Both
+// the stacks and their resources are retrieved page by page, so resources in
+// large accounts or stacks aren't missed.
+func GetManagedResources(svc CloudFormationStacksAndResourcesAPI) ([]CfnResource, error) {
+	allstacks := ""
+	stacks, err := GetCfnStacks(&allstacks, svc)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]CfnResource, 0)
+	for _, stack := range stacks {
+		resources, err := ListStackResourceSummaries(stack.Id, svc)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			result = append(result, CfnResource{
+				StackName:  stack.Name,
+				Type:       aws.ToString(resource.ResourceType),
+				ResourceID: aws.ToString(resource.PhysicalResourceId),
+				LogicalID:  aws.ToString(resource.LogicalResourceId),
+				Status:     string(resource.ResourceStatus),
+			})
+		}
+	}
+	return result, nil
+}
+
+// ListPhysicalResources returns all resources of the provided type in the region
+func ListPhysicalResources(ctx context.Context, resourceType string, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	lister, ok := physicalResourceListers[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("resource type %v is not supported, supported types are %v", resourceType, strings.Join(GetSupportedOrphanTypes(), ", "))
+	}
+	return lister(ctx, svc)
+}
+
+// FindUnmanagedResources returns the physical resources that aren't managed by
+// any of the provided CloudFormation resources. If a wildcard pattern is
+// provided, only resources where the ID or Name matches it are returned.
+func FindUnmanagedResources(managed []CfnResource, physical []PhysicalResource, pattern string) []PhysicalResource {
+	managedIDs := make(map[string]bool)
+	for _, resource := range managed {
+		managedIDs[resource.ResourceID] = true
+	}
+	var patternRegex *regexp.Regexp
+	if pattern != "" {
+		patternRegex = regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$")
+	}
+	result := make([]PhysicalResource, 0)
+	for _, resource := range physical {
+		if managedIDs[resource.ID] {
+			continue
+		}
+		if patternRegex != nil && !patternRegex.MatchString(resource.ID) && !patternRegex.MatchString(resource.Name) {
+			continue
+		}
+		result = append(result, resource)
+	}
+	return result
+}
+
+func nameFromTags(tags []types.Tag) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == "Name" {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+func listVpcs(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	result := make([]PhysicalResource, 0)
+	paginator := ec2.NewDescribeVpcsPaginator(svc, &ec2.DescribeVpcsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range page.Vpcs {
+			if aws.ToBool(vpc.IsDefault) {
+				continue
+			}
+			result = append(result, PhysicalResource{Type: "AWS::EC2::VPC", ID: aws.ToString(vpc.VpcId), Name: nameFromTags(vpc.Tags)})
+		}
+	}
+	return result, nil
+}
+
+func listSubnets(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	result := make([]PhysicalResource, 0)
+	paginator := ec2.NewDescribeSubnetsPaginator(svc, &ec2.DescribeSubnetsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range page.Subnets {
+			if aws.ToBool(subnet.DefaultForAz) {
+				continue
+			}
+			result = append(result, PhysicalResource{Type: "AWS::EC2::Subnet", ID: aws.ToString(subnet.SubnetId), Name: nameFromTags(subnet.Tags)})
+		}
+	}
+	return result, nil
+}
+
+func listSecurityGroups(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	result := make([]PhysicalResource, 0)
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(svc, &ec2.DescribeSecurityGroupsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range page.SecurityGroups {
+			if aws.ToString(group.GroupName) == "default" {
+				continue
+			}
+			name := nameFromTags(group.Tags)
+			if name == "" {
+				name = aws.ToString(group.GroupName)
+			}
+			result = append(result, PhysicalResource{Type: "AWS::EC2::SecurityGroup", ID: aws.ToString(group.GroupId), Name: name})
+		}
+	}
+	return result, nil
+}
+
+func listRouteTables(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	result := make([]PhysicalResource, 0)
+	paginator := ec2.NewDescribeRouteTablesPaginator(svc, &ec2.DescribeRouteTablesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, routetable := range page.RouteTables {
+			isMain := false
+			for _, association := range routetable.Associations {
+				if aws.ToBool(association.Main) {
+					isMain = true
+				}
+			}
+			if isMain {
+				continue
+			}
+			result = append(result, PhysicalResource{Type: "AWS::EC2::RouteTable", ID: aws.ToString(routetable.RouteTableId), Name: nameFromTags(routetable.Tags)})
+		}
+	}
+	return result, nil
+}
+
+func listNetworkAcls(ctx context.Context, svc EC2DescribeNetworkResourcesAPI) ([]PhysicalResource, error) {
+	result := make([]PhysicalResource, 0)
+	paginator := ec2.NewDescribeNetworkAclsPaginator(svc, &ec2.DescribeNetworkAclsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, nacl := range page.NetworkAcls {
+			if aws.ToBool(nacl.IsDefault) {
+				continue
+			}
+			result = append(result, PhysicalResource{Type: "AWS::EC2::NetworkAcl", ID: aws.ToString(nacl.NetworkAclId), Name: nameFromTags(nacl.Tags)})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package lib
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type mockEC2DescribeNetworkResourcesAPI struct {
+	EC2DescribeNetworkResourcesAPI
+	vpcs []types.Vpc
+}
+
+func (m mockEC2DescribeNetworkResourcesAPI) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: m.vpcs}, nil
+}
+
+func TestListPhysicalResources(t *testing.T) {
+	svc := mockEC2DescribeNetworkResourcesAPI{vpcs: []types.Vpc{
+		{VpcId: aws.String("vpc-default"), IsDefault: aws.Bool(true)},
+		{VpcId: aws.String("vpc-123"), IsDefault: aws.Bool(false), Tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String("main")}}},
+	}}
+	got, err := ListPhysicalResources(context.Background(), "AWS::EC2::VPC", svc)
+	if err != nil {
+		t.Fatalf("ListPhysicalResources() error = %v", err)
+	}
+	want := []PhysicalResource{{Type: "AWS::EC2::VPC", ID: "vpc-123", Name: "main"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPhysicalResources() = %v, want %v", got, want)
+	}
+	if _, err := ListPhysicalResources(context.Background(), "AWS::S3::Bucket", svc); err == nil {
+		t.Errorf("ListPhysicalResources() expected an error for an unsupported type")
+	}
+}
+
+func TestFindUnmanagedResources(t *testing.T) {
+	managed := []CfnResource{
+		{StackName: "network", Type: "AWS::EC2::VPC", ResourceID: "vpc-managed"},
+	}
+	physical := []PhysicalResource{
+		{Type: "AWS::EC2::VPC", ID: "vpc-managed", Name: "managed"},
+		{Type: "AWS::EC2::VPC", ID: "vpc-111", Name: "legacy-dev"},
+		{Type: "AWS::EC2::VPC", ID: "vpc-222", Name: "legacy-prod"},
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"No pattern", "", []string{"vpc-111", "vpc-222"}},
+		{"Pattern on ID", "vpc-1*", []string{"vpc-111"}},
+		{"Pattern on Name", "*prod", []string{"vpc-222"}},
+		{"No match", "subnet-*", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, resource := range FindUnmanagedResources(managed, physical, tt.pattern) {
+				got = append(got, resource.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindUnmanagedResources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockPagedStacksAndResources returns one stack and one resource per page
+type mockPagedStacksAndResources struct {
+	CloudFormationListImportsAPI
+	stacks    []cfntypes.Stack
+	resources map[string][]cfntypes.StackResourceSummary
+}
+
+func (m mockPagedStacksAndResources) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	index := pageIndex(params.NextToken)
+	output := &cloudformation.DescribeStacksOutput{Stacks: m.stacks[index : index+1]}
+	if index+1 < len(m.stacks) {
+		output.NextToken = aws.String(string(rune('0' + index + 1)))
+	}
+	return output, nil
+}
+
+func (m mockPagedStacksAndResources) ListStackResources(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+	resources := m.resources[aws.ToString(params.StackName)]
+	index := pageIndex(params.NextToken)
+	output := &cloudformation.ListStackResourcesOutput{StackResourceSummaries: resources[index : index+1]}
+	if index+1 < len(resources) {
+		output.NextToken = aws.String(string(rune('0' + index + 1)))
+	}
+	return output, nil
+}
+
+func pageIndex(token *string) int {
+	if token == nil {
+		return 0
+	}
+	return int(aws.ToString(token)[0] - '0')
+}
+
+func TestGetManagedResources(t *testing.T) {
+	svc := mockPagedStacksAndResources{
+		stacks: []cfntypes.Stack{
+			{StackName: aws.String("network"), StackId: aws.String("network-id")},
+			{StackName: aws.String("app"), StackId: aws.String("app-id")},
+		},
+		resources: map[string][]cfntypes.StackResourceSummary{
+			"network-id": {
+				{LogicalResourceId: aws.String("Vpc"), PhysicalResourceId: aws.String("vpc-123"), ResourceType: aws.String("AWS::EC2::VPC")},
+				{LogicalResourceId: aws.String("Subnet"), PhysicalResourceId: aws.String("subnet-123"), ResourceType: aws.String("AWS::EC2::Subnet")},
+			},
+			"app-id": {
+				{LogicalResourceId: aws.String("AppSg"), PhysicalResourceId: aws.String("sg-123"), ResourceType: aws.String("AWS::EC2::SecurityGroup")},
+			},
+		},
+	}
+	got, err := GetManagedResources(svc)
+	if err != nil {
+		t.Fatalf("GetManagedResources() error = %v", err)
+	}
+	ids := make(map[string]string)
+	for _, resource := range got {
+		ids[resource.ResourceID] = resource.StackName
+	}
+	want := map[string]string{"vpc-123": "network", "subnet-123": "network", "sg-123": "app"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetManagedResources() = %v, want %v", ids, want)
+	}
+}
@@ -3,8 +3,10 @@ package lib
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -105,7 +107,7 @@ func getOutputsForStack(stack types.Stack, stackfilter string, exportfilter stri
 	return result
 }
 
-func (output *CfnOutput) FillImports(svc *cloudformation.Client) {
+func (output *CfnOutput) FillImports(svc CloudFormationListImportsAPI) {
 	if output.ExportName == "" {
 		return
 	}
@@ -120,3 +122,100 @@ func (output *CfnOutput) FillImports(svc *cloudformation.Client) {
 		output.ImportedBy = imports.Imports
 	}
 }
+
+// GetStackOutputValue returns the value of the output with the provided key
+func GetStackOutputValue(stack types.Stack, key string) (string, error) {
+	for _, output := range stack.Outputs {
+		if aws.ToString(output.OutputKey) == key {
+			return aws.ToString(output.OutputValue), nil
+		}
+	}
+	return "", fmt.Errorf("stack %v doesn't have an output named %v", aws.ToString(stack.StackName), key)
+}
+
+// GetImportedOutputs returns the exported outputs of the stack that are imported by other stacks
+func GetImportedOutputs(stack types.Stack, svc CloudFormationListImportsAPI) []CfnOutput {
+	result := make([]CfnOutput, 0)
+	for _, output := range getOutputsForStack(stack, "", "", true) {
+		output.FillImports(svc)
+		if output.Imported {
+			result = append(result, output)
+		}
+	}
+	return result
+}
+
+// GetExportImporters returns the names of the stacks that import the export.
+// An export that isn't imported by any stack results in an empty list.
+func GetExportImporters(exportName string, svc CloudFormationListImportsAPI) ([]string, error) {
+	importers := make([]string, 0)
+	paginator := cloudformation.NewListImportsPaginator(svc, &cloudformation.ListImportsInput{ExportName: &exportName})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			// ListImports returns an error instead of an empty list for exports that aren't imported
+			if strings.Contains(err.Error(), "is not imported by any stack") {
+				return importers, nil
+			}
+			return nil, err
+		}
+		importers = append(importers, page.Imports...)
+	}
+	return importers, nil
+}
+
+// GetExportsByName returns all exports in the account and region, keyed by
+// their name
+func GetExportsByName(svc CloudFormationListExportsAPI) (map[string]types.Export, error) {
+	exports := make(map[string]types.Export)
+	paginator := cloudformation.NewListExportsPaginator(svc, &cloudformation.ListExportsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, export := range page.Exports {
+			exports[aws.ToString(export.Name)] = export
+		}
+	}
+	return exports, nil
+}
+
+// StackImport is an export that is imported by a stack
+type StackImport struct {
+	ExportName     string
+	ExportingStack string
+	Value          string
+	// Found is false when the export doesn't exist (anymore)
+	Found bool
+}
+
+// GetStackImports returns the exports imported by the template, sorted by
+// name, together with the stack exporting them and their current value. The
+// template needs to be parsed with the same exports.
+func GetStackImports(template CfnTemplateBody, exports map[string]types.Export) []StackImport {
+	result := make([]StackImport, 0, len(template.ImportedExports))
+	for _, exportName := range template.ImportedExports {
+		imported := StackImport{ExportName: exportName}
+		if export, ok := exports[exportName]; ok {
+			imported.Found = true
+			imported.Value = aws.ToString(export.Value)
+			imported.ExportingStack = stackNameFromId(aws.ToString(export.ExportingStackId))
+		}
+		result = append(result, imported)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ExportName < result[j].ExportName
+	})
+	return result
+}
+
+// stackNameFromId returns the name of the stack from its ID, which has the
+// format arn:aws:cloudformation:region:account:stack/name/uuid
+func stackNameFromId(stackId string) string {
+	parts := strings.Split(stackId, "/")
+	if len(parts) < 3 {
+		return stackId
+	}
+	return parts[1]
+}
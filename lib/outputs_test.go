@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestGetStackOutputValue(t *testing.T) {
+	stack := types.Stack{
+		StackName: aws.String("my-stack"),
+		Outputs: []types.Output{
+			{OutputKey: aws.String("VpcId"), OutputValue: aws.String("vpc-123")},
+			{OutputKey: aws.String("BucketName"), OutputValue: aws.String("my-bucket")},
+		},
+	}
+	tests := map[string]struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		"existing output": {key: "BucketName", want: "my-bucket"},
+		"missing output":  {key: "SubnetId", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := GetStackOutputValue(stack, tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetStackOutputValue() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetStackOutputValue() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetImportedOutputs(t *testing.T) {
+	svc := testutil.NewScenarioBuilder().
+		WithImport("network", "app", "network-VpcId", "vpc-123").
+		WithImport("network", "database", "network-VpcId", "vpc-123").
+		WithStack("network", func(stack *testutil.StackBuilder) {
+			stack.WithExport("SubnetIds", "network-SubnetIds", "subnet-1,subnet-2")
+			stack.WithOutput("Region", "us-east-1")
+		}).
+		Build()
+	imported := GetImportedOutputs(svc.Stacks[0], svc)
+	if len(imported) != 1 {
+		t.Fatalf("GetImportedOutputs() returned %d outputs, want 1", len(imported))
+	}
+	if imported[0].ExportName != "network-VpcId" {
+		t.Errorf("GetImportedOutputs() ExportName = %v, want network-VpcId", imported[0].ExportName)
+	}
+	if !reflect.DeepEqual(imported[0].ImportedBy, []string{"app", "database"}) {
+		t.Errorf("GetImportedOutputs() ImportedBy = %v, want [app database]", imported[0].ImportedBy)
+	}
+}
+
+func TestGetStackImports(t *testing.T) {
+	template := `
+Parameters:
+  Environment:
+    Type: String
+    Default: prod
+Resources:
+  Instance:
+    Type: AWS::EC2::Instance
+    Properties:
+      SubnetId: !ImportValue network-SubnetId
+      SecurityGroupIds:
+        - Fn::ImportValue: !Sub "${Environment}-SecurityGroup"
+  Route:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !ImportValue network-RouteTable
+      TransitGatewayId: !ImportValue network-TgwId
+  OtherInstance:
+    Type: AWS::EC2::Instance
+    Properties:
+      SubnetId: !ImportValue network-SubnetId
+`
+	svc := &MockExportsClient{PageSize: 2, Exports: []types.Export{
+		{Name: aws.String("network-SubnetId"), Value: aws.String("subnet-123"), ExportingStackId: aws.String("arn:aws:cloudformation:ap-southeast-2:123456789012:stack/network/abc-123")},
+		{Name: aws.String("network-RouteTable"), Value: aws.String("rtb-123"), ExportingStackId: aws.String("arn:aws:cloudformation:ap-southeast-2:123456789012:stack/network/abc-123")},
+		{Name: aws.String("prod-SecurityGroup"), Value: aws.String("sg-123"), ExportingStackId: aws.String("arn:aws:cloudformation:ap-southeast-2:123456789012:stack/security/def-456")},
+	}}
+	exports, err := GetExportsByName(svc)
+	if err != nil {
+		t.Fatalf("GetExportsByName() error = %v", err)
+	}
+	parsed := ParseTemplateString(template, &map[string]interface{}{"Environment": "prod"}, exports)
+	got := GetStackImports(parsed, exports)
+	want := []StackImport{
+		{ExportName: "network-RouteTable", ExportingStack: "network", Value: "rtb-123", Found: true},
+		{ExportName: "network-SubnetId", ExportingStack: "network", Value: "subnet-123", Found: true},
+		{ExportName: "network-TgwId"},
+		{ExportName: "prod-SecurityGroup", ExportingStack: "security", Value: "sg-123", Found: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetStackImports() = %+v, want %+v", got, want)
+	}
+	if svc.Calls != 2 {
+		t.Errorf("ListExports was called %v times, want 2", svc.Calls)
+	}
+
+	noImports := ParseTemplateString("Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n", nil, exports)
+	if got := GetStackImports(noImports, exports); len(got) != 0 {
+		t.Errorf("GetStackImports() without imports = %v, want nothing", got)
+	}
+}
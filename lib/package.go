@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PackageSettings holds where the local artifacts of a template are uploaded to
+type PackageSettings struct {
+	// Bucket is the S3 bucket the artifacts are uploaded to
+	Bucket string
+	// Prefix is prepended to the key of every uploaded artifact
+	Prefix string
+	// Region is the region of the bucket, used for the uploads and the URLs of
+	// nested templates
+	Region string
+}
+
+// PackagedArtifact describes a local file that was uploaded to S3
+type PackagedArtifact struct {
+	// LogicalID is the logical ID of the resource referencing the file
+	LogicalID string
+	// LocalPath is the path of the file or directory that was uploaded
+	LocalPath string
+	// S3Key is the key of the uploaded object
+	S3Key string
+}
+
+// PackageTemplate uploads the local files referenced by the template to S3 and
+// returns the template rewritten to use the uploaded objects, similar to aws
+// cloudformation package. This covers the code of AWS::Lambda::Function
+// resources and the TemplateURL of AWS::CloudFormation::Stack resources. Nested
+// templates are packaged themselves before they're uploaded. Relative paths are
+// resolved from templateDir. If nothing needs to be uploaded, the template is
+// returned unchanged, otherwise it's returned as JSON.
+func PackageTemplate(template string, templateDir string, settings PackageSettings, svc S3PutObjectAPI) (string, []PackagedArtifact, error) {
+	parsed, err := NormalizeTemplate(template)
+	if err != nil {
+		return "", nil, err
+	}
+	resources, _ := parsed["Resources"].(map[string]interface{})
+	logicalIDs := make([]string, 0, len(resources))
+	for logicalID := range resources {
+		logicalIDs = append(logicalIDs, logicalID)
+	}
+	sort.Strings(logicalIDs)
+	artifacts := make([]PackagedArtifact, 0)
+	for _, logicalID := range logicalIDs {
+		resource, ok := resources[logicalID].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		properties, ok := resource["Properties"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var artifact *PackagedArtifact
+		switch resource["Type"] {
+		case "AWS::Lambda::Function":
+			artifact, err = packageLambdaCode(properties, templateDir, settings, svc)
+		case "AWS::CloudFormation::Stack":
+			artifact, err = packageNestedStack(properties, templateDir, settings, svc)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to package %v: %w", logicalID, err)
+		}
+		if artifact != nil {
+			artifact.LogicalID = logicalID
+			artifacts = append(artifacts, *artifact)
+		}
+	}
+	if len(artifacts) == 0 {
+		return template, artifacts, nil
+	}
+	result, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	return string(result), artifacts, nil
+}
+
+// packageLambdaCode uploads the code of a Lambda function when its Code, or the
+// ZipFile or S3Key within it, points to a local file or directory. The Code
+// property is then replaced by the S3Bucket and S3Key of the uploaded object.
+func packageLambdaCode(properties map[string]interface{}, templateDir string, settings PackageSettings, svc S3PutObjectAPI) (*PackagedArtifact, error) {
+	var localPath string
+	switch code := properties["Code"].(type) {
+	case string:
+		localPath = code
+	case map[string]interface{}:
+		if zipFile, ok := code["ZipFile"].(string); ok {
+			localPath = zipFile
+		} else if key, ok := code["S3Key"].(string); ok {
+			localPath = key
+		}
+	}
+	path, ok := localArtifactPath(localPath, templateDir)
+	if !ok {
+		return nil, nil
+	}
+	contents, extension, err := readArtifact(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := uploadArtifact(contents, extension, settings, svc)
+	if err != nil {
+		return nil, err
+	}
+	properties["Code"] = map[string]interface{}{
+		"S3Bucket": settings.Bucket,
+		"S3Key":    key,
+	}
+	return &PackagedArtifact{LocalPath: path, S3Key: key}, nil
+}
+
+// packageNestedStack packages and uploads the template of a nested stack when
+// its TemplateURL points to a local file, and replaces it with the S3 URL
+func packageNestedStack(properties map[string]interface{}, templateDir string, settings PackageSettings, svc S3PutObjectAPI) (*PackagedArtifact, error) {
+	templateURL, _ := properties["TemplateURL"].(string)
+	path, ok := localArtifactPath(templateURL, templateDir)
+	if !ok {
+		return nil, nil
+	}
+	nested, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	packaged, _, err := PackageTemplate(string(nested), filepath.Dir(path), settings, svc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := uploadArtifact([]byte(packaged), filepath.Ext(path), settings, svc)
+	if err != nil {
+		return nil, err
+	}
+	properties["TemplateURL"] = S3ObjectURL(settings.Bucket, settings.Region, key)
+	return &PackagedArtifact{LocalPath: path, S3Key: key}, nil
+}
+
+// localArtifactPath returns the path of the local file or directory the value
+// refers to. Values that are URLs, inline code, or that don't exist locally
+// aren't artifacts.
+func localArtifactPath(value string, templateDir string) (string, bool) {
+	if value == "" || strings.Contains(value, "\n") || strings.Contains(value, "://") {
+		return "", false
+	}
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(templateDir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// zipEpoch is used as the modification time of all files in generated zip
+// files, so an unchanged directory always results in the same zip file
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// readArtifact returns the contents and extension of the file, or of a zip
+// file containing the directory if the path is a directory
+func readArtifact(path string) ([]byte, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		contents, err := os.ReadFile(path)
+		return contents, filepath.Ext(path), err
+	}
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		header.Method = zip.Deflate
+		header.Modified = zipEpoch
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		source, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		_, err = io.Copy(entry, source)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buffer.Bytes(), ".zip", nil
+}
+
+// uploadArtifact uploads the contents to S3, using the hash of the contents as
+// the key so unchanged artifacts don't change the template
+func uploadArtifact(contents []byte, extension string, settings PackageSettings, svc S3PutObjectAPI) (string, error) {
+	if extension == "" {
+		extension = ".zip"
+	}
+	hash := sha256.Sum256(contents)
+	key := settings.Prefix + hex.EncodeToString(hash[:]) + extension
+	_, err := svc.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(settings.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(contents),
+	}, func(o *s3.Options) {
+		if settings.Region != "" {
+			o.Region = settings.Region
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to upload %v to bucket %v: %w", key, settings.Bucket, err)
+	}
+	return key, nil
+}
+
+// S3ObjectURL returns the virtual-hosted HTTPS URL of an object in a bucket in
+// the region, using the DNS suffix of the region's partition
+func S3ObjectURL(bucket string, region string, key string) string {
+	return fmt.Sprintf("https://%v.s3.%v.%v/%v", bucket, region, s3DNSSuffix(region), key)
+}
+
+// s3DNSSuffix returns the DNS suffix of the partition the region belongs to
+func s3DNSSuffix(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "amazonaws.com.cn"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "sc2s.sgov.gov"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "c2s.ic.gov"
+	}
+	return "amazonaws.com"
+}
+
+// GetBucketRegion returns the region the S3 bucket is in
+func GetBucketRegion(bucket string, svc S3GetBucketLocationAPI) (string, error) {
+	output, err := svc.GetBucketLocation(context.TODO(), &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return "", fmt.Errorf("unable to find the region of bucket %v: %w", bucket, err)
+	}
+	switch output.LocationConstraint {
+	case "":
+		// Buckets in us-east-1 don't have a location constraint
+		return "us-east-1", nil
+	case s3types.BucketLocationConstraintEu:
+		return "eu-west-1", nil
+	}
+	return string(output.LocationConstraint), nil
+}
@@ -0,0 +1,254 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestPackageTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"build/handler.zip": "zipped code",
+		"src/index.js":      "exports.handler = async () => {}",
+		"nested/vpc.yaml":   "Resources:\n  Vpc:\n    Type: AWS::EC2::VPC\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	settings := PackageSettings{Bucket: "artifacts", Prefix: "fog/", Region: "ap-southeast-2"}
+	tests := map[string]struct {
+		template      string
+		wantArtifacts []string
+		wantCode      map[string]interface{}
+		wantURL       bool
+		unchanged     bool
+	}{
+		"code as path": {
+			template:      "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code: build/handler.zip\n",
+			wantArtifacts: []string{"Function"},
+			wantCode:      map[string]interface{}{"S3Bucket": "artifacts", "S3Key": "fog/" + hashOf("zipped code") + ".zip"},
+		},
+		"zip file path": {
+			template:      "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code:\n        ZipFile: build/handler.zip\n",
+			wantArtifacts: []string{"Function"},
+			wantCode:      map[string]interface{}{"S3Bucket": "artifacts", "S3Key": "fog/" + hashOf("zipped code") + ".zip"},
+		},
+		"s3 key path": {
+			template:      "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code:\n        S3Key: build/handler.zip\n",
+			wantArtifacts: []string{"Function"},
+			wantCode:      map[string]interface{}{"S3Bucket": "artifacts", "S3Key": "fog/" + hashOf("zipped code") + ".zip"},
+		},
+		"directory": {
+			template:      "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code: src\n",
+			wantArtifacts: []string{"Function"},
+		},
+		"inline code": {
+			template:  "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code:\n        ZipFile: |\n          exports.handler = async () => {}\n",
+			unchanged: true,
+		},
+		"code in s3": {
+			template:  "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code:\n        S3Bucket: other-bucket\n        S3Key: code/handler.zip\n",
+			unchanged: true,
+		},
+		"nested stack": {
+			template:      "Resources:\n  Network:\n    Type: AWS::CloudFormation::Stack\n    Properties:\n      TemplateURL: nested/vpc.yaml\n",
+			wantArtifacts: []string{"Network"},
+			wantURL:       true,
+		},
+		"remote nested stack": {
+			template:  "Resources:\n  Network:\n    Type: AWS::CloudFormation::Stack\n    Properties:\n      TemplateURL: https://bucket.s3.amazonaws.com/vpc.yaml\n",
+			unchanged: true,
+		},
+		"short form functions": {
+			template:  "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Role: !GetAtt Role.Arn\n      Code:\n        S3Bucket: !Ref Bucket\n        S3Key: handler.zip\n",
+			unchanged: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := testutil.NewMockS3Client()
+			packaged, artifacts, err := PackageTemplate(tc.template, dir, settings, svc)
+			if err != nil {
+				t.Fatalf("PackageTemplate() error = %v", err)
+			}
+			if tc.unchanged {
+				if packaged != tc.template || len(artifacts) != 0 || len(svc.Objects) != 0 {
+					t.Errorf("PackageTemplate() changed the template or uploaded %v artifacts", len(svc.Objects))
+				}
+				return
+			}
+			got := make([]string, 0, len(artifacts))
+			for _, artifact := range artifacts {
+				got = append(got, artifact.LogicalID)
+				if _, ok := svc.Objects["artifacts/"+artifact.S3Key]; !ok {
+					t.Errorf("artifact %v wasn't uploaded to %v", artifact.LocalPath, artifact.S3Key)
+				}
+				if !strings.HasPrefix(artifact.S3Key, "fog/") {
+					t.Errorf("artifact key %v doesn't use the prefix", artifact.S3Key)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.wantArtifacts) {
+				t.Errorf("PackageTemplate() artifacts = %v, want %v", got, tc.wantArtifacts)
+			}
+			parsed, err := NormalizeTemplate(packaged)
+			if err != nil {
+				t.Fatalf("packaged template can't be parsed: %v", err)
+			}
+			for _, resource := range parsed["Resources"].(map[string]interface{}) {
+				properties := resource.(map[string]interface{})["Properties"].(map[string]interface{})
+				if tc.wantCode != nil && !reflect.DeepEqual(properties["Code"], tc.wantCode) {
+					t.Errorf("packaged Code = %v, want %v", properties["Code"], tc.wantCode)
+				}
+				if tc.wantURL {
+					url, _ := properties["TemplateURL"].(string)
+					want := "https://artifacts.s3.ap-southeast-2.amazonaws.com/fog/" + hashOf(files["nested/vpc.yaml"]) + ".yaml"
+					if url != want {
+						t.Errorf("packaged TemplateURL = %v, want %v", url, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPackageTemplate_NestedStackCode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "handler.zip"), []byte("zipped code"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code: handler.zip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := testutil.NewMockS3Client()
+	template := "Resources:\n  App:\n    Type: AWS::CloudFormation::Stack\n    Properties:\n      TemplateURL: app.yaml\n"
+	_, artifacts, err := PackageTemplate(template, dir, PackageSettings{Bucket: "artifacts"}, svc)
+	if err != nil {
+		t.Fatalf("PackageTemplate() error = %v", err)
+	}
+	if len(svc.Objects) != 2 {
+		t.Fatalf("PackageTemplate() uploaded %v objects, want the code and the nested template", len(svc.Objects))
+	}
+	nested, err := NormalizeTemplate(string(svc.Objects["artifacts/"+artifacts[0].S3Key]))
+	if err != nil {
+		t.Fatalf("uploaded nested template can't be parsed: %v", err)
+	}
+	code := nested["Resources"].(map[string]interface{})["Function"].(map[string]interface{})["Properties"].(map[string]interface{})["Code"]
+	if want := map[string]interface{}{"S3Bucket": "artifacts", "S3Key": hashOf("zipped code") + ".zip"}; !reflect.DeepEqual(code, want) {
+		t.Errorf("nested template Code = %v, want %v", code, want)
+	}
+}
+
+func TestPackageTemplate_UploadError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "handler.zip"), []byte("zipped code"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := testutil.NewMockS3Client()
+	svc.PutObjectErr = errors.New("AccessDenied")
+	template := "Resources:\n  Function:\n    Type: AWS::Lambda::Function\n    Properties:\n      Code: handler.zip\n"
+	if _, _, err := PackageTemplate(template, dir, PackageSettings{Bucket: "artifacts"}, svc); err == nil {
+		t.Error("PackageTemplate() should return an error when the upload fails")
+	}
+}
+
+func TestReadArtifact_DirectoryIsStable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.js"), []byte("exports.handler = async () => {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, extension, err := readArtifact(dir)
+	if err != nil {
+		t.Fatalf("readArtifact() error = %v", err)
+	}
+	if extension != ".zip" {
+		t.Errorf("readArtifact() extension = %v, want .zip", extension)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "index.js"), zipEpoch, zipEpoch.AddDate(1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := readArtifact(dir)
+	if err != nil {
+		t.Fatalf("readArtifact() error = %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("readArtifact() should return the same zip file for an unchanged directory")
+	}
+}
+
+func hashOf(contents string) string {
+	hash := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	tests := map[string]struct {
+		region string
+		want   string
+	}{
+		"commercial": {region: "us-east-1", want: "https://artifacts.s3.us-east-1.amazonaws.com/fog/vpc.yaml"},
+		"opt-in":     {region: "ap-southeast-4", want: "https://artifacts.s3.ap-southeast-4.amazonaws.com/fog/vpc.yaml"},
+		"china":      {region: "cn-north-1", want: "https://artifacts.s3.cn-north-1.amazonaws.com.cn/fog/vpc.yaml"},
+		"govcloud":   {region: "us-gov-west-1", want: "https://artifacts.s3.us-gov-west-1.amazonaws.com/fog/vpc.yaml"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := S3ObjectURL("artifacts", tc.region, "fog/vpc.yaml"); got != tc.want {
+				t.Errorf("S3ObjectURL() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+type mockS3GetBucketLocationAPI func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+
+func (m mockS3GetBucketLocationAPI) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetBucketRegion(t *testing.T) {
+	tests := map[string]struct {
+		constraint s3types.BucketLocationConstraint
+		err        error
+		want       string
+		wantErr    bool
+	}{
+		"us-east-1":     {constraint: "", want: "us-east-1"},
+		"legacy EU":     {constraint: s3types.BucketLocationConstraintEu, want: "eu-west-1"},
+		"other region":  {constraint: s3types.BucketLocationConstraintApSoutheast2, want: "ap-southeast-2"},
+		"lookup failed": {err: errors.New("access denied"), wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := mockS3GetBucketLocationAPI(func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return &s3.GetBucketLocationOutput{LocationConstraint: tc.constraint}, nil
+			})
+			got, err := GetBucketRegion("artifacts", svc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetBucketRegion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetBucketRegion() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// ParameterViolation is the type of constraint a parameter value doesn't meet
+type ParameterViolation string
+
+const (
+	ParameterViolationUnknown        ParameterViolation = "Unknown"
+	ParameterViolationType           ParameterViolation = "Type"
+	ParameterViolationAllowedValues  ParameterViolation = "AllowedValues"
+	ParameterViolationAllowedPattern ParameterViolation = "AllowedPattern"
+	ParameterViolationMinLength      ParameterViolation = "MinLength"
+	ParameterViolationMaxLength      ParameterViolation = "MaxLength"
+	ParameterViolationMinValue       ParameterViolation = "MinValue"
+	ParameterViolationMaxValue       ParameterViolation = "MaxValue"
+)
+
+// ParameterError describes a parameter value that doesn't meet the constraints of the template
+type ParameterError struct {
+	Parameter string
+	Violation ParameterViolation
+	Message   string
+}
+
+// ValidateParameterConstraints checks the parameter values against the
+// constraints defined for them in the template. Parameters that keep their
+// previous value and parameters with SSM parameter types aren't checked, as
+// their actual values aren't known.
+func ValidateParameterConstraints(params []types.Parameter, template CfnTemplateBody) []ParameterError {
+	result := make([]ParameterError, 0)
+	for _, param := range params {
+		if aws.ToBool(param.UsePreviousValue) {
+			continue
+		}
+		key := aws.ToString(param.ParameterKey)
+		definition, ok := template.Parameters[key]
+		if !ok {
+			result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationUnknown, Message: "The parameter isn't defined in the template"})
+			continue
+		}
+		if strings.HasPrefix(definition.Type, "AWS::SSM::Parameter::Value") {
+			continue
+		}
+		value := aws.ToString(param.ParameterValue)
+		values := []string{value}
+		if definition.Type == "CommaDelimitedList" || strings.HasPrefix(definition.Type, "List<") {
+			values = strings.Split(value, ",")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+		}
+		if definition.Type == "String" {
+			result = append(result, checkLength(key, value, definition)...)
+		}
+		for _, item := range values {
+			if definition.Type == "Number" || definition.Type == "List<Number>" {
+				result = append(result, checkNumber(key, item, definition)...)
+			}
+			result = append(result, checkAllowed(key, item, definition)...)
+		}
+	}
+	return result
+}
+
+// constraintMessage returns the ConstraintDescription of the parameter if it has one, or the default message otherwise
+func constraintMessage(definition CfnTemplateParameter, message string) string {
+	if definition.ConstraintDescription != "" {
+		return fmt.Sprintf("%v (%v)", message, definition.ConstraintDescription)
+	}
+	return message
+}
+
+func checkLength(key string, value string, definition CfnTemplateParameter) []ParameterError {
+	result := make([]ParameterError, 0)
+	if definition.MinLength != nil && len(value) < *definition.MinLength {
+		result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationMinLength, Message: constraintMessage(definition, fmt.Sprintf("The value is %d characters long, but needs to be at least %d", len(value), *definition.MinLength))})
+	}
+	if definition.MaxLength != nil && len(value) > *definition.MaxLength {
+		result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationMaxLength, Message: constraintMessage(definition, fmt.Sprintf("The value is %d characters long, but can be at most %d", len(value), *definition.MaxLength))})
+	}
+	return result
+}
+
+func checkNumber(key string, value string, definition CfnTemplateParameter) []ParameterError {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return []ParameterError{{Parameter: key, Violation: ParameterViolationType, Message: fmt.Sprintf("'%v' isn't a number", value)}}
+	}
+	result := make([]ParameterError, 0)
+	if definition.MinValue != nil && number < *definition.MinValue {
+		result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationMinValue, Message: constraintMessage(definition, fmt.Sprintf("%v is smaller than the minimum of %v", value, *definition.MinValue))})
+	}
+	if definition.MaxValue != nil && number > *definition.MaxValue {
+		result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationMaxValue, Message: constraintMessage(definition, fmt.Sprintf("%v is larger than the maximum of %v", value, *definition.MaxValue))})
+	}
+	return result
+}
+
+func checkAllowed(key string, value string, definition CfnTemplateParameter) []ParameterError {
+	result := make([]ParameterError, 0)
+	if len(definition.AllowedValues) != 0 {
+		allowed := make([]string, 0, len(definition.AllowedValues))
+		for _, allowedValue := range definition.AllowedValues {
+			allowed = append(allowed, fmt.Sprint(allowedValue))
+		}
+		if !stringInSlice(value, allowed) {
+			result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationAllowedValues, Message: constraintMessage(definition, fmt.Sprintf("'%v' isn't one of the allowed values: %v", value, strings.Join(allowed, ", ")))})
+		}
+	}
+	if definition.AllowedPattern != "" {
+		// CloudFormation requires the pattern to match the entire value
+		pattern, err := regexp.Compile("^(?:" + definition.AllowedPattern + ")$")
+		if err == nil && !pattern.MatchString(value) {
+			result = append(result, ParameterError{Parameter: key, Violation: ParameterViolationAllowedPattern, Message: constraintMessage(definition, fmt.Sprintf("'%v' doesn't match the pattern %v", value, definition.AllowedPattern))})
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestValidateParameterConstraints(t *testing.T) {
+	intPointer := func(value int) *int { return &value }
+	floatPointer := func(value float64) *float64 { return &value }
+	template := CfnTemplateBody{Parameters: map[string]CfnTemplateParameter{
+		"Environment":   {Type: "String", AllowedValues: []interface{}{"dev", "prod"}},
+		"BucketName":    {Type: "String", MinLength: intPointer(3), MaxLength: intPointer(10), AllowedPattern: "[a-z0-9-]+", ConstraintDescription: "lowercase letters, numbers, and dashes"},
+		"InstanceCount": {Type: "Number", MinValue: floatPointer(1), MaxValue: floatPointer(5)},
+		"Ports":         {Type: "List<Number>", AllowedValues: []interface{}{80, 443}},
+		"Subnets":       {Type: "CommaDelimitedList", AllowedPattern: "subnet-[0-9a-f]+"},
+		"AmiId":         {Type: "AWS::SSM::Parameter::Value<AWS::EC2::Image::Id>", AllowedPattern: "ami-.*"},
+	}}
+	parameter := func(key, value string) types.Parameter {
+		return types.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value)}
+	}
+	tests := map[string]struct {
+		params []types.Parameter
+		want   []ParameterViolation
+	}{
+		"valid values": {
+			params: []types.Parameter{
+				parameter("Environment", "prod"),
+				parameter("BucketName", "my-bucket"),
+				parameter("InstanceCount", "5"),
+				parameter("Ports", "80, 443"),
+				parameter("Subnets", "subnet-123,subnet-abc"),
+				parameter("AmiId", "/aws/service/ami-amazon-linux-latest"),
+			},
+			want: []ParameterViolation{},
+		},
+		"previous value":  {params: []types.Parameter{{ParameterKey: aws.String("Environment"), UsePreviousValue: aws.Bool(true)}}, want: []ParameterViolation{}},
+		"unknown":         {params: []types.Parameter{parameter("Region", "us-east-1")}, want: []ParameterViolation{ParameterViolationUnknown}},
+		"allowed values":  {params: []types.Parameter{parameter("Environment", "test")}, want: []ParameterViolation{ParameterViolationAllowedValues}},
+		"min length":      {params: []types.Parameter{parameter("BucketName", "ab")}, want: []ParameterViolation{ParameterViolationMinLength}},
+		"max length":      {params: []types.Parameter{parameter("BucketName", "much-too-long")}, want: []ParameterViolation{ParameterViolationMaxLength}},
+		"allowed pattern": {params: []types.Parameter{parameter("BucketName", "My_Bucket")}, want: []ParameterViolation{ParameterViolationAllowedPattern}},
+		"partial pattern": {params: []types.Parameter{parameter("Subnets", "subnet-123,my-subnet-123")}, want: []ParameterViolation{ParameterViolationAllowedPattern}},
+		"not a number":    {params: []types.Parameter{parameter("InstanceCount", "three")}, want: []ParameterViolation{ParameterViolationType}},
+		"min value":       {params: []types.Parameter{parameter("InstanceCount", "0")}, want: []ParameterViolation{ParameterViolationMinValue}},
+		"max value":       {params: []types.Parameter{parameter("InstanceCount", "6")}, want: []ParameterViolation{ParameterViolationMaxValue}},
+		"list of numbers": {params: []types.Parameter{parameter("Ports", "80,8080")}, want: []ParameterViolation{ParameterViolationAllowedValues}},
+		"multiple errors": {
+			params: []types.Parameter{parameter("Environment", "test"), parameter("InstanceCount", "10")},
+			want:   []ParameterViolation{ParameterViolationAllowedValues, ParameterViolationMaxValue},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			errors := ValidateParameterConstraints(tc.params, template)
+			got := make([]ParameterViolation, 0, len(errors))
+			for _, parameterError := range errors {
+				got = append(got, parameterError.Violation)
+				if parameterError.Message == "" {
+					t.Errorf("ValidateParameterConstraints() returned an error without message for %v", parameterError.Parameter)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ValidateParameterConstraints() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"gopkg.in/yaml.v2"
+)
+
+// noEchoMask is the value CloudFormation returns for NoEcho parameters
+const noEchoMask = "****"
+
+// PromotedParameter shows how the value of a parameter changes when a stack is
+// promoted to another environment
+type PromotedParameter struct {
+	Key string
+	// SourceValue is the value in the stack that is promoted
+	SourceValue string
+	// CurrentValue is the value in the destination stack, if it exists
+	CurrentValue string
+	// NewValue is the value the destination stack will get
+	NewValue string
+	// Unknown is true for NoEcho parameters, where the value isn't returned by CloudFormation
+	Unknown bool
+}
+
+// IsChanged returns whether the parameter gets a different value in the destination stack
+func (parameter PromotedParameter) IsChanged() bool {
+	return !parameter.Unknown && parameter.CurrentValue != parameter.NewValue
+}
+
+// ReadParameterMap reads a YAML or JSON file that maps the parameter values of
+// the source environment to the values of the destination environment
+func ReadParameterMap(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	if err := yaml.Unmarshal(contents, &result); err != nil {
+		return nil, fmt.Errorf("invalid parameter map %v: %w", path, err)
+	}
+	return result, nil
+}
+
+// PromoteParameters returns the parameters of the source stack with their values
+// replaced based on the value map. The current parameters of the destination
+// stack are used to show what changes.
+func PromoteParameters(source []types.Parameter, current []types.Parameter, valueMap map[string]string) []PromotedParameter {
+	currentValues := make(map[string]string)
+	for _, parameter := range current {
+		currentValues[aws.ToString(parameter.ParameterKey)] = aws.ToString(parameter.ParameterValue)
+	}
+	result := make([]PromotedParameter, 0, len(source))
+	for _, parameter := range source {
+		key := aws.ToString(parameter.ParameterKey)
+		value := aws.ToString(parameter.ParameterValue)
+		promoted := PromotedParameter{
+			Key:          key,
+			SourceValue:  value,
+			CurrentValue: currentValues[key],
+			NewValue:     value,
+		}
+		if value == noEchoMask {
+			promoted.Unknown = true
+			promoted.NewValue = ""
+		} else if mapped, ok := valueMap[value]; ok {
+			promoted.NewValue = mapped
+		}
+		result = append(result, promoted)
+	}
+	return result
+}
+
+// GetPromotionParameters converts the promoted parameters to deployment
+// parameters. NoEcho parameters keep their previous value if the destination
+// stack exists and are otherwise left out, in which case their keys are returned.
+func GetPromotionParameters(promoted []PromotedParameter, destinationExists bool) ([]types.Parameter, []string) {
+	parameters := make([]types.Parameter, 0, len(promoted))
+	unknown := make([]string, 0)
+	for _, parameter := range promoted {
+		if parameter.Unknown {
+			if destinationExists {
+				parameters = append(parameters, types.Parameter{ParameterKey: aws.String(parameter.Key), UsePreviousValue: aws.Bool(true)})
+			} else {
+				unknown = append(unknown, parameter.Key)
+			}
+			continue
+		}
+		parameters = append(parameters, types.Parameter{ParameterKey: aws.String(parameter.Key), ParameterValue: aws.String(parameter.NewValue)})
+	}
+	return parameters, unknown
+}
@@ -0,0 +1,73 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestReadParameterMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "params.yaml")
+	if err := os.WriteFile(path, []byte("t3.small: m5.large\nstaging.example.com: example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadParameterMap(path)
+	if err != nil {
+		t.Fatalf("ReadParameterMap() error = %v", err)
+	}
+	want := map[string]string{"t3.small": "m5.large", "staging.example.com": "example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadParameterMap() = %v, want %v", got, want)
+	}
+	if _, err := ReadParameterMap(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("ReadParameterMap() expected an error for a missing file")
+	}
+}
+
+func TestPromoteParameters(t *testing.T) {
+	parameter := func(key, value string) types.Parameter {
+		return types.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value)}
+	}
+	source := []types.Parameter{
+		parameter("InstanceType", "t3.small"),
+		parameter("DomainName", "staging.example.com"),
+		parameter("Retention", "7"),
+		parameter("DbPassword", "****"),
+	}
+	current := []types.Parameter{
+		parameter("InstanceType", "m5.large"),
+		parameter("DomainName", "old.example.com"),
+		parameter("Retention", "30"),
+		parameter("DbPassword", "****"),
+	}
+	valueMap := map[string]string{"t3.small": "m5.large", "staging.example.com": "example.com"}
+	promoted := PromoteParameters(source, current, valueMap)
+	want := []PromotedParameter{
+		{Key: "InstanceType", SourceValue: "t3.small", CurrentValue: "m5.large", NewValue: "m5.large"},
+		{Key: "DomainName", SourceValue: "staging.example.com", CurrentValue: "old.example.com", NewValue: "example.com"},
+		{Key: "Retention", SourceValue: "7", CurrentValue: "30", NewValue: "7"},
+		{Key: "DbPassword", SourceValue: "****", CurrentValue: "****", Unknown: true},
+	}
+	if !reflect.DeepEqual(promoted, want) {
+		t.Fatalf("PromoteParameters() = %+v, want %+v", promoted, want)
+	}
+	changed := []bool{false, true, true, false}
+	for i, parameter := range promoted {
+		if parameter.IsChanged() != changed[i] {
+			t.Errorf("%v IsChanged() = %v, want %v", parameter.Key, parameter.IsChanged(), changed[i])
+		}
+	}
+
+	existing, unknown := GetPromotionParameters(promoted, true)
+	if len(existing) != 4 || len(unknown) != 0 || !aws.ToBool(existing[3].UsePreviousValue) {
+		t.Errorf("GetPromotionParameters() for an existing stack = %v, %v", existing, unknown)
+	}
+	created, unknown := GetPromotionParameters(promoted, false)
+	if len(created) != 3 || !reflect.DeepEqual(unknown, []string{"DbPassword"}) {
+		t.Errorf("GetPromotionParameters() for a new stack = %v, %v", created, unknown)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/aws/smithy-go"
@@ -19,6 +20,8 @@ type CfnResource struct {
 	ResourceID string
 	LogicalID  string
 	Status     string
+	// DriftStatus is the result of the most recent drift detection, it's only set by GetStackResources
+	DriftStatus string
 }
 
 // GetResources returns all the exports in the account and region. If stackname
@@ -86,3 +89,75 @@ func GetResources(stackname *string, svc *cloudformation.Client) []CfnResource {
 	}
 	return resourcelist
 }
+
+// ListStackResourceSummaries returns the summaries of all resources of the stack,
+// following the pagination of ListStackResources
+func ListStackResourceSummaries(stackname string, svc CloudFormationListStackResourcesAPI) ([]types.StackResourceSummary, error) {
+	result := make([]types.StackResourceSummary, 0)
+	paginator := cloudformation.NewListStackResourcesPaginator(svc, &cloudformation.ListStackResourcesInput{StackName: &stackname})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, output.StackResourceSummaries...)
+	}
+	return result, nil
+}
+
+// GetStackResources returns the resources of a single stack, including their
+// drift status from the most recent drift detection. When a resource type or
+// status is provided, only resources matching these are returned.
+func GetStackResources(stackname string, resourceType string, status string, svc CloudFormationListStackResourcesAPI) ([]CfnResource, error) {
+	summaries, err := ListStackResourceSummaries(stackname, svc)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]CfnResource, 0, len(summaries))
+	for _, resource := range summaries {
+		if resourceType != "" && aws.ToString(resource.ResourceType) != resourceType {
+			continue
+		}
+		if status != "" && string(resource.ResourceStatus) != status {
+			continue
+		}
+		driftStatus := string(types.StackResourceDriftStatusNotChecked)
+		if resource.DriftInformation != nil {
+			driftStatus = string(resource.DriftInformation.StackResourceDriftStatus)
+		}
+		result = append(result, CfnResource{
+			StackName:   stackname,
+			Type:        aws.ToString(resource.ResourceType),
+			ResourceID:  aws.ToString(resource.PhysicalResourceId),
+			LogicalID:   aws.ToString(resource.LogicalResourceId),
+			Status:      string(resource.ResourceStatus),
+			DriftStatus: driftStatus,
+		})
+	}
+	return result, nil
+}
+
+// FindResourceByPhysicalId returns the resources with the provided physical ID
+// in the stacks matching the stack name filter, which can contain wildcards.
+// CloudFormation can't look up resources by their physical ID across stacks,
+// so the resources of every matching stack are scanned. An empty filter scans
+// all stacks. When a resource type is provided, other types are skipped.
+func FindResourceByPhysicalId(physicalID string, stackNameFilter string, resourceType string, svc CloudFormationDescribeStacksAndResourcesAPI) ([]CfnResource, error) {
+	stacks, err := GetMatchingStacks(stackNameFilter, svc)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]CfnResource, 0)
+	for _, stack := range stacks {
+		resources, err := GetStackResources(aws.ToString(stack.StackName), resourceType, "", svc)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			if resource.ResourceID == physicalID {
+				result = append(result, resource)
+			}
+		}
+	}
+	return result, nil
+}
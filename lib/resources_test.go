@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func TestGetStackResources(t *testing.T) {
+	resource := func(logicalID, resourceType string, status types.ResourceStatus, drift types.StackResourceDriftStatus) types.StackResourceSummary {
+		summary := types.StackResourceSummary{
+			LogicalResourceId:  aws.String(logicalID),
+			PhysicalResourceId: aws.String("physical-" + logicalID),
+			ResourceType:       aws.String(resourceType),
+			ResourceStatus:     status,
+		}
+		if drift != "" {
+			summary.DriftInformation = &types.StackResourceDriftInformationSummary{StackResourceDriftStatus: drift}
+		}
+		return summary
+	}
+	// The resources are spread over two pages
+	pages := map[string]*cloudformation.ListStackResourcesOutput{
+		"": {
+			StackResourceSummaries: []types.StackResourceSummary{
+				resource("Logs", "AWS::S3::Bucket", types.ResourceStatusCreateComplete, types.StackResourceDriftStatusModified),
+				resource("Assets", "AWS::S3::Bucket", types.ResourceStatusCreateFailed, ""),
+			},
+			NextToken: aws.String("page2"),
+		},
+		"page2": {
+			StackResourceSummaries: []types.StackResourceSummary{
+				resource("Queue", "AWS::SQS::Queue", types.ResourceStatusCreateFailed, types.StackResourceDriftStatusInSync),
+			},
+		},
+	}
+	svc := mockCloudFormationListStackResourcesAPI(func(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+		if aws.ToString(params.StackName) != "my-stack" {
+			return nil, errors.New("stack not found")
+		}
+		return pages[aws.ToString(params.NextToken)], nil
+	})
+	tests := map[string]struct {
+		resourceType string
+		status       string
+		want         []string
+	}{
+		"all resources":   {want: []string{"Logs", "Assets", "Queue"}},
+		"by type":         {resourceType: "AWS::S3::Bucket", want: []string{"Logs", "Assets"}},
+		"by status":       {status: "CREATE_FAILED", want: []string{"Assets", "Queue"}},
+		"type and status": {resourceType: "AWS::S3::Bucket", status: "CREATE_FAILED", want: []string{"Assets"}},
+		"no matches":      {resourceType: "AWS::SNS::Topic", want: []string{}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resources, err := GetStackResources("my-stack", tc.resourceType, tc.status, svc)
+			if err != nil {
+				t.Fatalf("GetStackResources() error = %v", err)
+			}
+			got := make([]string, 0, len(resources))
+			for _, resource := range resources {
+				got = append(got, resource.LogicalID)
+				if resource.StackName != "my-stack" || resource.ResourceID != "physical-"+resource.LogicalID {
+					t.Errorf("GetStackResources() returned unexpected resource %+v", resource)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetStackResources() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	resources, err := GetStackResources("my-stack", "", "", svc)
+	if err != nil {
+		t.Fatalf("GetStackResources() error = %v", err)
+	}
+	driftStatuses := make(map[string]string, len(resources))
+	for _, resource := range resources {
+		driftStatuses[resource.LogicalID] = resource.DriftStatus
+	}
+	wantDrift := map[string]string{"Logs": "MODIFIED", "Assets": "NOT_CHECKED", "Queue": "IN_SYNC"}
+	if !reflect.DeepEqual(driftStatuses, wantDrift) {
+		t.Errorf("GetStackResources() drift statuses = %v, want %v", driftStatuses, wantDrift)
+	}
+	if _, err := GetStackResources("missing-stack", "", "", svc); err == nil {
+		t.Errorf("GetStackResources() expected an error for a missing stack")
+	}
+}
+
+type mockStackResourcesClient struct {
+	stacks    []string
+	resources map[string][]types.StackResourceSummary
+	scanned   []string
+}
+
+func (m *mockStackResourcesClient) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	output := &cloudformation.DescribeStacksOutput{}
+	for _, name := range m.stacks {
+		if params.StackName == nil || *params.StackName == name {
+			output.Stacks = append(output.Stacks, types.Stack{StackName: aws.String(name)})
+		}
+	}
+	return output, nil
+}
+
+// ListStackResources returns one resource per page to verify that every page is scanned
+func (m *mockStackResourcesClient) ListStackResources(ctx context.Context, params *cloudformation.ListStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+	stackname := aws.ToString(params.StackName)
+	index := 0
+	if params.NextToken == nil {
+		m.scanned = append(m.scanned, stackname)
+	} else {
+		index, _ = strconv.Atoi(*params.NextToken)
+	}
+	resources := m.resources[stackname]
+	output := &cloudformation.ListStackResourcesOutput{}
+	if index < len(resources) {
+		output.StackResourceSummaries = resources[index : index+1]
+	}
+	if index+1 < len(resources) {
+		output.NextToken = aws.String(strconv.Itoa(index + 1))
+	}
+	return output, nil
+}
+
+func TestFindResourceByPhysicalId(t *testing.T) {
+	resource := func(logicalID, physicalID, resourceType string) types.StackResourceSummary {
+		return types.StackResourceSummary{
+			LogicalResourceId:  aws.String(logicalID),
+			PhysicalResourceId: aws.String(physicalID),
+			ResourceType:       aws.String(resourceType),
+			ResourceStatus:     types.ResourceStatusCreateComplete,
+		}
+	}
+	tests := map[string]struct {
+		physicalID   string
+		filter       string
+		resourceType string
+		want         []string
+		wantScanned  []string
+	}{
+		"all stacks":        {physicalID: "i-0abc123", want: []string{"app-prod/Server"}, wantScanned: []string{"app-prod", "app-test", "network"}},
+		"wildcard filter":   {physicalID: "i-0abc123", filter: "app-*", want: []string{"app-prod/Server"}, wantScanned: []string{"app-prod", "app-test"}},
+		"exact stack":       {physicalID: "i-0abc123", filter: "network", want: []string{}, wantScanned: []string{"network"}},
+		"matching type":     {physicalID: "i-0abc123", resourceType: "AWS::EC2::Instance", want: []string{"app-prod/Server"}, wantScanned: []string{"app-prod", "app-test", "network"}},
+		"other type":        {physicalID: "i-0abc123", resourceType: "AWS::S3::Bucket", want: []string{}, wantScanned: []string{"app-prod", "app-test", "network"}},
+		"multiple matches":  {physicalID: "shared-bucket", want: []string{"app-prod/Logs", "app-test/Logs"}, wantScanned: []string{"app-prod", "app-test", "network"}},
+		"unknown physical":  {physicalID: "i-0def456", want: []string{}, wantScanned: []string{"app-prod", "app-test", "network"}},
+		"no matching stack": {physicalID: "i-0abc123", filter: "db-*", want: []string{}, wantScanned: nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := &mockStackResourcesClient{
+				stacks: []string{"app-prod", "app-test", "network"},
+				resources: map[string][]types.StackResourceSummary{
+					"app-prod": {resource("Server", "i-0abc123", "AWS::EC2::Instance"), resource("Logs", "shared-bucket", "AWS::S3::Bucket")},
+					"app-test": {resource("Server", "i-0fff999", "AWS::EC2::Instance"), resource("Logs", "shared-bucket", "AWS::S3::Bucket")},
+					"network":  {resource("Vpc", "vpc-123", "AWS::EC2::VPC")},
+				},
+			}
+			resources, err := FindResourceByPhysicalId(tc.physicalID, tc.filter, tc.resourceType, svc)
+			if err != nil {
+				t.Fatalf("FindResourceByPhysicalId() error = %v", err)
+			}
+			got := make([]string, 0, len(resources))
+			for _, resource := range resources {
+				got = append(got, resource.StackName+"/"+resource.LogicalID)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("FindResourceByPhysicalId() = %v, want %v", got, tc.want)
+			}
+			if !reflect.DeepEqual(svc.scanned, tc.wantScanned) {
+				t.Errorf("FindResourceByPhysicalId() scanned %v, want %v", svc.scanned, tc.wantScanned)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultRetryMaxDelay   = 60 * time.Second
+	defaultRetryMaxRetries = 20
+	retryInitialDelay      = 5 * time.Second
+)
+
+// IsThrottleError checks if the error is caused by AWS throttling the requests
+func IsThrottleError(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.ErrorCode() {
+	case "Throttling", "ThrottlingException", "ThrottledException", "RequestLimitExceeded", "TooManyRequestsException":
+		return true
+	}
+	return false
+}
+
+// RetryWithBackoff calls fn until it succeeds, retrying up to maxRetries times
+// when it fails because of throttling. The delay between attempts starts at
+// initialDelay and doubles up to the retry.max-delay setting (60 seconds by
+// default). Any other error is returned right away.
+func RetryWithBackoff(fn func() error, maxRetries int, initialDelay time.Duration) error {
+	maxDelay := time.Duration(viper.GetInt("retry.max-delay")) * time.Second
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	delay := initialDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !IsThrottleError(err) || attempt >= maxRetries {
+			return err
+		}
+		sleepFunc(delay)
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// retryMaxRetries returns the configured maximum number of retries for throttled requests
+func retryMaxRetries() int {
+	if configured := viper.GetInt("retry.max-retries"); configured > 0 {
+		return configured
+	}
+	return defaultRetryMaxRetries
+}
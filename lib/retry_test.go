@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	var sleeps []time.Duration
+	originalSleep := sleepFunc
+	sleepFunc = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { sleepFunc = originalSleep }()
+
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	invalid := errors.New("ValidationError")
+	tests := map[string]struct {
+		failures   int
+		failWith   error
+		maxRetries int
+		wantCalls  int
+		wantSleeps []time.Duration
+		wantErr    error
+	}{
+		"succeeds right away": {
+			maxRetries: 3,
+			wantCalls:  1,
+			wantSleeps: nil,
+		},
+		"succeeds after throttling": {
+			failures:   5,
+			failWith:   throttled,
+			maxRetries: 20,
+			wantCalls:  6,
+			wantSleeps: []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second},
+		},
+		"gives up after the maximum retries": {
+			failures:   10,
+			failWith:   throttled,
+			maxRetries: 2,
+			wantCalls:  3,
+			wantSleeps: []time.Duration{5 * time.Second, 10 * time.Second},
+			wantErr:    throttled,
+		},
+		"other errors aren't retried": {
+			failures:   1,
+			failWith:   invalid,
+			maxRetries: 20,
+			wantCalls:  1,
+			wantSleeps: nil,
+			wantErr:    invalid,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sleeps = nil
+			calls := 0
+			err := RetryWithBackoff(func() error {
+				calls++
+				if calls <= tc.failures {
+					return tc.failWith
+				}
+				return nil
+			}, tc.maxRetries, 5*time.Second)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("RetryWithBackoff() error = %v, want %v", err, tc.wantErr)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("RetryWithBackoff() called the function %d times, want %d", calls, tc.wantCalls)
+			}
+			if !reflect.DeepEqual(sleeps, tc.wantSleeps) {
+				t.Errorf("RetryWithBackoff() waited %v, want %v", sleeps, tc.wantSleeps)
+			}
+		})
+	}
+}
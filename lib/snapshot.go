@@ -0,0 +1,222 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"gopkg.in/yaml.v2"
+)
+
+// The file names used inside a snapshot directory
+const (
+	SnapshotTemplateFile   = "template.yaml"
+	SnapshotParametersFile = "parameters.yaml"
+	SnapshotTagsFile       = "tags.yaml"
+	SnapshotOutputsFile    = "outputs.yaml"
+	SnapshotResourcesFile  = "resources.json"
+	SnapshotDeploymentFile = "deployment.yaml"
+)
+
+// noEchoValue is what CloudFormation returns as the value of NoEcho parameters
+const noEchoValue = "****"
+
+// StackSnapshot contains the full state of a stack at a point in time
+type StackSnapshot struct {
+	StackName  string
+	Template   string
+	Parameters []SnapshotParameter
+	Tags       []SnapshotTag
+	Outputs    []SnapshotOutput
+	Resources  []CfnResource
+}
+
+// SnapshotParameter is a parameter in the same format as a parameters file
+type SnapshotParameter struct {
+	ParameterKey     string `json:"ParameterKey" yaml:"ParameterKey"`
+	ParameterValue   string `json:"ParameterValue,omitempty" yaml:"ParameterValue,omitempty"`
+	UsePreviousValue bool   `json:"UsePreviousValue,omitempty" yaml:"UsePreviousValue,omitempty"`
+}
+
+// SnapshotTag is a tag in the same format as a tags file
+type SnapshotTag struct {
+	Key   string `json:"Key" yaml:"Key"`
+	Value string `json:"Value" yaml:"Value"`
+}
+
+// SnapshotOutput is an output of the stack
+type SnapshotOutput struct {
+	OutputKey   string `json:"OutputKey" yaml:"OutputKey"`
+	OutputValue string `json:"OutputValue" yaml:"OutputValue"`
+	Description string `json:"Description,omitempty" yaml:"Description,omitempty"`
+	ExportName  string `json:"ExportName,omitempty" yaml:"ExportName,omitempty"`
+}
+
+// CreateStackSnapshot collects the template, parameters, tags, outputs, and
+// resources of the stack
+func CreateStackSnapshot(stackName string, svc *cloudformation.Client) (StackSnapshot, error) {
+	stack, err := GetStack(&stackName, svc)
+	if err != nil {
+		return StackSnapshot{}, err
+	}
+	template, err := GetCurrentTemplateBody(stackName, svc)
+	if err != nil {
+		return StackSnapshot{}, err
+	}
+	resources := GetResources(&stackName, svc)
+	return NewStackSnapshot(stack, template, resources), nil
+}
+
+// NewStackSnapshot creates a snapshot from the provided stack information.
+// NoEcho parameters can't be retrieved, so they are stored with UsePreviousValue.
+func NewStackSnapshot(stack types.Stack, template string, resources []CfnResource) StackSnapshot {
+	snapshot := StackSnapshot{
+		StackName:  aws.ToString(stack.StackName),
+		Template:   template,
+		Parameters: make([]SnapshotParameter, 0, len(stack.Parameters)),
+		Tags:       make([]SnapshotTag, 0, len(stack.Tags)),
+		Outputs:    make([]SnapshotOutput, 0, len(stack.Outputs)),
+		Resources:  resources,
+	}
+	for _, parameter := range stack.Parameters {
+		snapshotParameter := SnapshotParameter{ParameterKey: aws.ToString(parameter.ParameterKey)}
+		if aws.ToString(parameter.ParameterValue) == noEchoValue {
+			snapshotParameter.UsePreviousValue = true
+		} else {
+			snapshotParameter.ParameterValue = aws.ToString(parameter.ParameterValue)
+		}
+		snapshot.Parameters = append(snapshot.Parameters, snapshotParameter)
+	}
+	for _, tag := range stack.Tags {
+		snapshot.Tags = append(snapshot.Tags, SnapshotTag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)})
+	}
+	for _, output := range stack.Outputs {
+		snapshot.Outputs = append(snapshot.Outputs, SnapshotOutput{
+			OutputKey:   aws.ToString(output.OutputKey),
+			OutputValue: aws.ToString(output.OutputValue),
+			Description: aws.ToString(output.Description),
+			ExportName:  aws.ToString(output.ExportName),
+		})
+	}
+	return snapshot
+}
+
+// SnapshotDirectory returns the directory a snapshot taken at the provided time should be stored in
+func SnapshotDirectory(outputDir string, stackName string, timestamp time.Time) string {
+	return filepath.Join(outputDir, stackName, timestamp.Format("2006-01-02T15-04-05"))
+}
+
+// Write stores the snapshot in the provided directory, creating it if needed.
+// Alongside the individual files a deployment file is written so the snapshot
+// can be used directly with fog deploy.
+func (snapshot StackSnapshot) Write(directory string) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	files := map[string]interface{}{
+		SnapshotParametersFile: snapshot.Parameters,
+		SnapshotTagsFile:       snapshot.Tags,
+		SnapshotOutputsFile:    snapshot.Outputs,
+		SnapshotDeploymentFile: snapshot.DeploymentFile(),
+	}
+	for filename, contents := range files {
+		data, err := yaml.Marshal(contents)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(directory, filename), data, 0644); err != nil {
+			return err
+		}
+	}
+	resources, err := json.MarshalIndent(snapshot.Resources, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(directory, SnapshotResourcesFile), resources, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(directory, SnapshotTemplateFile), []byte(snapshot.Template), 0644)
+}
+
+// DeploymentFile returns the snapshot as a deployment file. NoEcho parameters
+// are left out as their values aren't known.
+func (snapshot StackSnapshot) DeploymentFile() StackDeploymentFile {
+	result := StackDeploymentFile{
+		TemplateFilePath: SnapshotTemplateFile,
+		Parameters:       make(map[string]string),
+		Tags:             make(map[string]string),
+	}
+	for _, parameter := range snapshot.Parameters {
+		if !parameter.UsePreviousValue {
+			result.Parameters[parameter.ParameterKey] = parameter.ParameterValue
+		}
+	}
+	for _, tag := range snapshot.Tags {
+		result.Tags[tag.Key] = tag.Value
+	}
+	return result
+}
+
+// ReadStackSnapshot loads a snapshot from the provided directory
+func ReadStackSnapshot(directory string) (StackSnapshot, error) {
+	snapshot := StackSnapshot{StackName: filepath.Base(filepath.Dir(filepath.Clean(directory)))}
+	template, err := os.ReadFile(filepath.Join(directory, SnapshotTemplateFile))
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to read the snapshot template: %w", err)
+	}
+	snapshot.Template = string(template)
+	files := map[string]interface{}{
+		SnapshotParametersFile: &snapshot.Parameters,
+		SnapshotTagsFile:       &snapshot.Tags,
+		SnapshotOutputsFile:    &snapshot.Outputs,
+	}
+	for filename, target := range files {
+		data, err := os.ReadFile(filepath.Join(directory, filename))
+		if err != nil {
+			return snapshot, fmt.Errorf("unable to read the snapshot %s: %w", filename, err)
+		}
+		if err := yaml.Unmarshal(data, target); err != nil {
+			return snapshot, fmt.Errorf("unable to parse the snapshot %s: %w", filename, err)
+		}
+	}
+	resources, err := os.ReadFile(filepath.Join(directory, SnapshotResourcesFile))
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to read the snapshot %s: %w", SnapshotResourcesFile, err)
+	}
+	if err := json.Unmarshal(resources, &snapshot.Resources); err != nil {
+		return snapshot, fmt.Errorf("unable to parse the snapshot %s: %w", SnapshotResourcesFile, err)
+	}
+	return snapshot, nil
+}
+
+// GetDeploymentParameters returns the parameters that can be used to create a
+// new stack, and the keys of the NoEcho parameters whose values are unknown
+func (snapshot StackSnapshot) GetDeploymentParameters() ([]types.Parameter, []string) {
+	parameters := make([]types.Parameter, 0, len(snapshot.Parameters))
+	unknown := make([]string, 0)
+	for _, parameter := range snapshot.Parameters {
+		if parameter.UsePreviousValue {
+			unknown = append(unknown, parameter.ParameterKey)
+			continue
+		}
+		parameters = append(parameters, types.Parameter{
+			ParameterKey:   aws.String(parameter.ParameterKey),
+			ParameterValue: aws.String(parameter.ParameterValue),
+		})
+	}
+	return parameters, unknown
+}
+
+// GetDeploymentTags returns the tags of the snapshot for use in a deployment
+func (snapshot StackSnapshot) GetDeploymentTags() []types.Tag {
+	tags := make([]types.Tag, 0, len(snapshot.Tags))
+	for _, tag := range snapshot.Tags {
+		tags = append(tags, types.Tag{Key: aws.String(tag.Key), Value: aws.String(tag.Value)})
+	}
+	return tags
+}
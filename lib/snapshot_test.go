@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+func testSnapshotStack() types.Stack {
+	return types.Stack{
+		StackName: aws.String("my-stack"),
+		Parameters: []types.Parameter{
+			{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")},
+			{ParameterKey: aws.String("DbPassword"), ParameterValue: aws.String("****")},
+		},
+		Tags: []types.Tag{
+			{Key: aws.String("Team"), Value: aws.String("platform")},
+		},
+		Outputs: []types.Output{
+			{OutputKey: aws.String("BucketName"), OutputValue: aws.String("my-bucket"), ExportName: aws.String("my-stack-BucketName")},
+		},
+	}
+}
+
+func TestNewStackSnapshot(t *testing.T) {
+	resources := []CfnResource{{StackName: "my-stack", Type: "AWS::S3::Bucket", ResourceID: "my-bucket", LogicalID: "Bucket", Status: "CREATE_COMPLETE"}}
+	snapshot := NewStackSnapshot(testSnapshotStack(), "Resources: {}", resources)
+	wantParameters := []SnapshotParameter{
+		{ParameterKey: "Environment", ParameterValue: "prod"},
+		{ParameterKey: "DbPassword", UsePreviousValue: true},
+	}
+	if !reflect.DeepEqual(snapshot.Parameters, wantParameters) {
+		t.Errorf("NewStackSnapshot() parameters = %v, want %v", snapshot.Parameters, wantParameters)
+	}
+	wantOutputs := []SnapshotOutput{{OutputKey: "BucketName", OutputValue: "my-bucket", ExportName: "my-stack-BucketName"}}
+	if !reflect.DeepEqual(snapshot.Outputs, wantOutputs) {
+		t.Errorf("NewStackSnapshot() outputs = %v, want %v", snapshot.Outputs, wantOutputs)
+	}
+	parameters, unknown := snapshot.GetDeploymentParameters()
+	if len(parameters) != 1 || *parameters[0].ParameterKey != "Environment" {
+		t.Errorf("GetDeploymentParameters() parameters = %v, want only Environment", parameters)
+	}
+	if !reflect.DeepEqual(unknown, []string{"DbPassword"}) {
+		t.Errorf("GetDeploymentParameters() unknown = %v, want [DbPassword]", unknown)
+	}
+	tags := snapshot.GetDeploymentTags()
+	if len(tags) != 1 || *tags[0].Key != "Team" || *tags[0].Value != "platform" {
+		t.Errorf("GetDeploymentTags() = %v", tags)
+	}
+}
+
+func TestStackSnapshot_WriteAndRead(t *testing.T) {
+	resources := []CfnResource{{StackName: "my-stack", Type: "AWS::S3::Bucket", ResourceID: "my-bucket", LogicalID: "Bucket", Status: "CREATE_COMPLETE"}}
+	snapshot := NewStackSnapshot(testSnapshotStack(), "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n", resources)
+	directory := SnapshotDirectory(t.TempDir(), "my-stack", time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC))
+	if filepath.Base(directory) != "2024-02-03T04-05-06" {
+		t.Errorf("SnapshotDirectory() = %v", directory)
+	}
+	if err := snapshot.Write(directory); err != nil {
+		t.Fatalf("StackSnapshot.Write() error = %v", err)
+	}
+	got, err := ReadStackSnapshot(directory)
+	if err != nil {
+		t.Fatalf("ReadStackSnapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, snapshot) {
+		t.Errorf("ReadStackSnapshot() = %v, want %v", got, snapshot)
+	}
+	// The deployment file should be usable as a regular deployment file
+	deploymentFile, err := os.ReadFile(filepath.Join(directory, SnapshotDeploymentFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseDeploymentFile(string(deploymentFile))
+	if err != nil {
+		t.Fatalf("ParseDeploymentFile() error = %v", err)
+	}
+	want := StackDeploymentFile{
+		TemplateFilePath: SnapshotTemplateFile,
+		Parameters:       map[string]string{"Environment": "prod"},
+		Tags:             map[string]string{"Team": "platform"},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("deployment file = %v, want %v", parsed, want)
+	}
+	// Deploying the deployment file has to find the template of the snapshot
+	var deployment DeployInfo
+	if err := deployment.LoadDeploymentFile(filepath.Join(directory, SnapshotDeploymentFile), "", nil); err != nil {
+		t.Fatalf("LoadDeploymentFile() error = %v", err)
+	}
+	template, _, err := ReadDeploymentTemplate(deployment.StackDeploymentFile.TemplateFilePath, deployment.DeploymentFilePath)
+	if err != nil {
+		t.Fatalf("ReadDeploymentTemplate() error = %v", err)
+	}
+	if template != snapshot.Template {
+		t.Errorf("ReadDeploymentTemplate() = %v, want %v", template, snapshot.Template)
+	}
+}
+
+func TestReadStackSnapshot_Missing(t *testing.T) {
+	if _, err := ReadStackSnapshot(t.TempDir()); err == nil {
+		t.Errorf("ReadStackSnapshot() expected an error for an empty directory")
+	}
+}
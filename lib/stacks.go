@@ -3,7 +3,9 @@ package lib
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -18,18 +20,30 @@ import (
 type DeployInfo struct {
 	// Changeset contains the ChangesetInfo object with the change set information
 	Changeset *ChangesetInfo
+	// ChangesetDescription contains the description of the change set
+	ChangesetDescription string
 	// ChangesetName contains the name of the change set
 	ChangesetName string
+	// DeploymentError holds the error that stopped the deployment from completing, if any
+	DeploymentError error
+	// DeploymentFilePath is the local path of the deployment file, if one was used
+	DeploymentFilePath string
 	// IsDryRun shows whether this is a dry run or not
 	IsDryRun bool
 	// IsNew shows whether this is a new stack or if it will update one
 	IsNew bool
+	// NotificationARNs holds the ARNs of the SNS topics that receive the stack events
+	NotificationARNs []string
 	// Parameters holds a slice of parameter objects
 	Parameters []types.Parameter
 	// PrechecksFailed shows whether the deployment failed the prechecks
 	PrechecksFailed bool
 	// RawStack holds the raw version of the stack as returned by AWS
 	RawStack *types.Stack
+	// RoleARN holds the ARN of the service role CloudFormation uses to deploy the stack
+	RoleARN string
+	// RollbackConfiguration holds the alarms that trigger a rollback of the deployment
+	RollbackConfiguration *types.RollbackConfiguration
 	// StackArn holds the ARN of the stack
 	StackArn string
 	// StackDeploymentFile holds the contents of the stack deployment file
@@ -82,6 +96,156 @@ type ResourceEvent struct {
 	ExpectedEndStatus string
 }
 
+// ToDeploymentFile returns a deployment file with the template path, parameters,
+// and tags of the deployment. The template path is taken from TemplateRelativePath,
+// deployments of a template URL without a local template use the URL instead.
+// Parameters that use their previous value are left out, as their value isn't known.
+func (deployment DeployInfo) ToDeploymentFile() StackDeploymentFile {
+	result := StackDeploymentFile{
+		TemplateFilePath: filepath.ToSlash(deployment.TemplateRelativePath),
+		Parameters:       make(map[string]string),
+		Tags:             make(map[string]string),
+	}
+	for _, parameter := range deployment.Parameters {
+		if aws.ToBool(parameter.UsePreviousValue) {
+			continue
+		}
+		result.Parameters[aws.ToString(parameter.ParameterKey)] = aws.ToString(parameter.ParameterValue)
+	}
+	for _, tag := range deployment.Tags {
+		result.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if deployment.TemplateRelativePath == "" {
+		result.TemplateURL = deployment.TemplateUrl
+	}
+	result.NotificationARNs = deployment.NotificationARNs
+	result.ChangesetDescription = deployment.ChangesetDescription
+	result.RoleARN = deployment.RoleARN
+	if deployment.RollbackConfiguration != nil {
+		rollback := DeploymentFileRollbackConfiguration{
+			MonitoringTime: aws.ToInt32(deployment.RollbackConfiguration.MonitoringTimeInMinutes),
+		}
+		for _, trigger := range deployment.RollbackConfiguration.RollbackTriggers {
+			rollback.AlarmARNs = append(rollback.AlarmARNs, aws.ToString(trigger.Arn))
+		}
+		result.RollbackConfiguration = &rollback
+	}
+	return result
+}
+
+// maxChangesetDescriptionLength is the longest description CloudFormation accepts for a change set
+const maxChangesetDescriptionLength = 1024
+
+// SelectChangesetDescription returns the description to use for the change
+// set. A description provided as a flag takes precedence over the one in the
+// deployment file, which in turn takes precedence over the configured one.
+func SelectChangesetDescription(flagValue string, deploymentFile *StackDeploymentFile, configured string) (string, error) {
+	description := configured
+	if flagValue != "" {
+		description = flagValue
+	} else if deploymentFile != nil && deploymentFile.ChangesetDescription != "" {
+		description = deploymentFile.ChangesetDescription
+	}
+	if len(description) > maxChangesetDescriptionLength {
+		return "", fmt.Errorf("the change set description can't be longer than %d characters", maxChangesetDescriptionLength)
+	}
+	return description, nil
+}
+
+// roleARNRegex matches the ARN of an IAM role in any partition
+var roleARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+
+// SelectRoleARN returns the ARN of the service role to use for the deployment.
+// A role provided as a flag takes precedence over the one in the deployment
+// file, which in turn takes precedence over the configured one.
+func SelectRoleARN(flagValue string, deploymentFile *StackDeploymentFile, configured string) (string, error) {
+	roleARN := configured
+	if flagValue != "" {
+		roleARN = flagValue
+	} else if deploymentFile != nil && deploymentFile.RoleARN != "" {
+		roleARN = deploymentFile.RoleARN
+	}
+	if roleARN != "" && !roleARNRegex.MatchString(roleARN) {
+		return "", fmt.Errorf("'%v' isn't a valid IAM role ARN", roleARN)
+	}
+	return roleARN, nil
+}
+
+// maxRollbackMonitoringTime is the longest monitoring time in minutes CloudFormation accepts
+const maxRollbackMonitoringTime = 180
+
+// SelectRollbackConfiguration returns the rollback configuration to use for
+// the deployment. The monitoring time and alarm ARNs provided as flags each
+// take precedence over those in the deployment file. When neither is set, nil
+// is returned.
+func SelectRollbackConfiguration(monitoringTime int32, alarmARNs string, deploymentFile *StackDeploymentFile) (*types.RollbackConfiguration, error) {
+	var fileConfig DeploymentFileRollbackConfiguration
+	if deploymentFile != nil && deploymentFile.RollbackConfiguration != nil {
+		fileConfig = *deploymentFile.RollbackConfiguration
+	}
+	if monitoringTime == 0 {
+		monitoringTime = fileConfig.MonitoringTime
+	}
+	arns := selectCommaSeparated(alarmARNs, fileConfig.AlarmARNs)
+	if monitoringTime < 0 || monitoringTime > maxRollbackMonitoringTime {
+		return nil, fmt.Errorf("the rollback monitoring time needs to be between 0 and %d minutes", maxRollbackMonitoringTime)
+	}
+	if monitoringTime == 0 && len(arns) == 0 {
+		return nil, nil
+	}
+	result := types.RollbackConfiguration{
+		MonitoringTimeInMinutes: aws.Int32(monitoringTime),
+		RollbackTriggers:        make([]types.RollbackTrigger, 0, len(arns)),
+	}
+	for _, arn := range arns {
+		result.RollbackTriggers = append(result.RollbackTriggers, types.RollbackTrigger{
+			Arn:  aws.String(arn),
+			Type: aws.String("AWS::CloudWatch::Alarm"),
+		})
+	}
+	return &result, nil
+}
+
+// RollbackConfigurationWarning returns a warning when the rollback
+// configuration is incomplete, or an empty string if it isn't
+func RollbackConfigurationWarning(rollback *types.RollbackConfiguration) string {
+	if rollback == nil {
+		return ""
+	}
+	if len(rollback.RollbackTriggers) == 0 {
+		return "A rollback monitoring time is set without any alarms, so there is nothing to monitor"
+	}
+	if aws.ToInt32(rollback.MonitoringTimeInMinutes) == 0 {
+		return "Rollback alarms are set without a monitoring time, so they are only monitored while the stack is being deployed"
+	}
+	return ""
+}
+
+// SelectNotificationARNs returns the notification ARNs to use for a deployment.
+// ARNs provided as a comma separated flag value take precedence over those in
+// the deployment file, which in turn take precedence over the configured ones.
+func SelectNotificationARNs(flagValue string, deploymentFile *StackDeploymentFile, configured []string) []string {
+	if flagValue == "" && deploymentFile != nil && len(deploymentFile.NotificationARNs) != 0 {
+		return deploymentFile.NotificationARNs
+	}
+	return selectCommaSeparated(flagValue, configured)
+}
+
+// selectCommaSeparated returns the trimmed, non-empty values of a comma
+// separated flag value, or the fallback if the flag value is empty
+func selectCommaSeparated(flagValue string, fallback []string) []string {
+	if flagValue == "" {
+		return fallback
+	}
+	result := make([]string, 0)
+	for _, value := range strings.Split(flagValue, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
 func (deployment *DeployInfo) ChangesetType() types.ChangeSetType {
 	if deployment.IsNew {
 		return types.ChangeSetTypeCreate
@@ -89,7 +253,7 @@ func (deployment *DeployInfo) ChangesetType() types.ChangeSetType {
 	return types.ChangeSetTypeUpdate
 }
 
-func GetStack(stackname *string, svc *cloudformation.Client) (types.Stack, error) {
+func GetStack(stackname *string, svc CloudFormationDescribeStacksAPI) (types.Stack, error) {
 	input := &cloudformation.DescribeStacksInput{}
 	if *stackname != "" && !strings.Contains(*stackname, "*") {
 		input.StackName = stackname
@@ -101,30 +265,11 @@ func GetStack(stackname *string, svc *cloudformation.Client) (types.Stack, error
 	return resp.Stacks[0], err
 }
 
-func GetCfnStacks(stackname *string, svc *cloudformation.Client) (map[string]CfnStack, error) {
+func GetCfnStacks(stackname *string, svc CloudFormationDescribeStacksAndImportsAPI) (map[string]CfnStack, error) {
 	result := make(map[string]CfnStack)
-	input := &cloudformation.DescribeStacksInput{}
-	if *stackname != "" && !strings.Contains(*stackname, "*") {
-		input.StackName = stackname
-	}
-	paginator := cloudformation.NewDescribeStacksPaginator(svc, input)
-	allstacks := make([]types.Stack, 0)
-	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(context.TODO())
-		if err != nil {
-			return nil, err
-		}
-		allstacks = append(allstacks, output.Stacks...)
-	}
-	stackRegex := "^" + strings.Replace(*stackname, "*", ".*", -1) + "$"
-	tocheckstacks := make([]types.Stack, 0)
-	for _, stack := range allstacks {
-		if strings.Contains(*stackname, "*") {
-			if matched, _ := regexp.MatchString(stackRegex, *stack.StackName); !matched {
-				continue
-			}
-		}
-		tocheckstacks = append(tocheckstacks, stack)
+	tocheckstacks, err := GetMatchingStacks(*stackname, svc)
+	if err != nil {
+		return nil, err
 	}
 	for _, stack := range tocheckstacks {
 		stackobject := CfnStack{
@@ -136,10 +281,18 @@ func GetCfnStacks(stackname *string, svc *cloudformation.Client) (map[string]Cfn
 			stackobject.Description = *stack.Description
 		}
 		outputs := getOutputsForStack(stack, "", "", false)
-		for _, output := range outputs {
-			output.FillImports(svc)
-			if output.Imported {
-				stackobject.ImportedBy = append(stackobject.ImportedBy, output.ImportedBy...)
+		importers := make(map[string]bool)
+		for i := range outputs {
+			outputs[i].FillImports(svc)
+			if !outputs[i].Imported {
+				continue
+			}
+			// A stack that imports several exports is only listed once
+			for _, importer := range outputs[i].ImportedBy {
+				if !importers[importer] {
+					importers[importer] = true
+					stackobject.ImportedBy = append(stackobject.ImportedBy, importer)
+				}
 			}
 		}
 		stackobject.Outputs = outputs
@@ -148,6 +301,75 @@ func GetCfnStacks(stackname *string, svc *cloudformation.Client) (map[string]Cfn
 	return result, nil
 }
 
+// GetMatchingStacks returns the stacks whose name matches the filter, which
+// supports wildcards, using a paginated DescribeStacks. All stacks are returned
+// when the filter is empty. Unlike GetCfnStacks, the imports of the outputs
+// aren't retrieved.
+func GetMatchingStacks(stackNameFilter string, svc CloudFormationDescribeStacksAPI) ([]types.Stack, error) {
+	input := &cloudformation.DescribeStacksInput{}
+	if stackNameFilter != "" && !strings.Contains(stackNameFilter, "*") {
+		input.StackName = &stackNameFilter
+	}
+	result := make([]types.Stack, 0)
+	paginator := cloudformation.NewDescribeStacksPaginator(svc, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, stack := range output.Stacks {
+			if matchesStackNameFilter(stackNameFilter, aws.ToString(stack.StackName)) {
+				result = append(result, stack)
+			}
+		}
+	}
+	return result, nil
+}
+
+// matchesStackNameFilter returns whether the stack name matches the filter,
+// where * matches any number of characters. Filters without a wildcard are
+// used directly in the DescribeStacks call, so they always match.
+func matchesStackNameFilter(filter string, stackname string) bool {
+	if !strings.Contains(filter, "*") {
+		return true
+	}
+	stackRegex := "^" + strings.Replace(regexp.QuoteMeta(filter), "\\*", ".*", -1) + "$"
+	matched, _ := regexp.MatchString(stackRegex, stackname)
+	return matched
+}
+
+// GetStacksWithDrift returns the stacks that were found to be drifted by their
+// most recent drift detection, sorted by name. The stack name filter supports
+// wildcards, and all stacks are checked when it's empty. No new drift detection
+// is started, so stacks whose drift was never detected aren't included.
+func GetStacksWithDrift(stackNameFilter *string, svc CloudFormationDescribeStacksAPI) ([]CfnStack, error) {
+	filter := ""
+	if stackNameFilter != nil {
+		filter = *stackNameFilter
+	}
+	stacks, err := GetMatchingStacks(filter, svc)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]CfnStack)
+	for _, stack := range stacks {
+		candidates[aws.ToString(stack.StackId)] = CfnStack{
+			RawInfo:     stack,
+			Name:        aws.ToString(stack.StackName),
+			Id:          aws.ToString(stack.StackId),
+			Description: aws.ToString(stack.Description),
+		}
+	}
+	result := make([]CfnStack, 0)
+	for _, stack := range FilterStacksByDriftStatus(candidates, types.StackDriftStatusDrifted) {
+		result = append(result, stack)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
 func StackExists(deployment *DeployInfo, svc *cloudformation.Client) bool {
 	stack, err := GetStack(&deployment.StackName, svc)
 	if err != nil {
@@ -171,7 +393,52 @@ func (deployment DeployInfo) IsReadyForUpdate(svc *cloudformation.Client) (bool,
 	return stringInSlice(string(stack.StackStatus), availableStatuses), string(stack.StackStatus)
 }
 
-func (deployment DeployInfo) IsOngoing(svc *cloudformation.Client) bool {
+// GetRemediationSteps returns the steps that can be taken to get a stack in
+// the provided status back into a state where it can be updated. An empty
+// slice is returned for statuses without known remediation steps.
+func GetRemediationSteps(status types.StackStatus) []string {
+	switch status {
+	case types.StackStatusRollbackFailed:
+		return []string{
+			"The stack failed to roll back after a failed creation and can't be updated",
+			"Check the stack events to find the resources that couldn't be deleted and resolve the issue, for example by emptying S3 buckets",
+			"Delete the stack (optionally retaining the resources that fail to delete) and deploy it again",
+		}
+	case types.StackStatusUpdateRollbackFailed:
+		return []string{
+			"The stack failed to roll back an update and can't be updated",
+			"Check the stack events to find the resources that couldn't be rolled back and fix them manually",
+			"Continue the rollback with the ContinueUpdateRollback API, e.g. aws cloudformation continue-update-rollback --stack-name <stack>",
+			"If resources still can't be rolled back, skip them using the --resources-to-skip option of continue-update-rollback",
+		}
+	case types.StackStatusCreateFailed:
+		return []string{
+			"The stack failed to be created",
+			"Check the stack events for the root cause of the failure, e.g. with fog report or aws cloudformation describe-stack-events",
+			"Delete the stack and deploy it again after resolving the issue",
+		}
+	case types.StackStatusDeleteFailed:
+		return []string{
+			"The stack failed to be deleted",
+			"Check the stack events to identify the resource that is blocking the deletion, such as a non-empty S3 bucket or a resource that is still in use",
+			"Resolve the issue and delete the stack again, or retain the blocking resources when deleting the stack",
+		}
+	case types.StackStatusReviewInProgress:
+		return []string{
+			"The stack was created by a change set that hasn't been executed",
+			"Execute or delete the change set, or delete the empty stack",
+		}
+	}
+	if strings.HasSuffix(string(status), "_IN_PROGRESS") {
+		return []string{
+			"Another operation is in progress for the stack",
+			"Wait until the operation finishes before deploying again",
+		}
+	}
+	return []string{}
+}
+
+func (deployment DeployInfo) IsOngoing(svc CloudFormationDescribeStacksAPI) bool {
 	stack, err := deployment.GetFreshStack(svc)
 	if err != nil {
 		return false
@@ -186,6 +453,30 @@ func (deployment DeployInfo) IsOngoing(svc *cloudformation.Client) bool {
 	return !stringInSlice(string(stack.StackStatus), availableStatuses)
 }
 
+// ErrDeploymentTimeout is returned when a deployment didn't finish before the provided deadline
+var ErrDeploymentTimeout = errors.New("timed out waiting for the deployment to finish")
+
+// WaitUntilDone polls the stack until it's no longer in an ongoing state. The poll function is
+// called before every check so callers can show progress. If the context is done before the
+// stack reaches a terminal state, DeploymentError is set and returned. The stack itself is
+// left alone, so a deployment that times out will continue in CloudFormation.
+func (deployment *DeployInfo) WaitUntilDone(ctx context.Context, svc CloudFormationDescribeStacksAPI, interval time.Duration, poll func()) error {
+	for {
+		poll()
+		if err := ctx.Err(); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = ErrDeploymentTimeout
+			}
+			deployment.DeploymentError = fmt.Errorf("stack %v: %w", deployment.StackName, err)
+			return deployment.DeploymentError
+		}
+		sleepFunc(interval)
+		if !deployment.IsOngoing(svc) {
+			return nil
+		}
+	}
+}
+
 // IsNewStack verifies if a stack is new. This can mean either that it doesn't exist yet or is in review in progress state
 func (deployment DeployInfo) IsNewStack(svc *cloudformation.Client) bool {
 	stackExists := StackExists(&deployment, svc)
@@ -202,17 +493,36 @@ func (deployment DeployInfo) IsNewStack(svc *cloudformation.Client) bool {
 	return stringInSlice(string(stack.StackStatus), availableStatuses)
 }
 
-// LoadDeploymentFile loads a deployment file and sets the StackDeploymentFile field
-func (deployment *DeployInfo) LoadDeploymentFile(filelocation string) error {
-	deploymentFile, _, err := ReadDeploymentFile(filelocation)
+// LoadDeploymentFile loads a deployment file, either from a local path or a URL,
+// and sets the StackDeploymentFile field. The environment is used to pick the
+// overrides from deployment files that contain multiple environments.
+func (deployment *DeployInfo) LoadDeploymentFile(filelocation string, environment string, s3Svc S3GetObjectAPI) error {
+	deploymentFileObject, err := ParseDeploymentFileFromURL(filelocation, environment, s3Svc)
 	if err != nil {
 		return err
 	}
-	deploymentFileObject, err := ParseDeploymentFile(deploymentFile)
+	deployment.StackDeploymentFile = &deploymentFileObject
+	if !strings.Contains(filelocation, "://") {
+		deployment.DeploymentFilePath, _ = findFile(filelocation, "deployments")
+	}
+	return nil
+}
+
+// LoadEncryptedDeploymentFile loads a local deployment file that is encrypted with SOPS
+func (deployment *DeployInfo) LoadEncryptedDeploymentFile(filelocation string, environment string, decryptor SopsDecryptor) error {
+	contents, path, err := ReadEncryptedFile(&filelocation, "deployments", decryptor)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(contents) == "" {
+		return fmt.Errorf("deployment file %s is empty", filelocation)
+	}
+	deploymentFileObject, err := ParseDeploymentFileV2(contents, environment)
 	if err != nil {
 		return err
 	}
 	deployment.StackDeploymentFile = &deploymentFileObject
+	deployment.DeploymentFilePath = path
 	return nil
 }
 
@@ -226,7 +536,7 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-func (deployment *DeployInfo) CreateChangeSet(svc *cloudformation.Client) (string, error) {
+func (deployment *DeployInfo) CreateChangeSet(svc CloudFormationCreateChangeSetAPI) (string, error) {
 	input := &cloudformation.CreateChangeSetInput{
 		StackName:     &deployment.StackName,
 		ChangeSetType: deployment.ChangesetType(),
@@ -246,6 +556,18 @@ func (deployment *DeployInfo) CreateChangeSet(svc *cloudformation.Client) (strin
 	if len(deployment.Tags) != 0 {
 		input.Tags = deployment.Tags
 	}
+	if len(deployment.NotificationARNs) != 0 {
+		input.NotificationARNs = deployment.NotificationARNs
+	}
+	if deployment.ChangesetDescription != "" {
+		input.Description = &deployment.ChangesetDescription
+	}
+	if deployment.RoleARN != "" {
+		input.RoleARN = &deployment.RoleARN
+	}
+	if deployment.RollbackConfiguration != nil {
+		input.RollbackConfiguration = deployment.RollbackConfiguration
+	}
 	resp, err := svc.CreateChangeSet(context.TODO(), input)
 	if err != nil {
 		return "", err
@@ -253,15 +575,82 @@ func (deployment *DeployInfo) CreateChangeSet(svc *cloudformation.Client) (strin
 	return *resp.Id, nil
 }
 
+// SetTerminationProtection enables or disables the termination protection of the stack
+func SetTerminationProtection(stackname string, enabled bool, svc CloudFormationUpdateTerminationProtectionAPI) error {
+	input := &cloudformation.UpdateTerminationProtectionInput{
+		StackName:                   &stackname,
+		EnableTerminationProtection: aws.Bool(enabled),
+	}
+	_, err := svc.UpdateTerminationProtection(context.TODO(), input)
+	return err
+}
+
+// ParseParameterString parses the contents of a parameter file. Besides the
+// CloudFormation JSON format, the same structure in YAML and TOML files with a
+// [parameters] section of Key = "Value" pairs are supported.
 func ParseParameterString(parameters string) ([]types.Parameter, error) {
 	result := make([]types.Parameter, 0)
-	err := json.Unmarshal([]byte(parameters), &result)
+	converted, err := keyValueFileToJson(parameters, "parameters", "ParameterKey", "ParameterValue")
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(converted, &result)
 	if err != nil {
 		return result, err
 	}
 	return result, nil
 }
 
+// ParseParameterOverrides parses parameters in the Key=Value format used by
+// the --parameter-overrides flag of aws cloudformation deploy. Only the first
+// = is used as separator, so values may contain = themselves.
+func ParseParameterOverrides(overrides []string) ([]types.Parameter, error) {
+	result := make([]types.Parameter, 0, len(overrides))
+	for _, override := range overrides {
+		key, value, found := strings.Cut(override, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return result, fmt.Errorf("invalid parameter override '%v', expected the format Key=Value", override)
+		}
+		result = append(result, types.Parameter{
+			ParameterKey:   aws.String(strings.TrimSpace(key)),
+			ParameterValue: aws.String(value),
+		})
+	}
+	return result, nil
+}
+
+// MergeParameters combines two sets of parameters, where the additional
+// parameters replace existing ones with the same key
+func MergeParameters(existing []types.Parameter, additional []types.Parameter) []types.Parameter {
+	result := make([]types.Parameter, 0, len(existing)+len(additional))
+	positions := make(map[string]int)
+	for _, parameter := range append(append([]types.Parameter{}, existing...), additional...) {
+		key := aws.ToString(parameter.ParameterKey)
+		if position, ok := positions[key]; ok {
+			result[position] = parameter
+			continue
+		}
+		positions[key] = len(result)
+		result = append(result, parameter)
+	}
+	return result
+}
+
+// InheritParameters returns the parameters of the current stack set to use
+// their previous values, combined with the overrides. Overrides replace the
+// inherited parameter with the same key and parameters that don't exist in the
+// current stack are added.
+func InheritParameters(current []types.Parameter, overrides []types.Parameter) []types.Parameter {
+	inherited := make([]types.Parameter, 0, len(current))
+	for _, parameter := range current {
+		inherited = append(inherited, types.Parameter{
+			ParameterKey:     parameter.ParameterKey,
+			UsePreviousValue: aws.Bool(true),
+		})
+	}
+	return MergeParameters(inherited, overrides)
+}
+
 // ParseDeploymentFile parses a deployment file and returns a StackDeploymentFile object
 func ParseDeploymentFile(deploymentFile string) (StackDeploymentFile, error) {
 	// If the deploymentfile is yaml, convert it to json
@@ -283,37 +672,265 @@ func ParseDeploymentFile(deploymentFile string) (StackDeploymentFile, error) {
 	return result, nil
 }
 
+// deploymentFileV2 is the deployment file format that supports overrides per environment
+type deploymentFileV2 struct {
+	Defaults     StackDeploymentFile            `json:"defaults"`
+	Environments map[string]StackDeploymentFile `json:"environments"`
+}
+
+// ParseDeploymentFileV2 parses a deployment file that has a defaults section and
+// an environments section with overrides per environment. The defaults are merged
+// with the overrides of the provided environment, where the values of the
+// environment take precedence. Deployment files without a defaults section are
+// parsed as regular deployment files, which can't be combined with an environment.
+func ParseDeploymentFileV2(deploymentFile string, environment string) (StackDeploymentFile, error) {
+	deploymentFile = strings.TrimSpace(deploymentFile)
+	if len(deploymentFile) == 0 {
+		return StackDeploymentFile{}, fmt.Errorf("the deployment file is empty")
+	}
+	// If the deploymentfile is yaml, convert it to json
+	if deploymentFile[0] != '{' {
+		deploymentFileBytes, err := YamlToJson([]byte(deploymentFile))
+		if err != nil {
+			return StackDeploymentFile{}, err
+		}
+		deploymentFile = string(deploymentFileBytes)
+	}
+	sections := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(deploymentFile), &sections); err != nil {
+		return StackDeploymentFile{}, err
+	}
+	if _, ok := sections["defaults"]; !ok {
+		if environment != "" {
+			return StackDeploymentFile{}, fmt.Errorf("environment %v was requested, but the deployment file doesn't have a defaults and environments section", environment)
+		}
+		return ParseDeploymentFile(deploymentFile)
+	}
+	parsed := deploymentFileV2{}
+	if err := json.Unmarshal([]byte(deploymentFile), &parsed); err != nil {
+		return StackDeploymentFile{}, err
+	}
+	if environment == "" {
+		return parsed.Defaults, nil
+	}
+	overrides, ok := parsed.Environments[environment]
+	if !ok {
+		return StackDeploymentFile{}, fmt.Errorf("environment %v isn't defined in the deployment file", environment)
+	}
+	return mergeDeploymentFiles(parsed.Defaults, overrides), nil
+}
+
+// mergeDeploymentFiles merges the overrides into the defaults
+func mergeDeploymentFiles(defaults StackDeploymentFile, overrides StackDeploymentFile) StackDeploymentFile {
+	result := StackDeploymentFile{
+		TemplateFilePath: defaults.TemplateFilePath,
+		Parameters:       make(map[string]string),
+		Tags:             make(map[string]string),
+	}
+	result.TemplateURL = defaults.TemplateURL
+	// An override of the template replaces both the path and the URL
+	if overrides.TemplateFilePath != "" || overrides.TemplateURL != "" {
+		result.TemplateFilePath = overrides.TemplateFilePath
+		result.TemplateURL = overrides.TemplateURL
+	}
+	result.NotificationARNs = defaults.NotificationARNs
+	if len(overrides.NotificationARNs) != 0 {
+		result.NotificationARNs = overrides.NotificationARNs
+	}
+	result.ChangesetDescription = defaults.ChangesetDescription
+	if overrides.ChangesetDescription != "" {
+		result.ChangesetDescription = overrides.ChangesetDescription
+	}
+	result.RoleARN = defaults.RoleARN
+	if overrides.RoleARN != "" {
+		result.RoleARN = overrides.RoleARN
+	}
+	result.RollbackConfiguration = defaults.RollbackConfiguration
+	if overrides.RollbackConfiguration != nil {
+		result.RollbackConfiguration = overrides.RollbackConfiguration
+	}
+	for _, source := range []StackDeploymentFile{defaults, overrides} {
+		for key, value := range source.Parameters {
+			result.Parameters[key] = value
+		}
+		for key, value := range source.Tags {
+			result.Tags[key] = value
+		}
+	}
+	return result
+}
+
+// ParseTagString parses the contents of a tag file. Besides the CloudFormation
+// JSON format, the same structure in YAML and TOML files with a [tags] section
+// of Key = "Value" pairs are supported.
 func ParseTagString(tags string) ([]types.Tag, error) {
 	result := make([]types.Tag, 0)
-	err := json.Unmarshal([]byte(tags), &result)
+	converted, err := keyValueFileToJson(tags, "tags", "Key", "Value")
 	if err != nil {
 		return result, err
 	}
+	err = json.Unmarshal(converted, &result)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// GetMissingRequiredTags returns the required tag keys that aren't present in
+// the tags or have an empty value. When a key is present multiple times, the
+// last value is used. With ignoreCase, a tag key only needs to match the
+// required key regardless of case.
+func GetMissingRequiredTags(tags []types.Tag, required []string, ignoreCase bool) []string {
+	normalize := func(key string) string {
+		if ignoreCase {
+			return strings.ToLower(key)
+		}
+		return key
+	}
+	values := make(map[string]string)
+	for _, tag := range tags {
+		values[normalize(aws.ToString(tag.Key))] = aws.ToString(tag.Value)
+	}
+	missing := make([]string, 0)
+	for _, key := range required {
+		if key == "" || stringInSlice(key, missing) {
+			continue
+		}
+		if strings.TrimSpace(values[normalize(key)]) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// ParseTagFilterString parses a comma separated list of key=value pairs, such as
+// "Environment=prod,Team=platform", into a map of tag keys and values
+func ParseTagFilterString(filters string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(filters) == "" {
+		return result, nil
+	}
+	for _, filter := range strings.Split(filters, ",") {
+		key, value, err := ParseTagFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		if err := AddTagFilter(result, key, value); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
-func (deployment *DeployInfo) WaitUntilChangesetDone(svc *cloudformation.Client) (*ChangesetInfo, error) {
-	time.Sleep(5 * time.Second)
+// ParseTagFilter parses a single key=value pair. Everything after the first
+// equals sign, including any commas, is part of the value
+func ParseTagFilter(filter string) (string, string, error) {
+	key, value, found := strings.Cut(filter, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid tag filter %q, expected the format Key=Value", filter)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// AddTagFilter adds the key and value to the filters. It returns an error if
+// the key is already filtered on a different value, as no stack can match both
+func AddTagFilter(filters map[string]string, key string, value string) error {
+	if existing, ok := filters[key]; ok && existing != value {
+		return fmt.Errorf("tag %q is filtered on both %q and %q", key, existing, value)
+	}
+	filters[key] = value
+	return nil
+}
+
+// FilterStacksByTags returns only the stacks that have all of the provided tags
+// with the matching values
+func FilterStacksByTags(stacks map[string]CfnStack, filters map[string]string) map[string]CfnStack {
+	result := make(map[string]CfnStack)
+	for id, stack := range stacks {
+		stacktags := make(map[string]string)
+		for _, tag := range stack.RawInfo.Tags {
+			stacktags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		matches := true
+		for key, value := range filters {
+			if stackvalue, ok := stacktags[key]; !ok || stackvalue != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result[id] = stack
+		}
+	}
+	return result
+}
+
+// FilterStacksByDriftStatus returns only the stacks with the provided drift
+// status. Stacks without drift information are treated as NOT_CHECKED.
+func FilterStacksByDriftStatus(stacks map[string]CfnStack, status types.StackDriftStatus) map[string]CfnStack {
+	result := make(map[string]CfnStack)
+	for id, stack := range stacks {
+		if GetStackDriftStatus(stack) == status {
+			result[id] = stack
+		}
+	}
+	return result
+}
+
+// GetStackDriftStatus returns the drift status of the stack, or NOT_CHECKED if
+// the stack doesn't have any drift information
+func GetStackDriftStatus(stack CfnStack) types.StackDriftStatus {
+	if stack.RawInfo.DriftInformation == nil || stack.RawInfo.DriftInformation.StackDriftStatus == "" {
+		return types.StackDriftStatusNotChecked
+	}
+	return stack.RawInfo.DriftInformation.StackDriftStatus
+}
+
+// sleepFunc is used to wait between polls, it can be replaced in tests
+var sleepFunc = time.Sleep
+
+const (
+	changesetPollInitialInterval = 2 * time.Second
+	changesetPollMaxInterval     = 30 * time.Second
+)
+
+// WaitUntilChangesetDone polls the change set until it's either created or has
+// failed. The interval between polls starts at 2 seconds and doubles up to 30
+// seconds, so simple change sets are picked up quickly without polling large
+// ones too often. Throttled requests are retried using RetryWithBackoff.
+// Polling stops when the context is cancelled.
+func (deployment *DeployInfo) WaitUntilChangesetDone(ctx context.Context, svc CloudFormationDescribeChangeSetAPI) (*ChangesetInfo, error) {
 	changeset := ChangesetInfo{}
 	availableStatuses := []string{
 		string(types.ChangeSetStatusCreateComplete),
 		string(types.ChangeSetStatusFailed),
 		string(types.ChangeSetStatusDeleteFailed),
 	}
-	resp, err := deployment.GetChangeset(svc)
-	if err != nil {
-		return &changeset, err
-	}
-
-	for !stringInSlice(string(resp[0].Status), availableStatuses) {
-		time.Sleep(5 * time.Second)
-		resp, err = deployment.GetChangeset(svc)
+	interval := changesetPollInitialInterval
+	for {
+		if err := ctx.Err(); err != nil {
+			return &changeset, err
+		}
+		sleepFunc(interval)
+		if err := ctx.Err(); err != nil {
+			return &changeset, err
+		}
+		var resp []cloudformation.DescribeChangeSetOutput
+		err := RetryWithBackoff(func() error {
+			var err error
+			resp, err = deployment.GetChangeset(svc)
+			return err
+		}, retryMaxRetries(), retryInitialDelay)
 		if err != nil {
 			return &changeset, err
 		}
+		if stringInSlice(string(resp[0].Status), availableStatuses) {
+			changeset = deployment.AddChangeset(resp)
+			return &changeset, nil
+		}
+		interval = min(interval*2, changesetPollMaxInterval)
 	}
-	changeset = deployment.AddChangeset(resp)
-	return &changeset, err
 }
 
 func (deployment *DeployInfo) AddChangeset(resp []cloudformation.DescribeChangeSetOutput) ChangesetInfo {
@@ -337,6 +954,7 @@ func (deployment *DeployInfo) AddChangeset(resp []cloudformation.DescribeChangeS
 	changeset.StackID = *resp[0].StackId
 	changeset.StackName = *resp[0].StackName
 	changeset.Status = string(resp[0].Status)
+	changeset.ExecutionStatus = string(resp[0].ExecutionStatus)
 	statusreason := ""
 	if resp[0].StatusReason != nil {
 		statusreason = *resp[0].StatusReason
@@ -350,7 +968,7 @@ func (deployment *DeployInfo) AddChangeset(resp []cloudformation.DescribeChangeS
 	return changeset
 }
 
-func (deployment *DeployInfo) GetChangeset(svc *cloudformation.Client) ([]cloudformation.DescribeChangeSetOutput, error) {
+func (deployment *DeployInfo) GetChangeset(svc CloudFormationDescribeChangeSetAPI) ([]cloudformation.DescribeChangeSetOutput, error) {
 	results := []cloudformation.DescribeChangeSetOutput{}
 	input := &cloudformation.DescribeChangeSetInput{
 		ChangeSetName: &deployment.ChangesetName,
@@ -358,10 +976,10 @@ func (deployment *DeployInfo) GetChangeset(svc *cloudformation.Client) ([]cloudf
 		StackName:     &deployment.StackName,
 	}
 	resp, err := svc.DescribeChangeSet(context.TODO(), input)
-	results = append(results, *resp)
 	if err != nil {
 		return results, err
 	}
+	results = append(results, *resp)
 	// write a for loop to get all the changesets
 	for resp.NextToken != nil {
 		input = &cloudformation.DescribeChangeSetInput{
@@ -370,15 +988,15 @@ func (deployment *DeployInfo) GetChangeset(svc *cloudformation.Client) ([]cloudf
 			StackName:     &deployment.StackName,
 		}
 		resp, err = svc.DescribeChangeSet(context.TODO(), input)
-		results = append(results, *resp)
 		if err != nil {
 			return results, err
 		}
+		results = append(results, *resp)
 	}
 	return results, nil
 }
 
-func (deployment *DeployInfo) GetFreshStack(svc *cloudformation.Client) (types.Stack, error) {
+func (deployment *DeployInfo) GetFreshStack(svc CloudFormationDescribeStacksAPI) (types.Stack, error) {
 	return GetStack(&deployment.StackArn, svc)
 }
 
@@ -393,13 +1011,14 @@ func (deployment *DeployInfo) GetStack(svc *cloudformation.Client) (types.Stack,
 	return *deployment.RawStack, nil
 }
 
-func (deployment *DeployInfo) GetEvents(svc *cloudformation.Client) ([]types.StackEvent, error) {
-	input := &cloudformation.DescribeStackEventsInput{
-		StackName: &deployment.StackName,
+// GetEvents returns the events of the stack. If a change set is available, only
+// the events since the creation of the change set are returned.
+func (deployment *DeployInfo) GetEvents(ctx context.Context, svc CloudFormationEventsFetcher) ([]types.StackEvent, error) {
+	opts := EventFetchOptions{}
+	if deployment.Changeset != nil {
+		opts.Since = deployment.Changeset.CreationTime
 	}
-	resp, err := svc.DescribeStackEvents(context.TODO(), input)
-	return resp.StackEvents, err
-
+	return GetAllEvents(ctx, deployment.StackName, opts, svc)
 }
 
 func (deployment *DeployInfo) GetCleanedStackName() string {
@@ -411,21 +1030,13 @@ func (deployment *DeployInfo) GetCleanedStackName() string {
 	return deployment.StackName
 }
 
-func (stack *CfnStack) GetEvents(svc *cloudformation.Client) ([]StackEvent, error) {
+func (stack *CfnStack) GetEvents(ctx context.Context, svc CloudFormationEventsFetcher) ([]StackEvent, error) {
 	if len(stack.Events) != 0 {
 		return stack.Events, nil
 	}
-	input := &cloudformation.DescribeStackEventsInput{
-		StackName: &stack.Id,
-	}
-	paginator := cloudformation.NewDescribeStackEventsPaginator(svc, input)
-	allevents := make([]types.StackEvent, 0)
-	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(context.TODO())
-		if err != nil {
-			return nil, err
-		}
-		allevents = append(allevents, output.StackEvents...)
+	allevents, err := GetAllEvents(ctx, stack.Id, EventFetchOptions{}, svc)
+	if err != nil {
+		return nil, err
 	}
 	sort.Sort(ReverseEvents(allevents))
 	var resources map[string]ResourceEvent
@@ -534,6 +1145,35 @@ func (stack *CfnStack) GetEvents(svc *cloudformation.Client) ([]StackEvent, erro
 	return stack.Events, nil
 }
 
+// GetRecentFailureReason returns the logical ID and failure reason of the first
+// resource that failed in the most recent stack event. This is usually the root
+// cause of a failed deployment, as later failures tend to be cancellations.
+// Empty strings are returned if no resource failed.
+func GetRecentFailureReason(events []StackEvent) (string, string) {
+	if len(events) == 0 {
+		return "", ""
+	}
+	latest := events[0]
+	for _, event := range events[1:] {
+		if event.StartDate.After(latest.StartDate) {
+			latest = event
+		}
+	}
+	var failed *ResourceEvent
+	for i, resource := range latest.ResourceEvents {
+		if !strings.HasSuffix(resource.EndStatus, "FAILED") {
+			continue
+		}
+		if failed == nil || resource.EndDate.Before(failed.EndDate) {
+			failed = &latest.ResourceEvents[i]
+		}
+	}
+	if failed == nil {
+		return "", ""
+	}
+	return failed.Resource.LogicalID, failed.EndStatusReason
+}
+
 func GetSuccessStates() []string {
 	return []string{
 		string(types.StackStatusCreateComplete),
@@ -553,27 +1193,162 @@ func (event *StackEvent) GetDuration() time.Duration {
 	return event.EndDate.Sub(event.StartDate)
 }
 
-func (stack *CfnStack) GetEventSummaries(svc *cloudformation.Client) ([]types.StackEvent, error) {
-	input := &cloudformation.DescribeStackEventsInput{
-		StackName: &stack.Id,
-	}
-	resp, err := svc.DescribeStackEvents(context.TODO(), input)
-	return resp.StackEvents, err
-
+func (stack *CfnStack) GetEventSummaries(ctx context.Context, svc CloudFormationEventsFetcher) ([]types.StackEvent, error) {
+	return GetAllEvents(ctx, stack.Id, EventFetchOptions{}, svc)
 }
 
 func (deployment *DeployInfo) DeleteStack(svc *cloudformation.Client) bool {
+	return deployment.StartStackDeletion(svc) == nil
+}
+
+// StartStackDeletion requests the deletion of the stack without waiting for it to finish
+func (deployment *DeployInfo) StartStackDeletion(svc CloudFormationDeleteStackAPI) error {
 	input := &cloudformation.DeleteStackInput{
 		StackName: &deployment.StackName,
 	}
 	_, err := svc.DeleteStack(context.TODO(), input)
+	return err
+}
 
-	return err == nil
+// ErrStackDeleteFailed is returned when a stack ends up in the DELETE_FAILED status
+var ErrStackDeleteFailed = errors.New("the stack couldn't be deleted")
+
+// WaitUntilStackDeleted polls the stack until it's deleted or the deletion
+// failed. The stack is looked up by its ARN as the name can't be used once the
+// stack is deleted, so StackArn needs to be set. The poll function is called
+// with the state of the stack after every check.
+func (deployment *DeployInfo) WaitUntilStackDeleted(ctx context.Context, svc CloudFormationDescribeStacksAPI, interval time.Duration, poll func(types.Stack)) (types.Stack, error) {
+	for {
+		stack, err := deployment.GetFreshStack(svc)
+		if err != nil {
+			if strings.Contains(err.Error(), "does not exist") {
+				return stack, nil
+			}
+			return stack, err
+		}
+		poll(stack)
+		switch stack.StackStatus {
+		case types.StackStatusDeleteComplete:
+			return stack, nil
+		case types.StackStatusDeleteFailed:
+			return stack, ErrStackDeleteFailed
+		}
+		if err := ctx.Err(); err != nil {
+			return stack, err
+		}
+		sleepFunc(interval)
+	}
+}
+
+// ErrStackNotRollbackFailed is returned when continuing the rollback of a stack whose rollback didn't fail
+var ErrStackNotRollbackFailed = errors.New("the stack isn't in status UPDATE_ROLLBACK_FAILED")
+
+// ErrRollbackFailed is returned when a continued rollback failed again
+var ErrRollbackFailed = errors.New("the rollback of the stack failed")
+
+// ErrNotRolledBack is returned when the stack finished in a status that isn't
+// the result of a rollback, for example because the update completed before
+// it could be cancelled
+var ErrNotRolledBack = errors.New("the stack wasn't rolled back")
+
+// ContinueUpdateRollback retries the rollback of a stack in status
+// UPDATE_ROLLBACK_FAILED. The resources to skip are the logical IDs of
+// resources that CloudFormation should consider rolled back without trying.
+func ContinueUpdateRollback(stack types.Stack, resourcesToSkip []string, svc CloudFormationContinueUpdateRollbackAPI) error {
+	if stack.StackStatus != types.StackStatusUpdateRollbackFailed {
+		return fmt.Errorf("%w: stack %v is in status %v", ErrStackNotRollbackFailed, aws.ToString(stack.StackName), stack.StackStatus)
+	}
+	input := &cloudformation.ContinueUpdateRollbackInput{
+		StackName: stack.StackId,
+	}
+	if len(resourcesToSkip) != 0 {
+		input.ResourcesToSkip = resourcesToSkip
+	}
+	_, err := svc.ContinueUpdateRollback(context.TODO(), input)
+	return err
+}
+
+// GetRollbackFailures returns the most recent failure of each resource that
+// failed during the latest rollback of the stack. The events need to be sorted
+// newest first, as returned by the DescribeStackEvents API.
+func GetRollbackFailures(events []types.StackEvent) []types.StackEvent {
+	result := make([]types.StackEvent, 0)
+	seen := make(map[string]bool)
+	for _, event := range events {
+		isStack := aws.ToString(event.PhysicalResourceId) == aws.ToString(event.StackId)
+		if isStack {
+			if event.ResourceStatus == types.ResourceStatusUpdateRollbackInProgress || event.ResourceStatus == types.ResourceStatusRollbackInProgress {
+				break
+			}
+			continue
+		}
+		logicalID := aws.ToString(event.LogicalResourceId)
+		if seen[logicalID] || !strings.HasSuffix(string(event.ResourceStatus), "_FAILED") {
+			continue
+		}
+		seen[logicalID] = true
+		result = append(result, event)
+	}
+	return result
+}
+
+// GetResourcesToSkip returns the logical IDs of the failed resources that can
+// be skipped when continuing the rollback. CloudFormation only allows skipping
+// resources in status UPDATE_FAILED, and nested stacks can't be skipped.
+func GetResourcesToSkip(failures []types.StackEvent) []string {
+	result := make([]string, 0)
+	for _, failure := range failures {
+		if failure.ResourceStatus != types.ResourceStatusUpdateFailed || aws.ToString(failure.ResourceType) == "AWS::CloudFormation::Stack" {
+			continue
+		}
+		result = append(result, aws.ToString(failure.LogicalResourceId))
+	}
+	return result
+}
+
+// ErrStackNotUpdating is returned when cancelling the update of a stack that isn't being updated
+var ErrStackNotUpdating = errors.New("the stack isn't in status UPDATE_IN_PROGRESS")
+
+// CancelUpdateStack cancels the update of a stack in status
+// UPDATE_IN_PROGRESS, which causes CloudFormation to roll back the update.
+func CancelUpdateStack(stack types.Stack, svc CloudFormationCancelUpdateStackAPI) error {
+	if stack.StackStatus != types.StackStatusUpdateInProgress {
+		return fmt.Errorf("%w: stack %v is in status %v", ErrStackNotUpdating, aws.ToString(stack.StackName), stack.StackStatus)
+	}
+	_, err := svc.CancelUpdateStack(context.TODO(), &cloudformation.CancelUpdateStackInput{StackName: stack.StackId})
+	return err
+}
+
+// WaitUntilRollbackDone polls the stack until its update rollback, either
+// continued or caused by cancelling the update, has finished or failed. The
+// poll function is called with the state of the stack after every check. Any
+// status that isn't in progress ends the wait, and a status other than
+// UPDATE_ROLLBACK_COMPLETE or UPDATE_ROLLBACK_FAILED results in ErrNotRolledBack.
+func (deployment *DeployInfo) WaitUntilRollbackDone(ctx context.Context, svc CloudFormationDescribeStacksAPI, interval time.Duration, poll func(types.Stack)) (types.Stack, error) {
+	for {
+		stack, err := deployment.GetFreshStack(svc)
+		if err != nil {
+			return stack, err
+		}
+		poll(stack)
+		switch {
+		case stack.StackStatus == types.StackStatusUpdateRollbackComplete:
+			return stack, nil
+		case stack.StackStatus == types.StackStatusUpdateRollbackFailed:
+			return stack, ErrRollbackFailed
+		case !strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS"):
+			return stack, fmt.Errorf("%w, it finished in status %v", ErrNotRolledBack, stack.StackStatus)
+		}
+		if err := ctx.Err(); err != nil {
+			return stack, err
+		}
+		sleepFunc(interval)
+	}
 }
 
-func (deployment *DeployInfo) GetExecutionTimes(svc *cloudformation.Client) (map[string]map[string]time.Time, error) {
+func (deployment *DeployInfo) GetExecutionTimes(ctx context.Context, svc CloudFormationEventsFetcher) (map[string]map[string]time.Time, error) {
 	result := make(map[string]map[string]time.Time)
-	events, err := deployment.GetEvents(svc)
+	events, err := deployment.GetEvents(ctx, svc)
 	if err != nil {
 		return result, err
 	}
@@ -608,3 +1383,57 @@ type SortStacks []CfnStack
 func (a SortStacks) Len() int           { return len(a) }
 func (a SortStacks) Less(i, j int) bool { return strings.Compare(a[i].Name, a[j].Name) == -1 }
 func (a SortStacks) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// SortField is the field stacks can be sorted by
+type SortField string
+
+const (
+	SortByName    SortField = "NAME"
+	SortByStatus  SortField = "STATUS"
+	SortByCreated SortField = "CREATED"
+	SortByUpdated SortField = "UPDATED"
+)
+
+// ParseSortField returns the sort field for the value, ignoring case
+func ParseSortField(value string) (SortField, error) {
+	field := SortField(strings.ToUpper(strings.TrimSpace(value)))
+	switch field {
+	case SortByName, SortByStatus, SortByCreated, SortByUpdated:
+		return field, nil
+	}
+	return "", fmt.Errorf("invalid sort field %v, use one of NAME, STATUS, CREATED, or UPDATED", value)
+}
+
+// SortCfnStacks returns a copy of the stacks sorted by the field, with the
+// name used for stacks that have the same value. Stacks that were never
+// updated use their creation time when sorting by UPDATED.
+func SortCfnStacks(stacks []CfnStack, by SortField, reverse bool) []CfnStack {
+	result := make([]CfnStack, len(stacks))
+	copy(result, stacks)
+	sort.Sort(SortStacks(result))
+	sort.SliceStable(result, func(i, j int) bool {
+		switch by {
+		case SortByStatus:
+			return result[i].RawInfo.StackStatus < result[j].RawInfo.StackStatus
+		case SortByCreated:
+			return aws.ToTime(result[i].RawInfo.CreationTime).Before(aws.ToTime(result[j].RawInfo.CreationTime))
+		case SortByUpdated:
+			return lastUpdated(result[i]).Before(lastUpdated(result[j]))
+		}
+		return false
+	})
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	return result
+}
+
+// lastUpdated returns when the stack was last updated, or created if it was never updated
+func lastUpdated(stack CfnStack) time.Time {
+	if stack.RawInfo.LastUpdatedTime != nil {
+		return *stack.RawInfo.LastUpdatedTime
+	}
+	return aws.ToTime(stack.RawInfo.CreationTime)
+}
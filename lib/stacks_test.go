@@ -1,10 +1,17 @@
 package lib
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
@@ -110,7 +117,7 @@ func TestDeployInfo_GetExecutionTimes(t *testing.T) {
 				TemplateRelativePath: tt.fields.TemplateRelativePath,
 				TemplateUrl:          tt.fields.TemplateUrl,
 			}
-			got, err := deployment.GetExecutionTimes(tt.args.svc)
+			got, err := deployment.GetExecutionTimes(context.TODO(), tt.args.svc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DeployInfo.GetExecutionTimes() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -121,3 +128,1432 @@ func TestDeployInfo_GetExecutionTimes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTagFilterString(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"Empty string", "", map[string]string{}, false},
+		{"Single filter", "Environment=prod", map[string]string{"Environment": "prod"}, false},
+		{"Multiple filters", "Environment=prod, Team=platform", map[string]string{"Environment": "prod", "Team": "platform"}, false},
+		{"Empty value", "Environment=", map[string]string{"Environment": ""}, false},
+		{"Missing equals sign", "Environment", nil, true},
+		{"Missing key", "=prod", nil, true},
+		{"Conflicting values", "Environment=prod,Environment=dev", nil, true},
+		{"Repeated value", "Environment=prod,Environment=prod", map[string]string{"Environment": "prod"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTagFilterString(tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTagFilterString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTagFilterString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		wantKey   string
+		wantValue string
+		wantErr   bool
+	}{
+		{"Simple pair", "Team=platform", "Team", "platform", false},
+		{"Value with commas", "Name=a,b", "Name", "a,b", false},
+		{"Value with equals sign", "Query=a=b", "Query", "a=b", false},
+		{"Missing equals sign", "Team", "", "", true},
+		{"Missing key", "=platform", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, value, err := ParseTagFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTagFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.wantKey || value != tt.wantValue {
+				t.Errorf("ParseTagFilter() = %q, %q, want %q, %q", key, value, tt.wantKey, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestFilterStacksByTags(t *testing.T) {
+	stackWithTags := func(name string, tags map[string]string) CfnStack {
+		stack := CfnStack{Name: name, Id: name}
+		for key, value := range tags {
+			stack.RawInfo.Tags = append(stack.RawInfo.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		return stack
+	}
+	stacks := map[string]CfnStack{
+		"prod-platform": stackWithTags("prod-platform", map[string]string{"Environment": "prod", "Team": "platform"}),
+		"prod-data":     stackWithTags("prod-data", map[string]string{"Environment": "prod", "Team": "data"}),
+		"dev-platform":  stackWithTags("dev-platform", map[string]string{"Environment": "dev", "Team": "platform"}),
+		"untagged":      stackWithTags("untagged", nil),
+	}
+	tests := []struct {
+		name    string
+		filters map[string]string
+		want    []string
+	}{
+		{"No filters", map[string]string{}, []string{"dev-platform", "prod-data", "prod-platform", "untagged"}},
+		{"Single filter", map[string]string{"Environment": "prod"}, []string{"prod-data", "prod-platform"}},
+		{"Multiple filters are ANDed", map[string]string{"Environment": "prod", "Team": "platform"}, []string{"prod-platform"}},
+		{"No matches", map[string]string{"Environment": "test"}, []string{}},
+		{"Empty value requires the tag", map[string]string{"Team": ""}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for id := range FilterStacksByTags(stacks, tt.filters) {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterStacksByTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterStacksByDriftStatus(t *testing.T) {
+	stackWithDrift := func(name string, status types.StackDriftStatus) CfnStack {
+		stack := CfnStack{Name: name, Id: name}
+		if status != "" {
+			stack.RawInfo.DriftInformation = &types.StackDriftInformation{StackDriftStatus: status}
+		}
+		return stack
+	}
+	stacks := map[string]CfnStack{
+		"drifted":     stackWithDrift("drifted", types.StackDriftStatusDrifted),
+		"in-sync":     stackWithDrift("in-sync", types.StackDriftStatusInSync),
+		"not-checked": stackWithDrift("not-checked", types.StackDriftStatusNotChecked),
+		"no-info":     stackWithDrift("no-info", ""),
+	}
+	tests := []struct {
+		name   string
+		status types.StackDriftStatus
+		want   []string
+	}{
+		{"Drifted", types.StackDriftStatusDrifted, []string{"drifted"}},
+		{"In sync", types.StackDriftStatusInSync, []string{"in-sync"}},
+		{"Missing drift information counts as not checked", types.StackDriftStatusNotChecked, []string{"no-info", "not-checked"}},
+		{"Unknown", types.StackDriftStatusUnknown, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for id := range FilterStacksByDriftStatus(stacks, tt.status) {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterStacksByDriftStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortCfnStacks(t *testing.T) {
+	day := func(d int) *time.Time {
+		moment := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &moment
+	}
+	stack := func(name string, status types.StackStatus, created *time.Time, updated *time.Time) CfnStack {
+		result := CfnStack{Name: name}
+		result.RawInfo.StackStatus = status
+		result.RawInfo.CreationTime = created
+		result.RawInfo.LastUpdatedTime = updated
+		return result
+	}
+	stacks := []CfnStack{
+		stack("web", types.StackStatusUpdateComplete, day(1), day(10)),
+		stack("api", types.StackStatusCreateComplete, day(5), nil),
+		stack("db", types.StackStatusUpdateComplete, day(2), day(3)),
+		stack("queue", types.StackStatusCreateComplete, day(8), nil),
+	}
+	tests := []struct {
+		name    string
+		by      SortField
+		reverse bool
+		want    []string
+	}{
+		{"Name", SortByName, false, []string{"api", "db", "queue", "web"}},
+		{"Name reversed", SortByName, true, []string{"web", "queue", "db", "api"}},
+		{"Status uses the name for ties", SortByStatus, false, []string{"api", "queue", "db", "web"}},
+		{"Created", SortByCreated, false, []string{"web", "db", "api", "queue"}},
+		{"Created reversed", SortByCreated, true, []string{"queue", "api", "db", "web"}},
+		{"Updated falls back to created", SortByUpdated, false, []string{"db", "api", "queue", "web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0, len(stacks))
+			for _, stack := range SortCfnStacks(stacks, tt.by, tt.reverse) {
+				got = append(got, stack.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortCfnStacks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if stacks[0].Name != "web" {
+		t.Errorf("SortCfnStacks() modified the provided stacks")
+	}
+}
+
+func TestParseSortField(t *testing.T) {
+	if got, err := ParseSortField("updated"); err != nil || got != SortByUpdated {
+		t.Errorf("ParseSortField(updated) = %v, %v, want %v", got, err, SortByUpdated)
+	}
+	if _, err := ParseSortField("size"); err == nil {
+		t.Error("ParseSortField(size) should return an error")
+	}
+}
+
+type mockCloudFormationDescribeStacksAndImportsAPI struct {
+	describeStacks func(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+	listImports    func(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error)
+}
+
+func (m mockCloudFormationDescribeStacksAndImportsAPI) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	return m.describeStacks(ctx, params, optFns...)
+}
+
+func (m mockCloudFormationDescribeStacksAndImportsAPI) ListImports(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error) {
+	if m.listImports == nil {
+		return nil, errors.New("Export is not imported by any stack.")
+	}
+	return m.listImports(ctx, params, optFns...)
+}
+
+// pagedDescribeStacks returns a DescribeStacks mock that serves the stacks over multiple pages.
+// If failOnPage is greater than 0, that page (1-indexed) returns an error.
+func pagedDescribeStacks(pages [][]string, failOnPage int) func(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	return func(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+		page := 0
+		if params.NextToken != nil {
+			page, _ = strconv.Atoi(*params.NextToken)
+		}
+		if page+1 == failOnPage {
+			return nil, errors.New("Rate exceeded")
+		}
+		output := &cloudformation.DescribeStacksOutput{}
+		for _, name := range pages[page] {
+			output.Stacks = append(output.Stacks, types.Stack{
+				StackName: aws.String(name),
+				StackId:   aws.String("arn:aws:cloudformation:ap-southeast-2:123456789012:stack/" + name + "/id"),
+			})
+		}
+		if page+1 < len(pages) {
+			output.NextToken = aws.String(strconv.Itoa(page + 1))
+		}
+		return output, nil
+	}
+}
+
+func TestGetCfnStacks_Pagination(t *testing.T) {
+	pages := [][]string{
+		{"dev-vpc", "dev-app"},
+		{"prod-vpc", "prod-app"},
+		{"dev-db", "shared-dns"},
+	}
+	tests := []struct {
+		name      string
+		stackname string
+		failPage  int
+		want      []string
+		wantErr   bool
+	}{
+		{"All stacks from all pages", "", 0, []string{"dev-app", "dev-db", "dev-vpc", "prod-app", "prod-vpc", "shared-dns"}, false},
+		{"Wildcard filter across pages", "dev-*", 0, []string{"dev-app", "dev-db", "dev-vpc"}, false},
+		{"Wildcard filter in the middle", "*-vpc", 0, []string{"dev-vpc", "prod-vpc"}, false},
+		{"Wildcard without matches", "test-*", 0, []string{}, false},
+		{"Error on page 2 of 3", "", 2, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := mockCloudFormationDescribeStacksAndImportsAPI{describeStacks: pagedDescribeStacks(pages, tt.failPage)}
+			got, err := GetCfnStacks(&tt.stackname, svc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetCfnStacks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if got != nil {
+					t.Errorf("GetCfnStacks() returned a partial result %v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("GetCfnStacks() returned %v stacks, want %v", len(got), len(tt.want))
+			}
+			names := make([]string, 0, len(got))
+			for _, stack := range got {
+				names = append(names, stack.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("GetCfnStacks() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRecentFailureReason(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	resource := func(logicalID string, status string, reason string, minutes int) ResourceEvent {
+		return ResourceEvent{
+			Resource:        CfnResource{LogicalID: logicalID},
+			EndStatus:       status,
+			EndStatusReason: reason,
+			EndDate:         base.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+	olderFailure := StackEvent{StartDate: base.Add(-time.Hour), ResourceEvents: []ResourceEvent{
+		resource("OldBucket", "CREATE_FAILED", "old failure", -50),
+	}}
+	latestFailure := StackEvent{StartDate: base, ResourceEvents: []ResourceEvent{
+		resource("Queue", "CREATE_FAILED", "Resource creation cancelled", 3),
+		resource("Topic", "CREATE_COMPLETE", "", 1),
+		resource("Bucket", "CREATE_FAILED", "my-bucket already exists", 2),
+	}}
+	latestSuccess := StackEvent{StartDate: base, ResourceEvents: []ResourceEvent{
+		resource("Bucket", "UPDATE_COMPLETE", "", 2),
+	}}
+	tests := []struct {
+		name       string
+		events     []StackEvent
+		wantID     string
+		wantReason string
+	}{
+		{"No events", []StackEvent{}, "", ""},
+		{"First failure in most recent event", []StackEvent{olderFailure, latestFailure}, "Bucket", "my-bucket already exists"},
+		{"Order of events doesn't matter", []StackEvent{latestFailure, olderFailure}, "Bucket", "my-bucket already exists"},
+		{"Most recent event succeeded", []StackEvent{olderFailure, latestSuccess}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotReason := GetRecentFailureReason(tt.events)
+			if gotID != tt.wantID || gotReason != tt.wantReason {
+				t.Errorf("GetRecentFailureReason() = %v, %v, want %v, %v", gotID, gotReason, tt.wantID, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestParseDeploymentFileV2(t *testing.T) {
+	v2file := `defaults:
+  template-file-path: templates/vpc.yaml
+  parameters:
+    CidrBlock: 10.0.0.0/16
+    EnableFlowLogs: "false"
+  tags:
+    Team: platform
+environments:
+  prod:
+    parameters:
+      EnableFlowLogs: "true"
+    tags:
+      Environment: prod
+  test:
+    template-file-path: templates/vpc-test.yaml
+`
+	v1file := `{"template-file-path": "templates/vpc.yaml", "parameters": {"CidrBlock": "10.0.0.0/16"}, "tags": {}}`
+	tests := []struct {
+		name        string
+		content     string
+		environment string
+		want        StackDeploymentFile
+		wantErr     bool
+	}{
+		{"Merges the environment", v2file, "prod", StackDeploymentFile{
+			TemplateFilePath: "templates/vpc.yaml",
+			Parameters:       map[string]string{"CidrBlock": "10.0.0.0/16", "EnableFlowLogs": "true"},
+			Tags:             map[string]string{"Team": "platform", "Environment": "prod"},
+		}, false},
+		{"Overrides the template", v2file, "test", StackDeploymentFile{
+			TemplateFilePath: "templates/vpc-test.yaml",
+			Parameters:       map[string]string{"CidrBlock": "10.0.0.0/16", "EnableFlowLogs": "false"},
+			Tags:             map[string]string{"Team": "platform"},
+		}, false},
+		{"Defaults without environment", v2file, "", StackDeploymentFile{
+			TemplateFilePath: "templates/vpc.yaml",
+			Parameters:       map[string]string{"CidrBlock": "10.0.0.0/16", "EnableFlowLogs": "false"},
+			Tags:             map[string]string{"Team": "platform"},
+		}, false},
+		{"Unknown environment", v2file, "staging", StackDeploymentFile{}, true},
+		{"Old format", v1file, "", StackDeploymentFile{
+			TemplateFilePath: "templates/vpc.yaml",
+			Parameters:       map[string]string{"CidrBlock": "10.0.0.0/16"},
+			Tags:             map[string]string{},
+		}, false},
+		{"Old format with environment", v1file, "prod", StackDeploymentFile{}, true},
+		{"Empty file", "", "", StackDeploymentFile{}, true},
+		{"Whitespace only", "  \n", "prod", StackDeploymentFile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDeploymentFileV2(tt.content, tt.environment)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDeploymentFileV2() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDeploymentFileV2() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCfnStacks_WithImports(t *testing.T) {
+	svc := testutil.NewScenarioBuilder().
+		WithStack("network", func(stack *testutil.StackBuilder) {
+			stack.WithDescription("Shared network").WithOutput("VpcCidr", "10.0.0.0/16")
+		}).
+		WithStack("app", nil).
+		WithStack("monitoring", nil).
+		WithImport("network", "app", "network-VpcId", "vpc-123").
+		WithImport("network", "monitoring", "network-VpcId", "vpc-123").
+		WithImport("network", "app", "network-SubnetIds", "subnet-1,subnet-2").
+		Build()
+
+	allstacks := ""
+	stacks, err := GetCfnStacks(&allstacks, svc)
+	if err != nil {
+		t.Fatalf("GetCfnStacks() error = %v", err)
+	}
+	if len(stacks) != 3 {
+		t.Fatalf("GetCfnStacks() returned %v stacks, want 3", len(stacks))
+	}
+	byName := make(map[string]CfnStack)
+	for _, stack := range stacks {
+		byName[stack.Name] = stack
+	}
+	network := byName["network"]
+	if network.Description != "Shared network" {
+		t.Errorf("network description = %v, want Shared network", network.Description)
+	}
+	if len(network.Outputs) != 3 {
+		t.Errorf("network has %v outputs, want 3", len(network.Outputs))
+	}
+	for _, output := range network.Outputs {
+		if output.ExportName == "network-VpcId" && len(output.ImportedBy) != 2 {
+			t.Errorf("network-VpcId ImportedBy = %v, want 2 stacks", output.ImportedBy)
+		}
+	}
+	sort.Strings(network.ImportedBy)
+	if want := []string{"app", "monitoring"}; !reflect.DeepEqual(network.ImportedBy, want) {
+		t.Errorf("network ImportedBy = %v, want %v", network.ImportedBy, want)
+	}
+	if len(byName["app"].ImportedBy) != 0 {
+		t.Errorf("app ImportedBy = %v, want none", byName["app"].ImportedBy)
+	}
+
+	single := "app"
+	stacks, err = GetCfnStacks(&single, svc)
+	if err != nil || len(stacks) != 1 {
+		t.Errorf("GetCfnStacks(app) = %v stacks, error %v, want 1 stack", len(stacks), err)
+	}
+}
+
+func TestGetMissingRequiredTags(t *testing.T) {
+	tags := []types.Tag{
+		{Key: aws.String("Owner"), Value: aws.String("platform")},
+		{Key: aws.String("CostCenter"), Value: aws.String(" ")},
+		{Key: aws.String("Project"), Value: aws.String("")},
+		{Key: aws.String("Project"), Value: aws.String("fog")},
+	}
+	// Tags from a tag file combined with those from a deployment file
+	tagFile, err := ParseTagString(`[{"Key": "Owner", "Value": "platform"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploymentFile := []types.Tag{{Key: aws.String("costcenter"), Value: aws.String("1234")}}
+	combined := append(tagFile, deploymentFile...)
+	tests := []struct {
+		name       string
+		tags       []types.Tag
+		required   []string
+		ignoreCase bool
+		want       []string
+	}{
+		{"No required tags", tags, nil, false, []string{}},
+		{"All present", tags, []string{"Owner", "Project"}, false, []string{}},
+		{"Missing and empty", tags, []string{"Owner", "CostCenter", "Team"}, false, []string{"CostCenter", "Team"}},
+		{"Duplicates and blanks", tags, []string{"Team", "", "Team"}, false, []string{"Team"}},
+		{"Different case", tags, []string{"owner", "PROJECT"}, false, []string{"owner", "PROJECT"}},
+		{"Different case ignored", tags, []string{"owner", "PROJECT"}, true, []string{}},
+		{"Empty value with ignored case", tags, []string{"costcenter"}, true, []string{"costcenter"}},
+		{"Multiple sources", combined, []string{"Owner", "costcenter", "Team"}, false, []string{"Team"}},
+		{"Multiple sources ignoring case", combined, []string{"Owner", "CostCenter"}, true, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetMissingRequiredTags(tt.tags, tt.required, tt.ignoreCase); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMissingRequiredTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRemediationSteps(t *testing.T) {
+	tests := []struct {
+		status   types.StackStatus
+		contains string
+	}{
+		{types.StackStatusRollbackFailed, "Delete the stack"},
+		{types.StackStatusUpdateRollbackFailed, "ContinueUpdateRollback"},
+		{types.StackStatusCreateFailed, "root cause"},
+		{types.StackStatusDeleteFailed, "blocking the deletion"},
+		{types.StackStatusUpdateInProgress, "Wait until the operation finishes"},
+		{types.StackStatusUpdateComplete, ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			steps := GetRemediationSteps(tt.status)
+			if tt.contains == "" {
+				if len(steps) != 0 {
+					t.Errorf("GetRemediationSteps() = %v, want no steps", steps)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(steps, "\n"), tt.contains) {
+				t.Errorf("GetRemediationSteps() = %v, want a step containing %q", steps, tt.contains)
+			}
+		})
+	}
+}
+
+// pollingChangesetClient returns the provided statuses in order, repeating the last one
+type pollingChangesetClient struct {
+	statuses []types.ChangeSetStatus
+	calls    int
+}
+
+func (m *pollingChangesetClient) DescribeChangeSet(ctx context.Context, params *cloudformation.DescribeChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error) {
+	status := m.statuses[min(m.calls, len(m.statuses)-1)]
+	m.calls++
+	return &cloudformation.DescribeChangeSetOutput{
+		ChangeSetId:   aws.String("arn:changeset/" + aws.ToString(params.ChangeSetName)),
+		ChangeSetName: params.ChangeSetName,
+		StackId:       aws.String("arn:stack/" + aws.ToString(params.StackName)),
+		StackName:     params.StackName,
+		Status:        status,
+		CreationTime:  aws.Time(time.Now()),
+	}, nil
+}
+
+func TestDeployInfo_WaitUntilChangesetDone(t *testing.T) {
+	var sleeps []time.Duration
+	originalSleep := sleepFunc
+	sleepFunc = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { sleepFunc = originalSleep }()
+
+	pending := []types.ChangeSetStatus{}
+	for i := 0; i < 6; i++ {
+		pending = append(pending, types.ChangeSetStatusCreateInProgress)
+	}
+	svc := &pollingChangesetClient{statuses: append(pending, types.ChangeSetStatusCreateComplete)}
+	deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset"}
+	changeset, err := deployment.WaitUntilChangesetDone(context.Background(), svc)
+	if err != nil {
+		t.Fatalf("WaitUntilChangesetDone() error = %v", err)
+	}
+	if changeset.Status != string(types.ChangeSetStatusCreateComplete) {
+		t.Errorf("WaitUntilChangesetDone() status = %v", changeset.Status)
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !reflect.DeepEqual(sleeps, want) {
+		t.Errorf("WaitUntilChangesetDone() waited %v, want %v", sleeps, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := &pollingChangesetClient{statuses: []types.ChangeSetStatus{types.ChangeSetStatusCreateInProgress}}
+	if _, err := deployment.WaitUntilChangesetDone(ctx, cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitUntilChangesetDone() with a cancelled context error = %v", err)
+	}
+	if cancelled.calls != 0 {
+		t.Errorf("WaitUntilChangesetDone() polled %d times after the context was cancelled", cancelled.calls)
+	}
+}
+
+func TestDeployInfo_ToDeploymentFile(t *testing.T) {
+	deployment := DeployInfo{
+		StackName:            "test-stack",
+		TemplateRelativePath: "../templates/vpc.yaml",
+		Parameters: []types.Parameter{
+			{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")},
+			{ParameterKey: aws.String("Secret"), UsePreviousValue: aws.Bool(true)},
+		},
+		Tags: []types.Tag{
+			{Key: aws.String("Owner"), Value: aws.String("team-a")},
+		},
+	}
+	want := StackDeploymentFile{
+		TemplateFilePath: "../templates/vpc.yaml",
+		Parameters:       map[string]string{"VpcCidr": "10.0.0.0/16"},
+		Tags:             map[string]string{"Owner": "team-a"},
+	}
+	got := deployment.ToDeploymentFile()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToDeploymentFile() = %+v, want %+v", got, want)
+	}
+	if empty := (DeployInfo{}).ToDeploymentFile(); empty.Parameters == nil || empty.Tags == nil {
+		t.Errorf("ToDeploymentFile() should always initialise the parameters and tags")
+	}
+}
+
+func TestDeployInfo_WaitUntilDone(t *testing.T) {
+	originalSleep := sleepFunc
+	sleepFunc = func(d time.Duration) { time.Sleep(time.Millisecond) }
+	defer func() { sleepFunc = originalSleep }()
+
+	stuck := testutil.NewScenarioBuilder().
+		WithStack("stuck", func(stack *testutil.StackBuilder) {
+			stack.WithStatus(types.StackStatusUpdateInProgress)
+		}).
+		Build()
+	done := testutil.NewScenarioBuilder().
+		WithStack("done", func(stack *testutil.StackBuilder) {
+			stack.WithStatus(types.StackStatusUpdateComplete)
+		}).
+		Build()
+
+	tests := map[string]struct {
+		stackName string
+		svc       CloudFormationDescribeStacksAPI
+		timeout   time.Duration
+		wantErr   error
+	}{
+		"stack finishes before the deadline":    {stackName: "done", svc: done, timeout: time.Second},
+		"stack never leaves UPDATE_IN_PROGRESS": {stackName: "stuck", svc: stuck, timeout: 20 * time.Millisecond, wantErr: ErrDeploymentTimeout},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			deployment := DeployInfo{StackName: tc.stackName, StackArn: tc.stackName}
+			polls := 0
+			err := deployment.WaitUntilDone(ctx, tc.svc, time.Second, func() { polls++ })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("WaitUntilDone() error = %v, want %v", err, tc.wantErr)
+			}
+			if !errors.Is(deployment.DeploymentError, tc.wantErr) {
+				t.Errorf("WaitUntilDone() DeploymentError = %v, want %v", deployment.DeploymentError, tc.wantErr)
+			}
+			if polls == 0 {
+				t.Errorf("WaitUntilDone() never called the poll function")
+			}
+		})
+	}
+}
+
+func TestParseParameterString(t *testing.T) {
+	want := []types.Parameter{
+		{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")},
+		{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("3")},
+	}
+	tests := map[string]struct {
+		contents string
+		want     []types.Parameter
+		wantErr  bool
+	}{
+		"json": {
+			contents: `[{"ParameterKey": "Environment", "ParameterValue": "prod"}, {"ParameterKey": "InstanceCount", "ParameterValue": "3"}]`,
+			want:     want,
+		},
+		"yaml": {
+			contents: "- ParameterKey: Environment\n  ParameterValue: prod\n- ParameterKey: InstanceCount\n  ParameterValue: \"3\"\n",
+			want:     want,
+		},
+		"toml": {
+			contents: "# Production values\n[parameters]\nInstanceCount = 3\nEnvironment = \"prod\"\n",
+			want:     want,
+		},
+		"toml without a parameters section": {
+			contents: "[tags]\nEnvironment = \"prod\"\n",
+			wantErr:  true,
+		},
+		"toml with a missing value": {
+			contents: "[parameters]\nEnvironment =\n",
+			wantErr:  true,
+		},
+		"toml with an unsupported value": {
+			contents: "[parameters]\nSubnets = [\"a\", \"b\"]\n",
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseParameterString(tc.contents)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseParameterString() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseParameterString() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTagString(t *testing.T) {
+	want := []types.Tag{
+		{Key: aws.String("Owner"), Value: aws.String("platform")},
+		{Key: aws.String("Project"), Value: aws.String("fog")},
+	}
+	tests := map[string]struct {
+		contents string
+		want     []types.Tag
+		wantErr  bool
+	}{
+		"json": {
+			contents: `[{"Key": "Owner", "Value": "platform"}, {"Key": "Project", "Value": "fog"}]`,
+			want:     want,
+		},
+		"yaml": {
+			contents: "- Key: Owner\n  Value: platform\n- Key: Project\n  Value: fog\n",
+			want:     want,
+		},
+		"toml": {
+			contents: "[tags]\nProject = \"fog\"\nOwner = \"platform\"\n",
+			want:     want,
+		},
+		"toml without a tags section": {
+			contents: "[parameters]\nOwner = \"platform\"\n",
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTagString(tc.contents)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseTagString() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseTagString() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_WaitUntilStackDeleted(t *testing.T) {
+	originalSleep := sleepFunc
+	sleepFunc = func(d time.Duration) {}
+	defer func() { sleepFunc = originalSleep }()
+
+	tests := map[string]struct {
+		statuses  []types.StackStatus
+		wantPolls int
+		wantErr   error
+	}{
+		"deleted": {
+			statuses:  []types.StackStatus{types.StackStatusDeleteInProgress, types.StackStatusDeleteInProgress, types.StackStatusDeleteComplete},
+			wantPolls: 3,
+		},
+		"deletion failed": {
+			statuses:  []types.StackStatus{types.StackStatusDeleteInProgress, types.StackStatusDeleteFailed},
+			wantPolls: 2,
+			wantErr:   ErrStackDeleteFailed,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := testutil.NewScenarioBuilder().WithStack("old-stack", func(stack *testutil.StackBuilder) {}).Build()
+			svc.StatusSequences = map[string][]types.StackStatus{"old-stack": tc.statuses}
+			deployment := DeployInfo{StackName: "old-stack", StackArn: aws.ToString(svc.Stacks[0].StackId)}
+			if err := deployment.StartStackDeletion(svc); err != nil {
+				t.Fatalf("StartStackDeletion() error = %v", err)
+			}
+			if !reflect.DeepEqual(svc.DeletedStacks, []string{"old-stack"}) {
+				t.Errorf("StartStackDeletion() deleted %v", svc.DeletedStacks)
+			}
+			polls := 0
+			stack, err := deployment.WaitUntilStackDeleted(context.Background(), svc, time.Second, func(types.Stack) { polls++ })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("WaitUntilStackDeleted() error = %v, want %v", err, tc.wantErr)
+			}
+			if stack.StackStatus != tc.statuses[len(tc.statuses)-1] {
+				t.Errorf("WaitUntilStackDeleted() status = %v", stack.StackStatus)
+			}
+			if polls != tc.wantPolls {
+				t.Errorf("WaitUntilStackDeleted() polled %d times, want %d", polls, tc.wantPolls)
+			}
+		})
+	}
+
+	missing := DeployInfo{StackName: "gone", StackArn: "gone"}
+	if _, err := missing.WaitUntilStackDeleted(context.Background(), testutil.NewScenarioBuilder().Build(), time.Second, func(types.Stack) {}); err != nil {
+		t.Errorf("WaitUntilStackDeleted() for a stack that no longer exists error = %v", err)
+	}
+}
+
+type mockCloudFormationCreateChangeSetAPI func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error)
+
+func (m mockCloudFormationCreateChangeSetAPI) CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestDeployInfo_CreateChangeSet_NotificationARNs(t *testing.T) {
+	configured := []string{"arn:aws:sns:us-east-1:123456789012:config"}
+	deploymentFile := &StackDeploymentFile{NotificationARNs: []string{"arn:aws:sns:us-east-1:123456789012:file"}}
+	tests := map[string]struct {
+		flag           string
+		deploymentFile *StackDeploymentFile
+		configured     []string
+		want           []string
+	}{
+		"flag":            {flag: "arn:aws:sns:us-east-1:123456789012:one, arn:aws:sns:us-east-1:123456789012:two", deploymentFile: deploymentFile, configured: configured, want: []string{"arn:aws:sns:us-east-1:123456789012:one", "arn:aws:sns:us-east-1:123456789012:two"}},
+		"deployment file": {deploymentFile: deploymentFile, configured: configured, want: deploymentFile.NotificationARNs},
+		"config":          {deploymentFile: &StackDeploymentFile{}, configured: configured, want: configured},
+		"none":            {},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset", Template: "Resources: {}"}
+			deployment.NotificationARNs = SelectNotificationARNs(tc.flag, tc.deploymentFile, tc.configured)
+			var received []string
+			svc := mockCloudFormationCreateChangeSetAPI(func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+				received = params.NotificationARNs
+				return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+			})
+			if _, err := deployment.CreateChangeSet(svc); err != nil {
+				t.Fatalf("CreateChangeSet() error = %v", err)
+			}
+			if len(received) != len(tc.want) || (len(tc.want) != 0 && !reflect.DeepEqual(received, tc.want)) {
+				t.Errorf("CreateChangeSet() NotificationARNs = %v, want %v", received, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_CreateChangeSet_TemplateURL(t *testing.T) {
+	templateURL := "https://my-bucket.s3.ap-southeast-2.amazonaws.com/templates/vpc.yaml?versionId=abc"
+	deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset", Template: "Resources: {}", TemplateUrl: templateURL}
+	var received *cloudformation.CreateChangeSetInput
+	svc := mockCloudFormationCreateChangeSetAPI(func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+		received = params
+		return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+	})
+	if _, err := deployment.CreateChangeSet(svc); err != nil {
+		t.Fatalf("CreateChangeSet() error = %v", err)
+	}
+	if aws.ToString(received.TemplateURL) != templateURL {
+		t.Errorf("CreateChangeSet() TemplateURL = %v, want %v", aws.ToString(received.TemplateURL), templateURL)
+	}
+	if received.TemplateBody != nil {
+		t.Errorf("CreateChangeSet() TemplateBody = %v, want nil when a URL is used", aws.ToString(received.TemplateBody))
+	}
+	if file := deployment.ToDeploymentFile(); file.TemplateURL != templateURL || file.TemplateFilePath != "" {
+		t.Errorf("ToDeploymentFile() template = %q, %q, want only the URL", file.TemplateFilePath, file.TemplateURL)
+	}
+}
+
+func TestMergeDeploymentFiles_Template(t *testing.T) {
+	defaults := StackDeploymentFile{TemplateURL: "https://my-bucket.s3.amazonaws.com/vpc.yaml"}
+	tests := map[string]struct {
+		overrides StackDeploymentFile
+		wantPath  string
+		wantURL   string
+	}{
+		"No override":   {wantURL: defaults.TemplateURL},
+		"Path override": {overrides: StackDeploymentFile{TemplateFilePath: "vpc-dev.yaml"}, wantPath: "vpc-dev.yaml"},
+		"URL override":  {overrides: StackDeploymentFile{TemplateURL: "https://my-bucket.s3.amazonaws.com/vpc-dev.yaml"}, wantURL: "https://my-bucket.s3.amazonaws.com/vpc-dev.yaml"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := mergeDeploymentFiles(defaults, tc.overrides)
+			if got.TemplateFilePath != tc.wantPath || got.TemplateURL != tc.wantURL {
+				t.Errorf("mergeDeploymentFiles() template = %q, %q, want %q, %q", got.TemplateFilePath, got.TemplateURL, tc.wantPath, tc.wantURL)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_CreateChangeSet_Description(t *testing.T) {
+	tests := map[string]struct {
+		flag           string
+		deploymentFile *StackDeploymentFile
+		configured     string
+		want           *string
+		wantErr        bool
+	}{
+		"flag":            {flag: "JIRA-123: add S3 bucket", deploymentFile: &StackDeploymentFile{ChangesetDescription: "from file"}, configured: "from config", want: aws.String("JIRA-123: add S3 bucket")},
+		"deployment file": {deploymentFile: &StackDeploymentFile{ChangesetDescription: "from file"}, configured: "from config", want: aws.String("from file")},
+		"config":          {configured: "from config", want: aws.String("from config")},
+		"none":            {},
+		"too long":        {flag: strings.Repeat("a", 1025), wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			description, err := SelectChangesetDescription(tc.flag, tc.deploymentFile, tc.configured)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SelectChangesetDescription() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset", Template: "Resources: {}", ChangesetDescription: description}
+			var received *string
+			svc := mockCloudFormationCreateChangeSetAPI(func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+				received = params.Description
+				return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+			})
+			if _, err := deployment.CreateChangeSet(svc); err != nil {
+				t.Fatalf("CreateChangeSet() error = %v", err)
+			}
+			if !reflect.DeepEqual(received, tc.want) {
+				t.Errorf("CreateChangeSet() Description = %v, want %v", aws.ToString(received), aws.ToString(tc.want))
+			}
+		})
+	}
+}
+
+func TestParseParameterOverrides(t *testing.T) {
+	tests := map[string]struct {
+		overrides []string
+		want      []types.Parameter
+		wantErr   bool
+	}{
+		"no overrides": {
+			overrides: []string{},
+			want:      []types.Parameter{},
+		},
+		"multiple overrides": {
+			overrides: []string{"Environment=prod", "InstanceCount=3"},
+			want: []types.Parameter{
+				{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")},
+				{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("3")},
+			},
+		},
+		"value containing equal signs": {
+			overrides: []string{"Query=a=b=c"},
+			want: []types.Parameter{
+				{ParameterKey: aws.String("Query"), ParameterValue: aws.String("a=b=c")},
+			},
+		},
+		"empty value": {
+			overrides: []string{"Suffix="},
+			want: []types.Parameter{
+				{ParameterKey: aws.String("Suffix"), ParameterValue: aws.String("")},
+			},
+		},
+		"missing separator": {
+			overrides: []string{"Environment"},
+			wantErr:   true,
+		},
+		"missing key": {
+			overrides: []string{"=prod"},
+			wantErr:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseParameterOverrides(tc.overrides)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseParameterOverrides() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseParameterOverrides() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeParameters(t *testing.T) {
+	existing := []types.Parameter{
+		{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("dev")},
+		{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("1")},
+	}
+	additional := []types.Parameter{
+		{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")},
+		{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")},
+	}
+	want := []types.Parameter{
+		{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")},
+		{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("1")},
+		{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")},
+	}
+	got := MergeParameters(existing, additional)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeParameters() = %v, want %v", got, want)
+	}
+}
+
+func TestInheritParameters(t *testing.T) {
+	current := []types.Parameter{
+		{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("dev")},
+		{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("1")},
+	}
+	previous := func(key string) types.Parameter {
+		return types.Parameter{ParameterKey: aws.String(key), UsePreviousValue: aws.Bool(true)}
+	}
+	tests := map[string]struct {
+		current   []types.Parameter
+		overrides []types.Parameter
+		want      []types.Parameter
+	}{
+		"Full inheritance": {
+			current:   current,
+			overrides: nil,
+			want:      []types.Parameter{previous("Environment"), previous("InstanceCount")},
+		},
+		"Partial override": {
+			current:   current,
+			overrides: []types.Parameter{{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("3")}},
+			want: []types.Parameter{
+				previous("Environment"),
+				{ParameterKey: aws.String("InstanceCount"), ParameterValue: aws.String("3")},
+			},
+		},
+		"New parameter": {
+			current:   current,
+			overrides: []types.Parameter{{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")}},
+			want: []types.Parameter{
+				previous("Environment"),
+				previous("InstanceCount"),
+				{ParameterKey: aws.String("VpcCidr"), ParameterValue: aws.String("10.0.0.0/16")},
+			},
+		},
+		"Nothing to inherit": {
+			current:   nil,
+			overrides: []types.Parameter{{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")}},
+			want:      []types.Parameter{{ParameterKey: aws.String("Environment"), ParameterValue: aws.String("prod")}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := InheritParameters(tc.current, tc.overrides)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("InheritParameters() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetRollbackFailures(t *testing.T) {
+	stackID := "arn:aws:cloudformation:us-east-1:123456789012:stack/stuck-stack/abc"
+	event := func(logicalID string, physicalID string, status types.ResourceStatus) types.StackEvent {
+		return types.StackEvent{
+			StackId:            aws.String(stackID),
+			LogicalResourceId:  aws.String(logicalID),
+			PhysicalResourceId: aws.String(physicalID),
+			ResourceStatus:     status,
+		}
+	}
+	tests := map[string]struct {
+		events []types.StackEvent
+		want   []string
+	}{
+		"Update rollback": {
+			events: []types.StackEvent{
+				event("stuck-stack", stackID, types.ResourceStatusUpdateRollbackFailed),
+				event("Bucket", "my-bucket", types.ResourceStatusUpdateFailed),
+				event("Queue", "my-queue", types.ResourceStatusDeleteFailed),
+				event("Bucket", "my-bucket", types.ResourceStatusUpdateFailed),
+				event("Role", "my-role", types.ResourceStatusUpdateComplete),
+				event("stuck-stack", stackID, types.ResourceStatusUpdateRollbackInProgress),
+				event("Table", "my-table", types.ResourceStatusUpdateFailed),
+				event("stuck-stack", stackID, types.ResourceStatusUpdateInProgress),
+			},
+			want: []string{"Bucket", "Queue"},
+		},
+		"Create rollback": {
+			events: []types.StackEvent{
+				event("stuck-stack", stackID, types.ResourceStatusRollbackFailed),
+				event("Bucket", "my-bucket", types.ResourceStatusDeleteFailed),
+				event("stuck-stack", stackID, types.ResourceStatusRollbackInProgress),
+				event("Function", "", types.ResourceStatusCreateFailed),
+			},
+			want: []string{"Bucket"},
+		},
+		"Nested stack failures are included": {
+			events: []types.StackEvent{
+				event("Nested", "arn:aws:cloudformation:us-east-1:123456789012:stack/nested/def", types.ResourceStatusUpdateFailed),
+				event("stuck-stack", stackID, types.ResourceStatusUpdateRollbackInProgress),
+			},
+			want: []string{"Nested"},
+		},
+		"No failures": {
+			events: []types.StackEvent{
+				event("stuck-stack", stackID, types.ResourceStatusUpdateRollbackComplete),
+				event("Bucket", "my-bucket", types.ResourceStatusUpdateComplete),
+			},
+			want: []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, failure := range GetRollbackFailures(tc.events) {
+				got = append(got, aws.ToString(failure.LogicalResourceId))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetRollbackFailures() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetResourcesToSkip(t *testing.T) {
+	failures := []types.StackEvent{
+		{LogicalResourceId: aws.String("Bucket"), ResourceType: aws.String("AWS::S3::Bucket"), ResourceStatus: types.ResourceStatusUpdateFailed},
+		{LogicalResourceId: aws.String("Queue"), ResourceType: aws.String("AWS::SQS::Queue"), ResourceStatus: types.ResourceStatusDeleteFailed},
+		{LogicalResourceId: aws.String("Nested"), ResourceType: aws.String("AWS::CloudFormation::Stack"), ResourceStatus: types.ResourceStatusUpdateFailed},
+		{LogicalResourceId: aws.String("Role"), ResourceType: aws.String("AWS::IAM::Role"), ResourceStatus: types.ResourceStatusUpdateFailed},
+	}
+	want := []string{"Bucket", "Role"}
+	if got := GetResourcesToSkip(failures); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResourcesToSkip() = %v, want %v", got, want)
+	}
+}
+
+type mockCloudFormationUpdateTerminationProtectionAPI func(ctx context.Context, params *cloudformation.UpdateTerminationProtectionInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateTerminationProtectionOutput, error)
+
+func (m mockCloudFormationUpdateTerminationProtectionAPI) UpdateTerminationProtection(ctx context.Context, params *cloudformation.UpdateTerminationProtectionInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateTerminationProtectionOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestSetTerminationProtection(t *testing.T) {
+	tests := map[string]struct {
+		enabled bool
+		err     error
+	}{
+		"enable":  {enabled: true},
+		"disable": {enabled: false},
+		"failure": {enabled: true, err: errors.New("access denied")},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received *cloudformation.UpdateTerminationProtectionInput
+			svc := mockCloudFormationUpdateTerminationProtectionAPI(func(ctx context.Context, params *cloudformation.UpdateTerminationProtectionInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateTerminationProtectionOutput, error) {
+				received = params
+				return &cloudformation.UpdateTerminationProtectionOutput{}, tc.err
+			})
+			err := SetTerminationProtection("my-stack", tc.enabled, svc)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("SetTerminationProtection() error = %v, want %v", err, tc.err)
+			}
+			if aws.ToString(received.StackName) != "my-stack" {
+				t.Errorf("SetTerminationProtection() StackName = %v, want my-stack", aws.ToString(received.StackName))
+			}
+			if aws.ToBool(received.EnableTerminationProtection) != tc.enabled {
+				t.Errorf("SetTerminationProtection() EnableTerminationProtection = %v, want %v", aws.ToBool(received.EnableTerminationProtection), tc.enabled)
+			}
+		})
+	}
+}
+
+func TestContinueUpdateRollback(t *testing.T) {
+	tests := map[string]struct {
+		status          types.StackStatus
+		resourcesToSkip []string
+		wantErr         error
+	}{
+		"rollback failed": {
+			status: types.StackStatusUpdateRollbackFailed,
+		},
+		"skipping resources": {
+			status:          types.StackStatusUpdateRollbackFailed,
+			resourcesToSkip: []string{"Bucket", "Queue"},
+		},
+		"stack in another state": {
+			status:  types.StackStatusUpdateComplete,
+			wantErr: ErrStackNotRollbackFailed,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := testutil.NewScenarioBuilder().WithStack("stuck-stack", func(stack *testutil.StackBuilder) {
+				stack.WithStatus(tc.status)
+			}).Build()
+			err := ContinueUpdateRollback(svc.Stacks[0], tc.resourcesToSkip, svc)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ContinueUpdateRollback() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if len(svc.ContinuedRollbacks) != 0 {
+					t.Errorf("ContinueUpdateRollback() continued the rollback of a stack in status %v", tc.status)
+				}
+				return
+			}
+			if len(svc.ContinuedRollbacks) != 1 {
+				t.Fatalf("ContinueUpdateRollback() called the API %d times, want 1", len(svc.ContinuedRollbacks))
+			}
+			if !reflect.DeepEqual(svc.ContinuedRollbacks[0].ResourcesToSkip, tc.resourcesToSkip) {
+				t.Errorf("ContinueUpdateRollback() ResourcesToSkip = %v, want %v", svc.ContinuedRollbacks[0].ResourcesToSkip, tc.resourcesToSkip)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_WaitUntilRollbackDone(t *testing.T) {
+	originalSleep := sleepFunc
+	sleepFunc = func(d time.Duration) {}
+	defer func() { sleepFunc = originalSleep }()
+
+	tests := map[string]struct {
+		statuses  []types.StackStatus
+		wantPolls int
+		wantErr   error
+	}{
+		"rolled back": {
+			statuses:  []types.StackStatus{types.StackStatusUpdateRollbackInProgress, types.StackStatusUpdateRollbackCompleteCleanupInProgress, types.StackStatusUpdateRollbackComplete},
+			wantPolls: 3,
+		},
+		"rollback failed again": {
+			statuses:  []types.StackStatus{types.StackStatusUpdateRollbackInProgress, types.StackStatusUpdateRollbackFailed},
+			wantPolls: 2,
+			wantErr:   ErrRollbackFailed,
+		},
+		"update finished before the cancel": {
+			statuses:  []types.StackStatus{types.StackStatusUpdateInProgress, types.StackStatusUpdateCompleteCleanupInProgress, types.StackStatusUpdateComplete},
+			wantPolls: 3,
+			wantErr:   ErrNotRolledBack,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := testutil.NewScenarioBuilder().WithStack("stuck-stack", func(stack *testutil.StackBuilder) {}).Build()
+			svc.StatusSequences = map[string][]types.StackStatus{"stuck-stack": tc.statuses}
+			deployment := DeployInfo{StackName: "stuck-stack", StackArn: aws.ToString(svc.Stacks[0].StackId)}
+			polls := 0
+			stack, err := deployment.WaitUntilRollbackDone(context.Background(), svc, time.Second, func(types.Stack) { polls++ })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("WaitUntilRollbackDone() error = %v, want %v", err, tc.wantErr)
+			}
+			if stack.StackStatus != tc.statuses[len(tc.statuses)-1] {
+				t.Errorf("WaitUntilRollbackDone() status = %v", stack.StackStatus)
+			}
+			if polls != tc.wantPolls {
+				t.Errorf("WaitUntilRollbackDone() polled %d times, want %d", polls, tc.wantPolls)
+			}
+		})
+	}
+}
+
+func TestCancelUpdateStack(t *testing.T) {
+	tests := map[string]struct {
+		status  types.StackStatus
+		wantErr error
+	}{
+		"update in progress": {status: types.StackStatusUpdateInProgress},
+		"update complete":    {status: types.StackStatusUpdateComplete, wantErr: ErrStackNotUpdating},
+		"create in progress": {status: types.StackStatusCreateInProgress, wantErr: ErrStackNotUpdating},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := testutil.NewScenarioBuilder().WithStack("busy-stack", func(stack *testutil.StackBuilder) {
+				stack.WithStatus(tc.status)
+			}).Build()
+			err := CancelUpdateStack(svc.Stacks[0], svc)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CancelUpdateStack() error = %v, want %v", err, tc.wantErr)
+			}
+			wantCancelled := 1
+			if tc.wantErr != nil {
+				wantCancelled = 0
+			}
+			if len(svc.CancelledUpdates) != wantCancelled {
+				t.Errorf("CancelUpdateStack() cancelled %d updates, want %d", len(svc.CancelledUpdates), wantCancelled)
+			}
+		})
+	}
+}
+
+func TestMatchesStackNameFilter(t *testing.T) {
+	tests := map[string]struct {
+		filter    string
+		stackname string
+		want      bool
+	}{
+		"no filter":            {filter: "", stackname: "app-network", want: true},
+		"exact name":           {filter: "app-network", stackname: "app-network", want: true},
+		"prefix wildcard":      {filter: "app-*", stackname: "app-network", want: true},
+		"surrounding wildcard": {filter: "*network*", stackname: "app-network-prod", want: true},
+		"no match":             {filter: "app-*", stackname: "shared-dns", want: false},
+		"literal dot":          {filter: "app.*", stackname: "app-network", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := matchesStackNameFilter(tc.filter, tc.stackname); got != tc.want {
+				t.Errorf("matchesStackNameFilter(%q, %q) = %v, want %v", tc.filter, tc.stackname, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetStacksWithDrift(t *testing.T) {
+	svc := testutil.NewScenarioBuilder().
+		WithStack("app-network", func(stack *testutil.StackBuilder) {
+			stack.WithDriftStatus(types.StackDriftStatusDrifted)
+		}).
+		WithStack("app-database", func(stack *testutil.StackBuilder) {
+			stack.WithDriftStatus(types.StackDriftStatusInSync)
+		}).
+		WithStack("app-compute", func(stack *testutil.StackBuilder) {
+			stack.WithDriftStatus(types.StackDriftStatusDrifted)
+		}).
+		WithStack("shared-dns", func(stack *testutil.StackBuilder) {
+			stack.WithDriftStatus(types.StackDriftStatusDrifted)
+		}).
+		WithStack("never-checked", func(stack *testutil.StackBuilder) {}).
+		Build()
+
+	tests := map[string]struct {
+		filter *string
+		want   []string
+	}{
+		"all stacks":        {filter: nil, want: []string{"app-compute", "app-network", "shared-dns"}},
+		"empty filter":      {filter: aws.String(""), want: []string{"app-compute", "app-network", "shared-dns"}},
+		"wildcard filter":   {filter: aws.String("app-*"), want: []string{"app-compute", "app-network"}},
+		"exact name":        {filter: aws.String("shared-dns"), want: []string{"shared-dns"}},
+		"no matching stack": {filter: aws.String("app-database"), want: []string{}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stacks, err := GetStacksWithDrift(tc.filter, svc)
+			if err != nil {
+				t.Fatalf("GetStacksWithDrift() error = %v", err)
+			}
+			got := make([]string, 0, len(stacks))
+			for _, stack := range stacks {
+				got = append(got, stack.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetStacksWithDrift() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_CreateChangeSet_RoleARN(t *testing.T) {
+	tests := map[string]struct {
+		flag           string
+		deploymentFile *StackDeploymentFile
+		configured     string
+		want           *string
+		wantErr        bool
+	}{
+		"flag":            {flag: "arn:aws:iam::123456789012:role/cfn-flag", deploymentFile: &StackDeploymentFile{RoleARN: "arn:aws:iam::123456789012:role/cfn-file"}, configured: "arn:aws:iam::123456789012:role/cfn-config", want: aws.String("arn:aws:iam::123456789012:role/cfn-flag")},
+		"deployment file": {deploymentFile: &StackDeploymentFile{RoleARN: "arn:aws:iam::123456789012:role/cfn-file"}, configured: "arn:aws:iam::123456789012:role/cfn-config", want: aws.String("arn:aws:iam::123456789012:role/cfn-file")},
+		"config":          {configured: "arn:aws-us-gov:iam::123456789012:role/path/cfn-config", want: aws.String("arn:aws-us-gov:iam::123456789012:role/path/cfn-config")},
+		"none":            {},
+		"not a role":      {flag: "arn:aws:iam::123456789012:user/deployer", wantErr: true},
+		"invalid account": {flag: "arn:aws:iam::1234:role/cfn-role", wantErr: true},
+		"role name only":  {flag: "cfn-role", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			roleARN, err := SelectRoleARN(tc.flag, tc.deploymentFile, tc.configured)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SelectRoleARN() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset", Template: "Resources: {}", RoleARN: roleARN}
+			var received *string
+			svc := mockCloudFormationCreateChangeSetAPI(func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+				received = params.RoleARN
+				return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+			})
+			if _, err := deployment.CreateChangeSet(svc); err != nil {
+				t.Fatalf("CreateChangeSet() error = %v", err)
+			}
+			if !reflect.DeepEqual(received, tc.want) {
+				t.Errorf("CreateChangeSet() RoleARN = %v, want %v", aws.ToString(received), aws.ToString(tc.want))
+			}
+		})
+	}
+}
+
+func TestSelectRollbackConfiguration(t *testing.T) {
+	alarm := "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:errors"
+	otherAlarm := "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:latency"
+	trigger := func(arn string) types.RollbackTrigger {
+		return types.RollbackTrigger{Arn: aws.String(arn), Type: aws.String("AWS::CloudWatch::Alarm")}
+	}
+	fileConfig := &StackDeploymentFile{RollbackConfiguration: &DeploymentFileRollbackConfiguration{MonitoringTime: 15, AlarmARNs: []string{otherAlarm}}}
+	tests := map[string]struct {
+		monitoringTime int32
+		alarmARNs      string
+		deploymentFile *StackDeploymentFile
+		want           *types.RollbackConfiguration
+		wantWarning    bool
+		wantErr        bool
+	}{
+		"nothing set":           {},
+		"empty deployment file": {deploymentFile: &StackDeploymentFile{}},
+		"flags":                 {monitoringTime: 10, alarmARNs: alarm + ", " + otherAlarm, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(10), RollbackTriggers: []types.RollbackTrigger{trigger(alarm), trigger(otherAlarm)}}},
+		"only monitoring time":  {monitoringTime: 10, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(10), RollbackTriggers: []types.RollbackTrigger{}}, wantWarning: true},
+		"only alarms":           {alarmARNs: alarm, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(0), RollbackTriggers: []types.RollbackTrigger{trigger(alarm)}}, wantWarning: true},
+		"deployment file":       {deploymentFile: fileConfig, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(15), RollbackTriggers: []types.RollbackTrigger{trigger(otherAlarm)}}},
+		"flags override file":   {monitoringTime: 5, alarmARNs: alarm, deploymentFile: fileConfig, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(5), RollbackTriggers: []types.RollbackTrigger{trigger(alarm)}}},
+		"flag overrides alarms": {alarmARNs: alarm, deploymentFile: fileConfig, want: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(15), RollbackTriggers: []types.RollbackTrigger{trigger(alarm)}}},
+		"monitoring too long":   {monitoringTime: 181, alarmARNs: alarm, wantErr: true},
+		"negative monitoring":   {monitoringTime: -1, alarmARNs: alarm, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SelectRollbackConfiguration(tc.monitoringTime, tc.alarmARNs, tc.deploymentFile)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SelectRollbackConfiguration() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("SelectRollbackConfiguration() = %+v, want %+v", got, tc.want)
+			}
+			if warning := RollbackConfigurationWarning(got); (warning != "") != tc.wantWarning {
+				t.Errorf("RollbackConfigurationWarning() = %q, wantWarning %v", warning, tc.wantWarning)
+			}
+			if tc.wantErr {
+				return
+			}
+			deployment := DeployInfo{StackName: "my-stack", ChangesetName: "my-changeset", Template: "Resources: {}", RollbackConfiguration: got}
+			var received *types.RollbackConfiguration
+			svc := mockCloudFormationCreateChangeSetAPI(func(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+				received = params.RollbackConfiguration
+				return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+			})
+			if _, err := deployment.CreateChangeSet(svc); err != nil {
+				t.Fatalf("CreateChangeSet() error = %v", err)
+			}
+			if !reflect.DeepEqual(received, tc.want) {
+				t.Errorf("CreateChangeSet() RollbackConfiguration = %+v, want %+v", received, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeployInfo_ToDeploymentFile_RollbackConfiguration(t *testing.T) {
+	alarm := "arn:aws:cloudwatch:ap-southeast-2:123456789012:alarm:errors"
+	deployment := DeployInfo{
+		RollbackConfiguration: &types.RollbackConfiguration{
+			MonitoringTimeInMinutes: aws.Int32(10),
+			RollbackTriggers:        []types.RollbackTrigger{{Arn: aws.String(alarm), Type: aws.String("AWS::CloudWatch::Alarm")}},
+		},
+	}
+	want := &DeploymentFileRollbackConfiguration{MonitoringTime: 10, AlarmARNs: []string{alarm}}
+	got := deployment.ToDeploymentFile()
+	if !reflect.DeepEqual(got.RollbackConfiguration, want) {
+		t.Errorf("ToDeploymentFile() RollbackConfiguration = %+v, want %+v", got.RollbackConfiguration, want)
+	}
+	contents, err := got.ToCommentedYAML()
+	if err != nil {
+		t.Fatalf("ToCommentedYAML() error = %v", err)
+	}
+	if !strings.Contains(contents, "rollback-configuration:\n  monitoring-time: 10\n  alarm-arns:\n  - "+alarm) {
+		t.Errorf("ToCommentedYAML() doesn't contain the rollback configuration:\n%v", contents)
+	}
+	if contents, _ := (StackDeploymentFile{}).ToCommentedYAML(); strings.Contains(contents, "rollback-configuration") {
+		t.Errorf("ToCommentedYAML() shouldn't contain an empty rollback configuration:\n%v", contents)
+	}
+}
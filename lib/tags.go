@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ParseTagsFromAttributes returns the tags from the properties of a resource that
+// uses the CloudFormation [{"Key": "k", "Value": "v"}] format. Tags without a
+// key or value are skipped.
+func ParseTagsFromAttributes(properties map[string]interface{}) map[string]string {
+	result := make(map[string]string)
+	tags, ok := properties["Tags"].([]interface{})
+	if !ok {
+		return result
+	}
+	for _, tag := range tags {
+		tagMap, ok := tag.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, ok := tagMap["Key"].(string)
+		if !ok || tagMap["Value"] == nil {
+			continue
+		}
+		result[key] = fmt.Sprint(tagMap["Value"])
+	}
+	return result
+}
+
+// ParseTagsFromAWSTags returns the tags of an EC2 resource as a map
+func ParseTagsFromAWSTags(tags []types.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
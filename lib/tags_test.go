@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestParseTagsFromAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		want       map[string]string
+	}{
+		{"No tags", map[string]interface{}{"CidrBlock": "10.0.0.0/16"}, map[string]string{}},
+		{"Tags", map[string]interface{}{"Tags": []interface{}{
+			map[string]interface{}{"Key": "Name", "Value": "main"},
+			map[string]interface{}{"Key": "Owner", "Value": "team-a"},
+		}}, map[string]string{"Name": "main", "Owner": "team-a"}},
+		{"Non-string values are converted", map[string]interface{}{"Tags": []interface{}{
+			map[string]interface{}{"Key": "Count", "Value": float64(3)},
+		}}, map[string]string{"Count": "3"}},
+		{"Invalid tags are skipped", map[string]interface{}{"Tags": []interface{}{
+			"Name",
+			map[string]interface{}{"Key": "Empty", "Value": nil},
+			map[string]interface{}{"Value": "no key"},
+		}}, map[string]string{}},
+		{"Tags in map format aren't supported", map[string]interface{}{"Tags": map[string]interface{}{"Name": "main"}}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTagsFromAttributes(tt.properties); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTagsFromAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagsFromAWSTags(t *testing.T) {
+	tags := []types.Tag{
+		{Key: aws.String("Name"), Value: aws.String("main")},
+		{Key: aws.String("Empty"), Value: aws.String("")},
+	}
+	want := map[string]string{"Name": "main", "Empty": ""}
+	if got := ParseTagsFromAWSTags(tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTagsFromAWSTags() = %v, want %v", got, want)
+	}
+	if got := ParseTagsFromAWSTags(nil); len(got) != 0 {
+		t.Errorf("ParseTagsFromAWSTags() = %v, want an empty map", got)
+	}
+}
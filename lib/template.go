@@ -1,22 +1,45 @@
 package lib
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	cfntypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/awslabs/goformation/v7/intrinsics"
+	"gopkg.in/yaml.v2"
 )
 
 type StackDeploymentFile struct {
-	TemplateFilePath string            `json:"template-file-path"`
-	Parameters       map[string]string `json:"parameters"`
-	Tags             map[string]string `json:"tags"`
+	TemplateFilePath string `json:"template-file-path" yaml:"template-file-path"`
+	// TemplateURL is the S3 URL of a template that's already uploaded, it's used instead of the TemplateFilePath
+	TemplateURL      string            `json:"template-url,omitempty" yaml:"template-url,omitempty"`
+	Parameters       map[string]string `json:"parameters" yaml:"parameters"`
+	Tags             map[string]string `json:"tags" yaml:"tags"`
+	NotificationARNs []string          `json:"notification-arns,omitempty" yaml:"notification-arns,omitempty"`
+	// ChangesetDescription is the description of the change sets created for the stack
+	ChangesetDescription string `json:"changeset-description,omitempty" yaml:"changeset-description,omitempty"`
+	// RoleARN is the ARN of the service role CloudFormation uses for the stack operations
+	RoleARN string `json:"role-arn,omitempty" yaml:"role-arn,omitempty"`
+	// RollbackConfiguration holds the alarms CloudFormation monitors during and after the deployment
+	RollbackConfiguration *DeploymentFileRollbackConfiguration `json:"rollback-configuration,omitempty" yaml:"rollback-configuration,omitempty"`
+}
+
+// DeploymentFileRollbackConfiguration is the rollback configuration as stored in a deployment file
+type DeploymentFileRollbackConfiguration struct {
+	// MonitoringTime is the number of minutes CloudFormation monitors the alarms after the deployment
+	MonitoringTime int32 `json:"monitoring-time,omitempty" yaml:"monitoring-time,omitempty"`
+	// AlarmARNs are the ARNs of the CloudWatch alarms that trigger a rollback
+	AlarmARNs []string `json:"alarm-arns,omitempty" yaml:"alarm-arns,omitempty"`
 }
 
 type CfnTemplateBody struct {
@@ -30,6 +53,11 @@ type CfnTemplateBody struct {
 	Resources                map[string]CfnTemplateResource  `json:"Resources"`
 	Conditions               map[string]bool                 `json:"Conditions"`
 	Outputs                  map[string]CfnTemplateOutput    `json:"Outputs"`
+	// ImportedExports contains the names of all exports used in Fn::ImportValue
+	ImportedExports []string `json:"-"`
+	// UnresolvedImports contains the names of exports used in Fn::ImportValue
+	// that couldn't be resolved while parsing the template
+	UnresolvedImports []string `json:"-"`
 }
 
 type CfnTemplateParameter struct {
@@ -39,13 +67,116 @@ type CfnTemplateParameter struct {
 	AllowedPattern        string        `json:"AllowedPattern,omitempty"`
 	AllowedValues         []interface{} `json:"AllowedValues,omitempty"`
 	ConstraintDescription string        `json:"ConstraintDescription,omitempty"`
-	MaxLength             int           `json:"MaxLength,omitempty"`
-	MinLength             int           `json:"MinLength,omitempty"`
-	MaxValue              float64       `json:"MaxValue,omitempty"`
-	MinValue              float64       `json:"MinValue,omitempty"`
+	MaxLength             *int          `json:"MaxLength,omitempty"`
+	MinLength             *int          `json:"MinLength,omitempty"`
+	MaxValue              *float64      `json:"MaxValue,omitempty"`
+	MinValue              *float64      `json:"MinValue,omitempty"`
 	NoEcho                bool          `json:"NoEcho,omitempty"`
 }
 
+// IsRequired returns whether a value needs to be provided for the parameter,
+// which is the case when it doesn't have a default value
+func (p CfnTemplateParameter) IsRequired() bool {
+	return p.Default == nil
+}
+
+// UnmarshalJSON parses a template parameter. CloudFormation accepts the
+// length and value constraints both as numbers and as strings, so both are
+// supported. Constraints that aren't set in the template stay nil.
+func (p *CfnTemplateParameter) UnmarshalJSON(b []byte) error {
+	type plainParameter CfnTemplateParameter
+	var raw struct {
+		plainParameter
+		MaxLength interface{} `json:"MaxLength"`
+		MinLength interface{} `json:"MinLength"`
+		MaxValue  interface{} `json:"MaxValue"`
+		MinValue  interface{} `json:"MinValue"`
+		NoEcho    interface{} `json:"NoEcho"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*p = CfnTemplateParameter(raw.plainParameter)
+	var err error
+	if p.MaxValue, err = constraintNumber("MaxValue", raw.MaxValue); err != nil {
+		return err
+	}
+	if p.MinValue, err = constraintNumber("MinValue", raw.MinValue); err != nil {
+		return err
+	}
+	if p.MaxLength, err = constraintLength("MaxLength", raw.MaxLength); err != nil {
+		return err
+	}
+	if p.MinLength, err = constraintLength("MinLength", raw.MinLength); err != nil {
+		return err
+	}
+	switch noEcho := raw.NoEcho.(type) {
+	case bool:
+		p.NoEcho = noEcho
+	case string:
+		p.NoEcho = strings.EqualFold(noEcho, "true")
+	}
+	return nil
+}
+
+// constraintNumber converts a numeric parameter constraint, which can be a number or a string, to a float
+func constraintNumber(name string, value interface{}) (*float64, error) {
+	switch typed := value.(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		return &typed, nil
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v '%v': %w", name, typed, err)
+		}
+		return &number, nil
+	default:
+		return nil, fmt.Errorf("invalid %v '%v'", name, typed)
+	}
+}
+
+// constraintLength converts a length parameter constraint, which can be a number or a string, to an int
+func constraintLength(name string, value interface{}) (*int, error) {
+	number, err := constraintNumber(name, value)
+	if number == nil || err != nil {
+		return nil, err
+	}
+	length := int(*number)
+	return &length, nil
+}
+
+// ParseTemplateParameters returns the parameters defined in the template
+func ParseTemplateParameters(template string) (map[string]CfnTemplateParameter, error) {
+	result := make(map[string]CfnTemplateParameter)
+	normalized, err := NormalizeTemplate(template)
+	if err != nil {
+		return result, err
+	}
+	parameters, ok := normalized["Parameters"]
+	if !ok {
+		return result, nil
+	}
+	contents, err := json.Marshal(parameters)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(contents, &result)
+	return result, err
+}
+
+// FilterRequiredParameters returns only the parameters without a default value
+func FilterRequiredParameters(parameters map[string]CfnTemplateParameter) map[string]CfnTemplateParameter {
+	result := make(map[string]CfnTemplateParameter)
+	for name, parameter := range parameters {
+		if parameter.IsRequired() {
+			result[name] = parameter
+		}
+	}
+	return result
+}
+
 type CfnTemplateResource struct {
 	Type       string                 `json:"Type"`
 	Condition  string                 `json:"Condition"`
@@ -122,7 +253,9 @@ func (t *CfnTemplateTransform) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-func GetTemplateBody(stackname *string, parameters *map[string]interface{}, svc *cloudformation.Client) CfnTemplateBody {
+// GetTemplateBody retrieves and parses the current template of the stack. See
+// ParseTemplateString for how the parameters and exports are used.
+func GetTemplateBody(stackname *string, parameters *map[string]interface{}, exports map[string]cfntypes.Export, svc *cloudformation.Client) CfnTemplateBody {
 	input := cloudformation.GetTemplateInput{
 		StackName: stackname,
 	}
@@ -131,7 +264,158 @@ func GetTemplateBody(stackname *string, parameters *map[string]interface{}, svc
 		panic(err)
 	}
 
-	return ParseTemplateString(*result.TemplateBody, parameters)
+	return ParseTemplateString(*result.TemplateBody, parameters, exports)
+}
+
+// GetTemplateBodyFromChangeset retrieves the processed template of a change set,
+// which is the template as it will be deployed after any transforms are applied
+func GetTemplateBodyFromChangeset(changesetName, stackName string, svc CloudFormationGetTemplateAPI) (string, error) {
+	input := cloudformation.GetTemplateInput{
+		ChangeSetName: &changesetName,
+		StackName:     &stackName,
+		TemplateStage: cfntypes.TemplateStageProcessed,
+	}
+	result, err := svc.GetTemplate(context.TODO(), &input)
+	if err != nil {
+		return "", err
+	}
+	if result.TemplateBody == nil {
+		return "", fmt.Errorf("no template found for change set %s of stack %s", changesetName, stackName)
+	}
+	return *result.TemplateBody, nil
+}
+
+// ConvertTemplateFormat converts a JSON or YAML template body to the requested
+// format (json or yaml). An empty format returns the body unchanged.
+func ConvertTemplateFormat(body string, format string) (string, error) {
+	isJson := strings.HasPrefix(strings.TrimSpace(body), "{")
+	switch strings.ToLower(format) {
+	case "":
+		return body, nil
+	case "json":
+		jsonBody := []byte(body)
+		if !isJson {
+			var err error
+			jsonBody, err = YamlToJson(jsonBody)
+			if err != nil {
+				return "", err
+			}
+		}
+		var formatted bytes.Buffer
+		if err := json.Indent(&formatted, jsonBody, "", "  "); err != nil {
+			return "", err
+		}
+		return formatted.String(), nil
+	case "yaml":
+		if !isJson {
+			return body, nil
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+			return "", err
+		}
+		result, err := yaml.Marshal(parsed)
+		if err != nil {
+			return "", err
+		}
+		return string(result), nil
+	default:
+		return "", fmt.Errorf("unsupported template format %q, use json or yaml", format)
+	}
+}
+
+// GetTemplateHash returns the hex-encoded SHA-256 hash of the template. The
+// template is normalized to JSON first so that formatting changes don't result
+// in a different hash. If the template can't be parsed, the raw content is hashed.
+func GetTemplateHash(template string) string {
+	content := []byte(strings.TrimSpace(template))
+	if parsed, err := NormalizeTemplate(template); err == nil {
+		if normalized, err := json.Marshal(parsed); err == nil {
+			content = normalized
+		}
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// GetDeploymentHash returns the hex-encoded SHA-256 hash of the template
+// together with the parameters and tags it's deployed with, so a change to
+// any of these results in a different hash. Parameters that use their
+// previous value are included as such, as their value isn't known. Tags whose
+// value changes on every run, the git:deployed-at tag and the keys in
+// ignoredTags, are left out so they don't prevent a match.
+func GetDeploymentHash(template string, parameters []cfntypes.Parameter, tags []cfntypes.Tag, ignoredTags []string) string {
+	content := struct {
+		Template   string
+		Parameters map[string]string
+		Tags       map[string]string
+	}{
+		Template:   GetTemplateHash(template),
+		Parameters: make(map[string]string, len(parameters)),
+		Tags:       make(map[string]string, len(tags)),
+	}
+	for _, parameter := range parameters {
+		value := "value:" + aws.ToString(parameter.ParameterValue)
+		if aws.ToBool(parameter.UsePreviousValue) {
+			value = "previous"
+		}
+		content.Parameters[aws.ToString(parameter.ParameterKey)] = value
+	}
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+		if key == GitDeployedAtTag || stringInSlice(key, ignoredTags) {
+			continue
+		}
+		content.Tags[key] = aws.ToString(tag.Value)
+	}
+	// Maps are marshalled with sorted keys, so the order of the parameters and tags doesn't matter
+	marshalled, _ := json.Marshal(content)
+	hash := sha256.Sum256(marshalled)
+	return hex.EncodeToString(hash[:])
+}
+
+// GetStackCostEstimate returns the URL of the AWS Simple Monthly Calculator,
+// filled in with the resources of the template and the parameter values.
+// When a templateURL is provided, the template at that URL is estimated
+// instead of the body. Parameters are sent as provided, including those that
+// use their previous value.
+func GetStackCostEstimate(template string, templateURL string, params []cfntypes.Parameter, svc CloudFormationEstimateTemplateCostAPI) (string, error) {
+	input := &cloudformation.EstimateTemplateCostInput{
+		Parameters: params,
+	}
+	if templateURL != "" {
+		input.TemplateURL = &templateURL
+	} else {
+		input.TemplateBody = &template
+	}
+	result, err := svc.EstimateTemplateCost(context.TODO(), input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.Url), nil
+}
+
+// NormalizeTemplate parses a JSON or YAML template into a generic map without
+// resolving any intrinsic functions. Short form functions like !Ref are
+// converted to their long form, so equivalent templates result in the same map.
+func NormalizeTemplate(template string) (map[string]interface{}, error) {
+	content := []byte(strings.TrimSpace(template))
+	options := &intrinsics.ProcessorOptions{NoProcess: true}
+	var processed []byte
+	var err error
+	if bytes.HasPrefix(content, []byte("{")) {
+		processed, err = intrinsics.ProcessJSON(content, options)
+	} else {
+		processed, err = intrinsics.ProcessYAML(content, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsed := make(map[string]interface{})
+	if err := json.Unmarshal(processed, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
 }
 
 // customRefHandler is a simple example of an intrinsic function handler function
@@ -188,10 +472,19 @@ func customRefHandler(name string, input interface{}, template interface{}) inte
 	return fmt.Sprintf("REF: %s", input)
 }
 
-func ParseTemplateString(template string, parameters *map[string]interface{}) CfnTemplateBody {
+// ParseTemplateString parses the template, resolving intrinsic functions where
+// possible. If exports are provided, such as from GetExportsByName,
+// Fn::ImportValue is resolved to the current value of the export. Otherwise,
+// imported values are left empty.
+func ParseTemplateString(template string, parameters *map[string]interface{}, exports map[string]cfntypes.Export) CfnTemplateBody {
 	parsedTemplate := CfnTemplateBody{}
 	override := map[string]intrinsics.IntrinsicHandler{}
 	override["Ref"] = customRefHandler
+	imports := make([]string, 0)
+	unresolved := make([]string, 0)
+	if exports != nil {
+		override["Fn::ImportValue"] = importValueHandler(exports, &imports, &unresolved)
+	}
 	options := intrinsics.ProcessorOptions{
 		IntrinsicHandlerOverrides: override,
 	}
@@ -212,9 +505,51 @@ func ParseTemplateString(template string, parameters *map[string]interface{}) Cf
 	if err := json.Unmarshal([]byte(intrinsified), &parsedTemplate); err != nil {
 		panic(err)
 	}
+	parsedTemplate.ImportedExports = imports
+	parsedTemplate.UnresolvedImports = unresolved
 	return parsedTemplate
 }
 
+// ResolveImportValue returns the current value of the export with the provided name
+func ResolveImportValue(exportName string, svc CloudFormationListExportsAPI) (string, error) {
+	paginator := cloudformation.NewListExportsPaginator(svc, &cloudformation.ListExportsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return "", err
+		}
+		for _, export := range page.Exports {
+			if aws.ToString(export.Name) == exportName {
+				return aws.ToString(export.Value), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("export %v not found", exportName)
+}
+
+// importValueHandler resolves Fn::ImportValue using the provided exports. The
+// names of all imported exports are added to imports once. Exports that can't
+// be found are added to unresolved once.
+func importValueHandler(exports map[string]cfntypes.Export, imports *[]string, unresolved *[]string) intrinsics.IntrinsicHandler {
+	return func(name string, input interface{}, template interface{}) interface{} {
+		exportName, ok := input.(string)
+		if !ok {
+			return nil
+		}
+		if !stringInSlice(exportName, *imports) {
+			*imports = append(*imports, exportName)
+		}
+		export, ok := exports[exportName]
+		if !ok {
+			if !stringInSlice(exportName, *unresolved) {
+				*unresolved = append(*unresolved, exportName)
+			}
+			return nil
+		}
+		return aws.ToString(export.Value)
+	}
+}
+
 func FilterNaclEntriesByLogicalId(logicalId string, template CfnTemplateBody, params []cfntypes.Parameter) map[string]types.NetworkAclEntry {
 	result := make(map[string]types.NetworkAclEntry)
 	for _, resource := range template.Resources {
@@ -248,6 +583,44 @@ func FilterRoutesByLogicalId(logicalId string, template CfnTemplateBody, params
 	return result
 }
 
+// FilterTGWRoutesByLogicalId returns the static routes in the template for the
+// transit gateway route table with the logical ID, keyed by their destination
+func FilterTGWRoutesByLogicalId(logicalId string, template CfnTemplateBody, params []cfntypes.Parameter, logicalToPhysical map[string]string) map[string]types.TransitGatewayRoute {
+	result := make(map[string]types.TransitGatewayRoute)
+	for _, resource := range template.Resources {
+		if resource.Type == "AWS::EC2::TransitGatewayRoute" && template.ShouldHaveResource(resource) {
+			rtid, _ := resource.Properties["TransitGatewayRouteTableId"].(string)
+			if strings.Replace(rtid, "REF: ", "", 1) != logicalId {
+				continue
+			}
+			convresource := TGWRouteResourceToTGWRoute(resource, params, logicalToPhysical)
+			result[GetTransitGatewayRouteDestination(convresource)] = convresource
+		}
+	}
+	return result
+}
+
+// TGWRouteResourceToTGWRoute converts an AWS::EC2::TransitGatewayRoute resource
+// into the route as it should show up in the transit gateway route table
+func TGWRouteResourceToTGWRoute(resource CfnTemplateResource, params []cfntypes.Parameter, logicalToPhysical map[string]string) types.TransitGatewayRoute {
+	prop := resource.Properties
+	result := types.TransitGatewayRoute{
+		DestinationCidrBlock: stringPointer(prop, params, logicalToPhysical, "DestinationCidrBlock"),
+		State:                types.TransitGatewayRouteStateActive,
+		Type:                 types.TransitGatewayRouteTypeStatic,
+	}
+	if blackhole, ok := resolveTemplateValue(prop["Blackhole"], nil); ok && strings.EqualFold(blackhole, "true") {
+		result.State = types.TransitGatewayRouteStateBlackhole
+		return result
+	}
+	if attachment := stringPointer(prop, params, logicalToPhysical, "TransitGatewayAttachmentId"); attachment != nil {
+		result.TransitGatewayAttachments = []types.TransitGatewayRouteAttachment{
+			{TransitGatewayAttachmentId: attachment},
+		}
+	}
+	return result
+}
+
 func NaclResourceToNaclEntry(resource CfnTemplateResource, params []cfntypes.Parameter) types.NetworkAclEntry {
 	protocol := ""
 	switch value := resource.Properties["Protocol"].(type) {
@@ -337,6 +710,113 @@ func NaclResourceToNaclEntry(resource CfnTemplateResource, params []cfntypes.Par
 	return result
 }
 
+// FilterSecurityGroupRulesByLogicalId returns the rules of the security group
+// with the logical ID, keyed by SecurityGroupRule.Key. This includes the rules
+// defined inline in the security group and those in separate
+// AWS::EC2::SecurityGroupIngress and AWS::EC2::SecurityGroupEgress resources.
+// If there are no inline egress rules, the default egress rule is expected.
+// The returned bool is false if a separate rule couldn't be linked to a
+// security group, for example because it uses Fn::GetAtt for the GroupId.
+func FilterSecurityGroupRulesByLogicalId(logicalId string, template CfnTemplateBody, params []cfntypes.Parameter, logicalToPhysical map[string]string) (map[string]SecurityGroupRule, bool) {
+	result := make(map[string]SecurityGroupRule)
+	complete := true
+	add := func(properties map[string]interface{}, egress bool) {
+		rule := SecurityGroupRuleFromProperties(properties, egress, params, logicalToPhysical)
+		result[rule.Key()] = rule
+	}
+	if group, ok := template.Resources[logicalId]; ok && group.Type == "AWS::EC2::SecurityGroup" {
+		for _, inline := range securityGroupRuleList(group.Properties["SecurityGroupIngress"]) {
+			add(inline, false)
+		}
+		egressRules := securityGroupRuleList(group.Properties["SecurityGroupEgress"])
+		for _, inline := range egressRules {
+			add(inline, true)
+		}
+		if len(egressRules) == 0 {
+			add(map[string]interface{}{"IpProtocol": "-1", "CidrIp": "0.0.0.0/0"}, true)
+		}
+	}
+	for _, resource := range template.Resources {
+		if resource.Type != "AWS::EC2::SecurityGroupIngress" && resource.Type != "AWS::EC2::SecurityGroupEgress" {
+			continue
+		}
+		if !template.ShouldHaveResource(resource) {
+			continue
+		}
+		groupId, ok := resource.Properties["GroupId"].(string)
+		if !ok {
+			complete = false
+			continue
+		}
+		if strings.TrimPrefix(groupId, "REF: ") == logicalId || groupId == logicalToPhysical[logicalId] {
+			add(resource.Properties, resource.Type == "AWS::EC2::SecurityGroupEgress")
+		}
+	}
+	return result, complete
+}
+
+// securityGroupRuleList returns the inline rules of a security group
+func securityGroupRuleList(value interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	list, ok := value.([]interface{})
+	if !ok {
+		return result
+	}
+	for _, item := range list {
+		if rule, ok := item.(map[string]interface{}); ok {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// SecurityGroupRuleFromProperties converts the properties of an inline or separate
+// security group rule from the template to a SecurityGroupRule
+func SecurityGroupRuleFromProperties(properties map[string]interface{}, egress bool, params []cfntypes.Parameter, logicalToPhysical map[string]string) SecurityGroupRule {
+	rule := SecurityGroupRule{
+		Egress:   egress,
+		FromPort: portValue(properties["FromPort"]),
+		ToPort:   portValue(properties["ToPort"]),
+	}
+	paramsMap := *GetParametersMap(params)
+	if value, ok := resolveTemplateValue(properties["IpProtocol"], paramsMap); ok {
+		rule.Protocol = value
+	}
+	if value, ok := resolveTemplateValue(properties["Description"], paramsMap); ok {
+		rule.Description = value
+	}
+	targets := []string{"CidrIp", "CidrIpv6", "SourcePrefixListId", "SourceSecurityGroupId"}
+	if egress {
+		targets = []string{"CidrIp", "CidrIpv6", "DestinationPrefixListId", "DestinationSecurityGroupId"}
+	}
+	for _, target := range targets {
+		if properties[target] == nil {
+			continue
+		}
+		if value := stringPointer(properties, params, logicalToPhysical, target); value != nil {
+			rule.Target = *value
+			break
+		}
+	}
+	return normalizeSecurityGroupRule(rule)
+}
+
+// portValue returns the port number of a template property, or -1 if it isn't set
+func portValue(value interface{}) int32 {
+	switch typed := value.(type) {
+	case float64:
+		return int32(typed)
+	case string:
+		port, err := strconv.Atoi(typed)
+		if err != nil {
+			return -1
+		}
+		return int32(port)
+	default:
+		return -1
+	}
+}
+
 func RouteResourceToRoute(resource CfnTemplateResource, params []cfntypes.Parameter, logicalToPhysical map[string]string) types.Route {
 	prop := resource.Properties
 	result := types.Route{
@@ -361,7 +841,8 @@ func RouteResourceToRoute(resource CfnTemplateResource, params []cfntypes.Parame
 }
 
 func stringPointer(array map[string]interface{}, params []cfntypes.Parameter, logicalToPhysical map[string]string, value string) *string {
-	if _, ok := array[value]; !ok {
+	// Values that couldn't be resolved, such as missing imports, are null
+	if val, ok := array[value]; !ok || val == nil {
 		return nil
 	}
 	result := ""
@@ -394,6 +875,282 @@ func stringPointer(array map[string]interface{}, params []cfntypes.Parameter, lo
 	return &result
 }
 
+// GetVpcFromTemplate returns the configuration of the VPC with the logical ID as
+// defined in the template. IPv6 CIDR blocks are taken from the
+// AWS::EC2::VPCCidrBlock resources attached to the VPC. Values that aren't set
+// in the template get the CloudFormation defaults.
+func GetVpcFromTemplate(logicalId string, template CfnTemplateBody, params map[string]any) VpcConfig {
+	result := VpcConfig{
+		EnableDNSSupport:   true,
+		EnableDNSHostnames: false,
+		InstanceTenancy:    string(types.TenancyDefault),
+		IPv6CIDRBlocks:     []string{},
+	}
+	resource, ok := template.Resources[logicalId]
+	if !ok || resource.Type != "AWS::EC2::VPC" {
+		return result
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["CidrBlock"], params); ok {
+		result.CidrBlock = value
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["EnableDnsSupport"], params); ok {
+		result.EnableDNSSupport = strings.EqualFold(value, "true")
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["EnableDnsHostnames"], params); ok {
+		result.EnableDNSHostnames = strings.EqualFold(value, "true")
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["InstanceTenancy"], params); ok {
+		result.InstanceTenancy = value
+	}
+	for _, cidrresource := range template.Resources {
+		if cidrresource.Type != "AWS::EC2::VPCCidrBlock" || !template.ShouldHaveResource(cidrresource) {
+			continue
+		}
+		if vpcid, _ := resolveTemplateValue(cidrresource.Properties["VpcId"], nil); strings.TrimPrefix(vpcid, "REF: ") != logicalId {
+			continue
+		}
+		if value, ok := resolveTemplateValue(cidrresource.Properties["AmazonProvidedIpv6CidrBlock"], params); ok && strings.EqualFold(value, "true") {
+			result.AmazonProvidedIPv6 = true
+		}
+		if value, ok := resolveTemplateValue(cidrresource.Properties["Ipv6CidrBlock"], params); ok {
+			result.IPv6CIDRBlocks = append(result.IPv6CIDRBlocks, value)
+		}
+	}
+	sort.Strings(result.IPv6CIDRBlocks)
+	return result
+}
+
+// GetSubnetFromTemplate returns the configuration of the subnet with the
+// logical ID as defined in the template. Values that aren't set in the template
+// get the CloudFormation defaults.
+func GetSubnetFromTemplate(logicalId string, template CfnTemplateBody, params map[string]any) SubnetConfig {
+	result := SubnetConfig{
+		Tags: make(map[string]string),
+	}
+	resource, ok := template.Resources[logicalId]
+	if !ok || resource.Type != "AWS::EC2::Subnet" {
+		return result
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["CidrBlock"], params); ok {
+		result.CidrBlock = value
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["AvailabilityZone"], params); ok {
+		result.AvailabilityZone = value
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["MapPublicIpOnLaunch"], params); ok {
+		result.MapPublicIPOnLaunch = strings.EqualFold(value, "true")
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["AssignIpv6AddressOnCreation"], params); ok {
+		result.AssignIPv6AddressOnCreation = strings.EqualFold(value, "true")
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["Ipv6CidrBlock"], params); ok {
+		result.IPv6CIDRBlock = value
+	}
+	if tags, ok := resource.Properties["Tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			tagMap, ok := tag.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, _ := resolveTemplateValue(tagMap["Key"], params)
+			if value, ok := resolveTemplateValue(tagMap["Value"], params); ok && key != "" {
+				result.Tags[key] = value
+			}
+		}
+	}
+	return result
+}
+
+// GetVPCEndpointFromTemplate returns the configuration of the VPC endpoint with
+// the logical ID as defined in the template. References to other resources are
+// resolved using logicalToPhysical. Lists that contain a value that can't be
+// resolved are left empty so they won't be compared.
+func GetVPCEndpointFromTemplate(logicalId string, template CfnTemplateBody, params map[string]any, logicalToPhysical map[string]string) VPCEndpointConfig {
+	result := VPCEndpointConfig{}
+	resource, ok := template.Resources[logicalId]
+	if !ok || resource.Type != "AWS::EC2::VPCEndpoint" {
+		return result
+	}
+	if value, ok := resolveTemplateValue(resource.Properties["ServiceName"], params); ok {
+		result.ServiceName = value
+	}
+	if policy, ok := resource.Properties["PolicyDocument"]; ok && policy != nil {
+		if formatted, err := FormatPolicyDocument(resolveTemplateReferences(policy, params)); err == nil {
+			result.PolicyDocument = formatted
+		}
+	}
+	result.SubnetIds = resolveTemplateList(resource.Properties["SubnetIds"], params, logicalToPhysical)
+	result.SecurityGroupIds = resolveTemplateList(resource.Properties["SecurityGroupIds"], params, logicalToPhysical)
+	return result
+}
+
+// resolveTemplateList returns the string values of a list property, resolving
+// references to parameters and resources. It returns nil if the property isn't
+// a list or if any of its values can't be resolved.
+func resolveTemplateList(value interface{}, params map[string]any, logicalToPhysical map[string]string) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		resolved, ok := resolveTemplateValue(item, params)
+		if !ok || resolved == "" {
+			return nil
+		}
+		if refname, found := strings.CutPrefix(resolved, "REF: "); found {
+			physical, ok := logicalToPhysical[refname]
+			if !ok {
+				return nil
+			}
+			resolved = physical
+		}
+		result = append(result, resolved)
+	}
+	return result
+}
+
+// resolveTemplateValue returns the string value of a property in a parsed
+// template, looking up references to parameters that weren't resolved yet
+func resolveTemplateValue(value interface{}, params map[string]any) (string, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		if refname, found := strings.CutPrefix(typed, "REF: "); found {
+			if param, ok := params[refname]; ok {
+				return fmt.Sprint(param), true
+			}
+		}
+		return typed, true
+	case map[string]interface{}:
+		if refname, ok := typed["Ref"].(string); ok {
+			if param, ok := params[refname]; ok {
+				return fmt.Sprint(param), true
+			}
+			return "REF: " + refname, true
+		}
+		return "", false
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	default:
+		return fmt.Sprint(typed), true
+	}
+}
+
+// policyDocumentProperties maps the resource types that embed a resource
+// policy to the property holding the policy document
+var policyDocumentProperties = map[string]string{
+	"AWS::S3::BucketPolicy": "PolicyDocument",
+	"AWS::SNS::TopicPolicy": "PolicyDocument",
+	"AWS::SQS::QueuePolicy": "PolicyDocument",
+}
+
+// GetPolicyDocumentProperty returns the name of the property that holds the
+// resource policy for the resource type, if it has one
+func GetPolicyDocumentProperty(resourceType string) (string, bool) {
+	property, ok := policyDocumentProperties[resourceType]
+	return property, ok
+}
+
+// GetResourcePolicyFromTemplate returns the policy document of a resource
+// policy in the template as indented JSON. References to parameters are
+// replaced with their values. The boolean is false if the resource doesn't
+// exist, isn't a policy-bearing resource type, or has no valid policy document.
+func GetResourcePolicyFromTemplate(logicalId string, template CfnTemplateBody, params map[string]any) (string, bool) {
+	resource, ok := template.Resources[logicalId]
+	if !ok {
+		return "", false
+	}
+	property, ok := GetPolicyDocumentProperty(resource.Type)
+	if !ok {
+		return "", false
+	}
+	document, ok := resource.Properties[property]
+	if !ok {
+		return "", false
+	}
+	policy, err := FormatPolicyDocument(resolveTemplateReferences(document, params))
+	if err != nil {
+		return "", false
+	}
+	return policy, true
+}
+
+// FormatPolicyDocument returns the policy document as indented JSON with sorted
+// keys, so that two documents can be compared line by line. The document can be
+// provided as a JSON string or as an already parsed object.
+func FormatPolicyDocument(document interface{}) (string, error) {
+	if text, ok := document.(string); ok {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			return "", err
+		}
+		document = parsed
+	}
+	result, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// resolveTemplateReferences replaces the references to parameters in a
+// (nested) template value with the values of those parameters
+func resolveTemplateReferences(value interface{}, params map[string]any) interface{} {
+	switch typed := value.(type) {
+	case string:
+		if strings.HasPrefix(typed, "REF: ") {
+			resolved, _ := resolveTemplateValue(typed, params)
+			return resolved
+		}
+		return typed
+	case map[string]interface{}:
+		if _, ok := typed["Ref"]; ok && len(typed) == 1 {
+			resolved, _ := resolveTemplateValue(typed, params)
+			return resolved
+		}
+		result := make(map[string]interface{}, len(typed))
+		for key, nested := range typed {
+			result[key] = resolveTemplateReferences(nested, params)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(typed))
+		for _, nested := range typed {
+			result = append(result, resolveTemplateReferences(nested, params))
+		}
+		return result
+	default:
+		return typed
+	}
+}
+
+// GetResourceCount returns the number of resources in the template
+func (body CfnTemplateBody) GetResourceCount() int {
+	return len(body.Resources)
+}
+
+// GetParameterCount returns the number of parameters in the template
+func (body CfnTemplateBody) GetParameterCount() int {
+	return len(body.Parameters)
+}
+
+// GetOutputCount returns the number of outputs in the template
+func (body CfnTemplateBody) GetOutputCount() int {
+	return len(body.Outputs)
+}
+
+// GetConditionCount returns the number of conditions in the template
+func (body CfnTemplateBody) GetConditionCount() int {
+	return len(body.Conditions)
+}
+
+// GetMappingCount returns the number of mappings in the template
+func (body CfnTemplateBody) GetMappingCount() int {
+	return len(body.Mappings)
+}
+
 func (body *CfnTemplateBody) ShouldHaveResource(resource CfnTemplateResource) bool {
 	if resource.Condition != "" {
 		return body.Conditions[resource.Condition]
@@ -421,3 +1178,20 @@ func getParsedValueOfCondition(input interface{}, params []cfntypes.Parameter) s
 	}
 	return result
 }
+
+// MaxTemplateBodySize is the largest template in bytes that CloudFormation
+// accepts directly, bigger templates need to be uploaded to S3
+const MaxTemplateBodySize = 51200
+
+// ValidateTemplate asks CloudFormation to validate the template. When a
+// templateURL is provided, the template at that URL is validated instead of
+// the body.
+func ValidateTemplate(templateBody string, templateURL string, svc CloudFormationValidateTemplateAPI) (*cloudformation.ValidateTemplateOutput, error) {
+	input := &cloudformation.ValidateTemplateInput{}
+	if templateURL != "" {
+		input.TemplateURL = &templateURL
+	} else {
+		input.TemplateBody = &templateBody
+	}
+	return svc.ValidateTemplate(context.TODO(), input)
+}
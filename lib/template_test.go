@@ -0,0 +1,896 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ArjenSchwarz/fog/lib/testutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type mockCloudFormationGetTemplateAPI func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error)
+
+func (m mockCloudFormationGetTemplateAPI) GetTemplate(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func TestGetTemplateBodyFromChangeset(t *testing.T) {
+	tests := []struct {
+		name    string
+		svc     CloudFormationGetTemplateAPI
+		want    string
+		wantErr bool
+	}{
+		{"Processed template", mockCloudFormationGetTemplateAPI(func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+			if aws.ToString(params.ChangeSetName) != "fog-changeset" || aws.ToString(params.StackName) != "test-stack" || params.TemplateStage != types.TemplateStageProcessed {
+				return nil, errors.New("unexpected input")
+			}
+			return &cloudformation.GetTemplateOutput{TemplateBody: aws.String("Resources: {}")}, nil
+		}), "Resources: {}", false},
+		{"Missing template body", mockCloudFormationGetTemplateAPI(func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+			return &cloudformation.GetTemplateOutput{}, nil
+		}), "", true},
+		{"API error", mockCloudFormationGetTemplateAPI(func(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error) {
+			return nil, errors.New("ChangeSetNotFound")
+		}), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTemplateBodyFromChangeset("fog-changeset", "test-stack", tt.svc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetTemplateBodyFromChangeset() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetTemplateBodyFromChangeset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertTemplateFormat(t *testing.T) {
+	yamlBody := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n"
+	jsonBody := "{\n  \"Resources\": {\n    \"Bucket\": {\n      \"Type\": \"AWS::S3::Bucket\"\n    }\n  }\n}"
+	tests := []struct {
+		name    string
+		body    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"No format keeps body", yamlBody, "", yamlBody, false},
+		{"YAML to JSON", yamlBody, "json", jsonBody, false},
+		{"JSON to YAML", jsonBody, "yaml", yamlBody, false},
+		{"YAML stays YAML", yamlBody, "YAML", yamlBody, false},
+		{"Compact JSON is indented", `{"Resources":{"Bucket":{"Type":"AWS::S3::Bucket"}}}`, "json", jsonBody, false},
+		{"Unsupported format", yamlBody, "toml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertTemplateFormat(tt.body, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ConvertTemplateFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ConvertTemplateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTemplateHash(t *testing.T) {
+	yamlBody := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n    Properties:\n      BucketName: !Ref Name\n"
+	tests := []struct {
+		name      string
+		first     string
+		second    string
+		wantEqual bool
+	}{
+		{"Identical templates", yamlBody, yamlBody, true},
+		{"Whitespace differences are ignored", yamlBody, "\nResources:\n    Bucket:\n        Type: AWS::S3::Bucket\n        Properties:\n            BucketName: !Ref Name\n\n", true},
+		{"YAML and JSON of the same template", yamlBody, `{"Resources": {"Bucket": {"Type": "AWS::S3::Bucket", "Properties": {"BucketName": {"Ref": "Name"}}}}}`, true},
+		{"Changed content", yamlBody, strings.Replace(yamlBody, "Name", "OtherName", 1), false},
+		{"Short tag differs from plain value", yamlBody, strings.Replace(yamlBody, "!Ref Name", "Name", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := GetTemplateHash(tt.first)
+			second := GetTemplateHash(tt.second)
+			if len(first) != 64 {
+				t.Errorf("GetTemplateHash() returned %q, expected a hex-encoded SHA-256 hash", first)
+			}
+			if (first == second) != tt.wantEqual {
+				t.Errorf("GetTemplateHash() equality = %v, want %v", first == second, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestGetDeploymentHash(t *testing.T) {
+	template := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n"
+	parameters := []types.Parameter{
+		{ParameterKey: aws.String("Env"), ParameterValue: aws.String("prod")},
+		{ParameterKey: aws.String("Size"), ParameterValue: aws.String("10")},
+	}
+	tags := []types.Tag{{Key: aws.String("Owner"), Value: aws.String("team-a")}}
+	base := GetDeploymentHash(template, parameters, tags, nil)
+	tests := []struct {
+		name       string
+		template   string
+		parameters []types.Parameter
+		tags       []types.Tag
+		wantEqual  bool
+	}{
+		{"Identical deployment", template, parameters, tags, true},
+		{"Parameters in a different order", template, []types.Parameter{parameters[1], parameters[0]}, tags, true},
+		{"Reformatted template", "{\"Resources\": {\"Bucket\": {\"Type\": \"AWS::S3::Bucket\"}}}", parameters, tags, true},
+		{"Changed parameter value", template, []types.Parameter{parameters[0], {ParameterKey: aws.String("Size"), ParameterValue: aws.String("20")}}, tags, false},
+		{"Parameter using its previous value", template, []types.Parameter{parameters[0], {ParameterKey: aws.String("Size"), UsePreviousValue: aws.Bool(true)}}, tags, false},
+		{"Changed tag value", template, parameters, []types.Tag{{Key: aws.String("Owner"), Value: aws.String("team-b")}}, false},
+		{"Removed tag", template, parameters, nil, false},
+		{"Changed template", strings.Replace(template, "Bucket", "Other", 1), parameters, tags, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetDeploymentHash(tt.template, tt.parameters, tt.tags, nil)
+			if (got == base) != tt.wantEqual {
+				t.Errorf("GetDeploymentHash() equality = %v, want %v", got == base, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestGetDeploymentHash_VolatileTags(t *testing.T) {
+	template := "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n"
+	metadata := GitMetadata{Commit: "abc123", Branch: "main", Author: "Jane"}
+	firstRun := MergeTags([]types.Tag{{Key: aws.String("Owner"), Value: aws.String("team-a")}}, metadata.ToTags(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)))
+	secondRun := MergeTags([]types.Tag{{Key: aws.String("Owner"), Value: aws.String("team-a")}}, metadata.ToTags(time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC)))
+	if GetDeploymentHash(template, nil, firstRun, nil) != GetDeploymentHash(template, nil, secondRun, nil) {
+		t.Error("GetDeploymentHash() differs for runs that only differ in git:deployed-at")
+	}
+	otherCommit := MergeTags(secondRun, []types.Tag{{Key: aws.String("git:commit"), Value: aws.String("def456")}})
+	if GetDeploymentHash(template, nil, firstRun, nil) == GetDeploymentHash(template, nil, otherCommit, nil) {
+		t.Error("GetDeploymentHash() is the same for runs with a different git:commit")
+	}
+	withTimestamp := func(timestamp string) []types.Tag {
+		return []types.Tag{{Key: aws.String("DeployedAt"), Value: aws.String(timestamp)}}
+	}
+	if GetDeploymentHash(template, nil, withTimestamp("2024-01-01T10-00-00"), []string{"DeployedAt"}) != GetDeploymentHash(template, nil, withTimestamp("2024-01-02T12-30-00"), []string{"DeployedAt"}) {
+		t.Error("GetDeploymentHash() differs for runs that only differ in an ignored tag")
+	}
+}
+
+func TestCfnTemplateBody_Counts(t *testing.T) {
+	template := "Parameters:\n  Name:\n    Type: String\n  Size:\n    Type: Number\n" +
+		"Mappings:\n  Regions:\n    us-east-1:\n      Ami: ami-123\n" +
+		"Conditions:\n  HasName: !Not [!Equals [!Ref Name, \"\"]]\n" +
+		"Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n  Queue:\n    Type: AWS::SQS::Queue\n  Topic:\n    Type: AWS::SNS::Topic\n" +
+		"Outputs:\n  BucketName:\n    Value: !Ref Bucket\n"
+	params := map[string]interface{}{"Name": "test", "Size": "1"}
+	body := ParseTemplateString(template, &params, nil)
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"Resources", body.GetResourceCount(), 3},
+		{"Parameters", body.GetParameterCount(), 2},
+		{"Outputs", body.GetOutputCount(), 1},
+		{"Conditions", body.GetConditionCount(), 1},
+		{"Mappings", body.GetMappingCount(), 1},
+		{"Empty template", CfnTemplateBody{}.GetResourceCount(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("count = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSecurityGroupRulesByLogicalId(t *testing.T) {
+	template := `Parameters:
+  AllowedCidr:
+    Type: String
+  HealthCheckProtocol:
+    Type: String
+Resources:
+  WebSecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: Web
+      SecurityGroupIngress:
+        - IpProtocol: tcp
+          FromPort: 443
+          ToPort: 443
+          CidrIp: !Ref AllowedCidr
+          Description: HTTPS
+        - IpProtocol: !Ref HealthCheckProtocol
+          FromPort: 8443
+          ToPort: 8443
+          CidrIp: !Ref AllowedCidr
+  AppSecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: App
+      SecurityGroupEgress:
+        - IpProtocol: "6"
+          FromPort: 5432
+          ToPort: 5432
+          DestinationSecurityGroupId: !Ref DatabaseSecurityGroup
+  AppIngress:
+    Type: AWS::EC2::SecurityGroupIngress
+    Properties:
+      GroupId: !Ref AppSecurityGroup
+      IpProtocol: tcp
+      FromPort: 8080
+      ToPort: 8080
+      SourceSecurityGroupId: !Ref WebSecurityGroup
+  AllTraffic:
+    Type: AWS::EC2::SecurityGroupIngress
+    Properties:
+      GroupId: !Ref AppSecurityGroup
+      IpProtocol: -1
+      CidrIpv6: "::/0"
+`
+	params := []types.Parameter{
+		{ParameterKey: aws.String("AllowedCidr"), ParameterValue: aws.String("10.0.0.0/8")},
+		{ParameterKey: aws.String("HealthCheckProtocol"), ParameterValue: aws.String("udp")},
+	}
+	paramsMap := GetParametersMap(params)
+	body := ParseTemplateString(template, paramsMap, nil)
+	logicalToPhysical := map[string]string{
+		"WebSecurityGroup":      "sg-web",
+		"AppSecurityGroup":      "sg-app",
+		"DatabaseSecurityGroup": "sg-db",
+	}
+	tests := []struct {
+		name      string
+		logicalId string
+		want      []string
+	}{
+		{"Inline rules with default egress", "WebSecurityGroup", []string{
+			"egress -1 -1--1 0.0.0.0/0",
+			"ingress tcp 443-443 10.0.0.0/8",
+			"ingress udp 8443-8443 10.0.0.0/8",
+		}},
+		{"Inline egress and separate ingress rules", "AppSecurityGroup", []string{
+			"egress tcp 5432-5432 sg-db",
+			"ingress -1 -1--1 ::/0",
+			"ingress tcp 8080-8080 sg-web",
+		}},
+		{"Unknown security group", "Missing", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, complete := FilterSecurityGroupRulesByLogicalId(tt.logicalId, body, params, logicalToPhysical)
+			if !complete {
+				t.Errorf("FilterSecurityGroupRulesByLogicalId() complete = false, want true")
+			}
+			keys := make([]string, 0, len(got))
+			for key := range got {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("FilterSecurityGroupRulesByLogicalId() = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+	if got := body.Resources["WebSecurityGroup"]; got.Type == "" {
+		t.Fatal("template wasn't parsed")
+	}
+	rules, _ := FilterSecurityGroupRulesByLogicalId("WebSecurityGroup", body, params, logicalToPhysical)
+	if rules["ingress tcp 443-443 10.0.0.0/8"].Description != "HTTPS" {
+		t.Errorf("FilterSecurityGroupRulesByLogicalId() didn't keep the description")
+	}
+	// References that weren't resolved while parsing are resolved from the parameters
+	unresolved := ParseTemplateString(template, nil, nil)
+	rules, _ = FilterSecurityGroupRulesByLogicalId("WebSecurityGroup", unresolved, params, logicalToPhysical)
+	if _, ok := rules["ingress udp 8443-8443 REF: AllowedCidr"]; !ok {
+		t.Errorf("FilterSecurityGroupRulesByLogicalId() didn't resolve the protocol from the parameters: %v", rules)
+	}
+}
+
+func TestFilterSecurityGroupRulesByLogicalId_GetAtt(t *testing.T) {
+	template := `Resources:
+  SecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: Test
+  Ingress:
+    Type: AWS::EC2::SecurityGroupIngress
+    Properties:
+      GroupId: !GetAtt SecurityGroup.GroupId
+      IpProtocol: tcp
+      FromPort: 22
+      ToPort: 22
+      CidrIp: 10.0.0.0/8
+`
+	body := ParseTemplateString(template, nil, nil)
+	_, complete := FilterSecurityGroupRulesByLogicalId("SecurityGroup", body, nil, map[string]string{})
+	if complete {
+		t.Errorf("FilterSecurityGroupRulesByLogicalId() complete = true, want false for a rule using Fn::GetAtt")
+	}
+}
+
+func TestGetVpcFromTemplate(t *testing.T) {
+	template := `
+Parameters:
+  VpcCidr:
+    Type: String
+    Default: 10.0.0.0/16
+  Tenancy:
+    Type: String
+Resources:
+  Vpc:
+    Type: AWS::EC2::VPC
+    Properties:
+      CidrBlock: !Ref VpcCidr
+      EnableDnsHostnames: true
+      InstanceTenancy: !Ref Tenancy
+  Ipv6Block:
+    Type: AWS::EC2::VPCCidrBlock
+    Properties:
+      VpcId: !Ref Vpc
+      AmazonProvidedIpv6CidrBlock: true
+  ExplicitIpv6Block:
+    Type: AWS::EC2::VPCCidrBlock
+    Properties:
+      VpcId: !Ref Vpc
+      Ipv6CidrBlock: 2001:db8::/56
+  OtherVpc:
+    Type: AWS::EC2::VPC
+    Properties:
+      CidrBlock: 10.1.0.0/16
+      EnableDnsSupport: "false"
+`
+	params := map[string]interface{}{"VpcCidr": "10.10.0.0/16", "Tenancy": "dedicated"}
+	parsed := ParseTemplateString(template, &params, nil)
+	tests := []struct {
+		name      string
+		logicalId string
+		want      VpcConfig
+	}{
+		{"VPC with parameters and IPv6", "Vpc", VpcConfig{
+			CidrBlock:          "10.10.0.0/16",
+			EnableDNSSupport:   true,
+			EnableDNSHostnames: true,
+			InstanceTenancy:    "dedicated",
+			IPv6CIDRBlocks:     []string{"2001:db8::/56"},
+			AmazonProvidedIPv6: true,
+		}},
+		{"VPC with defaults", "OtherVpc", VpcConfig{
+			CidrBlock:          "10.1.0.0/16",
+			EnableDNSSupport:   false,
+			EnableDNSHostnames: false,
+			InstanceTenancy:    "default",
+			IPv6CIDRBlocks:     []string{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetVpcFromTemplate(tt.logicalId, parsed, params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetVpcFromTemplate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// MockExportsClient returns the exports in pages of the provided size
+type MockExportsClient struct {
+	Exports  []types.Export
+	PageSize int
+	Calls    int
+	Err      error
+}
+
+func (m *MockExportsClient) ListExports(ctx context.Context, params *cloudformation.ListExportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListExportsOutput, error) {
+	m.Calls++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	start := 0
+	if params.NextToken != nil {
+		start, _ = strconv.Atoi(*params.NextToken)
+	}
+	end := start + m.PageSize
+	if end >= len(m.Exports) {
+		return &cloudformation.ListExportsOutput{Exports: m.Exports[start:]}, nil
+	}
+	return &cloudformation.ListExportsOutput{Exports: m.Exports[start:end], NextToken: aws.String(strconv.Itoa(end))}, nil
+}
+
+func TestResolveImportValue(t *testing.T) {
+	svc := &MockExportsClient{PageSize: 2, Exports: []types.Export{
+		{Name: aws.String("network-VpcId"), Value: aws.String("vpc-123")},
+		{Name: aws.String("network-SubnetA"), Value: aws.String("subnet-a")},
+		{Name: aws.String("network-TgwId"), Value: aws.String("tgw-123")},
+	}}
+	tests := []struct {
+		name       string
+		exportName string
+		want       string
+		wantErr    bool
+	}{
+		{"First page", "network-VpcId", "vpc-123", false},
+		{"Later page", "network-TgwId", "tgw-123", false},
+		{"Missing export", "network-Missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveImportValue(tt.exportName, svc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveImportValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveImportValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateString_ImportValue(t *testing.T) {
+	template := `
+Resources:
+  Route:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 10.0.0.0/8
+      TransitGatewayId: !ImportValue network-TgwId
+  OtherRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 172.16.0.0/12
+      TransitGatewayId: !ImportValue network-TgwId
+`
+	exports := map[string]types.Export{
+		"network-TgwId": {Name: aws.String("network-TgwId"), Value: aws.String("tgw-123")},
+	}
+	parsed := ParseTemplateString(template, nil, exports)
+	for _, name := range []string{"Route", "OtherRoute"} {
+		if got := parsed.Resources[name].Properties["TransitGatewayId"]; got != "tgw-123" {
+			t.Errorf("TransitGatewayId of %v = %v, want tgw-123", name, got)
+		}
+	}
+	if !reflect.DeepEqual(parsed.ImportedExports, []string{"network-TgwId"}) {
+		t.Errorf("ImportedExports = %v, want [network-TgwId]", parsed.ImportedExports)
+	}
+	// Without exports, goformation's default handler leaves the imports empty
+	unresolved := ParseTemplateString(template, nil, nil)
+	if got := unresolved.Resources["Route"].Properties["TransitGatewayId"]; got != nil {
+		t.Errorf("TransitGatewayId without exports = %v, want nil", got)
+	}
+	if len(unresolved.UnresolvedImports) != 0 {
+		t.Errorf("UnresolvedImports without exports = %v, want none", unresolved.UnresolvedImports)
+	}
+	// When none of the exports exist, the imports are reported as unresolved
+	missing := ParseTemplateString(template, nil, map[string]types.Export{})
+	if !reflect.DeepEqual(missing.UnresolvedImports, []string{"network-TgwId"}) {
+		t.Errorf("UnresolvedImports without matching exports = %v, want [network-TgwId]", missing.UnresolvedImports)
+	}
+}
+
+func TestParseTemplateString_ImportValueConversions(t *testing.T) {
+	template := `
+Resources:
+  TgwRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref RouteTable
+      DestinationCidrBlock: !ImportValue network-OnPremCidr
+      TransitGatewayId: !ImportValue network-TgwId
+  MissingRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 172.16.0.0/12
+      TransitGatewayId: !ImportValue network-Missing
+  OtherMissingRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 192.168.0.0/16
+      TransitGatewayId: !ImportValue network-Missing
+  InboundRule:
+    Type: AWS::EC2::NetworkAclEntry
+    Properties:
+      NetworkAclId: !Ref Acl
+      CidrBlock: !ImportValue network-OnPremCidr
+      Egress: false
+      Protocol: -1
+      RuleAction: allow
+      RuleNumber: 100
+`
+	exports := map[string]types.Export{
+		"network-TgwId":      {Name: aws.String("network-TgwId"), Value: aws.String("tgw-123")},
+		"network-OnPremCidr": {Name: aws.String("network-OnPremCidr"), Value: aws.String("10.0.0.0/8")},
+	}
+	parsed := ParseTemplateString(template, nil, exports)
+
+	route := RouteResourceToRoute(parsed.Resources["TgwRoute"], nil, map[string]string{})
+	if aws.ToString(route.TransitGatewayId) != "tgw-123" || aws.ToString(route.DestinationCidrBlock) != "10.0.0.0/8" {
+		t.Errorf("RouteResourceToRoute() = %v via %v, want 10.0.0.0/8 via tgw-123", aws.ToString(route.DestinationCidrBlock), aws.ToString(route.TransitGatewayId))
+	}
+	entry := NaclResourceToNaclEntry(parsed.Resources["InboundRule"], nil)
+	if aws.ToString(entry.CidrBlock) != "10.0.0.0/8" {
+		t.Errorf("NaclResourceToNaclEntry() CidrBlock = %v, want 10.0.0.0/8", aws.ToString(entry.CidrBlock))
+	}
+	missing := RouteResourceToRoute(parsed.Resources["MissingRoute"], nil, map[string]string{})
+	if missing.TransitGatewayId != nil {
+		t.Errorf("RouteResourceToRoute() with a missing export TransitGatewayId = %v, want nil", aws.ToString(missing.TransitGatewayId))
+	}
+	if !reflect.DeepEqual(parsed.UnresolvedImports, []string{"network-Missing"}) {
+		t.Errorf("UnresolvedImports = %v, want [network-Missing]", parsed.UnresolvedImports)
+	}
+}
+
+func TestGetResourcePolicyFromTemplate(t *testing.T) {
+	template := `
+Parameters:
+  PrincipalArn:
+    Type: String
+Resources:
+  BucketPolicy:
+    Type: AWS::S3::BucketPolicy
+    Properties:
+      Bucket: my-bucket
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal:
+              AWS: !Ref PrincipalArn
+            Action: s3:GetObject
+            Resource: arn:aws:s3:::my-bucket/*
+  TopicPolicy:
+    Type: AWS::SNS::TopicPolicy
+    Properties:
+      Topics:
+        - !Ref Topic
+      PolicyDocument: '{"Version": "2012-10-17", "Statement": []}'
+  Topic:
+    Type: AWS::SNS::Topic
+`
+	params := map[string]interface{}{"PrincipalArn": "arn:aws:iam::123456789012:role/reader"}
+	parsed := ParseTemplateString(template, &params, nil)
+	tests := []struct {
+		name      string
+		logicalId string
+		want      string
+		wantOk    bool
+	}{
+		{"Bucket policy with parameter", "BucketPolicy", `{
+  "Statement": [
+    {
+      "Action": "s3:GetObject",
+      "Effect": "Allow",
+      "Principal": {
+        "AWS": "arn:aws:iam::123456789012:role/reader"
+      },
+      "Resource": "arn:aws:s3:::my-bucket/*"
+    }
+  ],
+  "Version": "2012-10-17"
+}`, true},
+		{"Policy as JSON string", "TopicPolicy", `{
+  "Statement": [],
+  "Version": "2012-10-17"
+}`, true},
+		{"Resource without policy", "Topic", "", false},
+		{"Unknown resource", "Missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := GetResourcePolicyFromTemplate(tt.logicalId, parsed, params)
+			if ok != tt.wantOk {
+				t.Fatalf("GetResourcePolicyFromTemplate() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("GetResourcePolicyFromTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTGWRoutesByLogicalId(t *testing.T) {
+	template := `
+Resources:
+  RouteTable:
+    Type: AWS::EC2::TransitGatewayRouteTable
+  Attachment:
+    Type: AWS::EC2::TransitGatewayAttachment
+  AttachedRoute:
+    Type: AWS::EC2::TransitGatewayRoute
+    Properties:
+      TransitGatewayRouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 10.0.0.0/16
+      TransitGatewayAttachmentId: !Ref Attachment
+  BlackholeRoute:
+    Type: AWS::EC2::TransitGatewayRoute
+    Properties:
+      TransitGatewayRouteTableId: !Ref RouteTable
+      DestinationCidrBlock: 10.1.0.0/16
+      Blackhole: true
+  OtherRoute:
+    Type: AWS::EC2::TransitGatewayRoute
+    Properties:
+      TransitGatewayRouteTableId: tgw-rtb-other
+      DestinationCidrBlock: 10.2.0.0/16
+      TransitGatewayAttachmentId: tgw-attach-other
+`
+	params := map[string]interface{}{}
+	parsed := ParseTemplateString(template, &params, nil)
+	logicalToPhysical := map[string]string{"RouteTable": "tgw-rtb-123", "Attachment": "tgw-attach-123"}
+	got := FilterTGWRoutesByLogicalId("RouteTable", parsed, nil, logicalToPhysical)
+	if len(got) != 2 {
+		t.Fatalf("FilterTGWRoutesByLogicalId() returned %d routes, want 2", len(got))
+	}
+	if target := GetTransitGatewayRouteTarget(got["10.0.0.0/16"]); target != "tgw-attach-123" {
+		t.Errorf("attached route has target %v, want tgw-attach-123", target)
+	}
+	if got["10.1.0.0/16"].State != ec2types.TransitGatewayRouteStateBlackhole {
+		t.Errorf("blackhole route has state %v", got["10.1.0.0/16"].State)
+	}
+}
+
+func TestGetSubnetFromTemplate(t *testing.T) {
+	template := `
+Parameters:
+  SubnetCidr:
+    Type: String
+Resources:
+  PublicSubnet:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: vpc-123
+      CidrBlock: !Ref SubnetCidr
+      AvailabilityZone: ap-southeast-2a
+      MapPublicIpOnLaunch: true
+      Tags:
+        - Key: Name
+          Value: public
+        - Key: Tier
+          Value: !Ref SubnetCidr
+  PrivateSubnet:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: vpc-123
+      CidrBlock: 10.0.1.0/24
+`
+	params := map[string]interface{}{"SubnetCidr": "10.0.0.0/24"}
+	parsed := ParseTemplateString(template, &params, nil)
+	tests := []struct {
+		name      string
+		logicalId string
+		want      SubnetConfig
+	}{
+		{"Public subnet with parameters and tags", "PublicSubnet", SubnetConfig{
+			CidrBlock:           "10.0.0.0/24",
+			AvailabilityZone:    "ap-southeast-2a",
+			MapPublicIPOnLaunch: true,
+			Tags:                map[string]string{"Name": "public", "Tier": "10.0.0.0/24"},
+		}},
+		{"Private subnet with defaults", "PrivateSubnet", SubnetConfig{
+			CidrBlock: "10.0.1.0/24",
+			Tags:      map[string]string{},
+		}},
+		{"Unknown resource", "Missing", SubnetConfig{Tags: map[string]string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetSubnetFromTemplate(tt.logicalId, parsed, params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSubnetFromTemplate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := map[string]struct {
+		body        string
+		url         string
+		validateErr error
+		wantBody    bool
+		wantErr     bool
+	}{
+		"body":    {body: "Resources: {}", wantBody: true},
+		"url":     {body: "Resources: {}", url: "https://bucket.s3.amazonaws.com/fog/template"},
+		"invalid": {body: "Resources: [", validateErr: errors.New("Template format error"), wantBody: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received *cloudformation.ValidateTemplateInput
+			svc := &testutil.MockCFNClient{
+				ValidateTemplateFunc: func(params *cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error) {
+					received = params
+					if tc.validateErr != nil {
+						return nil, tc.validateErr
+					}
+					return &cloudformation.ValidateTemplateOutput{Description: aws.String("test")}, nil
+				},
+			}
+			_, err := ValidateTemplate(tc.body, tc.url, svc)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateTemplate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantBody && (received.TemplateBody == nil || received.TemplateURL != nil) {
+				t.Errorf("ValidateTemplate() expected the template body to be sent, got %+v", received)
+			}
+			if !tc.wantBody && (received.TemplateURL == nil || received.TemplateBody != nil) {
+				t.Errorf("ValidateTemplate() expected the template URL to be sent, got %+v", received)
+			}
+		})
+	}
+}
+
+func TestCfnTemplateParameter_UnmarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		check   func(CfnTemplateParameter) bool
+		wantErr bool
+	}{
+		"numeric constraints": {
+			input: `{"Type": "Number", "MinValue": 1, "MaxValue": 10.5}`,
+			check: func(p CfnTemplateParameter) bool {
+				return *p.MinValue == 1 && *p.MaxValue == 10.5 && p.MinLength == nil && p.MaxLength == nil
+			},
+		},
+		"string constraints": {
+			input: `{"Type": "String", "MinLength": "3", "MaxLength": 64, "AllowedPattern": "[a-z]+", "NoEcho": "true"}`,
+			check: func(p CfnTemplateParameter) bool {
+				return *p.MinLength == 3 && *p.MaxLength == 64 && p.AllowedPattern == "[a-z]+" && p.NoEcho
+			},
+		},
+		"zero values are kept": {
+			input: `{"Type": "Number", "MinValue": 0}`,
+			check: func(p CfnTemplateParameter) bool {
+				return p.MinValue != nil && *p.MinValue == 0 && p.MaxValue == nil
+			},
+		},
+		"allowed values": {
+			input: `{"Type": "String", "AllowedValues": ["dev", "prod"], "Default": "dev", "NoEcho": true}`,
+			check: func(p CfnTemplateParameter) bool {
+				return len(p.AllowedValues) == 2 && p.Default == "dev" && p.NoEcho
+			},
+		},
+		"invalid constraint": {
+			input:   `{"Type": "String", "MaxLength": "many"}`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var parameter CfnTemplateParameter
+			err := json.Unmarshal([]byte(tc.input), &parameter)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !tc.check(parameter) {
+				t.Errorf("UnmarshalJSON() = %+v", parameter)
+			}
+		})
+	}
+}
+
+func TestParseTemplateParameters(t *testing.T) {
+	template := `Parameters:
+  Environment:
+    Type: String
+    AllowedValues: [dev, prod]
+  Count:
+    Type: Number
+    MaxValue: 3
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${Environment}-bucket"
+`
+	parameters, err := ParseTemplateParameters(template)
+	if err != nil {
+		t.Fatalf("ParseTemplateParameters() error = %v", err)
+	}
+	if len(parameters) != 2 || parameters["Environment"].Type != "String" || *parameters["Count"].MaxValue != 3 {
+		t.Errorf("ParseTemplateParameters() = %+v", parameters)
+	}
+	empty, err := ParseTemplateParameters("Resources: {}")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ParseTemplateParameters() without parameters = %v, %v", empty, err)
+	}
+}
+
+func TestFilterRequiredParameters(t *testing.T) {
+	templates := map[string]string{
+		"YAML": `Parameters:
+  Environment:
+    Type: String
+    AllowedValues: [dev, prod]
+  Count:
+    Type: Number
+    Default: 1
+  Suffix:
+    Type: String
+    Default: ""
+Resources: {}
+`,
+		"JSON": `{
+  "Parameters": {
+    "Environment": {"Type": "String", "AllowedValues": ["dev", "prod"]},
+    "Count": {"Type": "Number", "Default": 1},
+    "Suffix": {"Type": "String", "Default": ""}
+  },
+  "Resources": {}
+}`,
+	}
+	for name, template := range templates {
+		t.Run(name, func(t *testing.T) {
+			parameters, err := ParseTemplateParameters(template)
+			if err != nil {
+				t.Fatalf("ParseTemplateParameters() error = %v", err)
+			}
+			required := FilterRequiredParameters(parameters)
+			if len(required) != 1 {
+				t.Fatalf("FilterRequiredParameters() = %v, want only Environment", required)
+			}
+			if _, ok := required["Environment"]; !ok {
+				t.Errorf("FilterRequiredParameters() = %v, want only Environment", required)
+			}
+		})
+	}
+}
+
+func TestGetStackCostEstimate(t *testing.T) {
+	var received *cloudformation.EstimateTemplateCostInput
+	svc := &testutil.MockCFNClient{
+		EstimateTemplateCostFunc: func(params *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+			received = params
+			return &cloudformation.EstimateTemplateCostOutput{Url: aws.String("https://calculator.s3.amazonaws.com/calc5.html?key=abc")}, nil
+		},
+	}
+	params := []types.Parameter{
+		{ParameterKey: aws.String("InstanceType"), ParameterValue: aws.String("t3.micro")},
+		{ParameterKey: aws.String("Secret"), UsePreviousValue: aws.Bool(true)},
+	}
+	url, err := GetStackCostEstimate("Resources: {}", "", params, svc)
+	if err != nil {
+		t.Fatalf("GetStackCostEstimate() error = %v", err)
+	}
+	if url != "https://calculator.s3.amazonaws.com/calc5.html?key=abc" {
+		t.Errorf("GetStackCostEstimate() = %v, want the calculator URL", url)
+	}
+	if aws.ToString(received.TemplateBody) != "Resources: {}" || received.TemplateURL != nil {
+		t.Errorf("GetStackCostEstimate() TemplateBody = %v, want the template", aws.ToString(received.TemplateBody))
+	}
+	if !reflect.DeepEqual(received.Parameters, params) {
+		t.Errorf("GetStackCostEstimate() Parameters = %v, want %v", received.Parameters, params)
+	}
+
+	if _, err := GetStackCostEstimate("Resources: {}", "https://bucket.s3.us-east-1.amazonaws.com/fog/template", nil, svc); err != nil {
+		t.Fatalf("GetStackCostEstimate() error = %v", err)
+	}
+	if aws.ToString(received.TemplateURL) != "https://bucket.s3.us-east-1.amazonaws.com/fog/template" || received.TemplateBody != nil {
+		t.Errorf("GetStackCostEstimate() TemplateURL = %v, want the URL instead of the body", aws.ToString(received.TemplateURL))
+	}
+
+	svc.EstimateTemplateCostFunc = func(params *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+		return nil, errors.New("Template format error")
+	}
+	if _, err := GetStackCostEstimate("not a template", "", nil, svc); err == nil {
+		t.Error("GetStackCostEstimate() should return the error of the API")
+	}
+}
@@ -0,0 +1,175 @@
+// Package testutil contains helpers for building test scenarios against the
+// CloudFormation API.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// MockCFNClient is an in-memory CloudFormation client that returns the
+// configured stacks and imports
+type MockCFNClient struct {
+	Stacks []types.Stack
+	// Imports maps export names to the names of the stacks that import them
+	Imports map[string][]string
+	// StatusSequences maps stack names to the statuses the stack goes through.
+	// Every time the stack is described by name or ID, the next status is
+	// used, and the last status is kept once the sequence is exhausted.
+	StatusSequences map[string][]types.StackStatus
+	// DeletedStacks contains the names of the stacks DeleteStack was called for
+	DeletedStacks []string
+	// DeleteStackErr is returned by DeleteStack when set
+	DeleteStackErr error
+	// ContinuedRollbacks contains the inputs ContinueUpdateRollback was called with
+	ContinuedRollbacks []cloudformation.ContinueUpdateRollbackInput
+	// CancelledUpdates contains the names or IDs of the stacks CancelUpdateStack was called for
+	CancelledUpdates []string
+	// ValidateTemplateFunc handles ValidateTemplate calls, when it isn't set
+	// every template is considered valid
+	ValidateTemplateFunc func(params *cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error)
+	// EstimateTemplateCostFunc handles EstimateTemplateCost calls, when it
+	// isn't set a calculator URL without any resources is returned
+	EstimateTemplateCostFunc func(params *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error)
+	// The change set errors are returned by the matching change set operation
+	// when set, so tests can control which stage of a deployment fails
+	CreateChangesetErr   error
+	DescribeChangesetErr error
+	ExecuteChangesetErr  error
+	DeleteChangesetErr   error
+}
+
+// WithCreateChangesetError makes CreateChangeSet return the error
+func (m *MockCFNClient) WithCreateChangesetError(err error) *MockCFNClient {
+	m.CreateChangesetErr = err
+	return m
+}
+
+// WithDescribeChangesetError makes DescribeChangeSet return the error
+func (m *MockCFNClient) WithDescribeChangesetError(err error) *MockCFNClient {
+	m.DescribeChangesetErr = err
+	return m
+}
+
+// WithExecuteChangesetError makes ExecuteChangeSet return the error
+func (m *MockCFNClient) WithExecuteChangesetError(err error) *MockCFNClient {
+	m.ExecuteChangesetErr = err
+	return m
+}
+
+// WithDeleteChangesetError makes DeleteChangeSet return the error
+func (m *MockCFNClient) WithDeleteChangesetError(err error) *MockCFNClient {
+	m.DeleteChangesetErr = err
+	return m
+}
+
+// DescribeStacks returns all stacks, or only the stack matching the name or ID
+// when a StackName is provided
+func (m *MockCFNClient) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	if params.StackName == nil {
+		return &cloudformation.DescribeStacksOutput{Stacks: m.Stacks}, nil
+	}
+	for index, stack := range m.Stacks {
+		if aws.ToString(stack.StackName) == *params.StackName || aws.ToString(stack.StackId) == *params.StackName {
+			name := aws.ToString(stack.StackName)
+			if sequence := m.StatusSequences[name]; len(sequence) > 0 {
+				m.Stacks[index].StackStatus = sequence[0]
+				stack.StackStatus = sequence[0]
+				m.StatusSequences[name] = sequence[1:]
+			}
+			return &cloudformation.DescribeStacksOutput{Stacks: []types.Stack{stack}}, nil
+		}
+	}
+	return nil, fmt.Errorf("Stack with id %v does not exist", *params.StackName)
+}
+
+// ListImports returns the stacks importing the export. Like the actual API, it
+// returns an error if the export isn't imported by any stack.
+func (m *MockCFNClient) ListImports(ctx context.Context, params *cloudformation.ListImportsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListImportsOutput, error) {
+	imports, ok := m.Imports[aws.ToString(params.ExportName)]
+	if !ok || len(imports) == 0 {
+		return nil, fmt.Errorf("Export '%v' is not imported by any stack.", aws.ToString(params.ExportName))
+	}
+	return &cloudformation.ListImportsOutput{Imports: imports}, nil
+}
+
+// ValidateTemplate passes the input to ValidateTemplateFunc
+func (m *MockCFNClient) ValidateTemplate(ctx context.Context, params *cloudformation.ValidateTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ValidateTemplateOutput, error) {
+	if m.ValidateTemplateFunc == nil {
+		return &cloudformation.ValidateTemplateOutput{}, nil
+	}
+	return m.ValidateTemplateFunc(params)
+}
+
+// EstimateTemplateCost passes the input to EstimateTemplateCostFunc
+func (m *MockCFNClient) EstimateTemplateCost(ctx context.Context, params *cloudformation.EstimateTemplateCostInput, optFns ...func(*cloudformation.Options)) (*cloudformation.EstimateTemplateCostOutput, error) {
+	if m.EstimateTemplateCostFunc == nil {
+		return &cloudformation.EstimateTemplateCostOutput{Url: aws.String("https://calculator.s3.amazonaws.com/calc5.html")}, nil
+	}
+	return m.EstimateTemplateCostFunc(params)
+}
+
+// DeleteStack records the deletion of the stack
+func (m *MockCFNClient) DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error) {
+	if m.DeleteStackErr != nil {
+		return nil, m.DeleteStackErr
+	}
+	m.DeletedStacks = append(m.DeletedStacks, aws.ToString(params.StackName))
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+// ContinueUpdateRollback records the request to continue the rollback
+func (m *MockCFNClient) ContinueUpdateRollback(ctx context.Context, params *cloudformation.ContinueUpdateRollbackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ContinueUpdateRollbackOutput, error) {
+	m.ContinuedRollbacks = append(m.ContinuedRollbacks, *params)
+	return &cloudformation.ContinueUpdateRollbackOutput{}, nil
+}
+
+// CancelUpdateStack records the cancellation of the stack update
+func (m *MockCFNClient) CancelUpdateStack(ctx context.Context, params *cloudformation.CancelUpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CancelUpdateStackOutput, error) {
+	m.CancelledUpdates = append(m.CancelledUpdates, aws.ToString(params.StackName))
+	return &cloudformation.CancelUpdateStackOutput{}, nil
+}
+
+// CreateChangeSet returns an ID based on the name of the change set
+func (m *MockCFNClient) CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	if m.CreateChangesetErr != nil {
+		return nil, m.CreateChangesetErr
+	}
+	return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset/" + aws.ToString(params.ChangeSetName))}, nil
+}
+
+// DescribeChangeSet returns a completed change set without any changes
+func (m *MockCFNClient) DescribeChangeSet(ctx context.Context, params *cloudformation.DescribeChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error) {
+	if m.DescribeChangesetErr != nil {
+		return nil, m.DescribeChangesetErr
+	}
+	return &cloudformation.DescribeChangeSetOutput{
+		ChangeSetId:   aws.String("arn:changeset/" + aws.ToString(params.ChangeSetName)),
+		ChangeSetName: params.ChangeSetName,
+		StackId:       aws.String("arn:stack/" + aws.ToString(params.StackName)),
+		StackName:     params.StackName,
+		Status:        types.ChangeSetStatusCreateComplete,
+		CreationTime:  aws.Time(time.Now()),
+	}, nil
+}
+
+// ExecuteChangeSet accepts the execution of the change set
+func (m *MockCFNClient) ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	if m.ExecuteChangesetErr != nil {
+		return nil, m.ExecuteChangesetErr
+	}
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+// DeleteChangeSet accepts the deletion of the change set
+func (m *MockCFNClient) DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+	if m.DeleteChangesetErr != nil {
+		return nil, m.DeleteChangesetErr
+	}
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
@@ -0,0 +1,35 @@
+package testutil
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MockS3Client is an in-memory S3 client that stores the uploaded objects
+type MockS3Client struct {
+	// Objects maps bucket/key to the contents of the uploaded objects
+	Objects map[string][]byte
+	// PutObjectErr is returned by PutObject when set
+	PutObjectErr error
+}
+
+// NewMockS3Client returns a MockS3Client without any objects
+func NewMockS3Client() *MockS3Client {
+	return &MockS3Client{Objects: make(map[string][]byte)}
+}
+
+// PutObject stores the contents of the object under bucket/key
+func (m *MockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if m.PutObjectErr != nil {
+		return nil, m.PutObjectErr
+	}
+	contents, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.Objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = contents
+	return &s3.PutObjectOutput{}, nil
+}
@@ -0,0 +1,126 @@
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// StackBuilder configures a single stack in a scenario
+type StackBuilder struct {
+	stack types.Stack
+}
+
+// WithStatus sets the status of the stack
+func (b *StackBuilder) WithStatus(status types.StackStatus) *StackBuilder {
+	b.stack.StackStatus = status
+	return b
+}
+
+// WithDriftStatus sets the drift status of the stack, as stored by the last drift detection
+func (b *StackBuilder) WithDriftStatus(status types.StackDriftStatus) *StackBuilder {
+	b.stack.DriftInformation = &types.StackDriftInformation{StackDriftStatus: status}
+	return b
+}
+
+// WithDescription sets the description of the stack
+func (b *StackBuilder) WithDescription(description string) *StackBuilder {
+	b.stack.Description = aws.String(description)
+	return b
+}
+
+// WithParameter adds a parameter to the stack
+func (b *StackBuilder) WithParameter(key, value string) *StackBuilder {
+	b.stack.Parameters = append(b.stack.Parameters, types.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value)})
+	return b
+}
+
+// WithTag adds a tag to the stack
+func (b *StackBuilder) WithTag(key, value string) *StackBuilder {
+	b.stack.Tags = append(b.stack.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	return b
+}
+
+// WithOutput adds an output without an export to the stack
+func (b *StackBuilder) WithOutput(key, value string) *StackBuilder {
+	b.stack.Outputs = append(b.stack.Outputs, types.Output{OutputKey: aws.String(key), OutputValue: aws.String(value)})
+	return b
+}
+
+// WithExport adds an exported output to the stack
+func (b *StackBuilder) WithExport(key, exportName, value string) *StackBuilder {
+	for _, output := range b.stack.Outputs {
+		if aws.ToString(output.ExportName) == exportName {
+			return b
+		}
+	}
+	b.stack.Outputs = append(b.stack.Outputs, types.Output{OutputKey: aws.String(key), OutputValue: aws.String(value), ExportName: aws.String(exportName)})
+	return b
+}
+
+// ScenarioBuilder builds a MockCFNClient containing multiple stacks and the
+// relationships between them
+type ScenarioBuilder struct {
+	stacks  []*StackBuilder
+	imports map[string][]string
+}
+
+// NewScenarioBuilder returns an empty scenario
+func NewScenarioBuilder() *ScenarioBuilder {
+	return &ScenarioBuilder{imports: make(map[string][]string)}
+}
+
+// WithStack adds a stack to the scenario, or updates it if it already exists,
+// and lets f configure it
+func (s *ScenarioBuilder) WithStack(name string, f func(*StackBuilder)) *ScenarioBuilder {
+	builder := s.getStack(name)
+	if f != nil {
+		f(builder)
+	}
+	return s
+}
+
+// WithImport exports the value from the exporting stack and marks it as
+// imported by the importing stack. Stacks that don't exist yet are added.
+func (s *ScenarioBuilder) WithImport(exportingStack, importingStack, exportName, value string) *ScenarioBuilder {
+	s.getStack(exportingStack).WithExport(exportName, exportName, value)
+	s.getStack(importingStack)
+	for _, existing := range s.imports[exportName] {
+		if existing == importingStack {
+			return s
+		}
+	}
+	s.imports[exportName] = append(s.imports[exportName], importingStack)
+	return s
+}
+
+// Build returns a MockCFNClient with all the stacks and imports of the scenario
+func (s *ScenarioBuilder) Build() *MockCFNClient {
+	client := &MockCFNClient{
+		Stacks:  make([]types.Stack, 0, len(s.stacks)),
+		Imports: make(map[string][]string),
+	}
+	for _, builder := range s.stacks {
+		client.Stacks = append(client.Stacks, builder.stack)
+	}
+	for exportName, importers := range s.imports {
+		client.Imports[exportName] = append([]string{}, importers...)
+	}
+	return client
+}
+
+func (s *ScenarioBuilder) getStack(name string) *StackBuilder {
+	for _, builder := range s.stacks {
+		if aws.ToString(builder.stack.StackName) == name {
+			return builder
+		}
+	}
+	builder := &StackBuilder{stack: types.Stack{
+		StackName:   aws.String(name),
+		StackId:     aws.String(fmt.Sprintf("arn:aws:cloudformation:us-east-1:123456789012:stack/%v/%08d", name, len(s.stacks)+1)),
+		StackStatus: types.StackStatusCreateComplete,
+	}}
+	s.stacks = append(s.stacks, builder)
+	return builder
+}
@@ -19,6 +19,8 @@ const (
 	DeployChangesetMessageNoResourceChanges DeployChangesetMessage = "No changes to resources have been found, but there are still changes to other parts of the stack"
 	DeployChangesetMessageChanges           DeployChangesetMessage = "Changes found in change set"
 	DeployChangesetMessageWillDelete        DeployChangesetMessage = "OK. I will now delete this change set for you."
+	DeployChangesetMessageSkipDestroy       DeployChangesetMessage = "The change set removes %v resource(s), which isn't allowed when using --skip-destroy"
+	DeployChangesetMessageHighRisk          DeployChangesetMessage = "The change set removes or replaces resources, which isn't allowed when using --block-on-high-risk"
 )
 
 type DeployStackMessage string
@@ -31,6 +33,28 @@ const (
 	DeployStackMessageSuccess               DeployStackMessage = "Deployment completed successfully."
 	DeployStackMessageFailed                DeployStackMessage = "The deployment had a problem, please look at the error messages below to figure out what happened."
 	DeployStackMessageRetrievePostFailed    DeployStackMessage = "Something went wrong when I tried to fetch the stack after the deployment."
+	DeployStackMessageMissingTags           DeployStackMessage = "The following required tags are missing or empty: %v"
+)
+
+type DeployTemplateDiffMessage string
+
+const (
+	DeployTemplateDiffHeader          DeployTemplateDiffMessage = "Changes to the template compared to the deployed version:"
+	DeployTemplateDiffNoChanges       DeployTemplateDiffMessage = "The template is identical to the deployed version"
+	DeployTemplateDiffRetrieveFailed  DeployTemplateDiffMessage = "Something went wrong trying to retrieve the deployed template"
+	DeployTemplateDiffContinueConfirm DeployTemplateDiffMessage = "Do you want to continue and create a change set?"
+)
+
+type DeployApprovalMessage string
+
+const (
+	DeployApprovalMessageNoAPI         DeployApprovalMessage = "An approval API needs to be provided with --approval-api when requiring change set approval"
+	DeployApprovalMessageNoAPIKey      DeployApprovalMessage = "The environment variable %v that should contain the key for the approval API isn't set"
+	DeployApprovalMessageRequestFailed DeployApprovalMessage = "Something went wrong when trying to request approval for the change set"
+	DeployApprovalMessageWaiting       DeployApprovalMessage = "Waiting for change request %v to be approved"
+	DeployApprovalMessageApproved      DeployApprovalMessage = "Change request %v has been approved"
+	DeployApprovalMessageRejected      DeployApprovalMessage = "Change request %v has been rejected"
+	DeployApprovalMessageNotApproved   DeployApprovalMessage = "The change set wasn't approved"
 )
 
 type FileMessage string